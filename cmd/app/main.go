@@ -3,9 +3,21 @@ package main
 import (
 	"context"
 	"log"
+	"math"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/LiFeAiR/crud-ai/internal/handlers"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/passwordpolicy"
 	"github.com/LiFeAiR/crud-ai/internal/server"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -40,15 +52,14 @@ dFwwxhKgBqzgCeIpvKLCGrhMztzPjUL9o4MSdNa92vajcilr8ld6
 )
 
 func main() {
-	log.Fatalf(
-		"Failed to serve and listen: %s",
-		run().Error(),
-	)
+	if err := run(); err != nil {
+		log.Fatalf("Failed to serve and listen: %s", err.Error())
+	}
 }
 
 func run() error {
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
+	// SIGINT и SIGTERM запускают штатную остановку сервера
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	// Получаем строку подключения к БД из переменной окружения или используем значение по умолчанию
@@ -62,7 +73,215 @@ func run() error {
 		privateKey = pk
 	}
 
+	utils.SetPasswordHasher(loadPasswordHasher())
+
 	// Create and start the server
-	s := server.NewServer("8080", "2662", dbURL, privateKey)
+	s := server.NewServer("8080", "2662", dbURL, privateKey, loadConfig(), newNotifier())
 	return s.Start(ctx)
 }
+
+// loadConfig читает настройки обработчиков из переменных окружения
+func loadConfig() handlers.Config {
+	cfg := handlers.DefaultConfig()
+
+	throttle := &cfg.LoginThrottle
+	throttle.Window = envDuration("LOGIN_FAILURE_WINDOW", throttle.Window)
+	throttle.DelayAfter = envInt("LOGIN_DELAY_AFTER", throttle.DelayAfter)
+	throttle.BaseDelay = envDuration("LOGIN_BASE_DELAY", throttle.BaseDelay)
+	throttle.MaxDelay = envDuration("LOGIN_MAX_DELAY", throttle.MaxDelay)
+	throttle.MaxAccountFailures = envInt("LOGIN_MAX_ACCOUNT_FAILURES", throttle.MaxAccountFailures)
+	throttle.MaxIPFailures = envInt("LOGIN_MAX_IP_FAILURES", throttle.MaxIPFailures)
+	throttle.LockoutDuration = envDuration("LOGIN_LOCKOUT_DURATION", throttle.LockoutDuration)
+
+	reset := &cfg.PasswordReset
+	reset.TokenTTL = envDuration("PASSWORD_RESET_TTL", reset.TokenTTL)
+	if value := os.Getenv("PASSWORD_RESET_URL"); value != "" {
+		reset.URL = value
+	}
+
+	magicLink := &cfg.MagicLink
+	magicLink.Enabled = envBool("MAGIC_LINK_ENABLED", magicLink.Enabled)
+	magicLink.TokenTTL = envDuration("MAGIC_LINK_TTL", magicLink.TokenTTL)
+	if value := os.Getenv("MAGIC_LINK_URL"); value != "" {
+		magicLink.URL = value
+	}
+
+	verification := &cfg.EmailVerification
+	verification.TokenTTL = envDuration("EMAIL_VERIFICATION_TTL", verification.TokenTTL)
+	if value := os.Getenv("EMAIL_VERIFICATION_URL"); value != "" {
+		verification.URL = value
+	}
+	if value := os.Getenv("EMAIL_VERIFICATION_MODE"); value != "" {
+		verification.Mode = handlers.EmailVerificationMode(value)
+		if !verification.Mode.IsValid() {
+			log.Fatalf("Invalid EMAIL_VERIFICATION_MODE: %s", value)
+		}
+	}
+
+	twoFactor := &cfg.TwoFactor
+	twoFactor.ChallengeTTL = envDuration("TWO_FACTOR_CHALLENGE_TTL", twoFactor.ChallengeTTL)
+	if value := os.Getenv("TWO_FACTOR_ISSUER"); value != "" {
+		twoFactor.Issuer = value
+	}
+
+	apiKeys := &cfg.APIKeys
+	apiKeys.DefaultTTL = envDuration("API_KEY_DEFAULT_TTL", apiKeys.DefaultTTL)
+	apiKeys.MaxTTL = envDuration("API_KEY_MAX_TTL", apiKeys.MaxTTL)
+
+	cfg.Impersonation.TTL = envDuration("IMPERSONATION_TTL", cfg.Impersonation.TTL)
+
+	if value := os.Getenv("OAUTH_AUDIENCE"); value != "" {
+		cfg.OAuth.Audience = value
+	}
+	cfg.OAuth.ExchangeTTL = envDuration("OAUTH_EXCHANGE_TTL", cfg.OAuth.ExchangeTTL)
+	cfg.OAuth.AccessTokenTTL = envDuration("OAUTH_ACCESS_TOKEN_TTL", cfg.OAuth.AccessTokenTTL)
+	cfg.OAuth.CodeTTL = envDuration("OAUTH_CODE_TTL", cfg.OAuth.CodeTTL)
+
+	if value := os.Getenv("OIDC_BASE_URL"); value != "" {
+		cfg.OIDC.BaseURL = value
+	}
+	cfg.OIDC.StateTTL = envDuration("OIDC_STATE_TTL", cfg.OIDC.StateTTL)
+	cfg.OIDC.HTTPTimeout = envDuration("OIDC_HTTP_TIMEOUT", cfg.OIDC.HTTPTimeout)
+
+	cfg.DPoP.ProofMaxAge = envDuration("DPOP_PROOF_MAX_AGE", cfg.DPoP.ProofMaxAge)
+	cfg.DPoP.BaseURL = os.Getenv("DPOP_BASE_URL")
+
+	sessionCookie := &cfg.SessionCookie
+	sessionCookie.Secure = envBool("SESSION_COOKIE_SECURE", sessionCookie.Secure)
+	sessionCookie.Domain = os.Getenv("SESSION_COOKIE_DOMAIN")
+	if value := os.Getenv("SESSION_COOKIE_SAMESITE"); value != "" {
+		sameSite, ok := parseSameSite(value)
+		if !ok {
+			log.Fatalf("Invalid SESSION_COOKIE_SAMESITE: %s", value)
+		}
+		sessionCookie.SameSite = sameSite
+	}
+
+	cfg.Grants.ExpiryInterval = envDuration("GRANT_EXPIRY_INTERVAL", cfg.Grants.ExpiryInterval)
+
+	cfg.Background.MaxTasks = envIntRange("BACKGROUND_MAX_TASKS", cfg.Background.MaxTasks, 0, math.MaxInt32)
+	cfg.Background.ShutdownTimeout = envDuration("SHUTDOWN_TIMEOUT", cfg.Background.ShutdownTimeout)
+
+	policy := &cfg.PasswordPolicy.Policy
+	policy.MinLength = envIntRange("PASSWORD_MIN_LENGTH", policy.MinLength, 0, passwordpolicy.MaxBytes)
+	// Максимальная длина ограничена так же, как в политиках организаций: от 1 до 72 байт
+	// и не меньше минимальной длины, иначе ни один пароль не пройдет проверку
+	policy.MaxBytes = envIntRange(
+		"PASSWORD_MAX_BYTES", policy.MaxBytes, max(policy.MinLength, 1), passwordpolicy.MaxBytes,
+	)
+	policy.RequireUpper = envBool("PASSWORD_REQUIRE_UPPER", policy.RequireUpper)
+	policy.RequireLower = envBool("PASSWORD_REQUIRE_LOWER", policy.RequireLower)
+	policy.RequireDigit = envBool("PASSWORD_REQUIRE_DIGIT", policy.RequireDigit)
+	policy.RequireSymbol = envBool("PASSWORD_REQUIRE_SYMBOL", policy.RequireSymbol)
+	policy.ForbidPersonalInfo = envBool("PASSWORD_FORBID_PERSONAL_INFO", policy.ForbidPersonalInfo)
+	if path := os.Getenv("PASSWORD_BREACHED_LIST"); path != "" {
+		breached, err := passwordpolicy.LoadBreachedList(path)
+		if err != nil {
+			log.Fatalf("Invalid PASSWORD_BREACHED_LIST: %v", err)
+		}
+		log.Printf("Loaded %d breached passwords", breached.Len())
+		cfg.PasswordPolicy.Breached = breached
+	}
+
+	return cfg
+}
+
+// loadPasswordHasher читает настройки хеширования паролей из переменных окружения
+func loadPasswordHasher() utils.PasswordHasher {
+	hasher := utils.DefaultPasswordHasher()
+	if value := os.Getenv("PASSWORD_HASH_ALGORITHM"); value != "" {
+		hasher.Algorithm = utils.PasswordAlgorithm(value)
+		if !hasher.Algorithm.IsValid() {
+			log.Fatalf("Invalid PASSWORD_HASH_ALGORITHM: %s", value)
+		}
+	}
+
+	// Параметры проверяются по диапазону: большие значения переполнились бы при преобразовании типа,
+	// а argon2 не работает с нулевыми параметрами
+	hasher.BcryptCost = envIntRange("PASSWORD_BCRYPT_COST", hasher.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+	hasher.Argon2.Threads = uint8(envIntRange("PASSWORD_ARGON2_THREADS", int(hasher.Argon2.Threads), 1, math.MaxUint8))
+	hasher.Argon2.Memory = uint32(envIntRange(
+		"PASSWORD_ARGON2_MEMORY", int(hasher.Argon2.Memory), 8*int(hasher.Argon2.Threads), math.MaxUint32,
+	))
+	hasher.Argon2.Time = uint32(envIntRange("PASSWORD_ARGON2_TIME", int(hasher.Argon2.Time), 1, math.MaxUint32))
+
+	return hasher
+}
+
+// newNotifier создает Notifier: если задан NOTIFY_FILE, сообщения сохраняются в файл, иначе выводятся в лог
+func newNotifier() notifier.Notifier {
+	if path := os.Getenv("NOTIFY_FILE"); path != "" {
+		return notifier.NewFileNotifier(path)
+	}
+
+	return notifier.NewLogNotifier()
+}
+
+// envInt читает целое число из переменной окружения
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+
+	return i
+}
+
+// envIntRange читает целое число из переменной окружения и проверяет, что оно в диапазоне [min, max]
+func envIntRange(name string, def, min, max int) int {
+	i := envInt(name, def)
+	if i < min || i > max {
+		log.Fatalf("Invalid %s: %d is out of range [%d, %d]", name, i, min, max)
+	}
+
+	return i
+}
+
+// envBool читает логическое значение (true/false) из переменной окружения
+func envBool(name string, def bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+
+	return b
+}
+
+// envDuration читает длительность (например 15m) из переменной окружения
+func envDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+
+	return d
+}
+
+// parseSameSite разбирает значение атрибута SameSite cookie: strict, lax или none
+func parseSameSite(value string) (http.SameSite, bool) {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode, true
+	case "lax":
+		return http.SameSiteLaxMode, true
+	case "none":
+		return http.SameSiteNoneMode, true
+	}
+
+	return 0, false
+}
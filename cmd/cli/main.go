@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 
+	"github.com/LiFeAiR/crud-ai/internal/export"
 	"github.com/LiFeAiR/crud-ai/internal/repository"
 )
 
@@ -21,42 +25,212 @@ func main() {
 	}
 	defer db.Close()
 
+	command := "init"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	switch command {
+	case "init":
+		err = initDB(db)
+	case "export-user":
+		err = exportUser(db, os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q, expected one of: init, export-user", command)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Сli executed successfully")
+}
+
+// initDB инициализирует таблицы в БД
+func initDB(db *repository.DB) error {
 	// Создаем репозиторий пользователей
 	userRepo := repository.NewUserRepository(db)
 	orgRepo := repository.NewOrganizationRepository(db)
 	permRepo := repository.NewPermissionRepository(db)
 	roleRepo := repository.NewRoleRepository(db)
 	tariffRepo := repository.NewTariffRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	attemptRepo := repository.NewLoginAttemptRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	twoFARepo := repository.NewTwoFactorRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	oidcRepo := repository.NewOIDCRepository(db)
+	oauthRepo := repository.NewOAuthRepository(db)
+	accessRequestRepo := repository.NewAccessRequestRepository(db)
+	accessReviewRepo := repository.NewAccessReviewRepository(db)
+	sodRepo := repository.NewSoDRepository(db)
+	dpopRepo := repository.NewDPoPRepository(db)
 
 	// Инициализируем таблицы в БД
-	err = permRepo.InitDB()
+	err := permRepo.InitDB()
 	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
+		return fmt.Errorf("Failed to initialize database: %w", err)
 	}
 
 	// Инициализируем таблицы в БД
 	err = roleRepo.InitDB()
 	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
+		return fmt.Errorf("Failed to initialize database: %w", err)
 	}
 
 	// Инициализируем таблицы в БД
 	err = userRepo.InitDB()
 	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
+		return fmt.Errorf("Failed to initialize database: %w", err)
 	}
 
 	// Инициализируем таблицы в БД
 	err = orgRepo.InitDB()
 	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
+		return fmt.Errorf("Failed to initialize database: %w", err)
 	}
 
 	// Инициализируем таблицы в БД
 	err = tariffRepo.InitDB()
 	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
+		return fmt.Errorf("Failed to initialize database: %w", err)
 	}
 
-	log.Println("Сli executed successfully")
+	// Инициализируем таблицы в БД
+	err = auditRepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	// Инициализируем таблицы в БД
+	err = attemptRepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	// Инициализируем таблицы в БД
+	err = tokenRepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	// Инициализируем таблицы в БД
+	err = twoFARepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	// Инициализируем таблицы в БД
+	err = sessionRepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	// Инициализируем таблицы в БД
+	err = apiKeyRepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	// Инициализируем таблицы в БД
+	err = oidcRepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	// Инициализируем таблицы в БД
+	err = oauthRepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	// Инициализируем таблицы в БД
+	err = accessRequestRepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	// Инициализируем таблицы в БД
+	err = accessReviewRepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	// Инициализируем таблицы в БД
+	err = sodRepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	// Инициализируем таблицы в БД
+	err = dpopRepo.InitDB()
+	if err != nil {
+		return fmt.Errorf("Failed to initialize database: %w", err)
+	}
+
+	return nil
+}
+
+// exportUser выгружает все данные пользователя в JSON или zip файл
+func exportUser(db *repository.DB, args []string) error {
+	fs := flag.NewFlagSet("export-user", flag.ContinueOnError)
+	userID := fs.Int("id", 0, "ID пользователя")
+	zipped := fs.Bool("zip", false, "упаковать выгрузку в zip архив")
+	out := fs.String("out", "", "путь к файлу (по умолчанию user_<id>_export.json или .zip)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *userID <= 0 {
+		return fmt.Errorf("export-user: -id is required")
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	orgRepo := repository.NewOrganizationRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+
+	exporter := export.NewExporter(export.UserSections(userRepo, orgRepo, roleRepo, auditRepo, sessionRepo)...)
+	exporter.Register(
+		export.TwoFactorSection(repository.NewTwoFactorRepository(db)),
+		export.OIDCIdentitySection(repository.NewOIDCRepository(db)),
+		export.APIKeySection(repository.NewAPIKeyRepository(db)),
+		export.AccessRequestSection(repository.NewAccessRequestRepository(db)),
+		export.AccessReviewSection(repository.NewAccessReviewRepository(db)),
+		export.LoginAttemptSection(repository.NewLoginAttemptRepository(db)),
+		export.OAuthConsentSection(repository.NewOAuthRepository(db)),
+		export.OrganizationAdminSection(orgRepo),
+	)
+	bundle, err := exporter.Collect(context.Background(), *userID)
+	if err != nil {
+		return fmt.Errorf("Failed to export user data: %w", err)
+	}
+
+	var (
+		data []byte
+		ext  = ".json"
+	)
+	if *zipped {
+		data, err = bundle.Zip()
+		ext = ".zip"
+	} else {
+		data, err = bundle.JSON()
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to export user data: %w", err)
+	}
+
+	path := *out
+	if path == "" {
+		path = bundle.FileName() + ext
+	}
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("Failed to write export file: %w", err)
+	}
+
+	log.Printf("User %d data exported to %s", *userID, path)
+	return nil
 }
@@ -0,0 +1,283 @@
+// Package dpop проверяет доказательства владения ключом клиента (DPoP, RFC 9449)
+package dpop
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// proofType значение заголовка typ DPoP доказательства
+	proofType = "dpop+jwt"
+	// clockSkew допустимое расхождение часов клиента и сервера
+	clockSkew = 30 * time.Second
+	// minRSABits минимальный размер RSA ключа клиента
+	minRSABits = 2048
+	// maxReplayEntries при превышении этого количества записей истекшие записи удаляются
+	maxReplayEntries = 100000
+)
+
+var (
+	ErrInvalidKey   = errors.New("invalid dpop key")
+	ErrInvalidProof = errors.New("invalid dpop proof")
+	ErrStaleProof   = errors.New("dpop proof is not fresh")
+	ErrReplay       = errors.New("dpop proof already used")
+)
+
+// JWK открытый ключ клиента (RFC 7517). Поддерживаются ключи EC P-256 и RSA
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	// D закрытая часть ключа, не должна передаваться
+	D string `json:"d,omitempty"`
+}
+
+// ParseJWK разбирает открытый ключ клиента в формате JSON
+func ParseJWK(raw []byte) (*JWK, error) {
+	k := &JWK{}
+	if err := json.Unmarshal(raw, k); err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	if _, err := k.PublicKey(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// PublicKey возвращает открытый ключ для проверки подписи
+func (k *JWK) PublicKey() (interface{}, error) {
+	if k.D != "" {
+		return nil, fmt.Errorf("%w: private key material", ErrInvalidKey)
+	}
+
+	switch k.Kty {
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("%w: unsupported curve %q", ErrInvalidKey, k.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil {
+			return nil, ErrInvalidKey
+		}
+
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, ErrInvalidKey
+		}
+
+		return key, nil
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return nil, ErrInvalidKey
+		}
+
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("%w: rsa key is too short", ErrInvalidKey)
+		}
+
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported key type %q", ErrInvalidKey, k.Kty)
+}
+
+// Thumbprint вычисляет отпечаток ключа (RFC 7638), который сохраняется в claim cnf.jkt токена
+func (k *JWK) Thumbprint() string {
+	// Обязательные члены ключа в лексикографическом порядке
+	var canonical []byte
+	switch k.Kty {
+	case "EC":
+		canonical, _ = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{k.Crv, k.Kty, k.X, k.Y})
+	default:
+		canonical, _ = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N})
+	}
+
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AccessTokenHash значение claim ath доказательства для токена доступа
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ReplayCache хранилище использованных доказательств
+type ReplayCache interface {
+	// RememberDPoPProof сохраняет идентификатор доказательства до expiresAt.
+	// Возвращает false, если доказательство уже использовано
+	RememberDPoPProof(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+}
+
+// Verifier проверяет DPoP доказательства и запоминает использованные, чтобы их нельзя было повторить
+type Verifier struct {
+	maxAge time.Duration
+	replay ReplayCache
+}
+
+// NewVerifier создает проверку доказательств, выпущенных не раньше чем maxAge назад.
+// Без хранилища replay использованные доказательства хранятся в памяти процесса, и доказательство,
+// принятое одной репликой сервиса, будет принято и другой
+func NewVerifier(maxAge time.Duration, replay ReplayCache) *Verifier {
+	if replay == nil {
+		replay = NewMemoryReplayCache()
+	}
+
+	return &Verifier{
+		maxAge: maxAge,
+		replay: replay,
+	}
+}
+
+// Verify проверяет доказательство для запроса method к адресу htu с токеном доступа accessToken
+// и возвращает отпечаток ключа, которым оно подписано
+func (v *Verifier) Verify(ctx context.Context, proof, method, htu, accessToken string) (string, error) {
+	var key *JWK
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(proof, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != proofType {
+			return nil, fmt.Errorf("unexpected typ %q", typ)
+		}
+
+		raw, err := json.Marshal(token.Header["jwk"])
+		if err != nil {
+			return nil, err
+		}
+
+		key, err = ParseJWK(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		return key.PublicKey()
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithoutClaimsValidation(),
+	)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+
+	jti, _ := claims["jti"].(string)
+	htm, _ := claims["htm"].(string)
+	proofURL, _ := claims["htu"].(string)
+	if jti == "" || htm != method || !sameURL(proofURL, htu) {
+		return "", fmt.Errorf("%w: request mismatch", ErrInvalidProof)
+	}
+
+	// Доказательство для запроса с токеном содержит его хеш (RFC 9449, раздел 4.2)
+	if ath, _ := claims["ath"].(string); accessToken != "" && ath != AccessTokenHash(accessToken) {
+		return "", fmt.Errorf("%w: access token hash mismatch", ErrInvalidProof)
+	}
+
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return "", fmt.Errorf("%w: iat is required", ErrInvalidProof)
+	}
+
+	now := time.Now()
+	if iat.Before(now.Add(-v.maxAge)) || iat.After(now.Add(clockSkew)) {
+		return "", ErrStaleProof
+	}
+
+	// Доказательство запоминается до момента, когда оно перестанет приниматься по iat
+	jkt := key.Thumbprint()
+	fresh, err := v.replay.RememberDPoPProof(ctx, proofID(jkt, jti), iat.Add(v.maxAge+clockSkew))
+	if err != nil {
+		return "", fmt.Errorf("failed to remember dpop proof: %w", err)
+	}
+	if !fresh {
+		return "", ErrReplay
+	}
+
+	return jkt, nil
+}
+
+// proofID идентификатор доказательства: jti уникален только в пределах ключа клиента
+func proofID(jkt, jti string) string {
+	sum := sha256.Sum256([]byte(jkt + ":" + jti))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// memoryReplayCache хранилище использованных доказательств в памяти процесса
+type memoryReplayCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewMemoryReplayCache создает хранилище использованных доказательств в памяти процесса.
+// Подходит, только если сервис запущен в одном экземпляре
+func NewMemoryReplayCache() ReplayCache {
+	return &memoryReplayCache{seen: make(map[string]time.Time)}
+}
+
+// RememberDPoPProof сохраняет идентификатор доказательства до expiresAt. Возвращает false, если он уже использован
+func (c *memoryReplayCache) RememberDPoPProof(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	return c.remember(id, expiresAt, time.Now()), nil
+}
+
+// remember сохраняет идентификатор доказательства до expiresAt. Возвращает false, если он уже использован
+func (c *memoryReplayCache) remember(id string, expiresAt, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if until, ok := c.seen[id]; ok && now.Before(until) {
+		return false
+	}
+
+	if len(c.seen) >= maxReplayEntries {
+		for key, until := range c.seen {
+			if !now.Before(until) {
+				delete(c.seen, key)
+			}
+		}
+	}
+
+	c.seen[id] = expiresAt
+	return true
+}
+
+// sameURL сравнивает адреса без учета query и fragment (RFC 9449, раздел 4.3)
+func sameURL(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host) &&
+		ua.EscapedPath() == ub.EscapedPath()
+}
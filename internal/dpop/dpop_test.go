@@ -0,0 +1,175 @@
+package dpop
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// testKey ключ клиента и его открытая часть в формате JWK
+type testKey struct {
+	private *ecdsa.PrivateKey
+	jwk     *JWK
+}
+
+func newTestKey(t *testing.T) *testKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	return &testKey{
+		private: key,
+		jwk: &JWK{
+			Kty: "EC",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		},
+	}
+}
+
+// proof подписывает доказательство с указанными claims
+func (k *testKey) proof(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = proofType
+	token.Header["jwk"] = k.jwk
+
+	signed, err := token.SignedString(k.private)
+	assert.NoError(t, err)
+
+	return signed
+}
+
+// proofClaims claims доказательства для запроса GET https://api.example.com/api/user с токеном
+func proofClaims(jti string, iat time.Time) jwt.MapClaims {
+	return jwt.MapClaims{
+		"jti": jti,
+		"htm": "GET",
+		"htu": "https://api.example.com/api/user",
+		"iat": iat.Unix(),
+		"ath": AccessTokenHash("access-token"),
+	}
+}
+
+// countingCache хранилище доказательств, которое запоминает обращения
+type countingCache struct {
+	ReplayCache
+	calls int
+}
+
+func (c *countingCache) RememberDPoPProof(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	c.calls++
+	return c.ReplayCache.RememberDPoPProof(ctx, id, expiresAt)
+}
+
+// TestVerifier_Verify тестирует проверку DPoP доказательств
+func TestVerifier_Verify(t *testing.T) {
+	ctx := context.Background()
+	key := newTestKey(t)
+	const (
+		method = "GET"
+		htu    = "https://api.example.com/api/user"
+		token  = "access-token"
+	)
+
+	// Test 1: Корректное доказательство принимается, возвращается отпечаток ключа подписи
+	t.Run("ValidProof", func(t *testing.T) {
+		verifier := NewVerifier(time.Minute, nil)
+
+		// query и fragment адреса не сравниваются
+		jkt, err := verifier.Verify(ctx, key.proof(t, proofClaims("p1", time.Now())), method, htu+"?page=2", token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, key.jwk.Thumbprint(), jkt)
+	})
+
+	// Test 2: Доказательство для другого запроса или токена не принимается
+	t.Run("RequestMismatch", func(t *testing.T) {
+		verifier := NewVerifier(time.Minute, nil)
+
+		tests := []struct {
+			name   string
+			claims func(claims jwt.MapClaims)
+		}{
+			{name: "Method", claims: func(claims jwt.MapClaims) { claims["htm"] = "POST" }},
+			{name: "URL", claims: func(claims jwt.MapClaims) { claims["htu"] = "https://api.example.com/api/users" }},
+			{name: "Host", claims: func(claims jwt.MapClaims) { claims["htu"] = "https://evil.example.com/api/user" }},
+			{name: "AccessToken", claims: func(claims jwt.MapClaims) { claims["ath"] = AccessTokenHash("other-token") }},
+			{name: "NoJTI", claims: func(claims jwt.MapClaims) { delete(claims, "jti") }},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				claims := proofClaims("mismatch-"+tt.name, time.Now())
+				tt.claims(claims)
+
+				jkt, err := verifier.Verify(ctx, key.proof(t, claims), method, htu, token)
+
+				assert.ErrorIs(t, err, ErrInvalidProof)
+				assert.Empty(t, jkt)
+			})
+		}
+	})
+
+	// Test 3: Доказательство старше maxAge или из будущего не принимается
+	t.Run("StaleProof", func(t *testing.T) {
+		verifier := NewVerifier(time.Minute, nil)
+
+		for name, iat := range map[string]time.Time{
+			"Old":    time.Now().Add(-2 * time.Minute),
+			"Future": time.Now().Add(2 * clockSkew),
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := verifier.Verify(ctx, key.proof(t, proofClaims("stale-"+name, iat)), method, htu, token)
+				assert.ErrorIs(t, err, ErrStaleProof)
+			})
+		}
+	})
+
+	// Test 4: Повторное доказательство с тем же jti не принимается, jti другого ключа не мешает
+	t.Run("Replay", func(t *testing.T) {
+		cache := &countingCache{ReplayCache: NewMemoryReplayCache()}
+		verifier := NewVerifier(time.Minute, cache)
+		proof := key.proof(t, proofClaims("replayed", time.Now()))
+
+		_, err := verifier.Verify(ctx, proof, method, htu, token)
+		assert.NoError(t, err)
+
+		_, err = verifier.Verify(ctx, proof, method, htu, token)
+		assert.ErrorIs(t, err, ErrReplay)
+
+		// jti уникален только в пределах ключа клиента
+		other := newTestKey(t)
+		jkt, err := verifier.Verify(ctx, other.proof(t, proofClaims("replayed", time.Now())), method, htu, token)
+		assert.NoError(t, err)
+		assert.Equal(t, other.jwk.Thumbprint(), jkt)
+		assert.NotEqual(t, key.jwk.Thumbprint(), jkt)
+
+		// Использованные доказательства сохраняются в переданном хранилище
+		assert.Equal(t, 3, cache.calls)
+	})
+
+	// Test 5: Доказательство, подписанное не ключом из заголовка jwk, не принимается
+	t.Run("KeyMismatch", func(t *testing.T) {
+		verifier := NewVerifier(time.Minute, nil)
+		other := newTestKey(t)
+
+		unsigned := jwt.NewWithClaims(jwt.SigningMethodES256, proofClaims("foreign", time.Now()))
+		unsigned.Header["typ"] = proofType
+		unsigned.Header["jwk"] = key.jwk
+		proof, err := unsigned.SignedString(other.private)
+		assert.NoError(t, err)
+
+		_, err = verifier.Verify(ctx, proof, method, htu, token)
+		assert.ErrorIs(t, err, ErrInvalidProof)
+	})
+}
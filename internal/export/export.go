@@ -0,0 +1,102 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Section раздел выгрузки персональных данных пользователя
+type Section struct {
+	// Name имя раздела в итоговом документе
+	Name string
+	// Collect собирает данные раздела для пользователя
+	Collect func(ctx context.Context, userID int) (any, error)
+}
+
+// Bundle выгрузка всех данных, которые хранятся о пользователе
+type Bundle struct {
+	UserID      int            `json:"user_id"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Sections    map[string]any `json:"sections"`
+}
+
+// Exporter собирает выгрузку данных пользователя из зарегистрированных разделов
+type Exporter struct {
+	sections []Section
+}
+
+// NewExporter создает новый экземпляр выгрузки с указанными разделами
+func NewExporter(sections ...Section) *Exporter {
+	return &Exporter{sections: sections}
+}
+
+// Register добавляет раздел в выгрузку
+func (e *Exporter) Register(sections ...Section) {
+	e.sections = append(e.sections, sections...)
+}
+
+// Collect собирает данные пользователя по всем разделам
+func (e *Exporter) Collect(ctx context.Context, userID int) (*Bundle, error) {
+	bundle := &Bundle{
+		UserID:      userID,
+		GeneratedAt: time.Now().UTC(),
+		Sections:    make(map[string]any, len(e.sections)),
+	}
+
+	for _, section := range e.sections {
+		data, err := section.Collect(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect section %s: %w", section.Name, err)
+		}
+		bundle.Sections[section.Name] = data
+	}
+
+	return bundle, nil
+}
+
+// FileName возвращает имя файла выгрузки без расширения
+func (b *Bundle) FileName() string {
+	return fmt.Sprintf("user_%d_export", b.UserID)
+}
+
+// JSON сериализует выгрузку в JSON
+func (b *Bundle) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal export: %w", err)
+	}
+	return data, nil
+}
+
+// Zip упаковывает JSON выгрузки в zip архив
+func (b *Bundle) Zip() ([]byte, error) {
+	data, err := b.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     b.FileName() + ".json",
+		Method:   zip.Deflate,
+		Modified: b.GeneratedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zip entry: %w", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write zip entry: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close zip: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,284 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/repository"
+)
+
+// profile данные профиля пользователя без хеша пароля
+type profile struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	Email          string `json:"email"`
+	OrganizationID *int   `json:"organization_id,omitempty"`
+	TariffID       *int   `json:"tariff_id,omitempty"`
+	Status         string `json:"status"`
+}
+
+// permissions права пользователя с разделением по источнику
+type permissions struct {
+	Direct    []*models.Permission `json:"direct"`
+	Effective []*models.Permission `json:"effective"`
+}
+
+// tariffs текущий тариф пользователя и история его изменений
+type tariffs struct {
+	Current *models.Tariff `json:"current"`
+	// History изменения тарифа пользователя из журнала аудита, от старых к новым
+	History []*models.AuditEntry `json:"history"`
+}
+
+// tariffActionPrefix префикс действий журнала аудита, изменяющих тариф пользователя
+const tariffActionPrefix = "user.tariff_"
+
+// UserSections возвращает стандартные разделы выгрузки, построенные на репозиториях
+func UserSections(
+	userRepo repository.UserRepository,
+	orgRepo repository.OrganizationRepository,
+	roleRepo repository.RoleRepository,
+	auditRepo repository.AuditRepository,
+	sessionRepo repository.SessionRepository,
+) []Section {
+	return []Section{
+		{
+			Name: "profile",
+			Collect: func(ctx context.Context, userID int) (any, error) {
+				user, err := userRepo.GetUserByID(ctx, userID)
+				if err != nil {
+					return nil, err
+				}
+
+				out := profile{
+					ID:       user.ID,
+					Name:     user.Name,
+					Email:    user.Email,
+					TariffID: user.TariffID,
+					Status:   string(user.Status),
+				}
+				if user.Organization != nil {
+					out.OrganizationID = &user.Organization.ID
+				}
+
+				return out, nil
+			},
+		},
+		{
+			Name: "organizations",
+			Collect: func(ctx context.Context, userID int) (any, error) {
+				user, err := userRepo.GetUserByID(ctx, userID)
+				if err != nil {
+					return nil, err
+				}
+
+				organizations := make([]*models.Organization, 0, 1)
+				if user.Organization != nil {
+					org, err := orgRepo.GetOrganizationByID(ctx, user.Organization.ID)
+					if err != nil {
+						return nil, err
+					}
+					organizations = append(organizations, org)
+				}
+
+				return organizations, nil
+			},
+		},
+		{
+			Name: "roles",
+			Collect: func(ctx context.Context, userID int) (any, error) {
+				roles, err := userRepo.GetUserRoles(ctx, userID)
+				if err != nil {
+					return nil, err
+				}
+
+				// Для каждой роли выгружаем права, которые она дает
+				out := make([]*models.Role, 0, len(roles))
+				for _, role := range roles {
+					withPermissions, err := roleRepo.GetRoleWithPermissions(ctx, role.ID)
+					if err != nil {
+						return nil, fmt.Errorf("role %d: %w", role.ID, err)
+					}
+					out = append(out, withPermissions)
+				}
+
+				return out, nil
+			},
+		},
+		{
+			Name: "permissions",
+			Collect: func(ctx context.Context, userID int) (any, error) {
+				direct, err := userRepo.GetUserDirectPermissions(ctx, userID)
+				if err != nil {
+					return nil, err
+				}
+
+				effective, err := userRepo.GetUserPermissions(ctx, userID)
+				if err != nil {
+					return nil, err
+				}
+
+				return permissions{Direct: direct, Effective: effective}, nil
+			},
+		},
+		{
+			Name: "tariffs",
+			Collect: func(ctx context.Context, userID int) (any, error) {
+				user, err := userRepo.GetUserByID(ctx, userID)
+				if err != nil {
+					return nil, err
+				}
+
+				out := tariffs{History: []*models.AuditEntry{}}
+				if user.TariffID != nil {
+					out.Current, err = userRepo.GetUserTariff(ctx, userID)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				// Прошлые тарифы не хранятся в таблицах, история восстанавливается по журналу аудита
+				entries, err := auditRepo.GetUserAuditEntries(ctx, userID)
+				if err != nil {
+					return nil, err
+				}
+				for _, entry := range entries {
+					if strings.HasPrefix(entry.Action, tariffActionPrefix) {
+						out.History = append(out.History, entry)
+					}
+				}
+
+				return out, nil
+			},
+		},
+		{
+			Name: "sessions",
+			Collect: func(ctx context.Context, userID int) (any, error) {
+				sessions, err := sessionRepo.GetUserSessionHistory(ctx, userID)
+				if err != nil {
+					return nil, err
+				}
+
+				if sessions == nil {
+					sessions = []*models.Session{}
+				}
+
+				return sessions, nil
+			},
+		},
+		{
+			Name: "audit",
+			Collect: func(ctx context.Context, userID int) (any, error) {
+				entries, err := auditRepo.GetUserAuditEntries(ctx, userID)
+				if err != nil {
+					return nil, err
+				}
+
+				if entries == nil {
+					entries = []*models.AuditEntry{}
+				}
+
+				return entries, nil
+			},
+		},
+	}
+}
+
+// twoFactor настройки двухфакторной аутентификации пользователя вместе с секретом и резервными кодами
+type twoFactor struct {
+	Enabled bool `json:"enabled"`
+	// Secret секрет TOTP в кодировке base32
+	Secret    string     `json:"secret"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	// RecoveryCodes хеши резервных кодов, сами коды не хранятся
+	RecoveryCodes []*models.RecoveryCode `json:"recovery_codes"`
+}
+
+// TwoFactorSection раздел с настройками двухфакторной аутентификации. Если пользователь не подключал
+// двухфакторную аутентификацию, раздел пустой
+func TwoFactorSection(twoFARepo repository.TwoFactorRepository) Section {
+	return Section{
+		Name: "two_factor",
+		Collect: func(ctx context.Context, userID int) (any, error) {
+			tf, err := twoFARepo.GetTwoFactor(ctx, userID)
+			if err != nil || tf == nil {
+				return nil, err
+			}
+
+			codes, err := twoFARepo.GetRecoveryCodes(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+
+			return twoFactor{
+				Enabled:       tf.Enabled,
+				Secret:        tf.Secret,
+				EnabledAt:     tf.EnabledAt,
+				CreatedAt:     tf.CreatedAt,
+				RecoveryCodes: nonNil(codes),
+			}, nil
+		},
+	}
+}
+
+// OIDCIdentitySection раздел с учетными записями провайдеров OpenID Connect, связанными с пользователем
+func OIDCIdentitySection(oidcRepo repository.OIDCRepository) Section {
+	return listSection("oidc_identities", oidcRepo.GetUserOIDCIdentities)
+}
+
+// APIKeySection раздел с API ключами и персональными токенами доступа пользователя, в том числе отозванными
+func APIKeySection(apiKeyRepo repository.APIKeyRepository) Section {
+	return listSection("api_keys", apiKeyRepo.GetAPIKeys)
+}
+
+// AccessRequestSection раздел с запросами доступа, которые пользователь создал или решил
+func AccessRequestSection(accessRepo repository.AccessRequestRepository) Section {
+	return listSection("access_requests", accessRepo.GetUserAccessRequests)
+}
+
+// AccessReviewSection раздел с выдачами пользователя в кампаниях пересмотра и его решениями по ним
+func AccessReviewSection(reviewRepo repository.AccessReviewRepository) Section {
+	return listSection("access_review_items", reviewRepo.GetUserAccessReviewItems)
+}
+
+// LoginAttemptSection раздел с попытками входа в аккаунт пользователя
+func LoginAttemptSection(attemptRepo repository.LoginAttemptRepository) Section {
+	return listSection("login_attempts", attemptRepo.GetUserLoginAttempts)
+}
+
+// OAuthConsentSection раздел с согласиями, выданными пользователем OAuth клиентам
+func OAuthConsentSection(oauthRepo repository.OAuthRepository) Section {
+	return listSection("oauth_consents", oauthRepo.GetUserOAuthConsents)
+}
+
+// OrganizationAdminSection раздел с организациями, администратором которых назначен пользователь
+func OrganizationAdminSection(orgRepo repository.OrganizationRepository) Section {
+	return listSection("organization_admins", orgRepo.GetUserAdminOrganizations)
+}
+
+// listSection раздел со списком записей пользователя. Пустой список выгружается как [], а не null
+func listSection[T any](name string, list func(ctx context.Context, userID int) ([]T, error)) Section {
+	return Section{
+		Name: name,
+		Collect: func(ctx context.Context, userID int) (any, error) {
+			items, err := list(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+
+			return nonNil(items), nil
+		},
+	}
+}
+
+// nonNil заменяет nil на пустой список
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// privilegedPermissions права, дающие административный доступ. Запросы ролей с ними одобряет только администратор
+var privilegedPermissions = []string{auth.IsAdmin, "organization.admin"}
+
+// RequestAccess создает запрос текущего пользователя на выдачу роли.
+// Запрос направляется утверждающим роли и администраторам организации пользователя
+func (bh *BaseHandler) RequestAccess(
+	ctx context.Context,
+	in *api_pb.AccessRequestCreateRequest,
+) (out *api_pb.AccessRequest, err error) {
+	userID := currentUserID(ctx)
+	if userID == nil {
+		return nil, status.Error(codes.Unauthenticated, "Unauthenticated")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.RoleId == 0 || strings.TrimSpace(in.Justification) == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	validity, err := parseGrantValidity(in.ValidFrom, in.ValidUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := bh.roleRepo.GetRoleByID(ctx, int(in.RoleId))
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "Role not found")
+	}
+
+	user, err := bh.userRepo.GetUserByID(ctx, *userID)
+	if err != nil {
+		log.Printf("Failed to get user, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create access request")
+	}
+
+	if user == nil {
+		return nil, status.Error(codes.Unauthenticated, "Unauthenticated")
+	}
+
+	request := &models.AccessRequest{
+		UserID:        user.ID,
+		RoleID:        role.ID,
+		Justification: strings.TrimSpace(in.Justification),
+		ValidFrom:     validity.ValidFrom,
+		ValidUntil:    validity.ValidUntil,
+	}
+	if user.Organization != nil {
+		request.OrganizationID = utils.Ptr(user.Organization.ID)
+	}
+
+	created, err := bh.accessRepo.CreateAccessRequest(ctx, request)
+	if err != nil {
+		log.Printf("Failed to create access request, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create access request")
+	}
+
+	if !created {
+		return nil, status.Error(codes.AlreadyExists, "Access request for this role is already pending")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: userID,
+		UserID:  utils.Ptr(user.ID),
+		Action:  "access_request.create",
+		Reason:  request.Justification,
+		Details: fmt.Sprintf("request %d: role %s%s", request.ID, role.Code, grantValidityDetails(validity)),
+	})
+
+	bh.notifyAccessApprovers(ctx, request, role, user)
+
+	return accessRequestToPb(request), nil
+}
+
+// GetAccessRequests получает запросы доступа, ожидающие решения текущего пользователя.
+// Администратору доступны все запросы
+func (bh *BaseHandler) GetAccessRequests(
+	ctx context.Context,
+	in *api_pb.ListRequest,
+) (out *api_pb.AccessRequestsResponse, err error) {
+	if currentUserID(ctx) == nil {
+		return nil, status.Error(codes.Unauthenticated, "Unauthenticated")
+	}
+
+	// Устанавливаем значения по умолчанию
+	limit := 10
+	offset := 0
+
+	// Парсим limit
+	if in.Limit > 0 && in.Limit < 100 {
+		limit = int(in.GetLimit())
+	}
+
+	// Парсим offset
+	if in.Offset > 0 {
+		offset = int(in.GetOffset())
+	}
+
+	approver, err := bh.accessApprover(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to get access requests")
+	}
+
+	requests, err := bh.accessRepo.GetPendingAccessRequests(ctx, approver, limit, offset)
+	if err != nil {
+		log.Printf("Failed to get access requests, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get access requests")
+	}
+
+	data := make([]*api_pb.AccessRequest, 0, len(requests))
+	for _, request := range requests {
+		data = append(data, accessRequestToPb(request))
+	}
+
+	return &api_pb.AccessRequestsResponse{Data: data}, nil
+}
+
+// ApproveAccessRequest одобряет запрос доступа и выдает пользователю роль на запрошенный срок
+func (bh *BaseHandler) ApproveAccessRequest(
+	ctx context.Context,
+	in *api_pb.AccessRequestDecision,
+) (out *api_pb.AccessRequest, err error) {
+	request, err := bh.decideAccessRequest(ctx, in, models.AccessRequestApproved)
+	if err != nil {
+		return nil, err
+	}
+
+	// Роль выдается так же, как при ручной выдаче, от имени утверждающего
+	_, err = bh.AddUserRoles(ctx, &api_pb.UserRolesRequest{
+		Id:         int32(request.UserID),
+		RoleIds:    []int32{int32(request.RoleID)},
+		ValidFrom:  formatGrantTime(request.ValidFrom),
+		ValidUntil: formatGrantTime(request.ValidUntil),
+	})
+	if err != nil {
+		log.Printf("Failed to grant role for access request %d, err:%v\n", request.ID, err)
+
+		// Запрос не должен остаться одобренным без выданной роли, его можно решить повторно
+		if err := bh.accessRepo.ReopenAccessRequest(ctx, request.ID); err != nil {
+			log.Printf("Failed to reopen access request %d, err:%v\n", request.ID, err)
+		} else {
+			bh.audit(ctx, &models.AuditEntry{
+				ActorID: request.DecidedBy,
+				UserID:  utils.Ptr(request.UserID),
+				Action:  "access_request.reopen",
+				Details: fmt.Sprintf("request %d: role %d was not granted", request.ID, request.RoleID),
+			})
+		}
+
+		return nil, err
+	}
+
+	bh.notifyAccessDecision(ctx, request)
+
+	return accessRequestToPb(request), nil
+}
+
+// DenyAccessRequest отклоняет запрос доступа
+func (bh *BaseHandler) DenyAccessRequest(
+	ctx context.Context,
+	in *api_pb.AccessRequestDecision,
+) (out *api_pb.AccessRequest, err error) {
+	request, err := bh.decideAccessRequest(ctx, in, models.AccessRequestDenied)
+	if err != nil {
+		return nil, err
+	}
+
+	bh.notifyAccessDecision(ctx, request)
+
+	return accessRequestToPb(request), nil
+}
+
+// decideAccessRequest проверяет, что текущий пользователь может решить запрос, и сохраняет решение.
+// Причина решения обязательна и записывается в запрос и журнал аудита
+func (bh *BaseHandler) decideAccessRequest(
+	ctx context.Context,
+	in *api_pb.AccessRequestDecision,
+	decision models.AccessRequestStatus,
+) (*models.AccessRequest, error) {
+	userID := currentUserID(ctx)
+	if userID == nil {
+		return nil, status.Error(codes.Unauthenticated, "Unauthenticated")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 || strings.TrimSpace(in.Reason) == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	request, err := bh.accessRepo.GetAccessRequest(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to get access request, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to decide access request")
+	}
+
+	if request == nil {
+		return nil, status.Error(codes.NotFound, "Access request not found")
+	}
+
+	if err := bh.checkAccessApprover(ctx, request, decision); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	if request.Status != models.AccessRequestPending {
+		return nil, status.Error(codes.FailedPrecondition, "Access request is already decided")
+	}
+
+	// Роль с истекшим сроком выдать нельзя, такой запрос можно только отклонить
+	if decision == models.AccessRequestApproved && request.ValidUntil != nil && !request.ValidUntil.After(time.Now()) {
+		return nil, status.Error(codes.FailedPrecondition, "Requested access period has ended")
+	}
+
+	// Конфликт с ограничениями разделения обязанностей проверяется до сохранения решения,
+	// чтобы запрос не остался одобренным без выданной роли
+	if decision == models.AccessRequestApproved {
+		if err := bh.checkSoD(ctx, models.GrantOwnerUser, request.UserID, []int{request.RoleID}); err != nil {
+			return nil, err
+		}
+	}
+
+	request.Status = decision
+	request.DecidedBy = userID
+	request.DecisionReason = strings.TrimSpace(in.Reason)
+
+	decided, err := bh.accessRepo.DecideAccessRequest(ctx, request)
+	if err != nil {
+		log.Printf("Failed to decide access request, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to decide access request")
+	}
+
+	// Запрос решен другим утверждающим одновременно с текущим
+	if !decided {
+		return nil, status.Error(codes.FailedPrecondition, "Access request is already decided")
+	}
+
+	action := "access_request.approve"
+	if decision == models.AccessRequestDenied {
+		action = "access_request.deny"
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: userID,
+		UserID:  utils.Ptr(request.UserID),
+		Action:  action,
+		Reason:  request.DecisionReason,
+		Details: fmt.Sprintf("request %d: role %d", request.ID, request.RoleID),
+	})
+
+	return request, nil
+}
+
+// accessApprover описывает текущего пользователя как утверждающего запросы доступа
+func (bh *BaseHandler) accessApprover(ctx context.Context) (models.AccessApprover, error) {
+	approver := models.AccessApprover{
+		UserID: utils.FromPtr(currentUserID(ctx)),
+		Admin:  checkAdmin(ctx) == nil,
+	}
+
+	if orgID := auth.OrganizationID(ctx); orgID != 0 && !approver.Admin {
+		ok, err := bh.orgRepo.IsOrganizationAdmin(ctx, orgID, approver.UserID)
+		if err != nil {
+			log.Printf("Failed to check organization admin, err:%v\n", err)
+			return approver, err
+		}
+		if ok {
+			approver.OrganizationID = orgID
+		}
+	}
+
+	return approver, nil
+}
+
+// checkAccessApprover проверяет, что текущий пользователь может принять решение decision по запросу:
+// это администратор, администратор организации пользователя или утверждающий роли.
+// Свой запрос решить нельзя. Администратор организации одобряет только роли, доступные организации,
+// а роли с административными правами одобряет только администратор
+func (bh *BaseHandler) checkAccessApprover(
+	ctx context.Context,
+	request *models.AccessRequest,
+	decision models.AccessRequestStatus,
+) error {
+	approver, err := bh.accessApprover(ctx)
+	if err != nil {
+		return err
+	}
+
+	if approver.UserID == request.UserID {
+		return errors.New("checkAccessApprover.OwnRequest")
+	}
+
+	if approver.Admin {
+		return nil
+	}
+
+	approve := decision == models.AccessRequestApproved
+	if approve {
+		privileged, err := bh.isPrivilegedRole(ctx, request.RoleID)
+		if err != nil {
+			return err
+		}
+		if privileged {
+			return errors.New("checkAccessApprover.PrivilegedRole")
+		}
+	}
+
+	if approver.OrganizationID != 0 && approver.OrganizationID == utils.FromPtr(request.OrganizationID) {
+		if !approve {
+			return nil
+		}
+
+		entitled, err := bh.orgRepo.IsOrganizationRole(ctx, approver.OrganizationID, request.RoleID)
+		if err != nil {
+			log.Printf("Failed to check organization role, err:%v\n", err)
+			return err
+		}
+		if entitled {
+			return nil
+		}
+	}
+
+	approvers, err := bh.accessRepo.GetRoleApprovers(ctx, request.RoleID)
+	if err != nil {
+		log.Printf("Failed to get role approvers, err:%v\n", err)
+		return err
+	}
+
+	for _, id := range approvers {
+		if id == approver.UserID {
+			return nil
+		}
+	}
+
+	return errors.New("checkAccessApprover.PermissionDenied")
+}
+
+// isPrivilegedRole проверяет, дает ли роль административные права
+func (bh *BaseHandler) isPrivilegedRole(ctx context.Context, roleID int) (bool, error) {
+	role, err := bh.roleRepo.GetRoleWithPermissions(ctx, roleID)
+	if err != nil {
+		log.Printf("Failed to get role permissions, err:%v\n", err)
+		return false, err
+	}
+
+	for _, permission := range role.Permissions {
+		if containsCode(privilegedPermissions, permission.Code) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// notifyAccessApprovers сообщает утверждающим о новом запросе, ошибка отправки не прерывает запрос
+func (bh *BaseHandler) notifyAccessApprovers(
+	ctx context.Context,
+	request *models.AccessRequest,
+	role *models.Role,
+	user *models.User,
+) {
+	approvers, err := bh.accessRepo.GetAccessApprovers(ctx, request.RoleID, utils.FromPtr(request.OrganizationID))
+	if err != nil {
+		log.Printf("Failed to get access approvers, err:%v\n", err)
+		return
+	}
+
+	for _, approver := range approvers {
+		if approver.ID == user.ID {
+			continue
+		}
+
+		err := bh.notifier.Notify(ctx, notifier.Message{
+			To:      approver.Email,
+			Subject: "Access request",
+			Body: fmt.Sprintf(
+				"%s requests role %s.\nJustification: %s\nApprove or deny access request %d.",
+				user.Email, role.Code, request.Justification, request.ID,
+			),
+		})
+		if err != nil {
+			log.Printf("Failed to notify access approver, err:%v\n", err)
+		}
+	}
+}
+
+// notifyAccessDecision сообщает пользователю о решении по его запросу
+func (bh *BaseHandler) notifyAccessDecision(ctx context.Context, request *models.AccessRequest) {
+	user, err := bh.userRepo.GetUserByID(ctx, request.UserID)
+	if err != nil || user == nil {
+		log.Printf("Failed to get user for access decision, err:%v\n", err)
+		return
+	}
+
+	err = bh.notifier.Notify(ctx, notifier.Message{
+		To:      user.Email,
+		Subject: "Access request " + string(request.Status),
+		Body: fmt.Sprintf(
+			"Your access request %d was %s.\nReason: %s", request.ID, request.Status, request.DecisionReason,
+		),
+	})
+	if err != nil {
+		log.Printf("Failed to notify access decision, err:%v\n", err)
+	}
+}
+
+// accessRequestToPb преобразует запрос доступа в ответ API
+func accessRequestToPb(request *models.AccessRequest) *api_pb.AccessRequest {
+	out := &api_pb.AccessRequest{
+		Id:             int32(request.ID),
+		UserId:         int32(request.UserID),
+		RoleId:         int32(request.RoleID),
+		Justification:  request.Justification,
+		ValidFrom:      formatGrantTime(request.ValidFrom),
+		ValidUntil:     formatGrantTime(request.ValidUntil),
+		Status:         string(request.Status),
+		DecisionReason: request.DecisionReason,
+		DecidedAt:      formatGrantTime(request.DecidedAt),
+		CreatedAt:      request.CreatedAt.Format(time.RFC3339),
+	}
+	if request.DecidedBy != nil {
+		out.DecidedBy = int32(*request.DecidedBy)
+	}
+
+	return out
+}
@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_AccessRequest тестирует запросы доступа к ролям и их утверждение
+func TestBaseHandler_AccessRequest(t *testing.T) {
+	validUntil := time.Now().Add(8 * time.Hour).UTC().Truncate(time.Second)
+	role := &models.Role{ID: 2, Name: "On-call", Code: "on_call"}
+
+	// Test 1: Запрос сохраняется с обоснованием и направляется утверждающим, кроме самого пользователя
+	t.Run("RequestAccess", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		roleRepo := new(mocks.MockRoleRepository)
+		accessRepo := new(mocks.MockAccessRequestRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+		mockNotifier := new(mocks.MockNotifier)
+
+		// Определяем ожидаемое поведение мока
+		roleRepo.On("GetRoleByID", ctx, 2).Return(role, nil)
+		userRepo.On("GetUserByID", ctx, 1).Return(&models.User{
+			ID:           1,
+			Email:        "user@example.com",
+			Organization: &models.Organization{ID: 3},
+		}, nil)
+		accessRepo.On("CreateAccessRequest", ctx, mock.MatchedBy(func(request *models.AccessRequest) bool {
+			return request.UserID == 1 && request.RoleID == 2 && *request.OrganizationID == 3 &&
+				request.Justification == "Incident #42" && request.ValidUntil.Equal(validUntil)
+		})).Run(func(args mock.Arguments) {
+			request := args.Get(1).(*models.AccessRequest)
+			request.ID = 7
+			request.Status = models.AccessRequestPending
+		}).Return(true, nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "access_request.create" && entry.Reason == "Incident #42"
+		})).Return(nil)
+		accessRepo.On("GetAccessApprovers", ctx, 2, 3).Return([]*models.User{
+			{ID: 1, Email: "user@example.com"},
+			{ID: 5, Email: "lead@example.com"},
+		}, nil)
+		mockNotifier.On("Notify", ctx, mock.MatchedBy(func(msg notifier.Message) bool {
+			return msg.To == "lead@example.com"
+		})).Return(nil).Once()
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			roleRepo:   roleRepo,
+			accessRepo: accessRepo,
+			auditRepo:  auditRepo,
+			notifier:   mockNotifier,
+		}
+
+		// Вызываем метод RequestAccess
+		result, err := baseHandler.RequestAccess(ctx, &grpc.AccessRequestCreateRequest{
+			RoleId:        2,
+			Justification: " Incident #42 ",
+			ValidUntil:    validUntil.Format(time.RFC3339),
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, int32(7), result.Id)
+		assert.Equal(t, string(models.AccessRequestPending), result.Status)
+		assert.Equal(t, validUntil.Format(time.RFC3339), result.ValidUntil)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		roleRepo.AssertExpectations(t)
+		accessRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+		mockNotifier.AssertExpectations(t)
+	})
+
+	// Test 2: Утверждающий роли одобряет запрос, роль выдается на запрошенный срок
+	t.Run("ApproveAccessRequest", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 5)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		roleRepo := new(mocks.MockRoleRepository)
+		accessRepo := new(mocks.MockAccessRequestRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+		mockNotifier := new(mocks.MockNotifier)
+
+		// Определяем ожидаемое поведение мока
+		accessRepo.On("GetAccessRequest", ctx, 7).Return(&models.AccessRequest{
+			ID:         7,
+			UserID:     1,
+			RoleID:     2,
+			ValidUntil: &validUntil,
+			Status:     models.AccessRequestPending,
+		}, nil)
+		roleRepo.On("GetRoleWithPermissions", ctx, 2).Return(&models.Role{
+			ID:          2,
+			Code:        "on_call",
+			Permissions: []models.Permission{{ID: 4, Code: "incidents.write"}},
+		}, nil)
+		accessRepo.On("GetRoleApprovers", ctx, 2).Return([]int{5}, nil)
+		accessRepo.On("DecideAccessRequest", ctx, mock.MatchedBy(func(request *models.AccessRequest) bool {
+			return request.Status == models.AccessRequestApproved && *request.DecidedBy == 5 &&
+				request.DecisionReason == "On-call this week"
+		})).Return(true, nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "access_request.approve" && *entry.ActorID == 5 &&
+				entry.Reason == "On-call this week"
+		})).Return(nil).Once()
+		userRepo.On("AddUserRoles", ctx, 1, []int{2}, models.GrantValidity{ValidUntil: &validUntil}).Return(nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "user.roles_add" && *entry.ActorID == 5
+		})).Return(nil).Once()
+		userRepo.On("GetUserRoles", ctx, 1).Return([]*models.Role{role}, nil)
+		userRepo.On("GetUserByID", ctx, 1).Return(&models.User{ID: 1, Email: "user@example.com"}, nil)
+		mockNotifier.On("Notify", ctx, mock.MatchedBy(func(msg notifier.Message) bool {
+			return msg.To == "user@example.com"
+		})).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			roleRepo:   roleRepo,
+			accessRepo: accessRepo,
+			auditRepo:  auditRepo,
+			notifier:   mockNotifier,
+		}
+
+		// Вызываем метод ApproveAccessRequest
+		result, err := baseHandler.ApproveAccessRequest(ctx, &grpc.AccessRequestDecision{
+			Id:     7,
+			Reason: "On-call this week",
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, string(models.AccessRequestApproved), result.Status)
+		assert.Equal(t, int32(5), result.DecidedBy)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		accessRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+		mockNotifier.AssertExpectations(t)
+	})
+
+	// Test 3: Администратор организации отклоняет запрос участника, роль не выдается
+	t.Run("DenyAccessRequest", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 6)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+		ctx = context.WithValue(ctx, auth.OrganizationIDKey, 3)
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		accessRepo := new(mocks.MockAccessRequestRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+		mockNotifier := new(mocks.MockNotifier)
+
+		// Определяем ожидаемое поведение мока
+		orgRepo.On("IsOrganizationAdmin", ctx, 3, 6).Return(true, nil)
+		accessRepo.On("GetAccessRequest", ctx, 7).Return(&models.AccessRequest{
+			ID:             7,
+			UserID:         1,
+			OrganizationID: utils.Ptr(3),
+			RoleID:         2,
+			Status:         models.AccessRequestPending,
+		}, nil)
+		accessRepo.On("DecideAccessRequest", ctx, mock.Anything).Return(true, nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "access_request.deny" && entry.Reason == "Not needed"
+		})).Return(nil)
+		userRepo.On("GetUserByID", ctx, 1).Return(&models.User{ID: 1, Email: "user@example.com"}, nil)
+		mockNotifier.On("Notify", ctx, mock.Anything).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			orgRepo:    orgRepo,
+			accessRepo: accessRepo,
+			auditRepo:  auditRepo,
+			notifier:   mockNotifier,
+		}
+
+		// Вызываем метод DenyAccessRequest
+		result, err := baseHandler.DenyAccessRequest(ctx, &grpc.AccessRequestDecision{Id: 7, Reason: "Not needed"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, string(models.AccessRequestDenied), result.Status)
+		accessRepo.AssertNotCalled(t, "GetRoleApprovers", ctx, 2)
+		userRepo.AssertNotCalled(t, "AddUserRoles", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		accessRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+	})
+
+	// Test 4: Пользователь не может одобрить свой запрос, даже будучи утверждающим роли
+	t.Run("ApproveOwnAccessRequest", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, true)
+
+		// Создаем мок репозиторий
+		accessRepo := new(mocks.MockAccessRequestRepository)
+
+		// Определяем ожидаемое поведение мока
+		accessRepo.On("GetAccessRequest", ctx, 7).Return(&models.AccessRequest{
+			ID:     7,
+			UserID: 1,
+			RoleID: 2,
+			Status: models.AccessRequestPending,
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{accessRepo: accessRepo}
+
+		// Вызываем метод ApproveAccessRequest
+		result, err := baseHandler.ApproveAccessRequest(ctx, &grpc.AccessRequestDecision{Id: 7, Reason: "Self"})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		accessRepo.AssertNotCalled(t, "DecideAccessRequest", mock.Anything, mock.Anything)
+	})
+
+	// Test 5: Администратор организации не может одобрить роль, не доступную организации
+	t.Run("ApproveAccessRequestNotOrganizationRole", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 6)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+		ctx = context.WithValue(ctx, auth.OrganizationIDKey, 3)
+
+		// Создаем мок репозиторий
+		orgRepo := new(mocks.MockOrganizationRepository)
+		roleRepo := new(mocks.MockRoleRepository)
+		accessRepo := new(mocks.MockAccessRequestRepository)
+
+		// Определяем ожидаемое поведение мока
+		orgRepo.On("IsOrganizationAdmin", ctx, 3, 6).Return(true, nil)
+		accessRepo.On("GetAccessRequest", ctx, 7).Return(&models.AccessRequest{
+			ID:             7,
+			UserID:         1,
+			OrganizationID: utils.Ptr(3),
+			RoleID:         2,
+			Status:         models.AccessRequestPending,
+		}, nil)
+		roleRepo.On("GetRoleWithPermissions", ctx, 2).Return(&models.Role{
+			ID:          2,
+			Permissions: []models.Permission{{ID: 4, Code: "incidents.write"}},
+		}, nil)
+		orgRepo.On("IsOrganizationRole", ctx, 3, 2).Return(false, nil)
+		accessRepo.On("GetRoleApprovers", ctx, 2).Return([]int{}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			orgRepo:    orgRepo,
+			roleRepo:   roleRepo,
+			accessRepo: accessRepo,
+		}
+
+		// Вызываем метод ApproveAccessRequest
+		result, err := baseHandler.ApproveAccessRequest(ctx, &grpc.AccessRequestDecision{Id: 7, Reason: "Needed"})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		accessRepo.AssertNotCalled(t, "DecideAccessRequest", mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		orgRepo.AssertExpectations(t)
+		roleRepo.AssertExpectations(t)
+		accessRepo.AssertExpectations(t)
+	})
+
+	// Test 6: Роль с административными правами одобряет только администратор, даже если роль доступна организации
+	t.Run("ApproveAccessRequestPrivilegedRole", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 6)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+		ctx = context.WithValue(ctx, auth.OrganizationIDKey, 3)
+
+		// Создаем мок репозиторий
+		orgRepo := new(mocks.MockOrganizationRepository)
+		roleRepo := new(mocks.MockRoleRepository)
+		accessRepo := new(mocks.MockAccessRequestRepository)
+
+		// Определяем ожидаемое поведение мока
+		orgRepo.On("IsOrganizationAdmin", ctx, 3, 6).Return(true, nil)
+		accessRepo.On("GetAccessRequest", ctx, 7).Return(&models.AccessRequest{
+			ID:             7,
+			UserID:         1,
+			OrganizationID: utils.Ptr(3),
+			RoleID:         9,
+			Status:         models.AccessRequestPending,
+		}, nil)
+		roleRepo.On("GetRoleWithPermissions", ctx, 9).Return(&models.Role{
+			ID:          9,
+			Permissions: []models.Permission{{ID: 4, Code: "incidents.write"}, {ID: 1, Code: auth.IsAdmin}},
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			orgRepo:    orgRepo,
+			roleRepo:   roleRepo,
+			accessRepo: accessRepo,
+		}
+
+		// Вызываем метод ApproveAccessRequest
+		result, err := baseHandler.ApproveAccessRequest(ctx, &grpc.AccessRequestDecision{Id: 7, Reason: "Needed"})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		orgRepo.AssertNotCalled(t, "IsOrganizationRole", mock.Anything, mock.Anything, mock.Anything)
+		accessRepo.AssertNotCalled(t, "GetRoleApprovers", mock.Anything, mock.Anything)
+		accessRepo.AssertNotCalled(t, "DecideAccessRequest", mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		orgRepo.AssertExpectations(t)
+		roleRepo.AssertExpectations(t)
+		accessRepo.AssertExpectations(t)
+	})
+
+	// Test 7: Если роль выдать не удалось, запрос возвращается в ожидание решения
+	t.Run("ApproveAccessRequestGrantFailed", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 5)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		roleRepo := new(mocks.MockRoleRepository)
+		accessRepo := new(mocks.MockAccessRequestRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+		mockNotifier := new(mocks.MockNotifier)
+
+		// Определяем ожидаемое поведение мока
+		accessRepo.On("GetAccessRequest", ctx, 7).Return(&models.AccessRequest{
+			ID:     7,
+			UserID: 1,
+			RoleID: 2,
+			Status: models.AccessRequestPending,
+		}, nil)
+		roleRepo.On("GetRoleWithPermissions", ctx, 2).Return(&models.Role{ID: 2}, nil)
+		accessRepo.On("GetRoleApprovers", ctx, 2).Return([]int{5}, nil)
+		accessRepo.On("DecideAccessRequest", ctx, mock.Anything).Return(true, nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "access_request.approve"
+		})).Return(nil).Once()
+		userRepo.On("AddUserRoles", ctx, 1, []int{2}, models.GrantValidity{}).Return(errors.New("database error"))
+		accessRepo.On("ReopenAccessRequest", ctx, 7).Return(nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "access_request.reopen" && *entry.ActorID == 5 && *entry.UserID == 1
+		})).Return(nil).Once()
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			roleRepo:   roleRepo,
+			accessRepo: accessRepo,
+			auditRepo:  auditRepo,
+			notifier:   mockNotifier,
+		}
+
+		// Вызываем метод ApproveAccessRequest
+		result, err := baseHandler.ApproveAccessRequest(ctx, &grpc.AccessRequestDecision{Id: 7, Reason: "Needed"})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.Internal, status.Code(err))
+		mockNotifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		roleRepo.AssertExpectations(t)
+		accessRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+	})
+}
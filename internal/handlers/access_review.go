@@ -0,0 +1,509 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateAccessReview создает кампанию пересмотра доступа со снимком текущих выдач ролей и прав,
+// входящих в ее область, и сообщает утверждающим о назначенных им выдачах
+func (bh *BaseHandler) CreateAccessReview(
+	ctx context.Context,
+	in *api_pb.AccessReviewCreateRequest,
+) (out *api_pb.AccessReview, err error) {
+	// Кампании создает только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные: область кампании должна быть ограничена ролями, правами или организацией
+	if in == nil || strings.TrimSpace(in.Name) == "" ||
+		(len(in.RoleIds) == 0 && len(in.PermissionIds) == 0 && in.OrganizationId == 0) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	review := &models.AccessReview{
+		Name:          strings.TrimSpace(in.Name),
+		RoleIDs:       convertInt32SliceToInt(in.RoleIds),
+		PermissionIDs: convertInt32SliceToInt(in.PermissionIds),
+		ReviewerID:    int(in.ReviewerId),
+		CreatedBy:     currentUserID(ctx),
+	}
+
+	// По умолчанию выдачи без утверждающих роли пересматривает создатель кампании
+	if review.ReviewerID == 0 {
+		review.ReviewerID = utils.FromPtr(review.CreatedBy)
+	}
+
+	// Репозиторий возвращает ошибку и для неизвестного пользователя
+	reviewer, err := bh.userRepo.GetUserByID(ctx, review.ReviewerID)
+	if err != nil || reviewer == nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Unknown user %d", review.ReviewerID))
+	}
+
+	if in.OrganizationId != 0 {
+		if _, err := bh.orgRepo.GetOrganizationByID(ctx, int(in.OrganizationId)); err != nil {
+			return nil, status.Error(codes.NotFound, "Organization not found")
+		}
+		review.OrganizationID = utils.Ptr(int(in.OrganizationId))
+	}
+
+	if err := bh.reviewRepo.CreateAccessReview(ctx, review); err != nil {
+		log.Printf("Failed to create access review, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create access review")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: review.CreatedBy,
+		Action:  "access_review.create",
+		Details: fmt.Sprintf("review %d: %s, %d grants", review.ID, review.Name, review.ItemCount),
+	})
+
+	bh.notifyAccessReviewers(ctx, review)
+
+	return accessReviewToPb(review), nil
+}
+
+// GetAccessReviews получает список кампаний, в которых текущему пользователю назначены выдачи.
+// Администратору доступны все кампании
+func (bh *BaseHandler) GetAccessReviews(
+	ctx context.Context,
+	in *api_pb.ListRequest,
+) (out *api_pb.AccessReviewsResponse, err error) {
+	reviewerID, err := accessReviewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Устанавливаем значения по умолчанию
+	limit := 10
+	offset := 0
+
+	// Парсим limit
+	if in.Limit > 0 && in.Limit < 100 {
+		limit = int(in.GetLimit())
+	}
+
+	// Парсим offset
+	if in.Offset > 0 {
+		offset = int(in.GetOffset())
+	}
+
+	reviews, err := bh.reviewRepo.GetAccessReviews(ctx, reviewerID, limit, offset)
+	if err != nil {
+		log.Printf("Failed to get access reviews, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get access reviews")
+	}
+
+	data := make([]*api_pb.AccessReview, 0, len(reviews))
+	for _, review := range reviews {
+		data = append(data, accessReviewToPb(review))
+	}
+
+	return &api_pb.AccessReviewsResponse{Data: data}, nil
+}
+
+// GetAccessReview получает кампанию пересмотра доступа по ID
+func (bh *BaseHandler) GetAccessReview(ctx context.Context, in *api_pb.Id) (out *api_pb.AccessReview, err error) {
+	reviewerID, err := accessReviewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	review, err := bh.getAccessReview(ctx, int(in.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	// Утверждающему доступны только кампании, в которых ему назначены выдачи
+	if reviewerID != 0 {
+		items, err := bh.reviewRepo.GetAccessReviewItems(ctx, review.ID, reviewerID, 1, 0)
+		if err != nil {
+			log.Printf("Failed to get access review items, err:%v\n", err)
+			return nil, status.Error(codes.Internal, "Failed to get access review")
+		}
+
+		if len(items) == 0 {
+			return nil, status.Error(codes.NotFound, "Access review not found")
+		}
+	}
+
+	return accessReviewToPb(review), nil
+}
+
+// GetAccessReviewItems получает выдачи кампании, назначенные текущему пользователю.
+// Администратору доступны все выдачи кампании
+func (bh *BaseHandler) GetAccessReviewItems(
+	ctx context.Context,
+	in *api_pb.AccessReviewItemsRequest,
+) (out *api_pb.AccessReviewItemsResponse, err error) {
+	reviewerID, err := accessReviewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Устанавливаем значения по умолчанию
+	limit := 10
+	offset := 0
+
+	// Парсим limit
+	if in.Limit > 0 && in.Limit < 100 {
+		limit = int(in.GetLimit())
+	}
+
+	// Парсим offset
+	if in.Offset > 0 {
+		offset = int(in.GetOffset())
+	}
+
+	if _, err := bh.getAccessReview(ctx, int(in.Id)); err != nil {
+		return nil, err
+	}
+
+	items, err := bh.reviewRepo.GetAccessReviewItems(ctx, int(in.Id), reviewerID, limit, offset)
+	if err != nil {
+		log.Printf("Failed to get access review items, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get access review items")
+	}
+
+	data := make([]*api_pb.AccessReviewItem, 0, len(items))
+	for _, item := range items {
+		data = append(data, accessReviewItemToPb(item))
+	}
+
+	return &api_pb.AccessReviewItemsResponse{Data: data}, nil
+}
+
+// DecideAccessReviewItem сохраняет решение утверждающего по выдаче: сохранить или отозвать.
+// Решение можно изменить, пока кампания не закрыта. Для отзыва причина обязательна
+func (bh *BaseHandler) DecideAccessReviewItem(
+	ctx context.Context,
+	in *api_pb.AccessReviewItemDecision,
+) (out *api_pb.AccessReviewItem, err error) {
+	userID := currentUserID(ctx)
+	if userID == nil {
+		return nil, status.Error(codes.Unauthenticated, "Unauthenticated")
+	}
+
+	// Проверяем входные данные
+	decision := models.AccessReviewDecision(in.GetDecision())
+	reason := strings.TrimSpace(in.GetReason())
+	if in == nil || in.Id == 0 || (decision != models.AccessReviewKeep && decision != models.AccessReviewRevoke) ||
+		(decision == models.AccessReviewRevoke && reason == "") {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	item, err := bh.getAccessReviewItem(ctx, int(in.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	// Решение принимает назначенный утверждающий или администратор, но не владелец выдачи
+	if item.OwnerType == models.GrantOwnerUser && item.OwnerID == *userID {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	if checkAdmin(ctx) != nil && utils.FromPtr(item.ReviewerID) != *userID {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	item.Decision = decision
+	item.Reason = reason
+	item.DecidedBy = userID
+
+	decided, err := bh.reviewRepo.DecideAccessReviewItem(ctx, item)
+	if err != nil {
+		log.Printf("Failed to decide access review item, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to decide access review item")
+	}
+
+	if !decided {
+		return nil, status.Error(codes.FailedPrecondition, "Access review is closed")
+	}
+
+	entry := &models.AuditEntry{
+		ActorID: userID,
+		Action:  "access_review.decide",
+		Reason:  item.Reason,
+		Details: fmt.Sprintf("review %d: %s", item.ReviewID, accessReviewItemDetails(item)),
+	}
+	if item.OwnerType == models.GrantOwnerUser {
+		entry.UserID = utils.Ptr(item.OwnerID)
+	}
+	bh.audit(ctx, entry)
+
+	return accessReviewItemToPb(item), nil
+}
+
+// ReassignAccessReviewItem назначает выдаче в кампании другого утверждающего
+func (bh *BaseHandler) ReassignAccessReviewItem(
+	ctx context.Context,
+	in *api_pb.AccessReviewItemReassign,
+) (out *api_pb.AccessReviewItem, err error) {
+	// Утверждающих назначает только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 || in.ReviewerId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	item, err := bh.getAccessReviewItem(ctx, int(in.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	// Репозиторий возвращает ошибку и для неизвестного пользователя
+	reviewer, err := bh.userRepo.GetUserByID(ctx, int(in.ReviewerId))
+	if err != nil || reviewer == nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Unknown user %d", in.ReviewerId))
+	}
+
+	// Владелец не может пересматривать собственную выдачу
+	if item.OwnerType == models.GrantOwnerUser && item.OwnerID == reviewer.ID {
+		return nil, status.Error(codes.InvalidArgument, "Reviewer cannot review own grant")
+	}
+
+	reassigned, err := bh.reviewRepo.ReassignAccessReviewItem(ctx, item.ID, reviewer.ID)
+	if err != nil {
+		log.Printf("Failed to reassign access review item, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to reassign access review item")
+	}
+
+	if !reassigned {
+		return nil, status.Error(codes.FailedPrecondition, "Access review is closed")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		Action:  "access_review.reassign",
+		Details: fmt.Sprintf(
+			"review %d: %s, reviewer %d -> %d",
+			item.ReviewID, accessReviewItemDetails(item), utils.FromPtr(item.ReviewerID), reviewer.ID,
+		),
+	})
+
+	item.ReviewerID = utils.Ptr(reviewer.ID)
+
+	return accessReviewItemToPb(item), nil
+}
+
+// CloseAccessReview закрывает кампанию и удаляет отозванные выдачи.
+// Выдачи без решения сохраняются. Повторный вызов для закрытой кампании
+// повторяет отзыв выдач, которые не удалось удалить
+func (bh *BaseHandler) CloseAccessReview(ctx context.Context, in *api_pb.Id) (out *api_pb.AccessReview, err error) {
+	// Кампании закрывает только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	review, err := bh.getAccessReview(ctx, int(in.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	// Кампания закрывается до отзыва, чтобы решения не менялись во время применения
+	if review.Status == models.AccessReviewOpen {
+		review.ClosedBy = currentUserID(ctx)
+		closed, err := bh.reviewRepo.CloseAccessReview(ctx, review)
+		if err != nil {
+			log.Printf("Failed to close access review, err:%v\n", err)
+			return nil, status.Error(codes.Internal, "Failed to close access review")
+		}
+
+		if closed {
+			bh.audit(ctx, &models.AuditEntry{
+				ActorID: review.ClosedBy,
+				Action:  "access_review.close",
+				Details: fmt.Sprintf("review %d: %d of %d grants decided", review.ID, review.DecidedCount, review.ItemCount),
+			})
+		}
+	}
+
+	revoked, err := bh.reviewRepo.RevokeAccessReviewGrants(ctx, review.ID)
+	for _, item := range revoked {
+		entry := &models.AuditEntry{
+			ActorID: currentUserID(ctx),
+			Action:  string(item.OwnerType) + "." + string(item.Kind) + "_revoke",
+			Details: fmt.Sprintf("access review %d: %s %d revoked", review.ID, item.Kind, item.ObjectID),
+		}
+		if item.OwnerType == models.GrantOwnerUser {
+			entry.UserID = utils.Ptr(item.OwnerID)
+		} else {
+			entry.Details = fmt.Sprintf("organization %d: %s", item.OwnerID, entry.Details)
+		}
+		bh.audit(ctx, entry)
+	}
+
+	if err != nil {
+		log.Printf("Failed to revoke access review grants, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to apply access review")
+	}
+
+	return bh.GetAccessReview(ctx, in)
+}
+
+// getAccessReview получает кампанию по ID и преобразует отсутствие кампании в ошибку NotFound
+func (bh *BaseHandler) getAccessReview(ctx context.Context, id int) (*models.AccessReview, error) {
+	review, err := bh.reviewRepo.GetAccessReview(ctx, id)
+	if err != nil {
+		log.Printf("Failed to get access review, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get access review")
+	}
+
+	if review == nil {
+		return nil, status.Error(codes.NotFound, "Access review not found")
+	}
+
+	return review, nil
+}
+
+// getAccessReviewItem получает выдачу в кампании по ID и преобразует отсутствие выдачи в ошибку NotFound
+func (bh *BaseHandler) getAccessReviewItem(ctx context.Context, id int) (*models.AccessReviewItem, error) {
+	item, err := bh.reviewRepo.GetAccessReviewItem(ctx, id)
+	if err != nil {
+		log.Printf("Failed to get access review item, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get access review item")
+	}
+
+	if item == nil {
+		return nil, status.Error(codes.NotFound, "Access review item not found")
+	}
+
+	return item, nil
+}
+
+// accessReviewer возвращает ID текущего пользователя для выбора назначенных ему выдач.
+// Для администратора возвращается 0 - все выдачи
+func accessReviewer(ctx context.Context) (int, error) {
+	userID := currentUserID(ctx)
+	if userID == nil {
+		return 0, status.Error(codes.Unauthenticated, "Unauthenticated")
+	}
+
+	if checkAdmin(ctx) == nil {
+		return 0, nil
+	}
+
+	return *userID, nil
+}
+
+// notifyAccessReviewers сообщает утверждающим о выдачах, назначенных им в новой кампании.
+// Ошибка отправки не прерывает запрос
+func (bh *BaseHandler) notifyAccessReviewers(ctx context.Context, review *models.AccessReview) {
+	items, err := bh.reviewRepo.GetAccessReviewItems(ctx, review.ID, 0, 0, 0)
+	if err != nil {
+		log.Printf("Failed to get access review items, err:%v\n", err)
+		return
+	}
+
+	// Считаем выдачи каждого утверждающего, сохраняя порядок первого появления
+	var reviewerIDs []int
+	counts := make(map[int]int)
+	for _, item := range items {
+		if item.ReviewerID == nil {
+			continue
+		}
+		if counts[*item.ReviewerID] == 0 {
+			reviewerIDs = append(reviewerIDs, *item.ReviewerID)
+		}
+		counts[*item.ReviewerID]++
+	}
+
+	for _, reviewerID := range reviewerIDs {
+		reviewer, err := bh.userRepo.GetUserByID(ctx, reviewerID)
+		if err != nil || reviewer == nil {
+			log.Printf("Failed to get access reviewer, err:%v\n", err)
+			continue
+		}
+
+		err = bh.notifier.Notify(ctx, notifier.Message{
+			To:      reviewer.Email,
+			Subject: "Access review",
+			Body: fmt.Sprintf(
+				"Access review %d %q has %d grants assigned to you.\nMark each grant keep or revoke.",
+				review.ID, review.Name, counts[reviewerID],
+			),
+		})
+		if err != nil {
+			log.Printf("Failed to notify access reviewer, err:%v\n", err)
+		}
+	}
+}
+
+// accessReviewItemDetails описывает выдачу в кампании для журнала аудита
+func accessReviewItemDetails(item *models.AccessReviewItem) string {
+	details := fmt.Sprintf("item %d, %s %d %s %d", item.ID, item.OwnerType, item.OwnerID, item.Kind, item.ObjectID)
+	if item.Decision != models.AccessReviewUndecided {
+		details += ": " + string(item.Decision)
+	}
+
+	return details
+}
+
+// accessReviewToPb преобразует кампанию пересмотра доступа в ответ API
+func accessReviewToPb(review *models.AccessReview) *api_pb.AccessReview {
+	return &api_pb.AccessReview{
+		Id:             int32(review.ID),
+		Name:           review.Name,
+		RoleIds:        convertIntSliceToInt32(review.RoleIDs),
+		PermissionIds:  convertIntSliceToInt32(review.PermissionIDs),
+		OrganizationId: int32(utils.FromPtr(review.OrganizationID)),
+		ReviewerId:     int32(review.ReviewerID),
+		Status:         string(review.Status),
+		CreatedBy:      int32(utils.FromPtr(review.CreatedBy)),
+		CreatedAt:      review.CreatedAt.Format(time.RFC3339),
+		ClosedBy:       int32(utils.FromPtr(review.ClosedBy)),
+		ClosedAt:       formatGrantTime(review.ClosedAt),
+		ItemCount:      int32(review.ItemCount),
+		DecidedCount:   int32(review.DecidedCount),
+	}
+}
+
+// accessReviewItemToPb преобразует выдачу в кампании пересмотра доступа в ответ API
+func accessReviewItemToPb(item *models.AccessReviewItem) *api_pb.AccessReviewItem {
+	return &api_pb.AccessReviewItem{
+		Id:         int32(item.ID),
+		ReviewId:   int32(item.ReviewID),
+		Kind:       string(item.Kind),
+		OwnerType:  string(item.OwnerType),
+		OwnerId:    int32(item.OwnerID),
+		OwnerName:  item.OwnerName,
+		ObjectId:   int32(item.ObjectID),
+		ObjectCode: item.ObjectCode,
+		ValidUntil: formatGrantTime(item.ValidUntil),
+		ReviewerId: int32(utils.FromPtr(item.ReviewerID)),
+		Decision:   string(item.Decision),
+		Reason:     item.Reason,
+		DecidedBy:  int32(utils.FromPtr(item.DecidedBy)),
+		DecidedAt:  formatGrantTime(item.DecidedAt),
+		Applied:    item.Applied,
+	}
+}
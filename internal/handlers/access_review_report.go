@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// accessReviewReportColumns заголовок CSV отчета кампании пересмотра доступа
+var accessReviewReportColumns = []string{
+	"item_id", "kind", "owner_type", "owner_id", "owner_name", "object_id", "object_code", "valid_until",
+	"reviewer_id", "decision", "reason", "decided_by", "decided_at", "applied",
+}
+
+// GetAccessReviewReport формирует отчет о решениях кампании пересмотра доступа в формате CSV или JSON.
+// Отчет закрытой кампании отражает итоговые решения и выполненные отзывы
+func (bh *BaseHandler) GetAccessReviewReport(
+	ctx context.Context,
+	in *api_pb.AccessReviewReportRequest,
+) (out *api_pb.AccessReviewReportResponse, err error) {
+	// Отчет доступен только администратору
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 || (in.Format != "" && in.Format != "json" && in.Format != "csv") {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	review, err := bh.getAccessReview(ctx, int(in.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := bh.reviewRepo.GetAccessReviewItems(ctx, review.ID, 0, 0, 0)
+	if err != nil {
+		log.Printf("Failed to get access review items, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to build access review report")
+	}
+
+	fileName := fmt.Sprintf("access_review_%d", review.ID)
+
+	// Формируем ответ в запрошенном формате
+	if in.Format == "csv" {
+		data, err := accessReviewCSV(items)
+		if err != nil {
+			log.Printf("Failed to write access review report, err:%v\n", err)
+			return nil, status.Error(codes.Internal, "Failed to build access review report")
+		}
+
+		return &api_pb.AccessReviewReportResponse{
+			FileName:    fileName + ".csv",
+			ContentType: "text/csv",
+			Data:        data,
+		}, nil
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Review *models.AccessReview       `json:"review"`
+		Items  []*models.AccessReviewItem `json:"items"`
+	}{Review: review, Items: items}, "", "  ")
+	if err != nil {
+		log.Printf("Failed to marshal access review report, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to build access review report")
+	}
+
+	return &api_pb.AccessReviewReportResponse{
+		FileName:    fileName + ".json",
+		ContentType: "application/json",
+		Data:        data,
+	}, nil
+}
+
+// accessReviewCSV записывает выдачи кампании и решения по ним в CSV, по одной выдаче в строке
+func accessReviewCSV(items []*models.AccessReviewItem) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(accessReviewReportColumns); err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		record := []string{
+			strconv.Itoa(item.ID),
+			string(item.Kind),
+			string(item.OwnerType),
+			strconv.Itoa(item.OwnerID),
+			item.OwnerName,
+			strconv.Itoa(item.ObjectID),
+			item.ObjectCode,
+			formatGrantTime(item.ValidUntil),
+			optionalID(item.ReviewerID),
+			string(item.Decision),
+			item.Reason,
+			optionalID(item.DecidedBy),
+			formatGrantTime(item.DecidedAt),
+			strconv.FormatBool(item.Applied),
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// optionalID форматирует необязательный ID, отсутствующий ID записывается пустой строкой
+func optionalID(id *int) string {
+	if id == nil {
+		return ""
+	}
+
+	return strconv.Itoa(*id)
+}
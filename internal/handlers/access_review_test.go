@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_AccessReview тестирует кампании пересмотра доступа
+func TestBaseHandler_AccessReview(t *testing.T) {
+	adminCtx := context.WithValue(context.Background(), auth.UserIDKey, 100)
+	adminCtx = context.WithValue(adminCtx, auth.IsAdminKey, true)
+
+	// Test 1: Кампания создается со снимком выдач, утверждающие получают уведомление
+	t.Run("CreateAccessReview", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		reviewRepo := new(mocks.MockAccessReviewRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+		mockNotifier := new(mocks.MockNotifier)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", adminCtx, 100).Return(&models.User{ID: 100, Email: "admin@example.com"}, nil)
+		userRepo.On("GetUserByID", adminCtx, 5).Return(&models.User{ID: 5, Email: "lead@example.com"}, nil)
+		reviewRepo.On("CreateAccessReview", adminCtx, mock.MatchedBy(func(review *models.AccessReview) bool {
+			return review.Name == "Q3 admins" && review.ReviewerID == 100 && *review.CreatedBy == 100 &&
+				assert.ObjectsAreEqual([]int{2}, review.RoleIDs)
+		})).Run(func(args mock.Arguments) {
+			review := args.Get(1).(*models.AccessReview)
+			review.ID = 1
+			review.Status = models.AccessReviewOpen
+			review.ItemCount = 3
+		}).Return(nil)
+		reviewRepo.On("GetAccessReviewItems", adminCtx, 1, 0, 0, 0).Return([]*models.AccessReviewItem{
+			{ID: 1, ReviewerID: utils.Ptr(5)},
+			{ID: 2, ReviewerID: utils.Ptr(5)},
+			{ID: 3, ReviewerID: utils.Ptr(100)},
+		}, nil)
+		auditRepo.On("AddAuditEntry", adminCtx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "access_review.create" && entry.Details == "review 1: Q3 admins, 3 grants"
+		})).Return(nil)
+		mockNotifier.On("Notify", adminCtx, mock.MatchedBy(func(msg notifier.Message) bool {
+			return msg.To == "lead@example.com" && strings.Contains(msg.Body, "has 2 grants assigned to you")
+		})).Return(nil).Once()
+		mockNotifier.On("Notify", adminCtx, mock.MatchedBy(func(msg notifier.Message) bool {
+			return msg.To == "admin@example.com" && strings.Contains(msg.Body, "has 1 grants assigned to you")
+		})).Return(nil).Once()
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			reviewRepo: reviewRepo,
+			auditRepo:  auditRepo,
+			notifier:   mockNotifier,
+		}
+
+		// Вызываем метод CreateAccessReview
+		result, err := baseHandler.CreateAccessReview(adminCtx, &grpc.AccessReviewCreateRequest{
+			Name:    "Q3 admins",
+			RoleIds: []int32{2},
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), result.Id)
+		assert.Equal(t, string(models.AccessReviewOpen), result.Status)
+		assert.Equal(t, int32(3), result.ItemCount)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		reviewRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+		mockNotifier.AssertExpectations(t)
+	})
+
+	// Test 2: Утверждающий отзывает назначенную ему выдачу с причиной, свою выдачу пересмотреть нельзя
+	t.Run("DecideAccessReviewItem", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 5)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+
+		// Создаем мок репозиторий
+		reviewRepo := new(mocks.MockAccessReviewRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+
+		// Определяем ожидаемое поведение мока
+		reviewRepo.On("GetAccessReviewItem", ctx, 1).Return(&models.AccessReviewItem{
+			ID: 1, ReviewID: 1, Kind: models.GrantKindRole, OwnerType: models.GrantOwnerUser, OwnerID: 7, ObjectID: 2,
+			ReviewerID: utils.Ptr(5),
+		}, nil)
+		reviewRepo.On("GetAccessReviewItem", ctx, 2).Return(&models.AccessReviewItem{
+			ID: 2, ReviewID: 1, Kind: models.GrantKindRole, OwnerType: models.GrantOwnerUser, OwnerID: 5, ObjectID: 2,
+			ReviewerID: utils.Ptr(5),
+		}, nil)
+		reviewRepo.On("DecideAccessReviewItem", ctx, mock.MatchedBy(func(item *models.AccessReviewItem) bool {
+			return item.ID == 1 && item.Decision == models.AccessReviewRevoke && *item.DecidedBy == 5
+		})).Return(true, nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "access_review.decide" && *entry.UserID == 7 && entry.Reason == "Left the team" &&
+				entry.Details == "review 1: item 1, user 7 role 2: revoke"
+		})).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			reviewRepo: reviewRepo,
+			auditRepo:  auditRepo,
+		}
+
+		// Отзыв без причины отклоняется
+		result, err := baseHandler.DecideAccessReviewItem(ctx, &grpc.AccessReviewItemDecision{Id: 1, Decision: "revoke"})
+		assert.Nil(t, result)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		// Вызываем метод DecideAccessReviewItem
+		result, err = baseHandler.DecideAccessReviewItem(ctx, &grpc.AccessReviewItemDecision{
+			Id:       1,
+			Decision: "revoke",
+			Reason:   "Left the team",
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, "revoke", result.Decision)
+
+		// Свою выдачу утверждающий пересмотреть не может
+		result, err = baseHandler.DecideAccessReviewItem(ctx, &grpc.AccessReviewItemDecision{Id: 2, Decision: "keep"})
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		// Проверяем, что моки были вызваны правильно
+		reviewRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+	})
+
+	// Test 3: При закрытии кампании отозванные выдачи удаляются и записываются в журнал аудита
+	t.Run("CloseAccessReview", func(t *testing.T) {
+		// Создаем мок репозиторий
+		reviewRepo := new(mocks.MockAccessReviewRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+
+		// Определяем ожидаемое поведение мока
+		reviewRepo.On("GetAccessReview", adminCtx, 1).Return(&models.AccessReview{
+			ID: 1, Status: models.AccessReviewOpen, ItemCount: 3, DecidedCount: 2,
+		}, nil).Once()
+		reviewRepo.On("CloseAccessReview", adminCtx, mock.MatchedBy(func(review *models.AccessReview) bool {
+			return review.ID == 1 && *review.ClosedBy == 100
+		})).Return(true, nil)
+		reviewRepo.On("RevokeAccessReviewGrants", adminCtx, 1).Return([]*models.AccessReviewItem{
+			{ID: 1, Kind: models.GrantKindRole, OwnerType: models.GrantOwnerUser, OwnerID: 7, ObjectID: 2},
+			{ID: 3, Kind: models.GrantKindPermission, OwnerType: models.GrantOwnerOrganization, OwnerID: 3, ObjectID: 4},
+		}, nil)
+		reviewRepo.On("GetAccessReview", adminCtx, 1).Return(&models.AccessReview{
+			ID: 1, Status: models.AccessReviewClosed, ItemCount: 3, DecidedCount: 2,
+		}, nil).Once()
+		auditRepo.On("AddAuditEntry", adminCtx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "access_review.close" && entry.Details == "review 1: 2 of 3 grants decided"
+		})).Return(nil).Once()
+		auditRepo.On("AddAuditEntry", adminCtx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "user.role_revoke" && *entry.UserID == 7 &&
+				entry.Details == "access review 1: role 2 revoked"
+		})).Return(nil).Once()
+		auditRepo.On("AddAuditEntry", adminCtx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "organization.permission_revoke" && entry.UserID == nil &&
+				entry.Details == "organization 3: access review 1: permission 4 revoked"
+		})).Return(nil).Once()
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			reviewRepo: reviewRepo,
+			auditRepo:  auditRepo,
+		}
+
+		// Вызываем метод CloseAccessReview
+		result, err := baseHandler.CloseAccessReview(adminCtx, &grpc.Id{Id: 1})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, string(models.AccessReviewClosed), result.Status)
+
+		// Проверяем, что моки были вызваны правильно
+		reviewRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+	})
+
+	// Test 4: Отчет о решениях кампании выгружается в CSV
+	t.Run("GetAccessReviewReportCSV", func(t *testing.T) {
+		// Создаем мок репозиторий
+		reviewRepo := new(mocks.MockAccessReviewRepository)
+
+		// Определяем ожидаемое поведение мока
+		reviewRepo.On("GetAccessReview", adminCtx, 1).Return(&models.AccessReview{
+			ID: 1, Status: models.AccessReviewClosed,
+		}, nil)
+		reviewRepo.On("GetAccessReviewItems", adminCtx, 1, 0, 0, 0).Return([]*models.AccessReviewItem{
+			{
+				ID: 1, Kind: models.GrantKindRole, OwnerType: models.GrantOwnerUser, OwnerID: 7,
+				OwnerName: "user@example.com", ObjectID: 2, ObjectCode: "admin", ReviewerID: utils.Ptr(5),
+				Decision: models.AccessReviewRevoke, Reason: "Left the team, moved to sales", Applied: true,
+			},
+			{
+				ID: 2, Kind: models.GrantKindPermission, OwnerType: models.GrantOwnerOrganization, OwnerID: 3,
+				OwnerName: "Example Corp", ObjectID: 4, ObjectCode: "billing.read",
+			},
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{reviewRepo: reviewRepo}
+
+		// Вызываем метод GetAccessReviewReport
+		result, err := baseHandler.GetAccessReviewReport(adminCtx, &grpc.AccessReviewReportRequest{Id: 1, Format: "csv"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, "access_review_1.csv", result.FileName)
+		assert.Equal(t, "text/csv", result.ContentType)
+		assert.Equal(t, strings.Join([]string{
+			"item_id,kind,owner_type,owner_id,owner_name,object_id,object_code,valid_until,reviewer_id,decision," +
+				"reason,decided_by,decided_at,applied",
+			`1,role,user,7,user@example.com,2,admin,,5,revoke,"Left the team, moved to sales",,,true`,
+			"2,permission,organization,3,Example Corp,4,billing.read,,,,,,,false",
+			"",
+		}, "\n"), string(result.Data))
+
+		// Проверяем, что моки были вызваны правильно
+		reviewRepo.AssertExpectations(t)
+	})
+}
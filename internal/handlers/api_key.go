@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyVisiblePrefix длина начала ключа, которое сохраняется и показывается в списке
+const apiKeyVisiblePrefix = 11
+
+// CreateApiKey выпускает API ключ сервисного аккаунта.
+// Ключ возвращается только в ответе на этот запрос, в БД хранится его хеш
+func (bh *BaseHandler) CreateApiKey(
+	ctx context.Context,
+	in *api_pb.ApiKeyCreateRequest,
+) (out *api_pb.ApiKeyCreateResponse, err error) {
+	// Управлять API ключами может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Name == "" || in.TtlDays < 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	account, err := bh.getServiceAccount(ctx, in.ServiceAccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := bh.cfg.APIKeys.DefaultTTL
+	if in.TtlDays > 0 {
+		ttl = time.Duration(in.TtlDays) * 24 * time.Hour
+	}
+	if ttl > bh.cfg.APIKeys.MaxTTL {
+		return nil, status.Error(codes.InvalidArgument, "API key TTL exceeds maximum")
+	}
+
+	// Ключ не может получить права, которых нет у сервисного аккаунта
+	if err := bh.checkAPIKeyScopes(ctx, account.ID, in.Scopes); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	key, secret, err := bh.issueAPIKey(ctx, auth.APIKeyPrefix, account.ID, in.Name, in.Scopes, &expiresAt)
+	if err != nil {
+		log.Printf("Failed to create api key, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create API key")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(account.ID),
+		Action:  "service_account.api_key_create",
+		Details: fmt.Sprintf("key %d", key.ID),
+	})
+
+	return &api_pb.ApiKeyCreateResponse{ApiKey: apiKeyToPb(key), Key: secret}, nil
+}
+
+// GetApiKeys получает API ключи сервисного аккаунта, включая отозванные и истекшие
+func (bh *BaseHandler) GetApiKeys(ctx context.Context, in *api_pb.Id) (out *api_pb.ApiKeysResponse, err error) {
+	// Управлять API ключами может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	if in == nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	account, err := bh.getServiceAccount(ctx, in.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := bh.apiKeyRepo.GetAPIKeys(ctx, account.ID)
+	if err != nil {
+		log.Printf("Failed to get api keys, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get API keys")
+	}
+
+	data := make([]*api_pb.ApiKey, len(keys))
+	for i, key := range keys {
+		data[i] = apiKeyToPb(key)
+	}
+
+	return &api_pb.ApiKeysResponse{Data: data}, nil
+}
+
+// RevokeApiKey отзывает API ключ, запросы с ним сразу перестают проходить аутентификацию
+func (bh *BaseHandler) RevokeApiKey(ctx context.Context, in *api_pb.ApiKeyRequest) (out *api_pb.Empty, err error) {
+	// Управлять API ключами может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.ServiceAccountId == 0 || in.KeyId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	ok, err := bh.apiKeyRepo.RevokeAPIKey(ctx, int(in.ServiceAccountId), int(in.KeyId))
+	if err != nil {
+		log.Printf("Failed to revoke api key, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to revoke API key")
+	}
+
+	if !ok {
+		return nil, status.Error(codes.NotFound, "API key not found")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(int(in.ServiceAccountId)),
+		Action:  "service_account.api_key_revoke",
+		Details: fmt.Sprintf("key %d", in.KeyId),
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// RotateApiKey выпускает новый ключ с тем же именем, правами и сроком жизни.
+// Старый ключ отзывается сразу или, если задан grace_period_seconds, истекает по окончании этого периода
+func (bh *BaseHandler) RotateApiKey(
+	ctx context.Context,
+	in *api_pb.ApiKeyRotateRequest,
+) (out *api_pb.ApiKeyCreateResponse, err error) {
+	// Управлять API ключами может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.ServiceAccountId == 0 || in.KeyId == 0 || in.GracePeriodSeconds < 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	old, err := bh.apiKeyRepo.GetAPIKey(ctx, int(in.ServiceAccountId), int(in.KeyId))
+	if err != nil {
+		log.Printf("Failed to get api key, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to rotate API key")
+	}
+
+	if old == nil {
+		return nil, status.Error(codes.NotFound, "API key not found")
+	}
+
+	now := time.Now()
+	if old.RevokedAt != nil || old.ExpiresAt == nil || !old.ExpiresAt.After(now) {
+		return nil, status.Error(codes.FailedPrecondition, "API key is not active")
+	}
+
+	ttl := old.ExpiresAt.Sub(old.CreatedAt)
+	if ttl > bh.cfg.APIKeys.MaxTTL {
+		ttl = bh.cfg.APIKeys.MaxTTL
+	}
+
+	expiresAt := now.Add(ttl)
+	key, secret, err := bh.issueAPIKey(ctx, auth.APIKeyPrefix, old.UserID, old.Name, old.Scopes, &expiresAt)
+	if err != nil {
+		log.Printf("Failed to create api key, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to rotate API key")
+	}
+
+	if in.GracePeriodSeconds > 0 {
+		expiresAt := now.Add(time.Duration(in.GracePeriodSeconds) * time.Second)
+		err = bh.apiKeyRepo.ExpireAPIKey(ctx, old.UserID, old.ID, expiresAt)
+	} else {
+		_, err = bh.apiKeyRepo.RevokeAPIKey(ctx, old.UserID, old.ID)
+	}
+	if err != nil {
+		log.Printf("Failed to retire rotated api key, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to rotate API key")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(old.UserID),
+		Action:  "service_account.api_key_rotate",
+		Details: fmt.Sprintf("key %d replaced by %d", old.ID, key.ID),
+	})
+
+	return &api_pb.ApiKeyCreateResponse{ApiKey: apiKeyToPb(key), Key: secret}, nil
+}
+
+// issueAPIKey генерирует ключ с указанным префиксом и сохраняет его хеш
+func (bh *BaseHandler) issueAPIKey(
+	ctx context.Context,
+	prefix string,
+	userID int,
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (*models.APIKey, string, error) {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	secret := prefix + token
+	key := &models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:apiKeyVisiblePrefix],
+		KeyHash:   utils.HashToken(secret),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := bh.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+// checkAPIKeyScopes проверяет, что все права ключа выданы его владельцу
+func (bh *BaseHandler) checkAPIKeyScopes(ctx context.Context, userID int, scopes []string) error {
+	if len(scopes) == 0 {
+		return nil
+	}
+
+	permissions, err := bh.userRepo.GetUserPermissions(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to get user permissions, err:%v\n", err)
+		return status.Error(codes.Internal, "Failed to get permissions")
+	}
+
+	granted := make(map[string]struct{}, len(permissions))
+	for _, permission := range permissions {
+		granted[permission.Code] = struct{}{}
+	}
+
+	for _, scope := range scopes {
+		if _, ok := granted[scope]; !ok {
+			return status.Error(codes.InvalidArgument, fmt.Sprintf("Scope %q is not granted", scope))
+		}
+	}
+
+	return nil
+}
+
+// apiKeyToPb преобразует API ключ в ответ API
+func apiKeyToPb(key *models.APIKey) *api_pb.ApiKey {
+	out := &api_pb.ApiKey{
+		Id:               int32(key.ID),
+		ServiceAccountId: int32(key.UserID),
+		Name:             key.Name,
+		Prefix:           key.Prefix,
+		Scopes:           key.Scopes,
+		CreatedAt:        key.CreatedAt.Format(time.RFC3339),
+	}
+	if key.ExpiresAt != nil {
+		out.ExpiresAt = key.ExpiresAt.Format(time.RFC3339)
+	}
+	if key.LastUsedAt != nil {
+		out.LastUsedAt = key.LastUsedAt.Format(time.RFC3339)
+	}
+	if key.RevokedAt != nil {
+		out.RevokedAt = key.RevokedAt.Format(time.RFC3339)
+	}
+
+	return out
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_APIKeys тестирует методы работы с сервисными аккаунтами и API ключами
+func TestBaseHandler_APIKeys(t *testing.T) {
+	adminCtx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+	adminCtx = context.WithValue(adminCtx, auth.IsAdminKey, true)
+
+	serviceAccount := &models.User{
+		ID:     10,
+		Name:   "billing",
+		Email:  "billing@service-accounts.invalid",
+		Status: models.UserStatusActive,
+		Kind:   models.UserKindService,
+	}
+
+	// Test 1: Сервисный аккаунт создается без пароля с синтетическим email
+	t.Run("CreateServiceAccount", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByEmail", adminCtx, "billing@service-accounts.invalid").Return((*models.User)(nil), nil)
+		var created *models.User
+		userRepo.On("CreateUser", adminCtx, mock.Anything).Run(func(args mock.Arguments) {
+			created = args.Get(1).(*models.User)
+			created.ID = 10
+		}).Return(serviceAccount, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{userRepo: userRepo}
+
+		// Вызываем метод CreateServiceAccount
+		result, err := baseHandler.CreateServiceAccount(adminCtx, &grpc.ServiceAccountCreateRequest{Name: "billing"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, int32(10), result.Id)
+		assert.Equal(t, "billing", result.Name)
+		assert.Equal(t, models.UserKindService, created.Kind)
+		assert.Empty(t, created.PasswordHash)
+		assert.True(t, created.EmailVerified)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Управлять сервисными аккаунтами может только администратор
+	t.Run("CreateServiceAccountNotAdmin", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 2)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{}
+
+		// Вызываем метод CreateServiceAccount
+		result, err := baseHandler.CreateServiceAccount(ctx, &grpc.ServiceAccountCreateRequest{Name: "billing"})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	// Test 3: Ключ выпускается один раз, в БД сохраняется только его хеш
+	t.Run("CreateApiKey", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		apiKeyRepo := new(mocks.MockAPIKeyRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", adminCtx, 10).Return(serviceAccount, nil)
+		userRepo.On("GetUserPermissions", adminCtx, 10).Return([]*models.Permission{
+			{ID: 1, Code: "invoices.read"},
+			{ID: 2, Code: "invoices.write"},
+		}, nil)
+		var stored *models.APIKey
+		apiKeyRepo.On("CreateAPIKey", adminCtx, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.APIKey)
+			stored.ID = 5
+			stored.CreatedAt = time.Now()
+		}).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			apiKeyRepo: apiKeyRepo,
+			cfg:        DefaultConfig(),
+		}
+
+		// Вызываем метод CreateApiKey
+		result, err := baseHandler.CreateApiKey(adminCtx, &grpc.ApiKeyCreateRequest{
+			ServiceAccountId: 10,
+			Name:             "deploy",
+			Scopes:           []string{"invoices.read"},
+			TtlDays:          30,
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(result.Key, "ak_"))
+		assert.Equal(t, utils.HashToken(result.Key), stored.KeyHash)
+		assert.Equal(t, result.Key[:11], result.ApiKey.Prefix)
+		assert.Equal(t, []string{"invoices.read"}, result.ApiKey.Scopes)
+		assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), *stored.ExpiresAt, time.Minute)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		apiKeyRepo.AssertExpectations(t)
+	})
+
+	// Test 4: Ключ не может получить права, которых нет у сервисного аккаунта
+	t.Run("CreateApiKeyScopeNotGranted", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		apiKeyRepo := new(mocks.MockAPIKeyRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", adminCtx, 10).Return(serviceAccount, nil)
+		userRepo.On("GetUserPermissions", adminCtx, 10).Return([]*models.Permission{
+			{ID: 1, Code: "invoices.read"},
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			apiKeyRepo: apiKeyRepo,
+			cfg:        DefaultConfig(),
+		}
+
+		// Вызываем метод CreateApiKey
+		result, err := baseHandler.CreateApiKey(adminCtx, &grpc.ApiKeyCreateRequest{
+			ServiceAccountId: 10,
+			Name:             "deploy",
+			Scopes:           []string{"users.delete"},
+		})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		apiKeyRepo.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+	})
+
+	// Test 5: Ключи выпускаются только для сервисных аккаунтов
+	t.Run("CreateApiKeyForHuman", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", adminCtx, 2).Return(&models.User{
+			ID:     2,
+			Status: models.UserStatusActive,
+			Kind:   models.UserKindHuman,
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			cfg:      DefaultConfig(),
+		}
+
+		// Вызываем метод CreateApiKey
+		result, err := baseHandler.CreateApiKey(adminCtx, &grpc.ApiKeyCreateRequest{ServiceAccountId: 2, Name: "deploy"})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+	})
+
+	// Test 6: При ротации со льготным периодом старый ключ истекает позже, а не отзывается сразу
+	t.Run("RotateApiKeyWithGracePeriod", func(t *testing.T) {
+		// Создаем мок репозиторий
+		apiKeyRepo := new(mocks.MockAPIKeyRepository)
+
+		// Определяем ожидаемое поведение мока
+		createdAt := time.Now().Add(-24 * time.Hour)
+		apiKeyRepo.On("GetAPIKey", adminCtx, 10, 5).Return(&models.APIKey{
+			ID:        5,
+			UserID:    10,
+			Name:      "deploy",
+			Scopes:    []string{"invoices.read"},
+			ExpiresAt: utils.Ptr(createdAt.Add(30 * 24 * time.Hour)),
+			CreatedAt: createdAt,
+		}, nil)
+		var rotated *models.APIKey
+		apiKeyRepo.On("CreateAPIKey", adminCtx, mock.Anything).Run(func(args mock.Arguments) {
+			rotated = args.Get(1).(*models.APIKey)
+			rotated.ID = 6
+		}).Return(nil)
+		apiKeyRepo.On("ExpireAPIKey", adminCtx, 10, 5, mock.Anything).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			apiKeyRepo: apiKeyRepo,
+			cfg:        DefaultConfig(),
+		}
+
+		// Вызываем метод RotateApiKey
+		result, err := baseHandler.RotateApiKey(adminCtx, &grpc.ApiKeyRotateRequest{
+			ServiceAccountId:   10,
+			KeyId:              5,
+			GracePeriodSeconds: 3600,
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, int32(6), result.ApiKey.Id)
+		assert.Equal(t, "deploy", rotated.Name)
+		assert.Equal(t, []string{"invoices.read"}, rotated.Scopes)
+		assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), *rotated.ExpiresAt, time.Minute)
+		apiKeyRepo.AssertNotCalled(t, "RevokeAPIKey", mock.Anything, mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		apiKeyRepo.AssertExpectations(t)
+	})
+
+	// Test 7: Сервисный аккаунт не может войти по паролю
+	t.Run("LoginServiceAccount", func(t *testing.T) {
+		ctx := context.Background()
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByEmail", ctx, "billing@service-accounts.invalid").Return(serviceAccount, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{userRepo: userRepo}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{
+			Email:    "billing@service-accounts.invalid",
+			Password: "password123",
+		})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		userRepo.AssertNotCalled(t, "CheckPassword", mock.Anything, mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+	})
+}
@@ -1,20 +1,50 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/export"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/oidc"
 	"github.com/LiFeAiR/crud-ai/internal/repository"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
 	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // BaseHandler базовый обработчик, который принимает репозитории
 type BaseHandler struct {
-	userRepo   repository.UserRepository
-	orgRepo    repository.OrganizationRepository
-	permRepo   repository.PermissionRepository
-	roleRepo   repository.RoleRepository
-	tariffRepo repository.TariffRepository
-	secretKey  string
+	userRepo    repository.UserRepository
+	orgRepo     repository.OrganizationRepository
+	permRepo    repository.PermissionRepository
+	roleRepo    repository.RoleRepository
+	tariffRepo  repository.TariffRepository
+	auditRepo   repository.AuditRepository
+	attemptRepo repository.LoginAttemptRepository
+	tokenRepo   repository.TokenRepository
+	twoFARepo   repository.TwoFactorRepository
+	sessionRepo repository.SessionRepository
+	apiKeyRepo  repository.APIKeyRepository
+	oidcRepo    repository.OIDCRepository
+	oauthRepo   repository.OAuthRepository
+	accessRepo  repository.AccessRequestRepository
+	reviewRepo  repository.AccessReviewRepository
+	sodRepo     repository.SoDRepository
+	tx          repository.Transactor
+	notifier    notifier.Notifier
+	secretKey   string
+	cfg         Config
+	exporter    *export.Exporter
+	verifier    *auth.Verifier
+	oidcClient  *oidc.Client
+	background  errgroup.Group
 
-	jwtFunc func(string, int, string, string, []string) (string, error)
+	jwtFunc func(string, *utils.Claims) (string, error)
 }
 
 // NewBaseHandler создает новый базовый обработчик
@@ -24,16 +54,167 @@ func NewBaseHandler(
 	permRepo repository.PermissionRepository,
 	roleRepo repository.RoleRepository,
 	tariffRepo repository.TariffRepository,
+	auditRepo repository.AuditRepository,
+	attemptRepo repository.LoginAttemptRepository,
+	tokenRepo repository.TokenRepository,
+	twoFARepo repository.TwoFactorRepository,
+	sessionRepo repository.SessionRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	oidcRepo repository.OIDCRepository,
+	oauthRepo repository.OAuthRepository,
+	accessRepo repository.AccessRequestRepository,
+	reviewRepo repository.AccessReviewRepository,
+	sodRepo repository.SoDRepository,
+	tx repository.Transactor,
+	n notifier.Notifier,
 	secretKey string,
+	cfg Config,
 ) *BaseHandler {
-	return &BaseHandler{
-		userRepo:   userRepo,
-		orgRepo:    orgRepo,
-		permRepo:   permRepo,
-		roleRepo:   roleRepo,
-		tariffRepo: tariffRepo,
-		secretKey:  secretKey,
-		jwtFunc:    utils.GenerateJWT,
+	bh := &BaseHandler{
+		userRepo:    userRepo,
+		orgRepo:     orgRepo,
+		permRepo:    permRepo,
+		roleRepo:    roleRepo,
+		tariffRepo:  tariffRepo,
+		auditRepo:   auditRepo,
+		attemptRepo: attemptRepo,
+		tokenRepo:   tokenRepo,
+		twoFARepo:   twoFARepo,
+		sessionRepo: sessionRepo,
+		apiKeyRepo:  apiKeyRepo,
+		oidcRepo:    oidcRepo,
+		oauthRepo:   oauthRepo,
+		accessRepo:  accessRepo,
+		reviewRepo:  reviewRepo,
+		sodRepo:     sodRepo,
+		tx:          tx,
+		notifier:    n,
+		secretKey:   secretKey,
+		cfg:         cfg,
+		exporter:    export.NewExporter(export.UserSections(userRepo, orgRepo, roleRepo, auditRepo, sessionRepo)...),
+		verifier:    auth.NewVerifier(secretKey, cfg.OAuth.Audience, userRepo, sessionRepo, apiKeyRepo, orgRepo),
+		oidcClient:  oidc.NewClient(cfg.OIDC.HTTPTimeout),
+		jwtFunc:     utils.GenerateJWT,
+	}
+	// Каждая подсистема, которая хранит данные пользователя, добавляет свой раздел выгрузки
+	bh.exporter.Register(
+		export.TwoFactorSection(twoFARepo),
+		export.OIDCIdentitySection(oidcRepo),
+		export.APIKeySection(apiKeyRepo),
+		export.AccessRequestSection(accessRepo),
+		export.AccessReviewSection(reviewRepo),
+		export.LoginAttemptSection(attemptRepo),
+		export.OAuthConsentSection(oauthRepo),
+		export.OrganizationAdminSection(orgRepo),
+	)
+	if cfg.Background.MaxTasks > 0 {
+		bh.background.SetLimit(cfg.Background.MaxTasks)
+	}
+
+	return bh
+}
+
+// checkAdmin проверяет, что запрос выполняет администратор
+func checkAdmin(ctx context.Context) error {
+	isAdmin, ok := ctx.Value(auth.IsAdminKey).(bool)
+	if !ok || !isAdmin {
+		return errors.New("checkAdmin.PermissionDenied")
+	}
+
+	return nil
+}
+
+// requireLoginToken проверяет, что запрос выполнен по токену, выданному пользователю при входе,
+// а не по API ключу, персональному токену, токену обмена или токену OAuth клиента
+func requireLoginToken(ctx context.Context) error {
+	if auth.APIKeyID(ctx) != 0 || auth.IsDelegated(ctx) {
+		return errors.New("requireLoginToken.PermissionDenied")
+	}
+
+	return nil
+}
+
+// requireInteractiveSession проверяет, что запрос выполнен по токену входа с полными правами.
+// Управлять учетной записью нельзя ни по выданным от ее имени токенам, ни по токену без прав,
+// выданному до подтверждения email или подключения 2FA
+func requireInteractiveSession(ctx context.Context) error {
+	if err := requireLoginToken(ctx); err != nil {
+		return err
+	}
+
+	if auth.IsLimited(ctx) {
+		return errors.New("requireInteractiveSession.PermissionDenied")
+	}
+
+	return nil
+}
+
+// currentUserID возвращает ID авторизованного пользователя
+func currentUserID(ctx context.Context) *int {
+	userID, ok := ctx.Value(auth.UserIDKey).(int)
+	if !ok {
+		return nil
+	}
+
+	return &userID
+}
+
+// inTx выполняет fn в одной транзакции репозиториев, без Transactor fn выполняется без транзакции.
+// Ошибки gRPC из fn возвращаются без изменений, ошибка самой транзакции - как Internal
+func (bh *BaseHandler) inTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if bh.tx == nil {
+		return fn(ctx)
+	}
+
+	err := bh.tx.InTx(ctx, fn)
+	if _, ok := status.FromError(err); !ok {
+		log.Printf("Transaction failed, err:%v\n", err)
+		return status.Error(codes.Internal, "Failed to save changes")
+	}
+
+	return err
+}
+
+// audit записывает действие в журнал аудита, ошибка записи не прерывает запрос
+func (bh *BaseHandler) audit(ctx context.Context, entry *models.AuditEntry) {
+	if bh.auditRepo == nil {
+		return
+	}
+
+	// Действия по токену имперсонации записываются вместе с администратором
+	if impersonatorID := auth.ImpersonatorID(ctx); impersonatorID != 0 && entry.ImpersonatorID == nil {
+		entry.ImpersonatorID = &impersonatorID
+	}
+
+	if err := bh.auditRepo.AddAuditEntry(ctx, entry); err != nil {
+		log.Printf("Failed to add audit entry %s, err:%v\n", entry.Action, err)
+	}
+}
+
+// runBackground выполняет fn в фоне, чтобы время ответа не зависело от ее работы.
+// Контекст fn не отменяется по завершении запроса. Если запущено Background.MaxTasks задач,
+// вызов ждет завершения одной из них до начала работы fn
+func (bh *BaseHandler) runBackground(ctx context.Context, fn func(ctx context.Context)) {
+	bh.background.Go(func() error {
+		fn(context.WithoutCancel(ctx))
+		return nil
+	})
+}
+
+// Shutdown ожидает завершения фоновых задач. Вызывается при остановке сервера после того,
+// как он перестал принимать запросы, и до закрытия подключения к базе данных
+func (bh *BaseHandler) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		_ = bh.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -45,3 +226,12 @@ func convertInt32SliceToInt(slice []int32) []int {
 	}
 	return result
 }
+
+// convertIntSliceToInt32 конвертирует slice int в slice int32
+func convertIntSliceToInt32(slice []int) []int32 {
+	result := make([]int32, len(slice))
+	for i, v := range slice {
+		result[i] = int32(v)
+	}
+	return result
+}
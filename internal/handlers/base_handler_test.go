@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBaseHandler_RunBackground тестирует фоновые задачи базового обработчика и их завершение при остановке
+func TestBaseHandler_RunBackground(t *testing.T) {
+	ctx := context.Background()
+
+	// Test 1: Shutdown ждет завершения запущенных задач
+	t.Run("ShutdownWaitsForTasks", func(t *testing.T) {
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{cfg: DefaultConfig()}
+
+		// Запускаем задачу, которая ждет сигнала
+		release := make(chan struct{})
+		var done atomic.Bool
+		baseHandler.runBackground(ctx, func(ctx context.Context) {
+			<-release
+			done.Store(true)
+		})
+
+		// Проверяем результат: пока задача не завершена, Shutdown прерывается по таймауту
+		shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, baseHandler.Shutdown(shutdownCtx), context.DeadlineExceeded)
+		assert.False(t, done.Load())
+
+		// После завершения задачи Shutdown возвращается без ошибки
+		close(release)
+		assert.NoError(t, baseHandler.Shutdown(ctx))
+		assert.True(t, done.Load())
+	})
+
+	// Test 2: Число одновременных задач ограничено Background.MaxTasks
+	t.Run("MaxTasks", func(t *testing.T) {
+		// Создаем базовый обработчик с ограничением в одну задачу
+		baseHandler := &BaseHandler{cfg: DefaultConfig()}
+		baseHandler.background.SetLimit(1)
+
+		release := make(chan struct{})
+		baseHandler.runBackground(ctx, func(ctx context.Context) {
+			<-release
+		})
+
+		// Вторая задача не запускается, пока выполняется первая
+		var started atomic.Bool
+		queued := make(chan struct{})
+		go func() {
+			baseHandler.runBackground(ctx, func(ctx context.Context) {
+				started.Store(true)
+			})
+			close(queued)
+		}()
+
+		// Проверяем результат
+		select {
+		case <-queued:
+			t.Fatal("second task started while the limit was reached")
+		case <-time.After(10 * time.Millisecond):
+		}
+		assert.False(t, started.Load())
+
+		close(release)
+		<-queued
+		assert.NoError(t, baseHandler.Shutdown(ctx))
+		assert.True(t, started.Load())
+	})
+
+	// Test 3: Контекст задачи не отменяется вместе с контекстом запроса
+	t.Run("ContextNotCanceled", func(t *testing.T) {
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{cfg: DefaultConfig()}
+
+		// Запускаем задачу с отмененным контекстом запроса
+		reqCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		var taskErr error
+		baseHandler.runBackground(reqCtx, func(ctx context.Context) {
+			taskErr = ctx.Err()
+		})
+
+		// Проверяем результат
+		assert.NoError(t, baseHandler.Shutdown(ctx))
+		assert.NoError(t, taskErr)
+	})
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/passwordpolicy"
+)
+
+// Config настройки обработчиков
+type Config struct {
+	LoginThrottle     LoginThrottleConfig
+	PasswordReset     PasswordResetConfig
+	MagicLink         MagicLinkConfig
+	EmailVerification EmailVerificationConfig
+	TwoFactor         TwoFactorConfig
+	PasswordPolicy    PasswordPolicyConfig
+	APIKeys           APIKeyConfig
+	Impersonation     ImpersonationConfig
+	OAuth             OAuthConfig
+	OIDC              OIDCConfig
+	DPoP              DPoPConfig
+	SessionCookie     SessionCookieConfig
+	Grants            GrantsConfig
+	Background        BackgroundConfig
+}
+
+// LoginThrottleConfig настройки защиты входа от перебора паролей.
+// Нулевые пороги отключают соответствующую проверку
+type LoginThrottleConfig struct {
+	// Window окно, в котором считаются неудачные попытки
+	Window time.Duration
+	// DelayAfter количество неудачных попыток, после которого включается прогрессивная задержка
+	DelayAfter int
+	// BaseDelay задержка после DelayAfter неудачных попыток, удваивается с каждой следующей
+	BaseDelay time.Duration
+	// MaxDelay максимальная задержка
+	MaxDelay time.Duration
+	// MaxAccountFailures количество неудачных попыток для одного email до временной блокировки
+	MaxAccountFailures int
+	// MaxIPFailures количество неудачных попыток с одного IP до временной блокировки
+	MaxIPFailures int
+	// LockoutDuration длительность временной блокировки после последней неудачной попытки
+	LockoutDuration time.Duration
+}
+
+// PasswordResetConfig настройки восстановления пароля
+type PasswordResetConfig struct {
+	// TokenTTL срок действия токена сброса пароля
+	TokenTTL time.Duration
+	// URL адрес страницы сброса пароля, токен передается в параметре token
+	URL string
+}
+
+// MagicLinkConfig настройки входа по ссылке из письма.
+// Участникам организации вход по ссылке доступен, только если он включен в настройках организации
+type MagicLinkConfig struct {
+	// Enabled вход по ссылке для пользователей без организации
+	Enabled bool
+	// TokenTTL срок действия ссылки
+	TokenTTL time.Duration
+	// URL адрес страницы входа по ссылке, токен передается в параметре token
+	URL string
+}
+
+// EmailVerificationMode правило входа для пользователей с неподтвержденным email
+type EmailVerificationMode string
+
+const (
+	// EmailVerificationNone вход без ограничений
+	EmailVerificationNone EmailVerificationMode = "none"
+	// EmailVerificationBlock вход запрещен до подтверждения email
+	EmailVerificationBlock EmailVerificationMode = "block"
+	// EmailVerificationLimit выдается токен без прав
+	EmailVerificationLimit EmailVerificationMode = "limit"
+)
+
+// IsValid проверяет, что правило известно
+func (m EmailVerificationMode) IsValid() bool {
+	switch m {
+	case EmailVerificationNone, EmailVerificationBlock, EmailVerificationLimit:
+		return true
+	}
+	return false
+}
+
+// EmailVerificationConfig настройки подтверждения email
+type EmailVerificationConfig struct {
+	// Mode правило входа до подтверждения email
+	Mode EmailVerificationMode
+	// TokenTTL срок действия токена подтверждения
+	TokenTTL time.Duration
+	// URL адрес страницы подтверждения, токен передается в параметре token
+	URL string
+}
+
+// TwoFactorConfig настройки двухфакторной аутентификации
+type TwoFactorConfig struct {
+	// Issuer название сервиса в приложении-аутентификаторе
+	Issuer string
+	// ChallengeTTL время, за которое нужно ввести код второго фактора после пароля
+	ChallengeTTL time.Duration
+	// RecoveryCodes количество выдаваемых кодов восстановления
+	RecoveryCodes int
+}
+
+// PasswordPolicyConfig глобальная политика паролей.
+// Организация может задать собственную политику в настройках
+type PasswordPolicyConfig struct {
+	// Policy политика паролей по умолчанию
+	Policy models.PasswordPolicy
+	// Breached список утекших паролей, nil - проверка отключена
+	Breached *passwordpolicy.BreachedList
+}
+
+// APIKeyConfig настройки API ключей сервисных аккаунтов
+type APIKeyConfig struct {
+	// DefaultTTL срок действия ключа, если он не указан при создании
+	DefaultTTL time.Duration
+	// MaxTTL максимальный срок действия ключа
+	MaxTTL time.Duration
+}
+
+// ImpersonationConfig настройки входа администратора от имени пользователя
+type ImpersonationConfig struct {
+	// TTL срок действия токена имперсонации
+	TTL time.Duration
+}
+
+// OAuthConfig настройки OAuth endpoint'ов
+type OAuthConfig struct {
+	// Audience идентификатор этого сервиса. Токены с другим aud сервис не принимает
+	Audience string
+	// ExchangeTTL максимальный срок действия токена, полученного обменом
+	ExchangeTTL time.Duration
+	// AccessTokenTTL срок действия токенов, выданных OAuth клиентам
+	AccessTokenTTL time.Duration
+	// CodeTTL срок действия authorization code
+	CodeTTL time.Duration
+}
+
+// OIDCConfig настройки входа через внешних провайдеров OpenID Connect
+type OIDCConfig struct {
+	// BaseURL внешний адрес сервиса, из которого формируется redirect_uri провайдера
+	BaseURL string
+	// StateTTL время, за которое нужно завершить вход у провайдера
+	StateTTL time.Duration
+	// HTTPTimeout таймаут запросов к провайдеру
+	HTTPTimeout time.Duration
+}
+
+// DPoPConfig настройки токенов, привязанных к ключу клиента (RFC 9449)
+type DPoPConfig struct {
+	// ProofMaxAge время, в течение которого принимается DPoP доказательство
+	ProofMaxAge time.Duration
+	// BaseURL внешний адрес сервиса для сравнения с htu доказательства, пустой - адрес из заголовка Host
+	BaseURL string
+}
+
+// SessionCookieConfig настройки cookie браузерной сессии
+type SessionCookieConfig struct {
+	// Secure cookie передаются только по HTTPS. Отключается только для локальной разработки
+	Secure bool
+	// SameSite ограничение отправки cookie со сторонних сайтов
+	SameSite http.SameSite
+	// Domain домен cookie, пустой - только домен сервиса
+	Domain string
+}
+
+// GrantsConfig настройки временных выдач ролей и прав
+type GrantsConfig struct {
+	// ExpiryInterval интервал удаления выдач с истекшим сроком. Права пользователей обновляются при удалении,
+	// поэтому интервал определяет, как долго выпущенные токены сохраняют истекшие права. 0 - не удалять
+	ExpiryInterval time.Duration
+}
+
+// BackgroundConfig настройки фоновых задач обработчиков (отправка писем и т.п.)
+type BackgroundConfig struct {
+	// MaxTasks максимальное число одновременно выполняемых фоновых задач. При достижении предела
+	// обработчик ждет завершения одной из задач. 0 - без ограничения
+	MaxTasks int
+	// ShutdownTimeout время ожидания завершения фоновых задач при остановке сервера
+	ShutdownTimeout time.Duration
+}
+
+// DefaultConfig возвращает настройки обработчиков по умолчанию
+func DefaultConfig() Config {
+	return Config{
+		LoginThrottle: LoginThrottleConfig{
+			Window:             15 * time.Minute,
+			DelayAfter:         3,
+			BaseDelay:          500 * time.Millisecond,
+			MaxDelay:           5 * time.Second,
+			MaxAccountFailures: 10,
+			MaxIPFailures:      100,
+			LockoutDuration:    15 * time.Minute,
+		},
+		PasswordReset: PasswordResetConfig{
+			TokenTTL: 30 * time.Minute,
+			URL:      "http://localhost:8080/reset-password",
+		},
+		MagicLink: MagicLinkConfig{
+			TokenTTL: 10 * time.Minute,
+			URL:      "http://localhost:8080/magic-link",
+		},
+		EmailVerification: EmailVerificationConfig{
+			Mode:     EmailVerificationLimit,
+			TokenTTL: 24 * time.Hour,
+			URL:      "http://localhost:8080/verify-email",
+		},
+		TwoFactor: TwoFactorConfig{
+			Issuer:        "crud-ai",
+			ChallengeTTL:  5 * time.Minute,
+			RecoveryCodes: 10,
+		},
+		PasswordPolicy: PasswordPolicyConfig{
+			Policy: models.PasswordPolicy{
+				MinLength:          8,
+				MaxBytes:           passwordpolicy.MaxBytes,
+				RequireLower:       true,
+				RequireDigit:       true,
+				ForbidPersonalInfo: true,
+			},
+		},
+		APIKeys: APIKeyConfig{
+			DefaultTTL: 90 * 24 * time.Hour,
+			MaxTTL:     365 * 24 * time.Hour,
+		},
+		Impersonation: ImpersonationConfig{
+			TTL: 15 * time.Minute,
+		},
+		OAuth: OAuthConfig{
+			Audience:       "crud-ai",
+			ExchangeTTL:    5 * time.Minute,
+			AccessTokenTTL: time.Hour,
+			CodeTTL:        5 * time.Minute,
+		},
+		OIDC: OIDCConfig{
+			BaseURL:     "http://localhost:8080",
+			StateTTL:    10 * time.Minute,
+			HTTPTimeout: 10 * time.Second,
+		},
+		DPoP: DPoPConfig{
+			ProofMaxAge: time.Minute,
+		},
+		SessionCookie: SessionCookieConfig{
+			Secure:   true,
+			SameSite: http.SameSiteStrictMode,
+		},
+		Grants: GrantsConfig{
+			ExpiryInterval: time.Minute,
+		},
+		Background: BackgroundConfig{
+			MaxTasks:        64,
+			ShutdownTimeout: 30 * time.Second,
+		},
+	}
+}
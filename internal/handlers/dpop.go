@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/LiFeAiR/crud-ai/internal/dpop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// tokenTypeBearer тип обычного токена доступа
+	tokenTypeBearer = "Bearer"
+	// tokenTypeDPoP тип токена, привязанного к ключу клиента (RFC 9449)
+	tokenTypeDPoP = "DPoP"
+)
+
+// dpopThumbprint проверяет открытый ключ клиента в формате JWK и возвращает его отпечаток.
+// Без ключа токен не привязывается и возвращается пустая строка
+func dpopThumbprint(key string) (string, error) {
+	if key == "" {
+		return "", nil
+	}
+
+	jwk, err := dpop.ParseJWK([]byte(key))
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, "Invalid DPoP key")
+	}
+
+	return jwk.Thumbprint(), nil
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/LiFeAiR/crud-ai/internal/dpop"
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_DPoP тестирует привязку токенов входа к ключу клиента
+func TestBaseHandler_DPoP(t *testing.T) {
+	ctx := context.Background()
+
+	// Открытый ключ клиента в формате JWK
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	jwk := &dpop.JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+	rawKey, err := json.Marshal(jwk)
+	assert.NoError(t, err)
+
+	user := &models.User{
+		ID:           1,
+		Email:        "test@example.com",
+		Status:       models.UserStatusActive,
+		Organization: &models.Organization{ID: 3},
+	}
+
+	// Test 1: С ключом клиента токен привязывается к его отпечатку, требование организации выполнено
+	t.Run("LoginBindsToken", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByEmail", ctx, "test@example.com").Return(user, nil)
+		userRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+		userRepo.On("GetUserPermissions", ctx, 1).Return([]*models.Permission(nil), nil)
+		orgRepo.On("GetOrganizationByID", ctx, 3).Return(&models.Organization{ID: 3, Name: "Org"}, nil)
+		orgRepo.On("GetOrganizationSettings", ctx, 3).
+			Return(&models.OrganizationSettings{OrganizationID: 3, RequireDPoP: true}, nil)
+
+		var tokenClaims *utils.Claims
+		f := func(s string, claims *utils.Claims) (string, error) {
+			tokenClaims = claims
+			return "test-test-test", nil
+		}
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			orgRepo:  orgRepo,
+			jwtFunc:  f,
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{
+			Email:    "test@example.com",
+			Password: "password123",
+			DpopKey:  string(rawKey),
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, tokenTypeDPoP, result.TokenType)
+		assert.NotNil(t, tokenClaims.Cnf)
+		assert.Equal(t, jwk.Thumbprint(), tokenClaims.Cnf.JKT)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		orgRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Закрытый ключ вместо открытого отклоняется до проверки пароля
+	t.Run("LoginInvalidKey", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{userRepo: userRepo}
+
+		private := *jwk
+		private.D = base64.RawURLEncoding.EncodeToString(key.D.Bytes())
+		rawPrivate, err := json.Marshal(private)
+		assert.NoError(t, err)
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{
+			Email:    "test@example.com",
+			Password: "password123",
+			DpopKey:  string(rawPrivate),
+		})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		userRepo.AssertNotCalled(t, "CheckPassword", ctx, 1, "password123")
+	})
+
+	// Test 3: Организация, требующая DPoP, не выдает токены без ключа клиента
+	t.Run("LoginOrganizationRequiresDPoP", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByEmail", ctx, "test@example.com").Return(user, nil)
+		userRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+		orgRepo.On("GetOrganizationSettings", ctx, 3).
+			Return(&models.OrganizationSettings{OrganizationID: 3, RequireDPoP: true}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			orgRepo:  orgRepo,
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{
+			Email:    "test@example.com",
+			Password: "password123",
+		})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		orgRepo.AssertExpectations(t)
+	})
+}
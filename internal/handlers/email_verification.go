@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// VerifyEmail подтверждает email по одноразовому токену.
+// Если подтверждается новый email, он заменяет текущий
+func (bh *BaseHandler) VerifyEmail(ctx context.Context, in *api_pb.VerifyEmailRequest) (out *api_pb.Empty, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	token, err := bh.tokenRepo.ConsumeToken(ctx, models.TokenPurposeEmailVerification, utils.HashToken(in.Token))
+	if err != nil {
+		log.Printf("Failed to consume email verification token, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to verify email")
+	}
+
+	if token == nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid or expired token")
+	}
+
+	// Email в токене мог смениться, пока письмо шло к пользователю
+	verified, err := bh.userRepo.VerifyUserEmail(ctx, token.UserID, token.Payload)
+	if err != nil {
+		log.Printf("Failed to verify user email, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to verify email")
+	}
+
+	if !verified {
+		return nil, status.Error(codes.InvalidArgument, "Invalid or expired token")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		UserID:  utils.Ptr(token.UserID),
+		Action:  "user.email_verify",
+		Details: token.Payload,
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// ResendVerification повторно отправляет письмо для подтверждения email.
+// Ответ не зависит от того, существует ли пользователь с таким email: поиск пользователя,
+// выпуск токена и отправка выполняются в фоне, поэтому не различается и время ответа
+func (bh *BaseHandler) ResendVerification(ctx context.Context, in *api_pb.ResendVerificationRequest) (out *api_pb.Empty, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	email := in.Email
+	bh.runBackground(ctx, func(ctx context.Context) {
+		bh.resendVerification(ctx, email)
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// resendVerification отправляет письмо для подтверждения email пользователю с email,
+// если его адрес или новый адрес ожидают подтверждения
+func (bh *BaseHandler) resendVerification(ctx context.Context, email string) {
+	user, err := bh.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Printf("Failed to get user by email, err:%v\n", err)
+		return
+	}
+
+	if user == nil {
+		return
+	}
+
+	// Подтверждать нужно новый email, если он ожидает подтверждения, иначе текущий
+	target := user.Email
+	if user.PendingEmail != nil {
+		target = *user.PendingEmail
+	} else if user.EmailVerified {
+		return
+	}
+
+	if err := bh.sendEmailVerification(ctx, user.ID, target); err != nil {
+		log.Printf("Failed to send email verification, err:%v\n", err)
+	}
+}
+
+// sendEmailVerification выпускает токен подтверждения email и отправляет ссылку на этот email
+func (bh *BaseHandler) sendEmailVerification(ctx context.Context, userID int, email string) error {
+	cfg := bh.cfg.EmailVerification
+	token, err := bh.issueToken(ctx, userID, models.TokenPurposeEmailVerification, email, cfg.TokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return bh.notifier.Notify(ctx, notifier.Message{
+		To:      email,
+		Subject: "Email verification",
+		Body: fmt.Sprintf(
+			"To verify your email follow the link: %s?token=%s\nThe link expires in %s.",
+			cfg.URL, url.QueryEscape(token), cfg.TokenTTL,
+		),
+	})
+}
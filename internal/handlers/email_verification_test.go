@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestBaseHandler_VerifyEmail тестирует метод VerifyEmail базового обработчика
+func TestBaseHandler_VerifyEmail(t *testing.T) {
+	ctx := context.Background()
+
+	// Test 1: Успешное подтверждение email
+	t.Run("VerifyEmailSuccess", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		tokenRepo := new(mocks.MockTokenRepository)
+
+		// Определяем ожидаемое поведение мока
+		tokenRepo.On("ConsumeToken", ctx, models.TokenPurposeEmailVerification, utils.HashToken("token")).
+			Return(&models.OneTimeToken{UserID: 1, Payload: "new@example.com"}, nil)
+		userRepo.On("VerifyUserEmail", ctx, 1, "new@example.com").Return(true, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			tokenRepo: tokenRepo,
+		}
+
+		// Вызываем метод VerifyEmail
+		result, err := baseHandler.VerifyEmail(ctx, &grpc.VerifyEmailRequest{Token: "token"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.NotNil(t, result)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		tokenRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Email сменился после отправки письма
+	t.Run("VerifyEmailOutdated", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		tokenRepo := new(mocks.MockTokenRepository)
+
+		// Определяем ожидаемое поведение мока
+		tokenRepo.On("ConsumeToken", ctx, models.TokenPurposeEmailVerification, utils.HashToken("token")).
+			Return(&models.OneTimeToken{UserID: 1, Payload: "old@example.com"}, nil)
+		userRepo.On("VerifyUserEmail", ctx, 1, "old@example.com").Return(false, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			tokenRepo: tokenRepo,
+		}
+
+		// Вызываем метод VerifyEmail
+		result, err := baseHandler.VerifyEmail(ctx, &grpc.VerifyEmailRequest{Token: "token"})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = InvalidArgument desc = Invalid or expired token", err.Error())
+	})
+}
+
+// TestBaseHandler_ResendVerification тестирует метод ResendVerification базового обработчика
+func TestBaseHandler_ResendVerification(t *testing.T) {
+	ctx := context.Background()
+
+	// Test 1: Письмо отправляется на новый email, ожидающий подтверждения
+	t.Run("ResendVerificationPendingEmail", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		tokenRepo := new(mocks.MockTokenRepository)
+		n := new(mocks.MockNotifier)
+
+		// Определяем ожидаемое поведение мока
+		// Письмо отправляется в фоне с контекстом, не зависящим от запроса
+		var sent notifier.Message
+		userRepo.On("GetUserByEmail", mock.Anything, "test@example.com").Return(&models.User{
+			ID:            1,
+			Email:         "test@example.com",
+			EmailVerified: true,
+			PendingEmail:  utils.Ptr("new@example.com"),
+		}, nil)
+		tokenRepo.On("DeleteUserTokens", mock.Anything, 1, models.TokenPurposeEmailVerification).Return(nil)
+		tokenRepo.On("CreateToken", mock.Anything, mock.Anything).Return(nil)
+		n.On("Notify", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			sent = args.Get(1).(notifier.Message)
+		}).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			tokenRepo: tokenRepo,
+			notifier:  n,
+			cfg:       DefaultConfig(),
+		}
+
+		// Вызываем метод ResendVerification
+		_, err := baseHandler.ResendVerification(ctx, &grpc.ResendVerificationRequest{Email: "test@example.com"})
+		assert.NoError(t, err)
+		baseHandler.background.Wait()
+
+		// Проверяем результат
+		assert.Equal(t, "new@example.com", sent.To)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		tokenRepo.AssertExpectations(t)
+		n.AssertExpectations(t)
+	})
+
+	// Test 2: Для неизвестного и уже подтвержденного email ответ одинаковый, письмо не отправляется
+	t.Run("ResendVerificationNotSent", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		n := new(mocks.MockNotifier)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByEmail", mock.Anything, "unknown@example.com").Return((*models.User)(nil), nil)
+		userRepo.On("GetUserByEmail", mock.Anything, "test@example.com").Return(&models.User{
+			ID:            1,
+			Email:         "test@example.com",
+			EmailVerified: true,
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			notifier: n,
+			cfg:      DefaultConfig(),
+		}
+
+		// Вызываем метод ResendVerification
+		unknown, err := baseHandler.ResendVerification(ctx, &grpc.ResendVerificationRequest{Email: "unknown@example.com"})
+		assert.NoError(t, err)
+		verified, err := baseHandler.ResendVerification(ctx, &grpc.ResendVerificationRequest{Email: "test@example.com"})
+		assert.NoError(t, err)
+
+		// Проверяем результат
+		assert.Equal(t, unknown, verified)
+		baseHandler.background.Wait()
+		n.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+	})
+}
+
+// TestBaseHandler_LoginUnverifiedEmail тестирует вход пользователя с неподтвержденным email
+func TestBaseHandler_LoginUnverifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	f := func(s string, claims *utils.Claims) (string, error) {
+		assert.Empty(t, claims.Permissions)
+		return "test-test-test", nil
+	}
+	unverifiedUser := &models.User{
+		ID:     1,
+		Name:   "Test User",
+		Email:  "test@example.com",
+		Status: models.UserStatusActive,
+	}
+
+	// Test 1: Вход запрещен до подтверждения email
+	t.Run("LoginUnverifiedBlocked", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		userRepo.On("GetUserByEmail", ctx, "test@example.com").Return(unverifiedUser, nil)
+		userRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			jwtFunc:  f,
+			cfg:      Config{EmailVerification: EmailVerificationConfig{Mode: EmailVerificationBlock}},
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{Email: "test@example.com", Password: "password123"})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = PermissionDenied desc = Email is not verified", err.Error())
+	})
+
+	// Test 2: До подтверждения email выдается токен без прав
+	t.Run("LoginUnverifiedLimited", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		userRepo.On("GetUserByEmail", ctx, "test@example.com").Return(unverifiedUser, nil)
+		userRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			jwtFunc:  f,
+			cfg:      Config{EmailVerification: EmailVerificationConfig{Mode: EmailVerificationLimit}},
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{Email: "test@example.com", Password: "password123"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.NotEmpty(t, result.Token)
+		assert.False(t, result.User.EmailVerified)
+		userRepo.AssertNotCalled(t, "GetUserPermissions", mock.Anything, mock.Anything)
+	})
+
+	// Test 3: Токен без прав не получает права после изменения прав пользователя
+	t.Run("LimitedTokenAfterGrantChange", func(t *testing.T) {
+		secretKey := testSecretKey(t)
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		userRepo.On("GetUserByEmail", ctx, "test@example.com").Return(unverifiedUser, nil)
+		userRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			secretKey: secretKey,
+			jwtFunc:   utils.GenerateJWT,
+			cfg:       Config{EmailVerification: EmailVerificationConfig{Mode: EmailVerificationLimit}},
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{Email: "test@example.com", Password: "password123"})
+		assert.NoError(t, err)
+
+		// Права пользователя изменились после выпуска токена
+		userRepo.On("GetUserAuthState", mock.Anything, 1).Return(&models.UserAuthState{
+			Status:       models.UserStatusActive,
+			GrantVersion: unverifiedUser.GrantVersion + 1,
+		}, nil)
+		userRepo.On("GetUserPermissions", mock.Anything, 1).Return([]*models.Permission{
+			{ID: 1, Code: "reports.read"},
+			{ID: 2, Code: "admin"},
+		}, nil)
+		verifier := auth.NewVerifier(secretKey, "crud-ai", userRepo, nil, nil, nil)
+
+		// Проверяем результат
+		identity, err := verifier.VerifyToken(ctx, result.Token)
+		assert.NoError(t, err)
+		assert.Empty(t, identity.Permissions)
+		assert.False(t, identity.IsAdmin())
+
+		// Обмен токена без прав не выдает токен с правами
+		baseHandler.verifier = verifier
+		baseHandler.cfg.OAuth = DefaultConfig().OAuth
+		w := httptest.NewRecorder()
+		baseHandler.Token(w, newFormRequest(ctx, "/oauth/token", url.Values{
+			"grant_type":         {grantTypeTokenExchange},
+			"subject_token":      {result.Token},
+			"subject_token_type": {tokenTypeAccessToken},
+			"audience":           {"billing"},
+		}))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"error":"invalid_scope"`)
+	})
+}
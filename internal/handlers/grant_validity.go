@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// parseGrantValidity разбирает срок действия выдачи роли или права в формате RFC 3339.
+// Пустые значения не ограничивают срок
+func parseGrantValidity(validFrom, validUntil string) (models.GrantValidity, error) {
+	var validity models.GrantValidity
+	if validFrom != "" {
+		t, err := time.Parse(time.RFC3339, validFrom)
+		if err != nil {
+			return validity, status.Error(codes.InvalidArgument, "Invalid valid_from")
+		}
+		validity.ValidFrom = &t
+	}
+
+	if validUntil != "" {
+		t, err := time.Parse(time.RFC3339, validUntil)
+		if err != nil {
+			return validity, status.Error(codes.InvalidArgument, "Invalid valid_until")
+		}
+
+		// Выдача, срок которой уже истек или закончится раньше, чем начнется, не имеет смысла
+		if !t.After(time.Now()) || (validity.ValidFrom != nil && !t.After(*validity.ValidFrom)) {
+			return validity, status.Error(codes.InvalidArgument, "Invalid valid_until")
+		}
+		validity.ValidUntil = &t
+	}
+
+	return validity, nil
+}
+
+// formatGrantTime форматирует границу срока действия выдачи, пустая граница не ограничивает срок
+func formatGrantTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// grantValidityDetails описывает срок действия выдачи для журнала аудита
+func grantValidityDetails(validity models.GrantValidity) string {
+	var details string
+	if validity.ValidFrom != nil {
+		details += ", valid_from=" + formatGrantTime(validity.ValidFrom)
+	}
+	if validity.ValidUntil != nil {
+		details += ", valid_until=" + formatGrantTime(validity.ValidUntil)
+	}
+
+	return details
+}
+
+// ExpireGrants удаляет роли и права пользователей и организаций с истекшим сроком
+// и записывает удаленные выдачи в журнал аудита
+func (bh *BaseHandler) ExpireGrants(ctx context.Context) {
+	expired, err := bh.userRepo.ExpireUserGrants(ctx)
+	if err != nil {
+		log.Printf("Failed to expire user grants, err:%v\n", err)
+	}
+
+	for _, grant := range expired {
+		bh.audit(ctx, &models.AuditEntry{
+			UserID:  utils.Ptr(grant.OwnerID),
+			Action:  "user." + string(grant.Kind) + "_expire",
+			Details: fmt.Sprintf("%s %d expired at %s", grant.Kind, grant.ObjectID, grant.ValidUntil.Format(time.RFC3339)),
+		})
+	}
+
+	expired, err = bh.orgRepo.ExpireOrganizationGrants(ctx)
+	if err != nil {
+		log.Printf("Failed to expire organization grants, err:%v\n", err)
+	}
+
+	for _, grant := range expired {
+		bh.audit(ctx, &models.AuditEntry{
+			Action: "organization." + string(grant.Kind) + "_expire",
+			Details: fmt.Sprintf(
+				"organization %d: %s %d expired at %s",
+				grant.OwnerID, grant.Kind, grant.ObjectID, grant.ValidUntil.Format(time.RFC3339),
+			),
+		})
+	}
+}
+
+// RunGrantExpiry вызывает ExpireGrants с интервалом interval до отмены ctx.
+// Нулевой интервал отключает удаление: истекшие выдачи не учитываются в правах, но остаются в БД
+func (bh *BaseHandler) RunGrantExpiry(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		bh.ExpireGrants(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
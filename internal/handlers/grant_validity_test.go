@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_GrantValidity тестирует временные выдачи ролей и прав
+func TestBaseHandler_GrantValidity(t *testing.T) {
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, 100)
+	validUntil := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	// Test 1: Роль выдается до указанного срока, срок возвращается в ответе и записывается в журнал аудита
+	t.Run("AddUserRolesValidUntil", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+
+		// Определяем ожидаемое поведение мока
+		validity := models.GrantValidity{ValidUntil: &validUntil}
+		userRepo.On("AddUserRoles", ctx, 1, []int{2}, validity).Return(nil)
+		userRepo.On("GetUserRoles", ctx, 1).Return([]*models.Role{
+			{ID: 2, Name: "On-call", Code: "on_call", ValidUntil: &validUntil},
+		}, nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "user.roles_add" && *entry.UserID == 1 &&
+				entry.Details == "roles [2], valid_until="+validUntil.Format(time.RFC3339)
+		})).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			auditRepo: auditRepo,
+		}
+
+		// Вызываем метод AddUserRoles
+		result, err := baseHandler.AddUserRoles(ctx, &grpc.UserRolesRequest{
+			Id:         1,
+			RoleIds:    []int32{2},
+			ValidUntil: validUntil.Format(time.RFC3339),
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Len(t, result.Data, 1)
+		assert.Empty(t, result.Data[0].ValidFrom)
+		assert.Equal(t, validUntil.Format(time.RFC3339), result.Data[0].ValidUntil)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Срок, который уже истек или заканчивается до начала, отклоняется
+	t.Run("AddOrganizationPermissionsInvalidValidity", func(t *testing.T) {
+		// Создаем мок репозиторий
+		orgRepo := new(mocks.MockOrganizationRepository)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{orgRepo: orgRepo}
+
+		requests := []*grpc.OrganizationPermissionsRequest{
+			{Id: 3, PermissionIds: []int32{4}, ValidUntil: time.Now().Add(-time.Hour).Format(time.RFC3339)},
+			{
+				Id:            3,
+				PermissionIds: []int32{4},
+				ValidFrom:     validUntil.Add(time.Hour).Format(time.RFC3339),
+				ValidUntil:    validUntil.Format(time.RFC3339),
+			},
+			{Id: 3, PermissionIds: []int32{4}, ValidFrom: "tomorrow"},
+		}
+
+		for _, request := range requests {
+			// Вызываем метод AddOrganizationPermissions
+			result, err := baseHandler.AddOrganizationPermissions(ctx, request)
+
+			// Проверяем результат
+			assert.Error(t, err)
+			assert.Nil(t, result)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		}
+
+		orgRepo.AssertNotCalled(t, "AddOrganizationPermissions", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	// Test 3: Удаленные по истечении срока выдачи записываются в журнал аудита
+	t.Run("ExpireGrants", func(t *testing.T) {
+		ctx := context.Background()
+		expiredAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("ExpireUserGrants", ctx).Return([]*models.ExpiredGrant{
+			{Kind: models.GrantKindRole, OwnerID: 1, ObjectID: 2, ValidUntil: expiredAt},
+		}, nil)
+		orgRepo.On("ExpireOrganizationGrants", ctx).Return([]*models.ExpiredGrant{
+			{Kind: models.GrantKindPermission, OwnerID: 3, ObjectID: 4, ValidUntil: expiredAt},
+		}, nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "user.role_expire" && *entry.UserID == 1 &&
+				entry.Details == "role 2 expired at 2025-01-02T03:04:05Z"
+		})).Return(nil).Once()
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "organization.permission_expire" && entry.UserID == nil &&
+				entry.Details == "organization 3: permission 4 expired at 2025-01-02T03:04:05Z"
+		})).Return(nil).Once()
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			orgRepo:   orgRepo,
+			auditRepo: auditRepo,
+		}
+
+		// Вызываем метод ExpireGrants
+		baseHandler.ExpireGrants(ctx)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		orgRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Impersonate выпускает администратору короткоживущий токен с правами пользователя.
+// Токен содержит claim act с администратором, сессия видна пользователю в списке сессий
+func (bh *BaseHandler) Impersonate(ctx context.Context, in *api_pb.ImpersonateRequest) (out *api_pb.ImpersonateResponse, err error) {
+	// Действовать от имени пользователя может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	reason := strings.TrimSpace(in.GetReason())
+	if in == nil || in.UserId == 0 || reason == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Имперсонация начинается только из обычной сессии администратора
+	adminID := currentUserID(ctx)
+	if adminID == nil || auth.APIKeyID(ctx) != 0 || auth.ImpersonatorID(ctx) != 0 {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	if *adminID == int(in.UserId) {
+		return nil, status.Error(codes.InvalidArgument, "Cannot impersonate yourself")
+	}
+
+	user, err := bh.userRepo.GetUserByID(ctx, int(in.UserId))
+	if err != nil {
+		log.Printf("Failed to get user by id, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to impersonate user")
+	}
+
+	if user == nil {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	if user.Status != models.UserStatusActive {
+		return nil, status.Error(codes.FailedPrecondition, "User is not active")
+	}
+
+	permissions, err := bh.userRepo.GetUserPermissions(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to get permissions, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to impersonate user")
+	}
+
+	jwtPermissions := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		// Действовать от имени другого администратора запрещено
+		if permission.Code == auth.IsAdmin {
+			return nil, status.Error(codes.PermissionDenied, "Cannot impersonate an administrator")
+		}
+		jwtPermissions = append(jwtPermissions, permission.Code)
+	}
+
+	sessionID, err := utils.GenerateToken()
+	if err != nil {
+		log.Printf("Failed to generate session id, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to impersonate user")
+	}
+
+	expiresAt := time.Now().Add(bh.cfg.Impersonation.TTL)
+	claims := &utils.Claims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Name:         user.Name,
+		Permissions:  jwtPermissions,
+		GrantVersion: user.GrantVersion,
+		Act:          &utils.Actor{UserID: *adminID},
+	}
+	claims.ID = sessionID
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+	token, err := bh.jwtFunc(bh.secretKey, claims)
+	if err != nil {
+		log.Printf("Failed to generate JWT token, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to impersonate user")
+	}
+
+	err = bh.createSession(ctx, &models.Session{
+		ID:                  sessionID,
+		UserID:              user.ID,
+		ExpiresAt:           expiresAt,
+		ImpersonatorID:      adminID,
+		ImpersonationReason: reason,
+	})
+	if err != nil {
+		log.Printf("Failed to create session, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to impersonate user")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: adminID,
+		UserID:  utils.Ptr(user.ID),
+		Action:  "user.impersonate",
+		Reason:  reason,
+	})
+
+	return &api_pb.ImpersonateResponse{
+		Token:     token,
+		ExpiresAt: expiresAt.Format(time.RFC3339),
+	}, nil
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_Impersonate тестирует метод Impersonate базового обработчика
+func TestBaseHandler_Impersonate(t *testing.T) {
+	adminCtx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+	adminCtx = context.WithValue(adminCtx, auth.IsAdminKey, true)
+
+	target := &models.User{
+		ID:           2,
+		Email:        "user@example.com",
+		Status:       models.UserStatusActive,
+		GrantVersion: 4,
+	}
+
+	// Test 1: Токен содержит права пользователя и claim act с администратором
+	t.Run("Impersonate", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		sessionRepo := new(mocks.MockSessionRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", adminCtx, 2).Return(target, nil)
+		userRepo.On("GetUserPermissions", adminCtx, 2).Return([]*models.Permission{
+			{ID: 1, Code: "reports.read"},
+		}, nil)
+		var session *models.Session
+		sessionRepo.On("CreateSession", adminCtx, mock.Anything).Run(func(args mock.Arguments) {
+			session = args.Get(1).(*models.Session)
+		}).Return(nil)
+		auditRepo.On("AddAuditEntry", adminCtx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "user.impersonate" && *entry.ActorID == 1 && *entry.UserID == 2 &&
+				entry.Reason == "ticket 42"
+		})).Return(nil)
+
+		var tokenClaims *utils.Claims
+		f := func(s string, claims *utils.Claims) (string, error) {
+			tokenClaims = claims
+			return "token", nil
+		}
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:    userRepo,
+			sessionRepo: sessionRepo,
+			auditRepo:   auditRepo,
+			jwtFunc:     f,
+			cfg:         DefaultConfig(),
+		}
+
+		// Вызываем метод Impersonate
+		result, err := baseHandler.Impersonate(adminCtx, &grpc.ImpersonateRequest{UserId: 2, Reason: "ticket 42"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, "token", result.Token)
+		assert.Equal(t, 2, tokenClaims.UserID)
+		assert.Equal(t, []string{"reports.read"}, tokenClaims.Permissions)
+		assert.Equal(t, int64(4), tokenClaims.GrantVersion)
+		assert.Equal(t, 1, tokenClaims.Act.UserID)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), tokenClaims.ExpiresAt.Time, time.Minute)
+		assert.Equal(t, tokenClaims.ID, session.ID)
+		assert.Equal(t, 1, *session.ImpersonatorID)
+		assert.Equal(t, "ticket 42", session.ImpersonationReason)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		sessionRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Действовать от имени другого администратора запрещено
+	t.Run("ImpersonateAdmin", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", adminCtx, 2).Return(target, nil)
+		userRepo.On("GetUserPermissions", adminCtx, 2).Return([]*models.Permission{
+			{ID: 1, Code: "admin"},
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			cfg:      DefaultConfig(),
+		}
+
+		// Вызываем метод Impersonate
+		result, err := baseHandler.Impersonate(adminCtx, &grpc.ImpersonateRequest{UserId: 2, Reason: "ticket 42"})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+	})
+
+	// Test 3: Без причины имперсонация не выполняется
+	t.Run("ImpersonateWithoutReason", func(t *testing.T) {
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{cfg: DefaultConfig()}
+
+		// Вызываем метод Impersonate
+		result, err := baseHandler.Impersonate(adminCtx, &grpc.ImpersonateRequest{UserId: 2, Reason: "  "})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	// Test 4: Действия по токену имперсонации записываются в аудит вместе с администратором
+	t.Run("AuditRecordsImpersonator", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 2)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+		ctx = context.WithValue(ctx, auth.ImpersonatorIDKey, 1)
+
+		// Создаем мок репозиторий
+		sessionRepo := new(mocks.MockSessionRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+
+		// Определяем ожидаемое поведение мока
+		sessionRepo.On("RevokeSession", ctx, 2, "abc").Return(true, nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return *entry.ActorID == 2 && entry.ImpersonatorID != nil && *entry.ImpersonatorID == 1
+		})).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			sessionRepo: sessionRepo,
+			auditRepo:   auditRepo,
+		}
+
+		// Вызываем метод RevokeSession
+		_, err := baseHandler.RevokeSession(ctx, &grpc.RevokeSessionRequest{UserId: 2, SessionId: "abc"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+
+		// Проверяем, что моки были вызваны правильно
+		sessionRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+	})
+
+	// Test 5: Каждый запрос по токену имперсонации записывается в аудит middleware
+	t.Run("MiddlewareAuditsImpersonatedRequest", func(t *testing.T) {
+		secretKey := testSecretKey(t)
+		claims := &utils.Claims{UserID: 2, Permissions: []string{"reports.read"}, Act: &utils.Actor{UserID: 1}}
+		claims.ID = "s1"
+		token, err := utils.GenerateJWT(secretKey, claims)
+		assert.NoError(t, err)
+
+		// Создаем мок репозиторий
+		auditRepo := new(mocks.MockAuditRepository)
+
+		// Определяем ожидаемое поведение мока
+		auditRepo.On("AddAuditEntry", mock.Anything, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "user.impersonated_request" && *entry.ActorID == 1 && *entry.UserID == 2 &&
+				*entry.ImpersonatorID == 1 && entry.Details == "GET /api/user/2"
+		})).Return(nil)
+
+		// Создаем middleware с моком
+		var impersonatorID int
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			impersonatorID = auth.ImpersonatorID(r.Context())
+		})
+		verifier := auth.NewVerifier(secretKey, "crud-ai", nil, nil, nil, nil)
+		handler := auth.New(verifier, nil, auditRepo)(next)
+
+		// Выполняем запрос
+		r := httptest.NewRequest(http.MethodGet, "/api/user/2", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		// Проверяем результат
+		assert.Equal(t, 1, impersonatorID)
+
+		// Проверяем, что моки были вызваны правильно
+		auditRepo.AssertExpectations(t)
+	})
+}
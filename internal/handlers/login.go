@@ -3,8 +3,13 @@ package handlers
 import (
 	"context"
 	"log"
+	"time"
 
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
 	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/golang-jwt/jwt/v5"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -17,6 +22,22 @@ func (bh *BaseHandler) Login(ctx context.Context, in *api_pb.LoginRequest) (out
 		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
 	}
 
+	// Ключ клиента, к которому привязывается токен
+	dpopJKT, err := dpopThumbprint(in.DpopKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.SessionCookie && dpopJKT != "" {
+		return nil, errDPoPWithCookie
+	}
+
+	// Проверяем, не заблокирован ли вход после неудачных попыток
+	ip := auth.ClientIP(ctx)
+	if err := bh.checkLoginThrottle(ctx, in.Email, ip); err != nil {
+		return nil, err
+	}
+
 	// Ищем пользователя по email
 	user, err := bh.userRepo.GetUserByEmail(ctx, in.Email)
 	if err != nil {
@@ -24,7 +45,18 @@ func (bh *BaseHandler) Login(ctx context.Context, in *api_pb.LoginRequest) (out
 		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
 	}
 
+	// Для неизвестного email пароль проверяется с фиксированным хешем, чтобы время ответа
+	// не отличалось от неверного пароля существующего пользователя
 	if user == nil {
+		utils.CheckDummyPassword(in.Password)
+		bh.recordLoginAttempt(ctx, in.Email, ip, nil, false)
+		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+	}
+
+	// Сервисные аккаунты аутентифицируются только API ключами
+	if user.Kind == models.UserKindService {
+		utils.CheckDummyPassword(in.Password)
+		bh.recordLoginAttempt(ctx, in.Email, ip, &user.ID, false)
 		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
 	}
 
@@ -36,13 +68,93 @@ func (bh *BaseHandler) Login(ctx context.Context, in *api_pb.LoginRequest) (out
 	}
 
 	if !isValid {
+		bh.recordLoginAttempt(ctx, in.Email, ip, &user.ID, false)
 		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
 	}
 
-	// Получаем права пользователя
-	permissions, err := bh.userRepo.GetUserPermissions(ctx, user.ID)
+	bh.recordLoginAttempt(ctx, in.Email, ip, &user.ID, true)
+
+	out, err = bh.completeLogin(ctx, user, models.LoginMethodPassword, dpopJKT)
+	if err != nil || !in.SessionCookie {
+		return out, err
+	}
+
+	return bh.setSessionCookie(ctx, out)
+}
+
+// completeLogin завершает вход пользователя, личность которого уже подтверждена способом method.
+// Если передан отпечаток ключа клиента, токен привязывается к этому ключу
+func (bh *BaseHandler) completeLogin(
+	ctx context.Context,
+	user *models.User,
+	method, dpopJKT string,
+) (*api_pb.LoginResponse, error) {
+	limited, err := bh.loginRestrictions(user)
 	if err != nil {
-		log.Printf("Failed to get permissions, err:%v\n", err)
+		return nil, err
+	}
+
+	// Проверяем настройки безопасности организации пользователя
+	if err := bh.checkOrganizationLogin(ctx, user, method, dpopJKT); err != nil {
+		return nil, err
+	}
+
+	// Если подключена двухфакторная аутентификация, вход завершается через Login2FA
+	challenge, setupRequired, err := bh.checkSecondFactor(ctx, user, dpopJKT)
+	if err != nil {
+		return nil, err
+	}
+	if challenge != nil {
+		return challenge, nil
+	}
+
+	// Пока второй фактор не подключен, выдается токен без прав
+	out, err := bh.issueLoginResponse(ctx, user, limited || setupRequired, dpopJKT)
+	if err != nil {
+		return nil, err
+	}
+	out.TwoFactorSetupRequired = setupRequired
+
+	return out, nil
+}
+
+// loginRestrictions проверяет, может ли пользователь войти, и нужно ли выдать токен без прав
+func (bh *BaseHandler) loginRestrictions(user *models.User) (limited bool, err error) {
+	// Входить могут только активные пользователи
+	if user.Status != models.UserStatusActive {
+		log.Printf("Login rejected for user %d with status %s\n", user.ID, user.Status)
+		return false, status.Error(codes.PermissionDenied, "User is not active")
+	}
+
+	// До подтверждения email вход запрещен или выдается токен без прав
+	if !user.EmailVerified {
+		switch bh.cfg.EmailVerification.Mode {
+		case EmailVerificationBlock:
+			return false, status.Error(codes.PermissionDenied, "Email is not verified")
+		case EmailVerificationLimit:
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// issueLoginResponse выпускает JWT токен и формирует ответ на успешный вход.
+// Для limited токен выпускается без прав, с dpopJKT токен привязывается к ключу клиента
+func (bh *BaseHandler) issueLoginResponse(
+	ctx context.Context,
+	user *models.User,
+	limited bool,
+	dpopJKT string,
+) (*api_pb.LoginResponse, error) {
+	// Получаем права пользователя
+	var permissions []*models.Permission
+	if !limited {
+		var err error
+		permissions, err = bh.userRepo.GetUserPermissions(ctx, user.ID)
+		if err != nil {
+			log.Printf("Failed to get permissions, err:%v\n", err)
+		}
 	}
 
 	// Формируем ответ
@@ -58,13 +170,47 @@ func (bh *BaseHandler) Login(ctx context.Context, in *api_pb.LoginRequest) (out
 		})
 	}
 
+	// Организация может ограничить время жизни сессии
+	ttl, err := bh.sessionTTL(ctx, user)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	// Идентификатор сессии сохраняется в jti токена
+	sessionID, err := utils.GenerateToken()
+	if err != nil {
+		log.Printf("Failed to generate session id, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
 	// Генерируем JWT токен
-	token, err := bh.jwtFunc(bh.secretKey, user.ID, user.Email, user.Name, jwtPermissions)
+	claims := &utils.Claims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Name:         user.Name,
+		Permissions:  jwtPermissions,
+		GrantVersion: user.GrantVersion,
+		Limited:      limited,
+	}
+	claims.ID = sessionID
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(ttl))
+	tokenType := tokenTypeBearer
+	if dpopJKT != "" {
+		claims.Cnf = &utils.Confirmation{JKT: dpopJKT}
+		tokenType = tokenTypeDPoP
+	}
+	token, err := bh.jwtFunc(bh.secretKey, claims)
 	if err != nil {
 		log.Printf("Failed to generate JWT token, err:%v\n", err)
 		return nil, status.Error(codes.Internal, "Authentication failed")
 	}
 
+	session := &models.Session{ID: sessionID, UserID: user.ID, ExpiresAt: claims.ExpiresAt.Time}
+	if err := bh.createSession(ctx, session); err != nil {
+		log.Printf("Failed to create session, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
 	// Формируем ответ
 	var orgOut *api_pb.Organization
 	if user.Organization != nil {
@@ -80,13 +226,16 @@ func (bh *BaseHandler) Login(ctx context.Context, in *api_pb.LoginRequest) (out
 	}
 
 	return &api_pb.LoginResponse{
-		Token: token,
+		Token:     token,
+		TokenType: tokenType,
 		User: &api_pb.User{
-			Id:           int32(user.ID),
-			Name:         user.Name,
-			Email:        user.Email,
-			Organization: orgOut,
-			Permissions:  permissionsOut,
+			Id:            int32(user.ID),
+			Name:          user.Name,
+			Email:         user.Email,
+			Organization:  orgOut,
+			Permissions:   permissionsOut,
+			Status:        string(user.Status),
+			EmailVerified: user.EmailVerified,
 		},
 	}, nil
 }
@@ -4,17 +4,20 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
 	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
 	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 // TestBaseHandler_Login тестирует метод Login базового обработчика
 func TestBaseHandler_Login(t *testing.T) {
 	ctx := context.Background()
-	f := func(s string, i int, s2 string, s3 string, strings []string) (string, error) {
+	f := func(s string, claims *utils.Claims) (string, error) {
 		return "test-test-test", nil
 	}
 
@@ -25,9 +28,10 @@ func TestBaseHandler_Login(t *testing.T) {
 
 		// Подготавливаем тестового пользователя
 		expectedUser := &models.User{
-			ID:    1,
-			Name:  "Test User",
-			Email: "test@example.com",
+			ID:     1,
+			Name:   "Test User",
+			Email:  "test@example.com",
+			Status: models.UserStatusActive,
 		}
 
 		// Определяем ожидаемое поведение мока
@@ -125,7 +129,117 @@ func TestBaseHandler_Login(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
-	// Test 4: Ошибка при получении пользователя из репозитория
+	// Test 4: Вход приостановленного пользователя
+	t.Run("LoginSuspendedUser", func(t *testing.T) {
+		// Создаем мок репозиторий
+		mockRepo := new(mocks.MockUserRepository)
+
+		// Подготавливаем приостановленного пользователя
+		suspendedUser := &models.User{
+			ID:     1,
+			Name:   "Test User",
+			Email:  "test@example.com",
+			Status: models.UserStatusSuspended,
+		}
+
+		// Определяем ожидаемое поведение мока
+		mockRepo.On("GetUserByEmail", ctx, "test@example.com").Return(suspendedUser, nil)
+		mockRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: mockRepo,
+			jwtFunc:  f,
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{
+			Email:    "test@example.com",
+			Password: "password123",
+		})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = PermissionDenied desc = User is not active", err.Error())
+
+		// Проверяем, что моки были вызваны правильно
+		mockRepo.AssertExpectations(t)
+	})
+
+	// Test 5: Вход заблокирован после превышения числа неудачных попыток
+	t.Run("LoginTooManyAttempts", func(t *testing.T) {
+		// Создаем мок репозиторий
+		mockRepo := new(mocks.MockUserRepository)
+		attemptRepo := new(mocks.MockLoginAttemptRepository)
+
+		// Определяем ожидаемое поведение мока - последняя неудача была только что
+		lastFailure := time.Now()
+		attemptRepo.On("GetFailedLoginStats", ctx, "test@example.com", "", mock.Anything).Return(&models.LoginFailureStats{
+			AccountFailures:    10,
+			AccountLastFailure: &lastFailure,
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:    mockRepo,
+			attemptRepo: attemptRepo,
+			cfg:         Config{LoginThrottle: LoginThrottleConfig{Window: time.Minute, MaxAccountFailures: 10, LockoutDuration: time.Minute}},
+		}
+
+		// Вызываем метод Login с email в другом регистре
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{
+			Email:    "Test@Example.com",
+			Password: "password123",
+		})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = ResourceExhausted desc = Too many login attempts, try again later", err.Error())
+
+		// Пароль не проверяется, пока вход заблокирован
+		mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+		attemptRepo.AssertExpectations(t)
+	})
+
+	// Test 6: Неудачная попытка входа сохраняется
+	t.Run("LoginFailedAttemptRecorded", func(t *testing.T) {
+		// Создаем мок репозиторий
+		mockRepo := new(mocks.MockUserRepository)
+		attemptRepo := new(mocks.MockLoginAttemptRepository)
+
+		// Определяем ожидаемое поведение мока
+		attemptRepo.On("GetFailedLoginStats", ctx, "unknown@example.com", "", mock.Anything).Return(&models.LoginFailureStats{}, nil)
+		mockRepo.On("GetUserByEmail", ctx, "unknown@example.com").Return((*models.User)(nil), nil)
+		attemptRepo.On("AddLoginAttempt", ctx, mock.MatchedBy(func(a *models.LoginAttempt) bool {
+			return a.Email == "unknown@example.com" && a.UserID == nil && !a.Success
+		})).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:    mockRepo,
+			attemptRepo: attemptRepo,
+			cfg:         DefaultConfig(),
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{
+			Email:    "unknown@example.com",
+			Password: "password123",
+		})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = Unauthenticated desc = Invalid credentials", err.Error())
+
+		// Проверяем, что моки были вызваны правильно
+		mockRepo.AssertExpectations(t)
+		attemptRepo.AssertExpectations(t)
+	})
+
+	// Test 7: Ошибка при получении пользователя из репозитория
 	t.Run("LoginRepositoryError", func(t *testing.T) {
 		// Создаем мок репозиторий
 		mockRepo := new(mocks.MockUserRepository)
@@ -152,4 +266,44 @@ func TestBaseHandler_Login(t *testing.T) {
 		// Проверяем, что моки были вызваны правильно
 		mockRepo.AssertExpectations(t)
 	})
+
+	// Test 8: Токен содержит права и версию прав пользователя
+	t.Run("LoginGrantVersion", func(t *testing.T) {
+		// Создаем мок репозиторий
+		mockRepo := new(mocks.MockUserRepository)
+
+		// Определяем ожидаемое поведение мока
+		mockRepo.On("GetUserByEmail", ctx, "test@example.com").Return(&models.User{
+			ID:           1,
+			Email:        "test@example.com",
+			Status:       models.UserStatusActive,
+			GrantVersion: 7,
+		}, nil)
+		mockRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+		mockRepo.On("GetUserPermissions", ctx, 1).Return([]*models.Permission{{ID: 1, Code: "admin"}}, nil)
+
+		var claims *utils.Claims
+		jwtFunc := func(s string, c *utils.Claims) (string, error) {
+			claims = c
+			return "test-test-test", nil
+		}
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: mockRepo,
+			jwtFunc:  jwtFunc,
+		}
+
+		// Вызываем метод Login
+		_, err := baseHandler.Login(ctx, &grpc.LoginRequest{
+			Email:    "test@example.com",
+			Password: "password123",
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), claims.GrantVersion)
+		assert.Equal(t, []string{"admin"}, claims.Permissions)
+		assert.NotEmpty(t, claims.ID)
+	})
 }
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// throttleKey нормализует email, чтобы попытки с разным регистром считались вместе
+func throttleKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// checkLoginThrottle проверяет временную блокировку входа и выдерживает прогрессивную задержку.
+// Блокировка считается по email, поэтому ответ не зависит от того, существует ли аккаунт
+func (bh *BaseHandler) checkLoginThrottle(ctx context.Context, email, ip string) error {
+	if bh.attemptRepo == nil {
+		return nil
+	}
+
+	cfg := bh.cfg.LoginThrottle
+	now := time.Now()
+	stats, err := bh.attemptRepo.GetFailedLoginStats(ctx, throttleKey(email), ip, now.Add(-cfg.Window))
+	if err != nil {
+		log.Printf("Failed to get failed login stats, err:%v\n", err)
+		return status.Error(codes.Internal, "Authentication failed")
+	}
+
+	if cfg.isLocked(stats.AccountFailures, stats.AccountLastFailure, cfg.MaxAccountFailures, now) ||
+		cfg.isLocked(stats.IPFailures, stats.IPLastFailure, cfg.MaxIPFailures, now) {
+		log.Printf("Login temporarily locked for email %q from ip %q\n", email, ip)
+		return status.Error(codes.ResourceExhausted, "Too many login attempts, try again later")
+	}
+
+	if delay := cfg.delay(stats.AccountFailures); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return status.Error(codes.Canceled, "Request canceled")
+		case <-timer.C:
+		}
+	}
+
+	return nil
+}
+
+// recordLoginAttempt сохраняет попытку входа, успешный вход сбрасывает счетчик неудач
+func (bh *BaseHandler) recordLoginAttempt(ctx context.Context, email, ip string, userID *int, success bool) {
+	if bh.attemptRepo == nil {
+		return
+	}
+
+	key := throttleKey(email)
+	err := bh.attemptRepo.AddLoginAttempt(ctx, &models.LoginAttempt{
+		Email:   key,
+		IP:      ip,
+		UserID:  userID,
+		Success: success,
+	})
+	if err != nil {
+		log.Printf("Failed to add login attempt, err:%v\n", err)
+	}
+
+	if success {
+		if err := bh.attemptRepo.ClearFailedLoginAttempts(ctx, key); err != nil {
+			log.Printf("Failed to clear failed login attempts, err:%v\n", err)
+		}
+	}
+}
+
+// isLocked проверяет, превышен ли порог неудачных попыток и не истекла ли блокировка
+func (c LoginThrottleConfig) isLocked(failures int, lastFailure *time.Time, max int, now time.Time) bool {
+	if max <= 0 || failures < max || lastFailure == nil {
+		return false
+	}
+
+	return now.Before(lastFailure.Add(c.LockoutDuration))
+}
+
+// delay возвращает прогрессивную задержку для количества неудачных попыток
+func (c LoginThrottleConfig) delay(failures int) time.Duration {
+	if c.DelayAfter <= 0 || c.BaseDelay <= 0 || failures < c.DelayAfter {
+		return 0
+	}
+
+	delay := c.BaseDelay
+	for i := c.DelayAfter; i < failures && (c.MaxDelay <= 0 || delay < c.MaxDelay); i++ {
+		delay *= 2
+	}
+
+	if c.MaxDelay > 0 && delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+
+	return delay
+}
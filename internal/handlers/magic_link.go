@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequestMagicLink отправляет пользователю ссылку для входа без пароля.
+// Ответ не зависит от того, существует ли пользователь и доступен ли ему вход по ссылке: поиск пользователя
+// и отправка выполняются в фоне, поэтому не различается и время ответа
+func (bh *BaseHandler) RequestMagicLink(ctx context.Context, in *api_pb.MagicLinkRequest) (out *api_pb.Empty, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	email := in.Email
+	bh.runBackground(ctx, func(ctx context.Context) {
+		bh.requestMagicLink(ctx, email)
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// requestMagicLink отправляет ссылку для входа активному пользователю с email, если ему доступен вход по ссылке
+func (bh *BaseHandler) requestMagicLink(ctx context.Context, email string) {
+	user, err := bh.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Printf("Failed to get user by email, err:%v\n", err)
+		return
+	}
+
+	// Неизвестный email, неактивный пользователь и сервисный аккаунт не раскрываются в ответе
+	if user == nil || user.Status != models.UserStatusActive || user.Kind == models.UserKindService {
+		return
+	}
+
+	enabled, err := bh.magicLinkEnabled(ctx, user)
+	if err != nil {
+		log.Printf("Failed to get organization settings, err:%v\n", err)
+		return
+	}
+
+	if !enabled {
+		return
+	}
+
+	if err := bh.sendMagicLink(ctx, user); err != nil {
+		log.Printf("Failed to send magic link, err:%v\n", err)
+		return
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		UserID: utils.Ptr(user.ID),
+		Action: "user.magic_link_request",
+	})
+}
+
+// ConsumeMagicLink выполняет вход по одноразовой ссылке.
+// Ответ совпадает с ответом Login, включая второй фактор
+func (bh *BaseHandler) ConsumeMagicLink(
+	ctx context.Context,
+	in *api_pb.ConsumeMagicLinkRequest,
+) (out *api_pb.LoginResponse, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Ключ клиента проверяется до использования ссылки, чтобы ошибка в ключе не расходовала ее
+	dpopJKT, err := dpopThumbprint(in.DpopKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if in.SessionCookie && dpopJKT != "" {
+		return nil, errDPoPWithCookie
+	}
+
+	token, err := bh.tokenRepo.ConsumeToken(ctx, models.TokenPurposeMagicLink, utils.HashToken(in.Token))
+	if err != nil {
+		log.Printf("Failed to consume magic link token, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	if token == nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid or expired token")
+	}
+
+	user, err := bh.userRepo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		log.Printf("Failed to get user, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	if user == nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid or expired token")
+	}
+
+	// Организация могла отключить вход по ссылке после ее отправки
+	enabled, err := bh.magicLinkEnabled(ctx, user)
+	if err != nil {
+		log.Printf("Failed to get organization settings, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	if !enabled {
+		return nil, status.Error(codes.PermissionDenied, "Magic link login is disabled")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		UserID: utils.Ptr(user.ID),
+		Action: "user.magic_link_login",
+	})
+
+	out, err = bh.completeLogin(ctx, user, models.LoginMethodMagicLink, dpopJKT)
+	if err != nil || !in.SessionCookie {
+		return out, err
+	}
+
+	return bh.setSessionCookie(ctx, out)
+}
+
+// magicLinkEnabled проверяет, доступен ли пользователю вход по ссылке.
+// Для участников организации это определяют настройки организации, в том числе разрешенные способы входа
+func (bh *BaseHandler) magicLinkEnabled(ctx context.Context, user *models.User) (bool, error) {
+	if user.Organization == nil {
+		return bh.cfg.MagicLink.Enabled, nil
+	}
+
+	settings, err := bh.orgRepo.GetOrganizationSettings(ctx, user.Organization.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return settings.MagicLinkEnabled && settings.LoginMethodAllowed(models.LoginMethodMagicLink), nil
+}
+
+// sendMagicLink выпускает новый токен входа и отправляет ссылку пользователю
+func (bh *BaseHandler) sendMagicLink(ctx context.Context, user *models.User) error {
+	cfg := bh.cfg.MagicLink
+	token, err := bh.issueToken(ctx, user.ID, models.TokenPurposeMagicLink, "", cfg.TokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return bh.notifier.Notify(ctx, notifier.Message{
+		To:      user.Email,
+		Subject: "Sign in link",
+		Body: fmt.Sprintf(
+			"To sign in follow the link: %s?token=%s\nThe link expires in %s and can be used once.",
+			cfg.URL, url.QueryEscape(token), cfg.TokenTTL,
+		),
+	})
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_MagicLink тестирует методы RequestMagicLink и ConsumeMagicLink базового обработчика
+func TestBaseHandler_MagicLink(t *testing.T) {
+	ctx := context.Background()
+	f := func(s string, claims *utils.Claims) (string, error) {
+		return "test-test-test", nil
+	}
+
+	user := &models.User{
+		ID:            1,
+		Email:         "test@example.com",
+		Status:        models.UserStatusActive,
+		EmailVerified: true,
+		Organization:  &models.Organization{ID: 3},
+	}
+
+	// Test 1: Вход по ссылке из сообщения выдает такой же ответ, как Login
+	t.Run("MagicLinkSuccess", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		tokenRepo := new(mocks.MockTokenRepository)
+		n := new(mocks.MockNotifier)
+
+		// Определяем ожидаемое поведение мока
+		var sent notifier.Message
+		var stored *models.OneTimeToken
+		// Ссылка отправляется в фоне с контекстом, не зависящим от запроса
+		userRepo.On("GetUserByEmail", mock.Anything, "test@example.com").Return(user, nil)
+		orgRepo.On("GetOrganizationSettings", mock.Anything, 3).
+			Return(&models.OrganizationSettings{OrganizationID: 3, MagicLinkEnabled: true}, nil)
+		tokenRepo.On("DeleteUserTokens", mock.Anything, 1, models.TokenPurposeMagicLink).Return(nil)
+		tokenRepo.On("CreateToken", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.OneTimeToken)
+		}).Return(nil)
+		n.On("Notify", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			sent = args.Get(1).(notifier.Message)
+		}).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			orgRepo:   orgRepo,
+			tokenRepo: tokenRepo,
+			notifier:  n,
+			jwtFunc:   f,
+			cfg:       DefaultConfig(),
+		}
+
+		// Вызываем метод RequestMagicLink
+		_, err := baseHandler.RequestMagicLink(ctx, &grpc.MagicLinkRequest{Email: "test@example.com"})
+		assert.NoError(t, err)
+		baseHandler.background.Wait()
+
+		// Токен в сообщении не совпадает с хранимым хешем
+		match := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(sent.Body)
+		assert.Len(t, match, 2)
+		token, err := url.QueryUnescape(match[1])
+		assert.NoError(t, err)
+		assert.Equal(t, models.TokenPurposeMagicLink, stored.Purpose)
+		assert.Equal(t, utils.HashToken(token), stored.TokenHash)
+
+		// Используем ссылку из сообщения
+		tokenRepo.On("ConsumeToken", ctx, models.TokenPurposeMagicLink, stored.TokenHash).
+			Return(&models.OneTimeToken{UserID: 1}, nil)
+		userRepo.On("GetUserByID", ctx, 1).Return(user, nil)
+		userRepo.On("GetUserPermissions", ctx, 1).Return([]*models.Permission(nil), nil)
+		orgRepo.On("GetOrganizationByID", ctx, 3).Return(&models.Organization{ID: 3, Name: "Org"}, nil)
+
+		// Вызываем метод ConsumeMagicLink
+		result, err := baseHandler.ConsumeMagicLink(ctx, &grpc.ConsumeMagicLinkRequest{Token: token})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, "test-test-test", result.Token)
+		assert.Equal(t, int32(1), result.User.Id)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		tokenRepo.AssertExpectations(t)
+		n.AssertExpectations(t)
+	})
+
+	// Test 2: Для неизвестного email и отключенного в организации входа ответ одинаковый, сообщение не отправляется
+	t.Run("MagicLinkNotSent", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		n := new(mocks.MockNotifier)
+		userRepo.On("GetUserByEmail", mock.Anything, "unknown@example.com").Return((*models.User)(nil), nil)
+		userRepo.On("GetUserByEmail", mock.Anything, "test@example.com").Return(user, nil)
+		orgRepo.On("GetOrganizationSettings", mock.Anything, 3).Return(&models.OrganizationSettings{OrganizationID: 3}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			orgRepo:  orgRepo,
+			notifier: n,
+			cfg:      DefaultConfig(),
+		}
+
+		// Вызываем метод RequestMagicLink
+		unknown, err := baseHandler.RequestMagicLink(ctx, &grpc.MagicLinkRequest{Email: "unknown@example.com"})
+		assert.NoError(t, err)
+		disabled, err := baseHandler.RequestMagicLink(ctx, &grpc.MagicLinkRequest{Email: "test@example.com"})
+		assert.NoError(t, err)
+
+		// Проверяем результат
+		assert.Equal(t, unknown, disabled)
+		baseHandler.background.Wait()
+		n.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		orgRepo.AssertExpectations(t)
+	})
+
+	// Test 3: Использованная или истекшая ссылка отклоняется
+	t.Run("ConsumeMagicLinkInvalidToken", func(t *testing.T) {
+		// Создаем мок репозиторий
+		tokenRepo := new(mocks.MockTokenRepository)
+		tokenRepo.On("ConsumeToken", ctx, models.TokenPurposeMagicLink, utils.HashToken("used-token")).
+			Return((*models.OneTimeToken)(nil), nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{tokenRepo: tokenRepo}
+
+		// Вызываем метод ConsumeMagicLink
+		result, err := baseHandler.ConsumeMagicLink(ctx, &grpc.ConsumeMagicLinkRequest{Token: "used-token"})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
@@ -0,0 +1,70 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAccessRequestRepository имитация репозитория запросов доступа для тестирования
+type MockAccessRequestRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccessRequestRepository) CreateAccessRequest(ctx context.Context, request *models.AccessRequest) (bool, error) {
+	args := m.Called(ctx, request)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAccessRequestRepository) GetAccessRequest(ctx context.Context, id int) (*models.AccessRequest, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.AccessRequest), args.Error(1)
+}
+
+func (m *MockAccessRequestRepository) GetPendingAccessRequests(
+	ctx context.Context,
+	approver models.AccessApprover,
+	limit, offset int,
+) ([]*models.AccessRequest, error) {
+	args := m.Called(ctx, approver, limit, offset)
+	return args.Get(0).([]*models.AccessRequest), args.Error(1)
+}
+
+func (m *MockAccessRequestRepository) GetUserAccessRequests(ctx context.Context, userID int) ([]*models.AccessRequest, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.AccessRequest), args.Error(1)
+}
+
+func (m *MockAccessRequestRepository) DecideAccessRequest(ctx context.Context, request *models.AccessRequest) (bool, error) {
+	args := m.Called(ctx, request)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAccessRequestRepository) ReopenAccessRequest(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAccessRequestRepository) GetRoleApprovers(ctx context.Context, roleID int) ([]int, error) {
+	args := m.Called(ctx, roleID)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockAccessRequestRepository) SetRoleApprovers(ctx context.Context, roleID int, userIDs []int) error {
+	args := m.Called(ctx, roleID, userIDs)
+	return args.Error(0)
+}
+
+func (m *MockAccessRequestRepository) GetAccessApprovers(
+	ctx context.Context,
+	roleID, organizationID int,
+) ([]*models.User, error) {
+	args := m.Called(ctx, roleID, organizationID)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockAccessRequestRepository) InitDB() error {
+	args := m.Called()
+	return args.Error(0)
+}
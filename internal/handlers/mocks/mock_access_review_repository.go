@@ -0,0 +1,80 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAccessReviewRepository имитация репозитория кампаний пересмотра доступа для тестирования
+type MockAccessReviewRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccessReviewRepository) CreateAccessReview(ctx context.Context, review *models.AccessReview) error {
+	args := m.Called(ctx, review)
+	return args.Error(0)
+}
+
+func (m *MockAccessReviewRepository) GetAccessReview(ctx context.Context, id int) (*models.AccessReview, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.AccessReview), args.Error(1)
+}
+
+func (m *MockAccessReviewRepository) GetAccessReviews(
+	ctx context.Context,
+	reviewerID, limit, offset int,
+) ([]*models.AccessReview, error) {
+	args := m.Called(ctx, reviewerID, limit, offset)
+	return args.Get(0).([]*models.AccessReview), args.Error(1)
+}
+
+func (m *MockAccessReviewRepository) GetAccessReviewItems(
+	ctx context.Context,
+	reviewID, reviewerID, limit, offset int,
+) ([]*models.AccessReviewItem, error) {
+	args := m.Called(ctx, reviewID, reviewerID, limit, offset)
+	return args.Get(0).([]*models.AccessReviewItem), args.Error(1)
+}
+
+func (m *MockAccessReviewRepository) GetUserAccessReviewItems(ctx context.Context, userID int) ([]*models.AccessReviewItem, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.AccessReviewItem), args.Error(1)
+}
+
+func (m *MockAccessReviewRepository) GetAccessReviewItem(ctx context.Context, id int) (*models.AccessReviewItem, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.AccessReviewItem), args.Error(1)
+}
+
+func (m *MockAccessReviewRepository) DecideAccessReviewItem(
+	ctx context.Context,
+	item *models.AccessReviewItem,
+) (bool, error) {
+	args := m.Called(ctx, item)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAccessReviewRepository) ReassignAccessReviewItem(ctx context.Context, id, reviewerID int) (bool, error) {
+	args := m.Called(ctx, id, reviewerID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAccessReviewRepository) CloseAccessReview(ctx context.Context, review *models.AccessReview) (bool, error) {
+	args := m.Called(ctx, review)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAccessReviewRepository) RevokeAccessReviewGrants(
+	ctx context.Context,
+	reviewID int,
+) ([]*models.AccessReviewItem, error) {
+	args := m.Called(ctx, reviewID)
+	return args.Get(0).([]*models.AccessReviewItem), args.Error(1)
+}
+
+func (m *MockAccessReviewRepository) InitDB() error {
+	args := m.Called()
+	return args.Error(0)
+}
@@ -0,0 +1,27 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAuditRepository имитация репозитория журнала аудита для тестирования
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) AddAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) GetUserAuditEntries(ctx context.Context, userID int) ([]*models.AuditEntry, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.AuditEntry), args.Error(1)
+}
+
+func (m *MockAuditRepository) InitDB() error {
+	panic("implement me")
+}
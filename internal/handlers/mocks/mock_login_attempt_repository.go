@@ -0,0 +1,42 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLoginAttemptRepository имитация репозитория попыток входа для тестирования
+type MockLoginAttemptRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptRepository) AddLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	args := m.Called(ctx, attempt)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepository) GetUserLoginAttempts(ctx context.Context, userID int) ([]*models.LoginAttempt, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.LoginAttempt), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) GetFailedLoginStats(
+	ctx context.Context,
+	email, ip string,
+	since time.Time,
+) (*models.LoginFailureStats, error) {
+	args := m.Called(ctx, email, ip, since)
+	return args.Get(0).(*models.LoginFailureStats), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) ClearFailedLoginAttempts(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepository) InitDB() error {
+	panic("implement me")
+}
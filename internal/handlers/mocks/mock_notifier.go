@@ -0,0 +1,18 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockNotifier имитация отправки сообщений для тестирования
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, msg notifier.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
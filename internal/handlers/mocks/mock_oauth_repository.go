@@ -0,0 +1,57 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOAuthRepository имитация репозитория OAuth клиентов для тестирования
+type MockOAuthRepository struct {
+	mock.Mock
+}
+
+func (m *MockOAuthRepository) CreateOAuthClient(ctx context.Context, client *models.OAuthClient) error {
+	args := m.Called(ctx, client)
+	return args.Error(0)
+}
+
+func (m *MockOAuthRepository) GetOAuthClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	args := m.Called(ctx, clientID)
+	return args.Get(0).(*models.OAuthClient), args.Error(1)
+}
+
+func (m *MockOAuthRepository) GetOAuthClients(ctx context.Context, limit, offset int) ([]*models.OAuthClient, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]*models.OAuthClient), args.Error(1)
+}
+
+func (m *MockOAuthRepository) DeleteOAuthClient(ctx context.Context, id int) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOAuthRepository) GetOAuthConsent(ctx context.Context, userID, oauthClientID int) (*models.OAuthConsent, error) {
+	args := m.Called(ctx, userID, oauthClientID)
+	return args.Get(0).(*models.OAuthConsent), args.Error(1)
+}
+
+func (m *MockOAuthRepository) SaveOAuthConsent(ctx context.Context, consent *models.OAuthConsent) error {
+	args := m.Called(ctx, consent)
+	return args.Error(0)
+}
+
+func (m *MockOAuthRepository) GetUserOAuthConsents(ctx context.Context, userID int) ([]*models.OAuthConsent, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.OAuthConsent), args.Error(1)
+}
+
+func (m *MockOAuthRepository) DeleteOAuthConsent(ctx context.Context, userID, oauthClientID int) (bool, error) {
+	args := m.Called(ctx, userID, oauthClientID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOAuthRepository) InitDB() error {
+	panic("implement me")
+}
@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockOIDCRepository имитация репозитория провайдеров OpenID Connect для тестирования
+type MockOIDCRepository struct {
+	mock.Mock
+}
+
+func (m *MockOIDCRepository) GetOIDCProvider(ctx context.Context, orgID int) (*models.OIDCProvider, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).(*models.OIDCProvider), args.Error(1)
+}
+
+func (m *MockOIDCRepository) SaveOIDCProvider(ctx context.Context, provider *models.OIDCProvider) error {
+	args := m.Called(ctx, provider)
+	return args.Error(0)
+}
+
+func (m *MockOIDCRepository) DeleteOIDCProvider(ctx context.Context, orgID int) (bool, error) {
+	args := m.Called(ctx, orgID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOIDCRepository) CreateOIDCState(ctx context.Context, state *models.OIDCLoginState) error {
+	args := m.Called(ctx, state)
+	return args.Error(0)
+}
+
+func (m *MockOIDCRepository) ConsumeOIDCState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	args := m.Called(ctx, stateHash)
+	return args.Get(0).(*models.OIDCLoginState), args.Error(1)
+}
+
+func (m *MockOIDCRepository) GetOIDCIdentity(ctx context.Context, providerID int, subject string) (*models.OIDCIdentity, error) {
+	args := m.Called(ctx, providerID, subject)
+	return args.Get(0).(*models.OIDCIdentity), args.Error(1)
+}
+
+func (m *MockOIDCRepository) GetUserOIDCIdentities(ctx context.Context, userID int) ([]*models.OIDCIdentity, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.OIDCIdentity), args.Error(1)
+}
+
+func (m *MockOIDCRepository) CreateOIDCIdentity(ctx context.Context, identity *models.OIDCIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *MockOIDCRepository) TouchOIDCIdentity(ctx context.Context, id int, email string) error {
+	args := m.Called(ctx, id, email)
+	return args.Error(0)
+}
+
+func (m *MockOIDCRepository) InitDB() error {
+	panic("implement me")
+}
@@ -22,13 +22,53 @@ func (m *MockOrganizationRepository) GetOrganizationTariff(ctx context.Context,
 	return args.Get(0).(*models.Tariff), args.Error(1)
 }
 
+func (m *MockOrganizationRepository) GetOrganizationSettings(ctx context.Context, orgID int) (*models.OrganizationSettings, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).(*models.OrganizationSettings), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) UpdateOrganizationSettings(ctx context.Context, settings *models.OrganizationSettings) error {
+	args := m.Called(ctx, settings)
+	return args.Error(0)
+}
+
+func (m *MockOrganizationRepository) GetOrganizationAdmins(ctx context.Context, orgID int) ([]int, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) GetUserAdminOrganizations(ctx context.Context, userID int) ([]int, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) SetOrganizationAdmins(ctx context.Context, orgID int, userIDs []int) error {
+	args := m.Called(ctx, orgID, userIDs)
+	return args.Error(0)
+}
+
+func (m *MockOrganizationRepository) IsOrganizationAdmin(ctx context.Context, orgID, userID int) (bool, error) {
+	args := m.Called(ctx, orgID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOrganizationRepository) IsOrganizationRole(ctx context.Context, orgID, roleID int) (bool, error) {
+	args := m.Called(ctx, orgID, roleID)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockOrganizationRepository) GetOrganizationPermissions(ctx context.Context, organizationID int) ([]*models.Permission, error) {
 	args := m.Called(ctx, organizationID)
 	return args.Get(0).([]*models.Permission), args.Error(1)
 }
 
-func (m *MockOrganizationRepository) AddOrganizationPermissions(ctx context.Context, organizationID int, permissionIDs []int) error {
-	args := m.Called(ctx, organizationID, permissionIDs)
+func (m *MockOrganizationRepository) AddOrganizationPermissions(
+	ctx context.Context,
+	organizationID int,
+	permissionIDs []int,
+	validity models.GrantValidity,
+) error {
+	args := m.Called(ctx, organizationID, permissionIDs, validity)
 	return args.Error(0)
 }
 
@@ -42,8 +82,13 @@ func (m *MockOrganizationRepository) GetOrganizationRoles(ctx context.Context, o
 	return args.Get(0).([]*models.Role), args.Error(1)
 }
 
-func (m *MockOrganizationRepository) AddOrganizationRoles(ctx context.Context, organizationID int, roleIDs []int) error {
-	args := m.Called(ctx, organizationID, roleIDs)
+func (m *MockOrganizationRepository) AddOrganizationRoles(
+	ctx context.Context,
+	organizationID int,
+	roleIDs []int,
+	validity models.GrantValidity,
+) error {
+	args := m.Called(ctx, organizationID, roleIDs, validity)
 	return args.Error(0)
 }
 
@@ -52,6 +97,11 @@ func (m *MockOrganizationRepository) DeleteOrganizationRoles(ctx context.Context
 	return args.Error(0)
 }
 
+func (m *MockOrganizationRepository) ExpireOrganizationGrants(ctx context.Context) ([]*models.ExpiredGrant, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*models.ExpiredGrant), args.Error(1)
+}
+
 func (m *MockOrganizationRepository) CreateOrganization(ctx context.Context, org *models.Organization) (*models.Organization, error) {
 	args := m.Called(ctx, org)
 
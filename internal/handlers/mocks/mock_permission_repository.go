@@ -37,6 +37,11 @@ func (m *MockPermissionRepository) GetPermissions(ctx context.Context, limit, of
 	return args.Get(0).([]*models.Permission), args.Error(1)
 }
 
+func (m *MockPermissionRepository) GetPermissionsByCodes(ctx context.Context, codes []string) ([]*models.Permission, error) {
+	args := m.Called(ctx, codes)
+	return args.Get(0).([]*models.Permission), args.Error(1)
+}
+
 func (m *MockPermissionRepository) InitDB() error {
 	args := m.Called()
 	return args.Error(0)
@@ -0,0 +1,57 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSessionRepository имитация репозитория сессий для тестирования
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) TouchSession(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) GetUserSessions(ctx context.Context, userID int) ([]*models.Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) GetUserSessionHistory(ctx context.Context, userID int) ([]*models.Session, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeSession(ctx context.Context, userID int, id string) (bool, error) {
+	args := m.Called(ctx, userID, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeUserSessions(ctx context.Context, userID int, exceptID string) (int, error) {
+	args := m.Called(ctx, userID, exceptID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSessionRepository) RevokeOAuthClientSessions(ctx context.Context, clientID, userID int) (int, error) {
+	args := m.Called(ctx, clientID, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockSessionRepository) InitDB() error {
+	panic("implement me")
+}
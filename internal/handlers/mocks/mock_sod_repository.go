@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSoDRepository имитация репозитория ограничений разделения обязанностей для тестирования
+type MockSoDRepository struct {
+	mock.Mock
+}
+
+func (m *MockSoDRepository) CreateSoDConstraint(ctx context.Context, constraint *models.SoDConstraint) error {
+	args := m.Called(ctx, constraint)
+	return args.Error(0)
+}
+
+func (m *MockSoDRepository) GetSoDConstraints(ctx context.Context, limit, offset int) ([]*models.SoDConstraint, error) {
+	args := m.Called(ctx, limit, offset)
+	return args.Get(0).([]*models.SoDConstraint), args.Error(1)
+}
+
+func (m *MockSoDRepository) DeleteSoDConstraint(ctx context.Context, id int) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockSoDRepository) GetSoDConflicts(
+	ctx context.Context,
+	owner models.GrantOwner,
+	ownerID int,
+	roleIDs []int,
+) ([]*models.SoDViolation, error) {
+	args := m.Called(ctx, owner, ownerID, roleIDs)
+	return args.Get(0).([]*models.SoDViolation), args.Error(1)
+}
+
+func (m *MockSoDRepository) GetSoDMembershipConflicts(
+	ctx context.Context,
+	owner models.GrantOwner,
+	ownerID int,
+	target models.GrantOwner,
+	targetID int,
+) ([]*models.SoDViolation, error) {
+	args := m.Called(ctx, owner, ownerID, target, targetID)
+	return args.Get(0).([]*models.SoDViolation), args.Error(1)
+}
+
+func (m *MockSoDRepository) GetSoDViolations(
+	ctx context.Context,
+	constraintID, limit, offset int,
+) ([]*models.SoDViolation, error) {
+	args := m.Called(ctx, constraintID, limit, offset)
+	return args.Get(0).([]*models.SoDViolation), args.Error(1)
+}
+
+func (m *MockSoDRepository) InitDB() error {
+	args := m.Called()
+	return args.Error(0)
+}
@@ -0,0 +1,45 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTokenRepository имитация репозитория одноразовых токенов для тестирования
+type MockTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenRepository) CreateToken(ctx context.Context, token *models.OneTimeToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) GetToken(
+	ctx context.Context,
+	purpose models.TokenPurpose,
+	tokenHash string,
+) (*models.OneTimeToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	return args.Get(0).(*models.OneTimeToken), args.Error(1)
+}
+
+func (m *MockTokenRepository) ConsumeToken(
+	ctx context.Context,
+	purpose models.TokenPurpose,
+	tokenHash string,
+) (*models.OneTimeToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	return args.Get(0).(*models.OneTimeToken), args.Error(1)
+}
+
+func (m *MockTokenRepository) DeleteUserTokens(ctx context.Context, userID int, purpose models.TokenPurpose) error {
+	args := m.Called(ctx, userID, purpose)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) InitDB() error {
+	panic("implement me")
+}
@@ -0,0 +1,17 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTransactor имитация транзакций для тестирования, fn выполняется с тем же контекстом
+type MockTransactor struct {
+	mock.Mock
+}
+
+func (m *MockTransactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.Called(ctx)
+	return fn(ctx)
+}
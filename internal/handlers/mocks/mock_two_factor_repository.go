@@ -0,0 +1,52 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTwoFactorRepository имитация репозитория двухфакторной аутентификации для тестирования
+type MockTwoFactorRepository struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorRepository) GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.TwoFactor), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) GetRecoveryCodes(ctx context.Context, userID int) ([]*models.RecoveryCode, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.RecoveryCode), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) SaveTwoFactorSecret(ctx context.Context, userID int, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) EnableTwoFactor(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	args := m.Called(ctx, userID, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) DisableTwoFactor(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) InitDB() error {
+	panic("implement me")
+}
@@ -22,13 +22,38 @@ func (m *MockUserRepository) GetUserTariff(ctx context.Context, userID int) (*mo
 	return args.Get(0).(*models.Tariff), args.Error(1)
 }
 
+func (m *MockUserRepository) ResetUserPassword(ctx context.Context, userID int, passwordHash string) error {
+	args := m.Called(ctx, userID, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetUserPendingEmail(ctx context.Context, userID int, email string) error {
+	args := m.Called(ctx, userID, email)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) VerifyUserEmail(ctx context.Context, userID int, email string) (bool, error) {
+	args := m.Called(ctx, userID, email)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockUserRepository) GetUserPermissions(ctx context.Context, userID int) ([]*models.Permission, error) {
 	args := m.Called(ctx, userID)
 	return args.Get(0).([]*models.Permission), args.Error(1)
 }
 
-func (m *MockUserRepository) AddUserPermissions(ctx context.Context, userID int, permissionIDs []int) error {
-	args := m.Called(ctx, userID, permissionIDs)
+func (m *MockUserRepository) GetUserDirectPermissions(ctx context.Context, userID int) ([]*models.Permission, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.Permission), args.Error(1)
+}
+
+func (m *MockUserRepository) AddUserPermissions(
+	ctx context.Context,
+	userID int,
+	permissionIDs []int,
+	validity models.GrantValidity,
+) error {
+	args := m.Called(ctx, userID, permissionIDs, validity)
 	return args.Error(0)
 }
 
@@ -42,8 +67,8 @@ func (m *MockUserRepository) GetUserRoles(ctx context.Context, userID int) ([]*m
 	return args.Get(0).([]*models.Role), args.Error(1)
 }
 
-func (m *MockUserRepository) AddUserRoles(ctx context.Context, userID int, roleIDs []int) error {
-	args := m.Called(ctx, userID, roleIDs)
+func (m *MockUserRepository) AddUserRoles(ctx context.Context, userID int, roleIDs []int, validity models.GrantValidity) error {
+	args := m.Called(ctx, userID, roleIDs, validity)
 	return args.Error(0)
 }
 
@@ -52,6 +77,11 @@ func (m *MockUserRepository) DeleteUserRoles(ctx context.Context, userID int, ro
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) ExpireUserGrants(ctx context.Context) ([]*models.ExpiredGrant, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*models.ExpiredGrant), args.Error(1)
+}
+
 func (m *MockUserRepository) CheckPassword(ctx context.Context, userID int, password string) (bool, error) {
 	args := m.Called(ctx, userID, password)
 	return args.Bool(0), args.Error(1)
@@ -62,11 +92,26 @@ func (m *MockUserRepository) GetUserByEmail(ctx context.Context, email string) (
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetUsers(ctx context.Context, limit, offset int) ([]*models.User, error) {
+func (m *MockUserRepository) GetUsers(ctx context.Context, limit, offset int, status models.UserStatus) ([]*models.User, error) {
+	args := m.Called(ctx, limit, offset, status)
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetServiceAccounts(ctx context.Context, limit, offset int) ([]*models.User, error) {
 	args := m.Called(ctx, limit, offset)
 	return args.Get(0).([]*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) SetUserStatus(ctx context.Context, userID int, status models.UserStatus, reason string) error {
+	args := m.Called(ctx, userID, status, reason)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetUserAuthState(ctx context.Context, userID int) (*models.UserAuthState, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*models.UserAuthState), args.Error(1)
+}
+
 func (m *MockUserRepository) InitDB() error {
 	panic("implement me")
 }
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// oauthError ответ OAuth endpoint'а с ошибкой (RFC 6749, раздел 5.2)
+type oauthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// writeOAuthJSON отправляет JSON ответ OAuth endpoint'а. Ответы с токенами не должны кешироваться
+func writeOAuthJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Failed to write oauth response, err:%v\n", err)
+	}
+}
+
+// writeOAuthError отправляет ошибку OAuth endpoint'а
+func writeOAuthError(w http.ResponseWriter, code int, errorCode, description string) {
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oauth"`)
+	}
+
+	writeOAuthJSON(w, code, oauthError{Error: errorCode, ErrorDescription: description})
+}
+
+// parseOAuthForm проверяет метод запроса и разбирает form-encoded тело
+func parseOAuthForm(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeOAuthError(w, http.StatusMethodNotAllowed, "invalid_request", "POST required")
+		return false
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Malformed request body")
+		return false
+	}
+
+	return true
+}
+
+// isSecureURL проверяет, что адрес использует TLS. Без TLS допускаются только локальные адреса
+func isSecureURL(u *url.URL) bool {
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		if host == "localhost" {
+			return true
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && ip.IsLoopback()
+	}
+
+	return false
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+)
+
+// codeChallengeMethodS256 единственный поддерживаемый метод PKCE (RFC 7636)
+const codeChallengeMethodS256 = "S256"
+
+// consentResponse ответ /oauth/authorize, если пользователь еще не дал согласие на запрошенные права
+type consentResponse struct {
+	ClientID        string   `json:"client_id"`
+	ClientName      string   `json:"client_name"`
+	Scopes          []string `json:"scopes"`
+	RedirectURI     string   `json:"redirect_uri"`
+	ConsentRequired bool     `json:"consent_required"`
+}
+
+// Authorize обрабатывает /oauth/authorize для authorization code с PKCE (RFC 6749, раздел 4.1).
+// Пользователь аутентифицируется токеном доступа. GET возвращает сведения для экрана согласия,
+// POST с consent=approve сохраняет согласие. Если согласие уже дано, клиент получает code редиректом
+func (bh *BaseHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		writeOAuthError(w, http.StatusMethodNotAllowed, "invalid_request", "GET or POST required")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Malformed request")
+		return
+	}
+
+	ctx := r.Context()
+	form := r.Form
+
+	// Пока клиент и адрес возврата не проверены, ошибки не отправляются редиректом
+	client, err := bh.oauthRepo.GetOAuthClient(ctx, form.Get("client_id"))
+	if err != nil {
+		log.Printf("Failed to get oauth client, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if client == nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_client", "Unknown client")
+		return
+	}
+
+	redirectURI := form.Get("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !containsCode(client.RedirectURIs, redirectURI) {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Redirect URI is not registered")
+		return
+	}
+
+	state := form.Get("state")
+
+	// Согласие дает только сам пользователь в обычной сессии
+	userID := currentUserID(ctx)
+	if userID == nil {
+		writeOAuthError(w, http.StatusUnauthorized, "login_required", "User authentication required")
+		return
+	}
+
+	if auth.APIKeyID(ctx) != 0 || auth.ImpersonatorID(ctx) != 0 {
+		writeOAuthError(w, http.StatusForbidden, "access_denied", "Consent requires an interactive user session")
+		return
+	}
+
+	if form.Get("response_type") != "code" {
+		redirectOAuthError(w, r, redirectURI, state, "unsupported_response_type", "")
+		return
+	}
+
+	// Без PKCE перехваченный code можно обменять на токен
+	codeChallenge := form.Get("code_challenge")
+	if codeChallenge == "" || form.Get("code_challenge_method") != codeChallengeMethodS256 {
+		redirectOAuthError(w, r, redirectURI, state, "invalid_request", "PKCE with S256 is required")
+		return
+	}
+
+	// Без scope запрашиваются все права клиента, которые есть у пользователя
+	scopes := strings.Fields(form.Get("scope"))
+	if len(scopes) == 0 {
+		for _, code := range client.Scopes {
+			if auth.HasPermission(ctx, code) {
+				scopes = append(scopes, code)
+			}
+		}
+	}
+	if len(scopes) == 0 {
+		redirectOAuthError(w, r, redirectURI, state, "invalid_scope", "No permissions to delegate")
+		return
+	}
+	for _, code := range scopes {
+		if !containsCode(client.Scopes, code) || !auth.HasPermission(ctx, code) {
+			redirectOAuthError(w, r, redirectURI, state, "invalid_scope", "Permission "+code+" cannot be granted")
+			return
+		}
+	}
+
+	if r.Method == http.MethodPost {
+		switch r.PostForm.Get("consent") {
+		case "approve":
+			err := bh.oauthRepo.SaveOAuthConsent(ctx, &models.OAuthConsent{
+				UserID:        *userID,
+				OAuthClientID: client.ID,
+				Scopes:        scopes,
+			})
+			if err != nil {
+				log.Printf("Failed to save oauth consent, err:%v\n", err)
+				writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+				return
+			}
+
+			bh.audit(ctx, &models.AuditEntry{
+				ActorID: userID,
+				UserID:  userID,
+				Action:  "user.oauth_consent",
+				Details: "client " + client.ClientID + ": " + strings.Join(scopes, " "),
+			})
+		case "deny":
+			redirectOAuthError(w, r, redirectURI, state, "access_denied", "")
+			return
+		default:
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Parameter consent must be approve or deny")
+			return
+		}
+	} else {
+		consent, err := bh.oauthRepo.GetOAuthConsent(ctx, *userID, client.ID)
+		if err != nil {
+			log.Printf("Failed to get oauth consent, err:%v\n", err)
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+
+		if !consentCovers(consent, scopes) {
+			writeOAuthJSON(w, http.StatusOK, consentResponse{
+				ClientID:        client.ClientID,
+				ClientName:      client.Name,
+				Scopes:          scopes,
+				RedirectURI:     redirectURI,
+				ConsentRequired: true,
+			})
+			return
+		}
+	}
+
+	code, err := bh.issueOAuthCode(r, *userID, &models.OAuthCode{
+		OAuthClientID: client.ID,
+		RedirectURI:   redirectURI,
+		Scope:         strings.Join(scopes, " "),
+		CodeChallenge: codeChallenge,
+	})
+	if err != nil {
+		log.Printf("Failed to issue oauth code, err:%v\n", err)
+		redirectOAuthError(w, r, redirectURI, state, "server_error", "")
+		return
+	}
+
+	redirectOAuth(w, r, redirectURI, url.Values{"code": {code}}, state)
+}
+
+// issueOAuthCode выпускает одноразовый authorization code
+func (bh *BaseHandler) issueOAuthCode(r *http.Request, userID int, data *models.OAuthCode) (string, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = bh.tokenRepo.CreateToken(r.Context(), &models.OneTimeToken{
+		UserID:    userID,
+		Purpose:   models.TokenPurposeOAuthCode,
+		TokenHash: utils.HashToken(code),
+		Payload:   string(payload),
+		ExpiresAt: time.Now().Add(bh.cfg.OAuth.CodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// consentCovers проверяет, что согласие пользователя включает все запрошенные права
+func consentCovers(consent *models.OAuthConsent, scopes []string) bool {
+	if consent == nil {
+		return false
+	}
+
+	for _, code := range scopes {
+		if !containsCode(consent.Scopes, code) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// redirectOAuthError возвращает ошибку авторизации клиенту через адрес возврата
+func redirectOAuthError(w http.ResponseWriter, r *http.Request, redirectURI, state, errorCode, description string) {
+	params := url.Values{"error": {errorCode}}
+	if description != "" {
+		params.Set("error_description", description)
+	}
+
+	redirectOAuth(w, r, redirectURI, params, state)
+}
+
+// redirectOAuth перенаправляет пользователя на адрес возврата клиента с параметрами ответа
+func redirectOAuth(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values, state string) {
+	if state != "" {
+		params.Set("state", state)
+	}
+
+	// Адрес возврата уже проверен при регистрации клиента
+	u, _ := url.Parse(redirectURI)
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
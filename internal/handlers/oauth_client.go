@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// oauthClientIDPrefix префикс публичного идентификатора OAuth клиента
+const oauthClientIDPrefix = "oc_"
+
+// CreateOauthClient регистрирует OAuth клиента. Секрет клиента возвращается только в этом ответе
+func (bh *BaseHandler) CreateOauthClient(
+	ctx context.Context,
+	in *api_pb.OauthClientCreateRequest,
+) (out *api_pb.OauthClientCreateResponse, err error) {
+	// Управлять OAuth клиентами может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Name == "" || len(in.Scopes) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	for _, redirectURI := range in.RedirectUris {
+		if !validRedirectURI(redirectURI) {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Invalid redirect URI %q", redirectURI))
+		}
+	}
+
+	// Scope клиента - коды существующих прав
+	permissions, err := bh.permRepo.GetPermissionsByCodes(ctx, in.Scopes)
+	if err != nil {
+		log.Printf("Failed to get permissions by codes, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create OAuth client")
+	}
+	known := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		known = append(known, permission.Code)
+	}
+	for _, scope := range in.Scopes {
+		if !containsCode(known, scope) {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Unknown scope %q", scope))
+		}
+	}
+
+	client := &models.OAuthClient{
+		Name:         in.Name,
+		RedirectURIs: in.RedirectUris,
+		Scopes:       in.Scopes,
+	}
+
+	// Токены client_credentials выдаются от имени сервисного аккаунта
+	if in.ServiceAccountId != 0 {
+		account, err := bh.getServiceAccount(ctx, in.ServiceAccountId)
+		if err != nil {
+			return nil, err
+		}
+		client.ServiceAccountID = utils.Ptr(account.ID)
+	}
+
+	clientID, err := utils.GenerateToken()
+	if err != nil {
+		log.Printf("Failed to generate client id, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create OAuth client")
+	}
+	client.ClientID = oauthClientIDPrefix + clientID[:20]
+
+	secret, err := utils.GenerateToken()
+	if err != nil {
+		log.Printf("Failed to generate client secret, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create OAuth client")
+	}
+	client.SecretHash = utils.HashToken(secret)
+
+	if err := bh.oauthRepo.CreateOAuthClient(ctx, client); err != nil {
+		log.Printf("Failed to create oauth client, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create OAuth client")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  client.ServiceAccountID,
+		Action:  "oauth_client.create",
+		Details: fmt.Sprintf("client %s", client.ClientID),
+	})
+
+	return &api_pb.OauthClientCreateResponse{OauthClient: oauthClientToPb(client), ClientSecret: secret}, nil
+}
+
+// GetOauthClients получает список OAuth клиентов
+func (bh *BaseHandler) GetOauthClients(ctx context.Context, in *api_pb.ListRequest) (out *api_pb.OauthClientsResponse, err error) {
+	// Управлять OAuth клиентами может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Устанавливаем значения по умолчанию
+	limit := 10
+	offset := 0
+
+	// Парсим limit
+	if in.Limit > 0 && in.Limit < 100 {
+		limit = int(in.GetLimit())
+	}
+
+	// Парсим offset
+	if in.Offset > 0 {
+		offset = int(in.GetOffset())
+	}
+
+	clients, err := bh.oauthRepo.GetOAuthClients(ctx, limit, offset)
+	if err != nil {
+		log.Printf("Failed to get oauth clients: %v", err)
+		return nil, status.Error(codes.Internal, "Failed to get OAuth clients")
+	}
+
+	data := make([]*api_pb.OauthClient, len(clients))
+	for i, client := range clients {
+		data[i] = oauthClientToPb(client)
+	}
+
+	return &api_pb.OauthClientsResponse{Data: data}, nil
+}
+
+// DeleteOauthClient удаляет OAuth клиента. Выданные клиенту токены перестают действовать
+func (bh *BaseHandler) DeleteOauthClient(ctx context.Context, in *api_pb.Id) (out *api_pb.Empty, err error) {
+	// Управлять OAuth клиентами может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	if _, err := bh.sessionRepo.RevokeOAuthClientSessions(ctx, int(in.Id), 0); err != nil {
+		log.Printf("Failed to revoke oauth client sessions, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to delete OAuth client")
+	}
+
+	ok, err := bh.oauthRepo.DeleteOAuthClient(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to delete oauth client, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to delete OAuth client")
+	}
+
+	if !ok {
+		return nil, status.Error(codes.NotFound, "OAuth client not found")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		Action:  "oauth_client.delete",
+		Details: fmt.Sprintf("client %d", in.Id),
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// GetOauthConsents получает согласия, которые пользователь дал OAuth клиентам
+func (bh *BaseHandler) GetOauthConsents(ctx context.Context, in *api_pb.Id) (out *api_pb.OauthConsentsResponse, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Согласия доступны самому пользователю и администратору
+	if err := checkPermissions(ctx, in.Id); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	consents, err := bh.oauthRepo.GetUserOAuthConsents(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to get oauth consents, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get OAuth consents")
+	}
+
+	data := make([]*api_pb.OauthConsent, len(consents))
+	for i, consent := range consents {
+		data[i] = &api_pb.OauthConsent{
+			ClientId:   consent.ClientID,
+			ClientName: consent.ClientName,
+			Scopes:     consent.Scopes,
+			CreatedAt:  consent.CreatedAt.Format(time.RFC3339),
+			UpdatedAt:  consent.UpdatedAt.Format(time.RFC3339),
+		}
+	}
+
+	return &api_pb.OauthConsentsResponse{Data: data}, nil
+}
+
+// RevokeOauthConsent отзывает согласие пользователя и токены, выданные клиенту от его имени
+func (bh *BaseHandler) RevokeOauthConsent(ctx context.Context, in *api_pb.OauthConsentRequest) (out *api_pb.Empty, err error) {
+	// Проверяем входные данные
+	if in == nil || in.UserId == 0 || in.ClientId == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Отзывать согласия может сам пользователь и администратор
+	if err := checkPermissions(ctx, in.UserId); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	client, err := bh.oauthRepo.GetOAuthClient(ctx, in.ClientId)
+	if err != nil {
+		log.Printf("Failed to get oauth client, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to revoke OAuth consent")
+	}
+
+	if client == nil {
+		return nil, status.Error(codes.NotFound, "OAuth consent not found")
+	}
+
+	ok, err := bh.oauthRepo.DeleteOAuthConsent(ctx, int(in.UserId), client.ID)
+	if err != nil {
+		log.Printf("Failed to delete oauth consent, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to revoke OAuth consent")
+	}
+
+	if !ok {
+		return nil, status.Error(codes.NotFound, "OAuth consent not found")
+	}
+
+	if _, err := bh.sessionRepo.RevokeOAuthClientSessions(ctx, client.ID, int(in.UserId)); err != nil {
+		log.Printf("Failed to revoke oauth client sessions, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to revoke OAuth consent")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(int(in.UserId)),
+		Action:  "user.oauth_consent_revoke",
+		Details: fmt.Sprintf("client %s", client.ClientID),
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// oauthClientToPb конвертирует OAuth клиента в ответ API без секрета
+func oauthClientToPb(client *models.OAuthClient) *api_pb.OauthClient {
+	out := &api_pb.OauthClient{
+		Id:           int32(client.ID),
+		ClientId:     client.ClientID,
+		Name:         client.Name,
+		RedirectUris: client.RedirectURIs,
+		Scopes:       client.Scopes,
+		CreatedAt:    client.CreatedAt.Format(time.RFC3339),
+	}
+	if client.ServiceAccountID != nil {
+		out.ServiceAccountId = int32(*client.ServiceAccountID)
+	}
+
+	return out
+}
+
+// validRedirectURI проверяет адрес возврата клиента
+func validRedirectURI(redirectURI string) bool {
+	u, err := url.Parse(redirectURI)
+	if err != nil || u.Host == "" || u.Fragment != "" {
+		return false
+	}
+
+	return isSecureURL(u)
+}
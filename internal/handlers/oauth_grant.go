@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/oidc"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clientCredentials выдает клиенту токен от имени привязанного к нему сервисного аккаунта.
+// Права токена ограничены scope клиента и текущими правами сервисного аккаунта
+func (bh *BaseHandler) clientCredentials(w http.ResponseWriter, r *http.Request) {
+	client, ok := bh.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	if client.ServiceAccountID == nil {
+		writeOAuthError(w, http.StatusBadRequest, "unauthorized_client", "Client has no service account")
+		return
+	}
+
+	account, err := bh.userRepo.GetUserByID(r.Context(), *client.ServiceAccountID)
+	if err != nil {
+		log.Printf("Failed to get user by id, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if account == nil || account.Kind != models.UserKindService || account.Status != models.UserStatusActive {
+		writeOAuthError(w, http.StatusBadRequest, "unauthorized_client", "Service account is not active")
+		return
+	}
+
+	// Без scope запрашиваются все права клиента
+	scopes := strings.Fields(r.PostForm.Get("scope"))
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	for _, code := range scopes {
+		if !containsCode(client.Scopes, code) {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "Scope "+code+" is not allowed for client")
+			return
+		}
+	}
+
+	bh.issueOAuthToken(w, r, client, account, scopes)
+}
+
+// authorizationCode обменивает authorization code на токен пользователя.
+// Code одноразовый и действует только для клиента, адреса возврата и code_verifier, с которыми был выпущен
+func (bh *BaseHandler) authorizationCode(w http.ResponseWriter, r *http.Request) {
+	client, ok := bh.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	form := r.PostForm
+	code := form.Get("code")
+	codeVerifier := form.Get("code_verifier")
+	if code == "" || codeVerifier == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Parameters code and code_verifier are required")
+		return
+	}
+
+	ctx := r.Context()
+	token, err := bh.tokenRepo.ConsumeToken(ctx, models.TokenPurposeOAuthCode, utils.HashToken(code))
+	if err != nil {
+		log.Printf("Failed to consume oauth code, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if token == nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Code is invalid or expired")
+		return
+	}
+
+	var data models.OAuthCode
+	if err := json.Unmarshal([]byte(token.Payload), &data); err != nil {
+		log.Printf("Failed to parse oauth code payload, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if data.OAuthClientID != client.ID || data.RedirectURI != form.Get("redirect_uri") {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Code was issued to another client")
+		return
+	}
+
+	challenge := oidc.CodeChallenge(codeVerifier)
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(data.CodeChallenge)) != 1 {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Code verifier does not match")
+		return
+	}
+
+	user, err := bh.userRepo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		log.Printf("Failed to get user by id, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if user == nil || user.Status != models.UserStatusActive {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "User is not active")
+		return
+	}
+
+	bh.issueOAuthToken(w, r, client, user, strings.Fields(data.Scope))
+}
+
+// authenticateClient проверяет client_id и client_secret из заголовка Authorization (Basic) или из формы
+func (bh *BaseHandler) authenticateClient(w http.ResponseWriter, r *http.Request) (*models.OAuthClient, bool) {
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		// В Basic значения закодированы как application/x-www-form-urlencoded (RFC 6749, раздел 2.3.1)
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	if clientID == "" || secret == "" {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication required")
+		return nil, false
+	}
+
+	client, err := bh.oauthRepo.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		log.Printf("Failed to get oauth client, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return nil, false
+	}
+
+	if client == nil ||
+		subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return nil, false
+	}
+
+	return client, true
+}
+
+// issueOAuthToken выпускает токен доступа клиенту от имени пользователя.
+// Токен содержит только те запрошенные права, которые есть у пользователя, и привязан к отдельной сессии,
+// поэтому удаление клиента или отзыв согласия отзывает выданные ему токены
+func (bh *BaseHandler) issueOAuthToken(
+	w http.ResponseWriter,
+	r *http.Request,
+	client *models.OAuthClient,
+	user *models.User,
+	scopes []string,
+) {
+	ctx := r.Context()
+	permissions, err := bh.userRepo.GetUserPermissions(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to get permissions, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	granted := make([]string, 0, len(scopes))
+	for _, permission := range permissions {
+		if containsCode(scopes, permission.Code) {
+			granted = append(granted, permission.Code)
+		}
+	}
+
+	if len(granted) == 0 {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "No permissions to delegate")
+		return
+	}
+
+	sessionID, err := utils.GenerateToken()
+	if err != nil {
+		log.Printf("Failed to generate session id, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	expiresAt := time.Now().Add(bh.cfg.OAuth.AccessTokenTTL)
+	claims := &utils.Claims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		Name:         user.Name,
+		Permissions:  granted,
+		GrantVersion: user.GrantVersion,
+		Scope:        strings.Join(granted, " "),
+	}
+	claims.ID = sessionID
+	claims.Audience = jwt.ClaimStrings{bh.cfg.OAuth.Audience}
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	token, err := bh.jwtFunc(bh.secretKey, claims)
+	if err != nil {
+		log.Printf("Failed to generate JWT token, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	err = bh.createSession(ctx, &models.Session{
+		ID:            sessionID,
+		UserID:        user.ID,
+		ExpiresAt:     expiresAt,
+		OAuthClientID: utils.Ptr(client.ID),
+	})
+	if err != nil {
+		log.Printf("Failed to create session, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	writeOAuthJSON(w, http.StatusOK, tokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(bh.cfg.OAuth.AccessTokenTTL.Seconds()),
+		Scope:       claims.Scope,
+	})
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/oidc"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestBaseHandler_OAuthGrants тестирует выдачу токенов OAuth клиентам
+func TestBaseHandler_OAuthGrants(t *testing.T) {
+	secretKey := testSecretKey(t)
+
+	client := &models.OAuthClient{
+		ID:               5,
+		ClientID:         "oc_test",
+		Name:             "Reports",
+		SecretHash:       utils.HashToken("secret"),
+		RedirectURIs:     []string{"https://app.example.com/callback"},
+		Scopes:           []string{"reports.read", "reports.write"},
+		ServiceAccountID: utils.Ptr(7),
+	}
+
+	userCtx := context.WithValue(context.Background(), auth.UserIDKey, 2)
+	userCtx = context.WithValue(userCtx, auth.IsAdminKey, false)
+	userCtx = context.WithValue(userCtx, auth.PermissionsKey, []string{"reports.read"})
+
+	// Test 1: Клиент получает токен сервисного аккаунта в пределах своего scope
+	t.Run("ClientCredentials", func(t *testing.T) {
+		// Создаем моки
+		mockUserRepo := new(mocks.MockUserRepository)
+		mockOAuthRepo := new(mocks.MockOAuthRepository)
+		mockSessionRepo := new(mocks.MockSessionRepository)
+
+		// Настройка ожиданий
+		mockOAuthRepo.On("GetOAuthClient", mock.Anything, "oc_test").Return(client, nil)
+		mockUserRepo.On("GetUserByID", mock.Anything, 7).Return(&models.User{
+			ID:     7,
+			Name:   "reports-bot",
+			Kind:   models.UserKindService,
+			Status: models.UserStatusActive,
+		}, nil)
+		mockUserRepo.On("GetUserPermissions", mock.Anything, 7).Return([]*models.Permission{
+			{Code: "reports.read"}, {Code: "users.read"},
+		}, nil)
+		mockSessionRepo.On("CreateSession", mock.Anything, mock.MatchedBy(func(session *models.Session) bool {
+			return session.UserID == 7 && session.OAuthClientID != nil && *session.OAuthClientID == 5
+		})).Return(nil)
+
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{
+			userRepo:    mockUserRepo,
+			oauthRepo:   mockOAuthRepo,
+			sessionRepo: mockSessionRepo,
+			secretKey:   secretKey,
+			jwtFunc:     utils.GenerateJWT,
+			cfg:         DefaultConfig(),
+		}
+
+		// Вызываем endpoint
+		r := newFormRequest(context.Background(), "/oauth/token", url.Values{"grant_type": {grantTypeClientCredentials}})
+		r.SetBasicAuth("oc_test", "secret")
+		w := httptest.NewRecorder()
+		baseHandler.Token(w, r)
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusOK, w.Code)
+		var out tokenResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		assert.Equal(t, "reports.read", out.Scope)
+
+		issued, err := utils.ValidateJWT(out.AccessToken, secretKey)
+		assert.NoError(t, err)
+		assert.Equal(t, 7, issued.UserID)
+		assert.Equal(t, []string{"crud-ai"}, []string(issued.Audience))
+		assert.Equal(t, []string{"reports.read"}, issued.Permissions)
+
+		// Проверяем, что все ожидания выполнены
+		mockUserRepo.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Неверный секрет клиента
+	t.Run("ClientCredentialsInvalidSecret", func(t *testing.T) {
+		// Создаем моки
+		mockOAuthRepo := new(mocks.MockOAuthRepository)
+
+		// Настройка ожиданий
+		mockOAuthRepo.On("GetOAuthClient", mock.Anything, "oc_test").Return(client, nil)
+
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{oauthRepo: mockOAuthRepo, cfg: DefaultConfig()}
+
+		// Вызываем endpoint
+		w := httptest.NewRecorder()
+		baseHandler.Token(w, newFormRequest(context.Background(), "/oauth/token", url.Values{
+			"grant_type":    {grantTypeClientCredentials},
+			"client_id":     {"oc_test"},
+			"client_secret": {"wrong"},
+		}))
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_client")
+	})
+
+	// Test 3: Без согласия пользователя authorize возвращает сведения для экрана согласия
+	t.Run("AuthorizeConsentRequired", func(t *testing.T) {
+		// Создаем моки
+		mockOAuthRepo := new(mocks.MockOAuthRepository)
+
+		// Настройка ожиданий
+		mockOAuthRepo.On("GetOAuthClient", mock.Anything, "oc_test").Return(client, nil)
+		mockOAuthRepo.On("GetOAuthConsent", mock.Anything, 2, 5).Return((*models.OAuthConsent)(nil), nil)
+
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{oauthRepo: mockOAuthRepo, cfg: DefaultConfig()}
+
+		// Вызываем endpoint
+		query := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {"oc_test"},
+			"scope":                 {"reports.read"},
+			"code_challenge":        {oidc.CodeChallenge("verifier")},
+			"code_challenge_method": {"S256"},
+		}
+		w := httptest.NewRecorder()
+		baseHandler.Authorize(w, httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+query.Encode(), nil).WithContext(userCtx))
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusOK, w.Code)
+		var out consentResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		assert.True(t, out.ConsentRequired)
+		assert.Equal(t, []string{"reports.read"}, out.Scopes)
+		assert.Equal(t, "https://app.example.com/callback", out.RedirectURI)
+	})
+
+	// Test 4: Code выдается после согласия и обменивается на токен только с верным code_verifier
+	t.Run("AuthorizationCodeWithPKCE", func(t *testing.T) {
+		// Создаем моки
+		mockUserRepo := new(mocks.MockUserRepository)
+		mockOAuthRepo := new(mocks.MockOAuthRepository)
+		mockTokenRepo := new(mocks.MockTokenRepository)
+		mockSessionRepo := new(mocks.MockSessionRepository)
+		mockAuditRepo := new(mocks.MockAuditRepository)
+
+		// Настройка ожиданий
+		var issued *models.OneTimeToken
+		mockOAuthRepo.On("GetOAuthClient", mock.Anything, "oc_test").Return(client, nil)
+		mockOAuthRepo.On("SaveOAuthConsent", mock.Anything, mock.Anything).Return(nil)
+		mockAuditRepo.On("AddAuditEntry", mock.Anything, mock.Anything).Return(nil)
+		mockTokenRepo.On("CreateToken", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			issued = args.Get(1).(*models.OneTimeToken)
+		}).Return(nil)
+		mockUserRepo.On("GetUserByID", mock.Anything, 2).Return(&models.User{
+			ID:     2,
+			Email:  "user@example.com",
+			Status: models.UserStatusActive,
+		}, nil)
+		mockUserRepo.On("GetUserPermissions", mock.Anything, 2).Return([]*models.Permission{{Code: "reports.read"}}, nil)
+		mockSessionRepo.On("CreateSession", mock.Anything, mock.Anything).Return(nil)
+
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{
+			userRepo:    mockUserRepo,
+			oauthRepo:   mockOAuthRepo,
+			tokenRepo:   mockTokenRepo,
+			sessionRepo: mockSessionRepo,
+			auditRepo:   mockAuditRepo,
+			secretKey:   secretKey,
+			jwtFunc:     utils.GenerateJWT,
+			cfg:         DefaultConfig(),
+		}
+
+		// Пользователь соглашается на доступ
+		w := httptest.NewRecorder()
+		baseHandler.Authorize(w, newFormRequest(userCtx, "/oauth/authorize", url.Values{
+			"response_type":         {"code"},
+			"client_id":             {"oc_test"},
+			"redirect_uri":          {"https://app.example.com/callback"},
+			"scope":                 {"reports.read"},
+			"state":                 {"xyz"},
+			"code_challenge":        {oidc.CodeChallenge("verifier")},
+			"code_challenge_method": {"S256"},
+			"consent":               {"approve"},
+		}))
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		location, err := url.Parse(w.Header().Get("Location"))
+		assert.NoError(t, err)
+		assert.Equal(t, "app.example.com", location.Host)
+		assert.Equal(t, "xyz", location.Query().Get("state"))
+		code := location.Query().Get("code")
+		assert.NotEmpty(t, code)
+		assert.Equal(t, models.TokenPurposeOAuthCode, issued.Purpose)
+		assert.Equal(t, utils.HashToken(code), issued.TokenHash)
+
+		exchange := func(verifier string) *httptest.ResponseRecorder {
+			r := newFormRequest(context.Background(), "/oauth/token", url.Values{
+				"grant_type":    {grantTypeAuthorizationCode},
+				"code":          {code},
+				"redirect_uri":  {"https://app.example.com/callback"},
+				"code_verifier": {verifier},
+			})
+			r.SetBasicAuth("oc_test", "secret")
+			w := httptest.NewRecorder()
+			baseHandler.Token(w, r)
+			return w
+		}
+
+		// Неверный code_verifier
+		mockTokenRepo.On("ConsumeToken", mock.Anything, models.TokenPurposeOAuthCode, issued.TokenHash).
+			Return(issued, nil).Once()
+		w = exchange("wrong")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_grant")
+
+		// Верный code_verifier
+		mockTokenRepo.On("ConsumeToken", mock.Anything, models.TokenPurposeOAuthCode, issued.TokenHash).
+			Return(issued, nil).Once()
+		w = exchange("verifier")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var out tokenResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		assert.Equal(t, "reports.read", out.Scope)
+
+		token, err := utils.ValidateJWT(out.AccessToken, secretKey)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, token.UserID)
+		assert.Equal(t, "reports.read", token.Scope)
+
+		// Проверяем, что все ожидания выполнены
+		mockOAuthRepo.AssertExpectations(t)
+		mockTokenRepo.AssertExpectations(t)
+	})
+}
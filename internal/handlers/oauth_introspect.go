@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+)
+
+// IntrospectPermission право, которое позволяет проверять чужие токены через /oauth/introspect
+const IntrospectPermission = "token.introspect"
+
+// introspectionResponse ответ endpoint'а проверки токена (RFC 7662)
+type introspectionResponse struct {
+	Active         bool   `json:"active"`
+	Sub            string `json:"sub,omitempty"`
+	Username       string `json:"username,omitempty"`
+	TokenType      string `json:"token_type,omitempty"`
+	Scope          string `json:"scope,omitempty"`
+	Exp            int64  `json:"exp,omitempty"`
+	Iat            int64  `json:"iat,omitempty"`
+	Jti            string `json:"jti,omitempty"`
+	OrganizationID *int   `json:"organization_id,omitempty"`
+	// Act администратор, действующий от имени пользователя (RFC 8693, раздел 4.1)
+	Act *introspectionActor `json:"act,omitempty"`
+	// Cnf ключ клиента, к которому привязан токен (RFC 9449, раздел 6.2)
+	Cnf *utils.Confirmation `json:"cnf,omitempty"`
+}
+
+// introspectionActor фактический исполнитель запросов с токеном
+type introspectionActor struct {
+	Sub string `json:"sub"`
+}
+
+// Introspect обрабатывает POST /oauth/introspect (RFC 7662).
+// Вызывающий клиент аутентифицируется middleware и должен иметь право token.introspect.
+// Ответ учитывает отзыв сессий и ключей, статус пользователя и его текущие права
+func (bh *BaseHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if !parseOAuthForm(w, r) {
+		return
+	}
+
+	ctx := r.Context()
+
+	// Проверяем, что клиент аутентифицирован и может проверять токены
+	if _, ok := ctx.Value(auth.UserIDKey).(int); !ok {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication required")
+		return
+	}
+
+	if !auth.HasPermission(ctx, IntrospectPermission) {
+		writeOAuthError(w, http.StatusForbidden, "insufficient_scope", "Permission "+IntrospectPermission+" required")
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Parameter token is required")
+		return
+	}
+
+	// Недействительный токен не раскрывает причину
+	identity, err := bh.verifier.VerifyToken(ctx, token)
+	if err != nil {
+		writeOAuthJSON(w, http.StatusOK, introspectionResponse{Active: false})
+		return
+	}
+
+	out := introspectionResponse{
+		Active:    true,
+		Sub:       strconv.Itoa(identity.UserID),
+		TokenType: "access_token",
+		Scope:     strings.Join(identity.Permissions, " "),
+	}
+	if identity.ExpiresAt != nil {
+		out.Exp = identity.ExpiresAt.Unix()
+	}
+	if identity.State != nil {
+		out.OrganizationID = identity.State.OrganizationID
+	}
+	if identity.APIKeyID != 0 {
+		out.TokenType = "api_key"
+	}
+	if claims := identity.Claims; claims != nil {
+		out.Username = claims.Email
+		out.Jti = claims.ID
+		if claims.IssuedAt != nil {
+			out.Iat = claims.IssuedAt.Unix()
+		}
+		if claims.Act != nil {
+			out.Act = &introspectionActor{Sub: strconv.Itoa(claims.Act.UserID)}
+		}
+		out.Cnf = claims.Cnf
+	}
+
+	writeOAuthJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testSecretKey генерирует RSA ключ в PEM, которым подписываются токены в тестах
+func testSecretKey(t *testing.T) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+}
+
+// newFormRequest создает form-encoded POST запрос с контекстом авторизованного клиента
+func newFormRequest(ctx context.Context, target string, form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r.WithContext(ctx)
+}
+
+// TestBaseHandler_Introspect тестирует endpoint проверки токенов
+func TestBaseHandler_Introspect(t *testing.T) {
+	secretKey := testSecretKey(t)
+
+	clientCtx := context.WithValue(context.Background(), auth.UserIDKey, 10)
+	clientCtx = context.WithValue(clientCtx, auth.IsAdminKey, false)
+	clientCtx = context.WithValue(clientCtx, auth.PermissionsKey, []string{IntrospectPermission})
+
+	claims := &utils.Claims{
+		UserID:       2,
+		Email:        "user@example.com",
+		Permissions:  []string{"reports.read"},
+		GrantVersion: 3,
+	}
+	claims.ID = "s1"
+	token, err := utils.GenerateJWT(secretKey, claims)
+	assert.NoError(t, err)
+
+	// Test 1: Для действующего токена возвращаются пользователь, права и организация
+	t.Run("IntrospectActiveToken", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		sessionRepo := new(mocks.MockSessionRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserAuthState", mock.Anything, 2).Return(&models.UserAuthState{
+			Status:         models.UserStatusActive,
+			GrantVersion:   3,
+			OrganizationID: utils.Ptr(7),
+		}, nil)
+		userRepo.On("GetUserPermissions", mock.Anything, 2).Return([]*models.Permission{
+			{ID: 1, Code: "reports.read"},
+		}, nil)
+		sessionRepo.On("GetSession", mock.Anything, "s1").Return(&models.Session{ID: "s1", UserID: 2}, nil)
+		sessionRepo.On("TouchSession", mock.Anything, "s1").Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{verifier: auth.NewVerifier(secretKey, "crud-ai", userRepo, sessionRepo, nil, nil)}
+
+		// Вызываем endpoint
+		w := httptest.NewRecorder()
+		baseHandler.Introspect(w, newFormRequest(clientCtx, "/oauth/introspect", url.Values{"token": {token}}))
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusOK, w.Code)
+		var out introspectionResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		assert.True(t, out.Active)
+		assert.Equal(t, "2", out.Sub)
+		assert.Equal(t, "reports.read", out.Scope)
+		assert.Equal(t, 7, *out.OrganizationID)
+		assert.Equal(t, "s1", out.Jti)
+		assert.InDelta(t, time.Now().Add(utils.JWTTTL).Unix(), out.Exp, 60)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		sessionRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Токен отозванной сессии неактивен, хотя подпись и срок действия корректны
+	t.Run("IntrospectRevokedSession", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		sessionRepo := new(mocks.MockSessionRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserAuthState", mock.Anything, 2).Return(&models.UserAuthState{
+			Status:       models.UserStatusActive,
+			GrantVersion: 3,
+		}, nil)
+		userRepo.On("GetUserPermissions", mock.Anything, 2).Return([]*models.Permission{
+			{ID: 1, Code: "reports.read"},
+		}, nil)
+		sessionRepo.On("GetSession", mock.Anything, "s1").Return(&models.Session{
+			ID:        "s1",
+			UserID:    2,
+			RevokedAt: utils.Ptr(time.Now()),
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{verifier: auth.NewVerifier(secretKey, "crud-ai", userRepo, sessionRepo, nil, nil)}
+
+		// Вызываем endpoint
+		w := httptest.NewRecorder()
+		baseHandler.Introspect(w, newFormRequest(clientCtx, "/oauth/introspect", url.Values{"token": {token}}))
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"active":false}`, w.Body.String())
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		sessionRepo.AssertExpectations(t)
+	})
+
+	// Test 3: Права пользователя перечитываются, если версия прав изменилась после выпуска токена
+	t.Run("IntrospectStaleGrants", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserAuthState", mock.Anything, 2).Return(&models.UserAuthState{
+			Status:       models.UserStatusActive,
+			GrantVersion: 4,
+		}, nil)
+		userRepo.On("GetUserPermissions", mock.Anything, 2).Return([]*models.Permission{
+			{ID: 2, Code: "reports.write"},
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{verifier: auth.NewVerifier(secretKey, "crud-ai", userRepo, nil, nil, nil)}
+
+		// Вызываем endpoint
+		w := httptest.NewRecorder()
+		baseHandler.Introspect(w, newFormRequest(clientCtx, "/oauth/introspect", url.Values{"token": {token}}))
+
+		// Проверяем результат
+		var out introspectionResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		assert.True(t, out.Active)
+		assert.Equal(t, "reports.write", out.Scope)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+	})
+
+	// Test 4: Выдача, срок которой истек после выпуска токена, не действует, хотя версия прав не изменилась
+	t.Run("IntrospectExpiredGrant", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserAuthState", mock.Anything, 2).Return(&models.UserAuthState{
+			Status:       models.UserStatusActive,
+			GrantVersion: 3,
+		}, nil)
+		userRepo.On("GetUserPermissions", mock.Anything, 2).Return([]*models.Permission{}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{verifier: auth.NewVerifier(secretKey, "crud-ai", userRepo, nil, nil, nil)}
+
+		// Вызываем endpoint
+		w := httptest.NewRecorder()
+		baseHandler.Introspect(w, newFormRequest(clientCtx, "/oauth/introspect", url.Values{"token": {token}}))
+
+		// Проверяем результат
+		var out introspectionResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		assert.True(t, out.Active)
+		assert.Empty(t, out.Scope)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+	})
+
+	// Test 5: Токен с более новой версией прав, чем в кеше, перечитывает состояние и права из источника
+	t.Run("IntrospectNewerGrantVersion", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserAuthState", mock.Anything, 2).Return(&models.UserAuthState{
+			Status:       models.UserStatusActive,
+			GrantVersion: 2,
+		}, nil).Once()
+		userRepo.On("GetUserPermissions", mock.Anything, 2).Return([]*models.Permission{}, nil).Once()
+		userRepo.On("GetUserAuthState", mock.Anything, 2).Return(&models.UserAuthState{
+			Status:       models.UserStatusActive,
+			GrantVersion: 3,
+		}, nil).Once()
+		userRepo.On("GetUserPermissions", mock.Anything, 2).Return([]*models.Permission{
+			{ID: 1, Code: "reports.read"},
+		}, nil).Once()
+
+		// Создаем базовый обработчик с моком, состояние берется через кеш
+		cache := auth.NewStateCache(userRepo, nil, time.Minute)
+		baseHandler := &BaseHandler{verifier: auth.NewVerifier(secretKey, "crud-ai", cache, nil, nil, nil)}
+
+		// Кеш заполнен до выдачи права, токен выпущен после нее
+		_, err := cache.GetUserAuthState(context.Background(), 2)
+		assert.NoError(t, err)
+		_, err = cache.GetUserPermissions(context.Background(), 2)
+		assert.NoError(t, err)
+
+		// Вызываем endpoint
+		w := httptest.NewRecorder()
+		baseHandler.Introspect(w, newFormRequest(clientCtx, "/oauth/introspect", url.Values{"token": {token}}))
+
+		// Проверяем результат
+		var out introspectionResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		assert.True(t, out.Active)
+		assert.Equal(t, "reports.read", out.Scope)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+	})
+
+	// Test 6: Персональный токен участника организации не действует дольше времени жизни сессии организации
+	t.Run("IntrospectPersonalTokenSessionTTL", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		apiKeyRepo := new(mocks.MockAPIKeyRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+
+		// Определяем ожидаемое поведение мока
+		createdAt := time.Now().Add(-2 * time.Hour)
+		apiKeyRepo.On("GetAPIKeyByHash", mock.Anything, utils.HashToken(auth.PersonalTokenPrefix+"old")).Return(&models.APIKey{
+			ID:        5,
+			UserID:    2,
+			ExpiresAt: utils.Ptr(time.Now().Add(24 * time.Hour)),
+			CreatedAt: createdAt,
+		}, nil)
+		userRepo.On("GetUserAuthState", mock.Anything, 2).Return(&models.UserAuthState{
+			Status:         models.UserStatusActive,
+			OrganizationID: utils.Ptr(7),
+		}, nil)
+		orgRepo.On("GetOrganizationSettings", mock.Anything, 7).Return(&models.OrganizationSettings{
+			OrganizationID: 7,
+			MaxSessionTTL:  time.Hour,
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{verifier: auth.NewVerifier(secretKey, "crud-ai", userRepo, nil, apiKeyRepo, orgRepo)}
+
+		// Вызываем endpoint
+		w := httptest.NewRecorder()
+		form := url.Values{"token": {auth.PersonalTokenPrefix + "old"}}
+		baseHandler.Introspect(w, newFormRequest(clientCtx, "/oauth/introspect", form))
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"active":false}`, w.Body.String())
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		apiKeyRepo.AssertExpectations(t)
+		orgRepo.AssertExpectations(t)
+	})
+
+	// Test 7: Клиент без права token.introspect не может проверять токены
+	t.Run("IntrospectForbidden", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 11)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{}
+
+		// Вызываем endpoint
+		w := httptest.NewRecorder()
+		baseHandler.Introspect(w, newFormRequest(ctx, "/oauth/introspect", url.Values{"token": {token}}))
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	// Test 8: Без аутентификации клиента endpoint недоступен
+	t.Run("IntrospectUnauthenticated", func(t *testing.T) {
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{}
+
+		// Вызываем endpoint
+		w := httptest.NewRecorder()
+		baseHandler.Introspect(w, newFormRequest(context.Background(), "/oauth/introspect", url.Values{"token": {token}}))
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+	})
+}
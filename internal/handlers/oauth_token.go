@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// grantTypeClientCredentials токен клиента от имени его сервисного аккаунта (RFC 6749, раздел 4.4)
+	grantTypeClientCredentials = "client_credentials"
+	// grantTypeAuthorizationCode обмен authorization code на токен (RFC 6749, раздел 4.1)
+	grantTypeAuthorizationCode = "authorization_code"
+	// grantTypeTokenExchange обмен токена (RFC 8693)
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	// tokenTypeAccessToken тип токена доступа в обмене токенов
+	tokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	// tokenTypeJWT тип JWT токена в обмене токенов
+	tokenTypeJWT = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// tokenResponse успешный ответ /oauth/token
+type tokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+	Scope           string `json:"scope,omitempty"`
+}
+
+// Token обрабатывает POST /oauth/token и выбирает обработчик по grant_type
+func (bh *BaseHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if !parseOAuthForm(w, r) {
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case grantTypeClientCredentials:
+		bh.clientCredentials(w, r)
+	case grantTypeAuthorizationCode:
+		bh.authorizationCode(w, r)
+	case grantTypeTokenExchange:
+		bh.exchangeToken(w, r)
+	case "":
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Parameter grant_type is required")
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+	}
+}
+
+// exchangeToken обменивает токен доступа на короткоживущий токен для сервиса audience (RFC 8693).
+// Новый токен содержит только пересечение запрошенных прав с текущими правами пользователя
+// и привязан к той же сессии, поэтому отзыв сессии отзывает и полученные обменом токены
+func (bh *BaseHandler) exchangeToken(w http.ResponseWriter, r *http.Request) {
+	form := r.PostForm
+	subjectToken := form.Get("subject_token")
+	audience := form.Get("audience")
+	if subjectToken == "" || audience == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Parameters subject_token and audience are required")
+		return
+	}
+
+	if tokenType := form.Get("subject_token_type"); tokenType != tokenTypeAccessToken && tokenType != tokenTypeJWT {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Unsupported subject_token_type")
+		return
+	}
+
+	if tokenType := form.Get("requested_token_type"); tokenType != "" && tokenType != tokenTypeAccessToken {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Unsupported requested_token_type")
+		return
+	}
+
+	identity, err := bh.verifier.VerifyToken(r.Context(), subjectToken)
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Subject token is not active")
+		return
+	}
+
+	// API ключи и персональные токены не привязаны к сессии и не обмениваются
+	if identity.Claims == nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "Only access tokens can be exchanged")
+		return
+	}
+
+	// Запрошенные права должны быть у пользователя, без scope передаются все текущие права
+	scope := identity.Permissions
+	if requested := strings.Fields(form.Get("scope")); len(requested) > 0 {
+		for _, code := range requested {
+			if !containsCode(identity.Permissions, code) {
+				writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "Permission "+code+" is not granted")
+				return
+			}
+		}
+		scope = requested
+	}
+
+	if len(scope) == 0 {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "No permissions to delegate")
+		return
+	}
+
+	// Полученный обменом токен не переживает исходный
+	expiresAt := time.Now().Add(bh.cfg.OAuth.ExchangeTTL)
+	if identity.ExpiresAt != nil && identity.ExpiresAt.Before(expiresAt) {
+		expiresAt = *identity.ExpiresAt
+	}
+
+	subject := identity.Claims
+	claims := &utils.Claims{
+		UserID:       subject.UserID,
+		Email:        subject.Email,
+		Name:         subject.Name,
+		Permissions:  scope,
+		GrantVersion: subject.GrantVersion,
+		Act:          subject.Act,
+		Scope:        strings.Join(scope, " "),
+		Limited:      subject.Limited,
+		// Токен, полученный обменом привязанного токена, привязан к тому же ключу клиента
+		Cnf: subject.Cnf,
+	}
+	if identity.State != nil {
+		claims.GrantVersion = identity.State.GrantVersion
+	}
+	claims.ID = subject.ID
+	claims.Audience = jwt.ClaimStrings{audience}
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	token, err := bh.jwtFunc(bh.secretKey, claims)
+	if err != nil {
+		log.Printf("Failed to generate JWT token, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	tokenType := tokenTypeBearer
+	if claims.Cnf != nil {
+		tokenType = tokenTypeDPoP
+	}
+
+	writeOAuthJSON(w, http.StatusOK, tokenResponse{
+		AccessToken:     token,
+		IssuedTokenType: tokenTypeAccessToken,
+		TokenType:       tokenType,
+		ExpiresIn:       int64(time.Until(expiresAt).Seconds()),
+		Scope:           claims.Scope,
+	})
+}
+
+// containsCode проверяет, есть ли код права в списке
+func containsCode(codes []string, code string) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestBaseHandler_TokenExchange тестирует обмен токена на токен для другого сервиса
+func TestBaseHandler_TokenExchange(t *testing.T) {
+	secretKey := testSecretKey(t)
+
+	claims := &utils.Claims{
+		UserID:       2,
+		Email:        "user@example.com",
+		Permissions:  []string{"reports.read", "reports.write"},
+		GrantVersion: 3,
+	}
+	claims.ID = "s1"
+	subjectToken, err := utils.GenerateJWT(secretKey, claims)
+	assert.NoError(t, err)
+
+	exchange := func(baseHandler *BaseHandler, scope string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		baseHandler.Token(w, newFormRequest(context.Background(), "/oauth/token", url.Values{
+			"grant_type":         {grantTypeTokenExchange},
+			"subject_token":      {subjectToken},
+			"subject_token_type": {tokenTypeAccessToken},
+			"audience":           {"billing"},
+			"scope":              {scope},
+		}))
+		return w
+	}
+
+	// Test 1: Новый токен привязан к audience, сессии исходного токена и содержит только запрошенные права
+	t.Run("ExchangeToken", func(t *testing.T) {
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{
+			secretKey: secretKey,
+			jwtFunc:   utils.GenerateJWT,
+			cfg:       DefaultConfig(),
+			verifier:  auth.NewVerifier(secretKey, "crud-ai", nil, nil, nil, nil),
+		}
+
+		// Вызываем endpoint
+		w := exchange(baseHandler, "reports.read")
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusOK, w.Code)
+		var out tokenResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		assert.Equal(t, tokenTypeAccessToken, out.IssuedTokenType)
+		assert.Equal(t, "reports.read", out.Scope)
+		assert.LessOrEqual(t, out.ExpiresIn, int64(5*time.Minute/time.Second))
+
+		issued, err := utils.ValidateJWT(out.AccessToken, secretKey)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"billing"}, []string(issued.Audience))
+		assert.Equal(t, []string{"reports.read"}, issued.Permissions)
+		assert.Equal(t, "s1", issued.ID)
+	})
+
+	// Test 2: Нельзя запросить права, которых нет в исходном токене
+	t.Run("ExchangeTokenScopeNotGranted", func(t *testing.T) {
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{
+			secretKey: secretKey,
+			jwtFunc:   utils.GenerateJWT,
+			cfg:       DefaultConfig(),
+			verifier:  auth.NewVerifier(secretKey, "crud-ai", nil, nil, nil, nil),
+		}
+
+		// Вызываем endpoint
+		w := exchange(baseHandler, "reports.read admin")
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"error":"invalid_scope"`)
+	})
+
+	// Test 3: Токен принимается только сервисом из audience и не расширяется при изменении прав пользователя
+	t.Run("ExchangedTokenAudience", func(t *testing.T) {
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{
+			secretKey: secretKey,
+			jwtFunc:   utils.GenerateJWT,
+			cfg:       DefaultConfig(),
+			verifier:  auth.NewVerifier(secretKey, "crud-ai", nil, nil, nil, nil),
+		}
+
+		// Вызываем endpoint
+		w := exchange(baseHandler, "reports.read")
+		var out tokenResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+
+		// Создаем мок репозиторий, права пользователя изменились после обмена
+		userRepo := new(mocks.MockUserRepository)
+		userRepo.On("GetUserAuthState", mock.Anything, 2).Return(&models.UserAuthState{
+			Status:       models.UserStatusActive,
+			GrantVersion: 4,
+		}, nil)
+		userRepo.On("GetUserPermissions", mock.Anything, 2).Return([]*models.Permission{
+			{ID: 1, Code: "reports.read"},
+			{ID: 3, Code: "admin"},
+		}, nil)
+
+		// Проверяем результат
+		_, err := auth.NewVerifier(secretKey, "crud-ai", userRepo, nil, nil, nil).VerifyToken(context.Background(), out.AccessToken)
+		assert.ErrorIs(t, err, auth.ErrInvalidAudience)
+
+		identity, err := auth.NewVerifier(secretKey, "billing", userRepo, nil, nil, nil).VerifyToken(context.Background(), out.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"reports.read"}, identity.Permissions)
+		assert.False(t, identity.IsAdmin())
+	})
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/oidc"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// OIDCLogin обрабатывает GET /oidc/{organization_id}/login и перенаправляет пользователя
+// на страницу входа провайдера OpenID Connect организации (authorization code + PKCE).
+// Параметр dpop_key привязывает выданный после входа токен к ключу клиента,
+// а session_cookie=true передает его в cookie браузерной сессии
+func (bh *BaseHandler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	provider, ok := bh.oidcProviderFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	dpopJKT, err := dpopThumbprint(r.URL.Query().Get("dpop_key"))
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Invalid DPoP key")
+		return
+	}
+
+	sessionCookie := r.URL.Query().Get("session_cookie") == "true"
+	if sessionCookie && dpopJKT != "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "DPoP key can not be used with session cookie")
+		return
+	}
+
+	discovery, err := bh.oidcClient.Discover(ctx, provider.Issuer)
+	if err != nil {
+		log.Printf("Failed to discover oidc provider %s, err:%v\n", provider.Issuer, err)
+		writeOAuthError(w, http.StatusBadGateway, "temporarily_unavailable", "Identity provider is unavailable")
+		return
+	}
+
+	// state защищает от подделки ответа, nonce - от повторного использования ID токена,
+	// code_verifier - от перехвата authorization code
+	var state, nonce, codeVerifier string
+	for _, value := range []*string{&state, &nonce, &codeVerifier} {
+		if *value, err = utils.GenerateToken(); err != nil {
+			log.Printf("Failed to generate oidc state, err:%v\n", err)
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+	}
+
+	err = bh.oidcRepo.CreateOIDCState(ctx, &models.OIDCLoginState{
+		StateHash:      utils.HashToken(state),
+		OrganizationID: provider.OrganizationID,
+		Nonce:          nonce,
+		CodeVerifier:   codeVerifier,
+		DPoPJKT:        dpopJKT,
+		SessionCookie:  sessionCookie,
+		ExpiresAt:      time.Now().Add(bh.cfg.OIDC.StateTTL),
+	})
+	if err != nil {
+		log.Printf("Failed to create oidc state, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	scopes := provider.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultOIDCScopes
+	}
+	if !containsCode(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	redirectURI := bh.oidcRedirectURI(provider.OrganizationID)
+	http.Redirect(w, r, discovery.AuthCodeURL(provider.ClientID, redirectURI, scopes, state, nonce, codeVerifier), http.StatusFound)
+}
+
+// OIDCCallback обрабатывает GET /oidc/{organization_id}/callback, на который провайдер возвращает пользователя.
+// Authorization code обменивается на ID токен, пользователь находится по связи с провайдером,
+// по подтвержденному email или создается. Ответ совпадает с ответом Login
+func (bh *BaseHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	// Пользователь отказался от входа или провайдер вернул ошибку
+	if errorCode := query.Get("error"); errorCode != "" {
+		log.Printf("OIDC login failed at provider: %s %s\n", errorCode, query.Get("error_description"))
+		writeOAuthError(w, http.StatusUnauthorized, "access_denied", "Identity provider returned "+errorCode)
+		return
+	}
+
+	code := query.Get("code")
+	stateParam := query.Get("state")
+	if code == "" || stateParam == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Parameters code and state are required")
+		return
+	}
+
+	provider, ok := bh.oidcProviderFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// Состояние используется один раз и только для организации, с которой начался вход
+	state, err := bh.oidcRepo.ConsumeOIDCState(ctx, utils.HashToken(stateParam))
+	if err != nil {
+		log.Printf("Failed to consume oidc state, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if state == nil || state.OrganizationID != provider.OrganizationID {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Invalid or expired state")
+		return
+	}
+
+	discovery, err := bh.oidcClient.Discover(ctx, provider.Issuer)
+	if err != nil {
+		log.Printf("Failed to discover oidc provider %s, err:%v\n", provider.Issuer, err)
+		writeOAuthError(w, http.StatusBadGateway, "temporarily_unavailable", "Identity provider is unavailable")
+		return
+	}
+
+	claims, err := bh.oidcClient.Exchange(
+		ctx, discovery, provider.ClientID, provider.ClientSecret,
+		bh.oidcRedirectURI(provider.OrganizationID), code, state.CodeVerifier, state.Nonce,
+	)
+	if err != nil {
+		log.Printf("Failed to complete oidc login for organization %d, err:%v\n", provider.OrganizationID, err)
+		writeOAuthError(w, http.StatusUnauthorized, "access_denied", "Identity provider rejected the login")
+		return
+	}
+
+	subject := oidc.StringClaim(claims, "sub")
+	if subject == "" {
+		writeOAuthError(w, http.StatusUnauthorized, "access_denied", "ID token has no subject")
+		return
+	}
+
+	user, err := bh.resolveOIDCUser(ctx, provider, &models.OIDCIdentity{
+		ProviderID: provider.ID,
+		Subject:    subject,
+		Email:      oidc.StringClaim(claims, provider.EmailClaim),
+	}, oidc.StringClaim(claims, provider.NameClaim), oidc.EmailVerified(claims))
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+
+	bh.recordLoginAttempt(ctx, user.Email, auth.ClientIP(ctx), &user.ID, true)
+
+	out, err := bh.completeLogin(ctx, user, models.LoginMethodSSO, state.DPoPJKT)
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+
+	if state.SessionCookie && out.Token != "" {
+		for _, cookie := range bh.sessionCookies(out.Token) {
+			http.SetCookie(w, cookie)
+		}
+		out.CsrfToken = utils.CSRFToken(bh.secretKey, out.Token)
+		out.Token = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	marshaler := jsonpb.Marshaler{OrigName: true, EmitDefaults: true}
+	if err := marshaler.Marshal(w, out); err != nil {
+		log.Printf("Failed to write oidc login response, err:%v\n", err)
+	}
+}
+
+// resolveOIDCUser находит пользователя, вошедшего через провайдера.
+// Учетная запись провайдера связывается с существующим пользователем организации только по email,
+// подтвержденному провайдером. Если пользователь не найден, он создается при включенном auto_provision
+func (bh *BaseHandler) resolveOIDCUser(
+	ctx context.Context,
+	provider *models.OIDCProvider,
+	identity *models.OIDCIdentity,
+	name string,
+	emailVerified bool,
+) (*models.User, error) {
+	linked, err := bh.oidcRepo.GetOIDCIdentity(ctx, provider.ID, identity.Subject)
+	if err != nil {
+		log.Printf("Failed to get oidc identity, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	if linked != nil {
+		user, err := bh.userRepo.GetUserByID(ctx, linked.UserID)
+		if err != nil {
+			log.Printf("Failed to get user by id, err:%v\n", err)
+			return nil, status.Error(codes.Internal, "Authentication failed")
+		}
+
+		if err := bh.oidcRepo.TouchOIDCIdentity(ctx, linked.ID, identity.Email); err != nil {
+			log.Printf("Failed to touch oidc identity, err:%v\n", err)
+		}
+
+		return user, nil
+	}
+
+	if identity.Email == "" {
+		return nil, status.Error(codes.PermissionDenied, "ID token has no email")
+	}
+
+	user, err := bh.userRepo.GetUserByEmail(ctx, identity.Email)
+	if err != nil {
+		log.Printf("Failed to get user by email, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	action := "user.oidc_link"
+	switch {
+	case user != nil:
+		// Без подтверждения email провайдером можно было бы войти в чужую учетную запись
+		if !emailVerified {
+			return nil, status.Error(codes.PermissionDenied, "Email is not verified by identity provider")
+		}
+
+		if user.Kind == models.UserKindService ||
+			user.Organization == nil || user.Organization.ID != provider.OrganizationID {
+			log.Printf("OIDC login rejected for user %d outside organization %d\n", user.ID, provider.OrganizationID)
+			return nil, status.Error(codes.PermissionDenied, "User does not belong to organization")
+		}
+	case provider.AutoProvision:
+		if name == "" {
+			name = identity.Email
+		}
+
+		user, err = bh.userRepo.CreateUser(ctx, &models.User{
+			Name:          name,
+			Email:         identity.Email,
+			Organization:  &models.Organization{ID: provider.OrganizationID},
+			Status:        models.UserStatusActive,
+			EmailVerified: emailVerified,
+		})
+		if err != nil {
+			log.Printf("Failed to create user, err:%v\n", err)
+			return nil, status.Error(codes.Internal, "Authentication failed")
+		}
+		action = "user.oidc_provision"
+	default:
+		return nil, status.Error(codes.PermissionDenied, "User is not registered")
+	}
+
+	identity.UserID = user.ID
+	if err := bh.oidcRepo.CreateOIDCIdentity(ctx, identity); err != nil {
+		log.Printf("Failed to create oidc identity, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		UserID:  utils.Ptr(user.ID),
+		Action:  action,
+		Details: fmt.Sprintf("organization %d: issuer=%s, subject=%s", provider.OrganizationID, provider.Issuer, identity.Subject),
+	})
+
+	return user, nil
+}
+
+// oidcProviderFromPath получает включенного провайдера организации из пути запроса
+func (bh *BaseHandler) oidcProviderFromPath(w http.ResponseWriter, r *http.Request) (*models.OIDCProvider, bool) {
+	orgID, err := strconv.Atoi(r.PathValue("organization_id"))
+	if err != nil || orgID <= 0 {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Invalid organization")
+		return nil, false
+	}
+
+	provider, err := bh.oidcRepo.GetOIDCProvider(r.Context(), orgID)
+	if err != nil {
+		log.Printf("Failed to get oidc provider, err:%v\n", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "")
+		return nil, false
+	}
+
+	if provider == nil || !provider.Enabled {
+		writeOAuthError(w, http.StatusNotFound, "invalid_request", "OpenID Connect is not configured for organization")
+		return nil, false
+	}
+
+	return provider, true
+}
+
+// writeStatusError отправляет gRPC ошибку обработчика как ошибку OAuth endpoint'а
+func writeStatusError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	errorCode := "access_denied"
+	if st.Code() == codes.Internal {
+		errorCode = "server_error"
+	}
+
+	writeOAuthError(w, runtime.HTTPStatusFromCode(st.Code()), errorCode, st.Message())
+}
@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/oidc"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockOIDCProvider локальный провайдер OpenID Connect для тестов входа
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// claims дополнительные claims выпускаемого ID токена
+	claims jwt.MapClaims
+	// nonce и challenge запомнены из запроса на страницу входа
+	nonce     string
+	challenge string
+}
+
+// newMockOIDCProvider запускает провайдера, который выдает ID токен на код "code-1"
+func newMockOIDCProvider(t *testing.T, claims jwt.MapClaims) *mockOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	p := &mockOIDCProvider{key: key, claims: claims}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, _ := r.BasicAuth()
+		if clientID != "client-1" || clientSecret != "secret-1" || r.PostFormValue("code") != "code-1" ||
+			oidc.CodeChallenge(r.PostFormValue("code_verifier")) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		idClaims := jwt.MapClaims{
+			"iss":   p.server.URL,
+			"aud":   "client-1",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": p.nonce,
+		}
+		for name, value := range p.claims {
+			idClaims[name] = value
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idClaims)
+		token.Header["kid"] = "k1"
+		idToken, err := token.SignedString(key)
+		assert.NoError(t, err)
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": idToken})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+// TestBaseHandler_OIDCLogin тестирует вход через провайдера OpenID Connect
+func TestBaseHandler_OIDCLogin(t *testing.T) {
+	// newProvider настройки провайдера организации 1
+	newProvider := func(p *mockOIDCProvider, autoProvision bool) *models.OIDCProvider {
+		return &models.OIDCProvider{
+			ID:             3,
+			OrganizationID: 1,
+			Issuer:         p.server.URL,
+			ClientID:       "client-1",
+			ClientSecret:   "secret-1",
+			EmailClaim:     "email",
+			NameClaim:      "name",
+			AutoProvision:  autoProvision,
+			Enabled:        true,
+		}
+	}
+
+	// startLogin начинает вход и возвращает адрес возврата от провайдера с кодом и state
+	startLogin := func(t *testing.T, baseHandler *BaseHandler, oidcRepo *mocks.MockOIDCRepository, p *mockOIDCProvider) string {
+		var state *models.OIDCLoginState
+		oidcRepo.On("CreateOIDCState", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			state = args.Get(1).(*models.OIDCLoginState)
+		}).Return(nil)
+
+		r := httptest.NewRequest(http.MethodGet, "/oidc/1/login", nil)
+		r.SetPathValue("organization_id", "1")
+		w := httptest.NewRecorder()
+		baseHandler.OIDCLogin(w, r)
+
+		assert.Equal(t, http.StatusFound, w.Code)
+		location, err := url.Parse(w.Header().Get("Location"))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(location.String(), p.server.URL+"/authorize?"))
+
+		query := location.Query()
+		assert.Equal(t, "client-1", query.Get("client_id"))
+		assert.Equal(t, "http://localhost:8080/oidc/1/callback", query.Get("redirect_uri"))
+		assert.Equal(t, "S256", query.Get("code_challenge_method"))
+		assert.Equal(t, oidc.CodeChallenge(state.CodeVerifier), query.Get("code_challenge"))
+		assert.Equal(t, utils.HashToken(query.Get("state")), state.StateHash)
+		p.nonce = query.Get("nonce")
+		p.challenge = query.Get("code_challenge")
+
+		oidcRepo.On("ConsumeOIDCState", mock.Anything, state.StateHash).Return(state, nil)
+
+		return "/oidc/1/callback?code=code-1&state=" + url.QueryEscape(query.Get("state"))
+	}
+
+	// callback возвращает пользователя от провайдера
+	callback := func(baseHandler *BaseHandler, target string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.SetPathValue("organization_id", "1")
+		w := httptest.NewRecorder()
+		baseHandler.OIDCCallback(w, r)
+		return w
+	}
+
+	// Test 1: Новый пользователь создается в организации и получает обычный токен
+	t.Run("OIDCLoginProvisionsUser", func(t *testing.T) {
+		p := newMockOIDCProvider(t, jwt.MapClaims{
+			"sub":            "idp-42",
+			"email":          "new@example.com",
+			"email_verified": true,
+			"name":           "New User",
+		})
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		oidcRepo := new(mocks.MockOIDCRepository)
+
+		// Определяем ожидаемое поведение мока
+		oidcRepo.On("GetOIDCProvider", mock.Anything, 1).Return(newProvider(p, true), nil)
+		oidcRepo.On("GetOIDCIdentity", mock.Anything, 3, "idp-42").Return((*models.OIDCIdentity)(nil), nil)
+		userRepo.On("GetUserByEmail", mock.Anything, "new@example.com").Return((*models.User)(nil), nil)
+		userRepo.On("CreateUser", mock.Anything, mock.MatchedBy(func(user *models.User) bool {
+			return user.Name == "New User" && user.Organization.ID == 1 && user.EmailVerified
+		})).Return(&models.User{
+			ID:            5,
+			Name:          "New User",
+			Email:         "new@example.com",
+			Organization:  &models.Organization{ID: 1},
+			Status:        models.UserStatusActive,
+			EmailVerified: true,
+		}, nil)
+		oidcRepo.On("CreateOIDCIdentity", mock.Anything, mock.MatchedBy(func(identity *models.OIDCIdentity) bool {
+			return identity.ProviderID == 3 && identity.UserID == 5 && identity.Subject == "idp-42"
+		})).Return(nil)
+		userRepo.On("GetUserPermissions", mock.Anything, 5).Return([]*models.Permission{}, nil)
+		orgRepo.On("GetOrganizationByID", mock.Anything, 1).Return(&models.Organization{ID: 1, Name: "Acme"}, nil)
+		orgRepo.On("GetOrganizationSettings", mock.Anything, 1).Return(&models.OrganizationSettings{OrganizationID: 1}, nil)
+
+		var tokenClaims *utils.Claims
+		f := func(s string, claims *utils.Claims) (string, error) {
+			tokenClaims = claims
+			return "token", nil
+		}
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			orgRepo:    orgRepo,
+			oidcRepo:   oidcRepo,
+			jwtFunc:    f,
+			cfg:        DefaultConfig(),
+			oidcClient: oidc.NewClient(5 * time.Second),
+		}
+
+		// Вызываем endpoint'ы
+		w := callback(baseHandler, startLogin(t, baseHandler, oidcRepo, p))
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusOK, w.Code)
+		var out struct {
+			Token string `json:"token"`
+			User  struct {
+				ID int `json:"id"`
+			} `json:"user"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		assert.Equal(t, "token", out.Token)
+		assert.Equal(t, 5, out.User.ID)
+		assert.Equal(t, 5, tokenClaims.UserID)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		oidcRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Существующий пользователь организации связывается по подтвержденному email
+	t.Run("OIDCLoginLinksVerifiedEmail", func(t *testing.T) {
+		p := newMockOIDCProvider(t, jwt.MapClaims{
+			"sub":            "idp-7",
+			"email":          "user@example.com",
+			"email_verified": "true",
+		})
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		oidcRepo := new(mocks.MockOIDCRepository)
+
+		// Определяем ожидаемое поведение мока
+		oidcRepo.On("GetOIDCProvider", mock.Anything, 1).Return(newProvider(p, false), nil)
+		oidcRepo.On("GetOIDCIdentity", mock.Anything, 3, "idp-7").Return((*models.OIDCIdentity)(nil), nil)
+		userRepo.On("GetUserByEmail", mock.Anything, "user@example.com").Return(&models.User{
+			ID:            2,
+			Email:         "user@example.com",
+			Organization:  &models.Organization{ID: 1},
+			Status:        models.UserStatusActive,
+			EmailVerified: true,
+		}, nil)
+		oidcRepo.On("CreateOIDCIdentity", mock.Anything, mock.MatchedBy(func(identity *models.OIDCIdentity) bool {
+			return identity.UserID == 2 && identity.Subject == "idp-7"
+		})).Return(nil)
+		userRepo.On("GetUserPermissions", mock.Anything, 2).Return([]*models.Permission{
+			{ID: 1, Code: "reports.read"},
+		}, nil)
+		orgRepo.On("GetOrganizationByID", mock.Anything, 1).Return(&models.Organization{ID: 1, Name: "Acme"}, nil)
+		orgRepo.On("GetOrganizationSettings", mock.Anything, 1).Return(&models.OrganizationSettings{OrganizationID: 1}, nil)
+
+		var tokenClaims *utils.Claims
+		f := func(s string, claims *utils.Claims) (string, error) {
+			tokenClaims = claims
+			return "token", nil
+		}
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			orgRepo:    orgRepo,
+			oidcRepo:   oidcRepo,
+			jwtFunc:    f,
+			cfg:        DefaultConfig(),
+			oidcClient: oidc.NewClient(5 * time.Second),
+		}
+
+		// Вызываем endpoint'ы
+		w := callback(baseHandler, startLogin(t, baseHandler, oidcRepo, p))
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 2, tokenClaims.UserID)
+		assert.Equal(t, []string{"reports.read"}, tokenClaims.Permissions)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		oidcRepo.AssertExpectations(t)
+	})
+
+	// Test 3: Без подтверждения email провайдером учетные записи не связываются
+	t.Run("OIDCLoginRejectsUnverifiedEmail", func(t *testing.T) {
+		p := newMockOIDCProvider(t, jwt.MapClaims{
+			"sub":   "idp-8",
+			"email": "user@example.com",
+		})
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		oidcRepo := new(mocks.MockOIDCRepository)
+
+		// Определяем ожидаемое поведение мока
+		oidcRepo.On("GetOIDCProvider", mock.Anything, 1).Return(newProvider(p, true), nil)
+		oidcRepo.On("GetOIDCIdentity", mock.Anything, 3, "idp-8").Return((*models.OIDCIdentity)(nil), nil)
+		userRepo.On("GetUserByEmail", mock.Anything, "user@example.com").Return(&models.User{
+			ID:           2,
+			Email:        "user@example.com",
+			Organization: &models.Organization{ID: 1},
+			Status:       models.UserStatusActive,
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			oidcRepo:   oidcRepo,
+			cfg:        DefaultConfig(),
+			oidcClient: oidc.NewClient(5 * time.Second),
+		}
+
+		// Вызываем endpoint'ы
+		w := callback(baseHandler, startLogin(t, baseHandler, oidcRepo, p))
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		oidcRepo.AssertNotCalled(t, "CreateOIDCIdentity", mock.Anything, mock.Anything)
+	})
+
+	// Test 4: ID токен, выпущенный для другого входа, не принимается
+	t.Run("OIDCCallbackNonceMismatch", func(t *testing.T) {
+		p := newMockOIDCProvider(t, jwt.MapClaims{
+			"sub":            "idp-42",
+			"email":          "new@example.com",
+			"email_verified": true,
+		})
+
+		// Создаем мок репозиторий
+		oidcRepo := new(mocks.MockOIDCRepository)
+
+		// Определяем ожидаемое поведение мока
+		oidcRepo.On("GetOIDCProvider", mock.Anything, 1).Return(newProvider(p, true), nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			oidcRepo:   oidcRepo,
+			cfg:        DefaultConfig(),
+			oidcClient: oidc.NewClient(5 * time.Second),
+		}
+
+		// Вызываем endpoint'ы
+		target := startLogin(t, baseHandler, oidcRepo, p)
+		p.nonce = "replayed"
+		w := callback(baseHandler, target)
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		oidcRepo.AssertNotCalled(t, "GetOIDCIdentity", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	// Test 5: Неизвестный или использованный state отклоняется без обращения к провайдеру
+	t.Run("OIDCCallbackInvalidState", func(t *testing.T) {
+		// Создаем мок репозиторий
+		oidcRepo := new(mocks.MockOIDCRepository)
+
+		// Определяем ожидаемое поведение мока
+		oidcRepo.On("GetOIDCProvider", mock.Anything, 1).Return(&models.OIDCProvider{
+			ID:             3,
+			OrganizationID: 1,
+			Issuer:         "http://127.0.0.1:1",
+			Enabled:        true,
+		}, nil)
+		oidcRepo.On("ConsumeOIDCState", mock.Anything, utils.HashToken("forged")).Return((*models.OIDCLoginState)(nil), nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{oidcRepo: oidcRepo, cfg: DefaultConfig()}
+
+		// Вызываем endpoint
+		w := callback(baseHandler, "/oidc/1/callback?code=code-1&state=forged")
+
+		// Проверяем результат
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		oidcRepo.AssertExpectations(t)
+	})
+}
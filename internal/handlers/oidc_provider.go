@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultOIDCEmailClaim claim ID токена с email пользователя по умолчанию
+	defaultOIDCEmailClaim = "email"
+	// defaultOIDCNameClaim claim ID токена с именем пользователя по умолчанию
+	defaultOIDCNameClaim = "name"
+)
+
+// defaultOIDCScopes scope, которые запрашиваются у провайдера, если они не заданы в настройках
+var defaultOIDCScopes = []string{"openid", "email", "profile"}
+
+// GetOidcProvider получает настройки провайдера OpenID Connect организации
+func (bh *BaseHandler) GetOidcProvider(ctx context.Context, in *api_pb.Id) (out *api_pb.OidcProvider, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Настройки доступны только администратору
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	provider, err := bh.oidcRepo.GetOIDCProvider(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to get oidc provider, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get OpenID Connect provider")
+	}
+
+	if provider == nil {
+		return nil, status.Error(codes.NotFound, "OpenID Connect provider not found")
+	}
+
+	return bh.oidcProviderToPb(provider), nil
+}
+
+// UpdateOidcProvider создает или обновляет провайдера OpenID Connect организации.
+// Пустой client_secret сохраняет ранее заданный секрет
+func (bh *BaseHandler) UpdateOidcProvider(ctx context.Context, in *api_pb.OidcProvider) (out *api_pb.OidcProvider, err error) {
+	// Проверяем входные данные
+	if in == nil || in.OrganizationId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Менять настройки может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	if !validOIDCIssuer(in.Issuer) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid issuer")
+	}
+
+	if in.ClientId == "" {
+		return nil, status.Error(codes.InvalidArgument, "Client ID is required")
+	}
+
+	// Проверяем, существует ли организация
+	if _, err := bh.orgRepo.GetOrganizationByID(ctx, int(in.OrganizationId)); err != nil {
+		return nil, status.Error(codes.NotFound, "Organization not found")
+	}
+
+	// Секрет обязателен при первой настройке провайдера
+	if in.ClientSecret == "" {
+		existing, err := bh.oidcRepo.GetOIDCProvider(ctx, int(in.OrganizationId))
+		if err != nil {
+			log.Printf("Failed to get oidc provider, err:%v\n", err)
+			return nil, status.Error(codes.Internal, "Failed to update OpenID Connect provider")
+		}
+
+		if existing == nil {
+			return nil, status.Error(codes.InvalidArgument, "Client secret is required")
+		}
+	}
+
+	provider := &models.OIDCProvider{
+		OrganizationID: int(in.OrganizationId),
+		Issuer:         strings.TrimSuffix(in.Issuer, "/"),
+		ClientID:       in.ClientId,
+		ClientSecret:   in.ClientSecret,
+		Scopes:         in.Scopes,
+		EmailClaim:     in.EmailClaim,
+		NameClaim:      in.NameClaim,
+		AutoProvision:  in.AutoProvision,
+		Enabled:        in.Enabled,
+	}
+	if provider.EmailClaim == "" {
+		provider.EmailClaim = defaultOIDCEmailClaim
+	}
+	if provider.NameClaim == "" {
+		provider.NameClaim = defaultOIDCNameClaim
+	}
+
+	if err := bh.oidcRepo.SaveOIDCProvider(ctx, provider); err != nil {
+		log.Printf("Failed to save oidc provider, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to update OpenID Connect provider")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		Action:  "organization.oidc_update",
+		Details: fmt.Sprintf(
+			"organization %d: issuer=%s, auto_provision=%t, enabled=%t",
+			provider.OrganizationID, provider.Issuer, provider.AutoProvision, provider.Enabled,
+		),
+	})
+
+	return bh.oidcProviderToPb(provider), nil
+}
+
+// DeleteOidcProvider удаляет провайдера OpenID Connect организации.
+// Связи пользователей с учетными записями провайдера удаляются вместе с ним
+func (bh *BaseHandler) DeleteOidcProvider(ctx context.Context, in *api_pb.Id) (out *api_pb.Empty, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Менять настройки может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	ok, err := bh.oidcRepo.DeleteOIDCProvider(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to delete oidc provider, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to delete OpenID Connect provider")
+	}
+
+	if !ok {
+		return nil, status.Error(codes.NotFound, "OpenID Connect provider not found")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		Action:  "organization.oidc_delete",
+		Details: fmt.Sprintf("organization %d", in.Id),
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// oidcRedirectURI адрес, на который провайдер возвращает пользователя после входа.
+// Этот адрес нужно зарегистрировать у провайдера
+func (bh *BaseHandler) oidcRedirectURI(orgID int) string {
+	return strings.TrimSuffix(bh.cfg.OIDC.BaseURL, "/") + "/oidc/" + strconv.Itoa(orgID) + "/callback"
+}
+
+// oidcProviderToPb конвертирует провайдера OpenID Connect в ответ API без секрета клиента
+func (bh *BaseHandler) oidcProviderToPb(provider *models.OIDCProvider) *api_pb.OidcProvider {
+	return &api_pb.OidcProvider{
+		OrganizationId: int32(provider.OrganizationID),
+		Issuer:         provider.Issuer,
+		ClientId:       provider.ClientID,
+		Scopes:         provider.Scopes,
+		EmailClaim:     provider.EmailClaim,
+		NameClaim:      provider.NameClaim,
+		AutoProvision:  provider.AutoProvision,
+		Enabled:        provider.Enabled,
+		RedirectUri:    bh.oidcRedirectURI(provider.OrganizationID),
+	}
+}
+
+// validOIDCIssuer проверяет адрес провайдера
+func validOIDCIssuer(issuer string) bool {
+	u, err := url.Parse(issuer)
+	if err != nil || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return false
+	}
+
+	return isSecureURL(u)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+)
+
+// issueToken выпускает одноразовый токен и сохраняет его хеш.
+// Ранее выпущенные токены пользователя с тем же назначением перестают действовать
+func (bh *BaseHandler) issueToken(
+	ctx context.Context,
+	userID int,
+	purpose models.TokenPurpose,
+	payload string,
+	ttl time.Duration,
+) (string, error) {
+	if err := bh.tokenRepo.DeleteUserTokens(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = bh.tokenRepo.CreateToken(ctx, &models.OneTimeToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		Payload:   payload,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"log"
 
+	"github.com/LiFeAiR/crud-ai/internal/models"
 	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -19,12 +21,24 @@ func (bh *BaseHandler) AddOrganizationPermissions(
 		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
 	}
 
+	// Проверяем срок действия выдачи
+	validity, err := parseGrantValidity(in.ValidFrom, in.ValidUntil)
+	if err != nil {
+		return nil, err
+	}
+
 	// Добавляем права к организации
-	if err := bh.orgRepo.AddOrganizationPermissions(ctx, int(in.Id), convertInt32SliceToInt(in.PermissionIds)); err != nil {
+	if err := bh.orgRepo.AddOrganizationPermissions(ctx, int(in.Id), convertInt32SliceToInt(in.PermissionIds), validity); err != nil {
 		log.Printf("add organization permissions failed, err:%v\n", err)
 		return nil, status.Error(codes.Internal, "Failed to add organization permissions")
 	}
 
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		Action:  "organization.permissions_add",
+		Details: fmt.Sprintf("organization %d: permissions %v%s", in.Id, in.PermissionIds, grantValidityDetails(validity)),
+	})
+
 	// Получаем обновленную организацию с правами
 	permissions, err := bh.orgRepo.GetOrganizationPermissions(ctx, int(in.Id))
 	if err != nil {
@@ -39,6 +53,8 @@ func (bh *BaseHandler) AddOrganizationPermissions(
 			Name:        permission.Name,
 			Code:        permission.Code,
 			Description: permission.Description,
+			ValidFrom:   formatGrantTime(permission.ValidFrom),
+			ValidUntil:  formatGrantTime(permission.ValidUntil),
 		})
 	}
 
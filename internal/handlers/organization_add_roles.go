@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"log"
 
+	"github.com/LiFeAiR/crud-ai/internal/models"
 	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -19,12 +21,37 @@ func (bh *BaseHandler) AddOrganizationRoles(
 		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
 	}
 
-	// Добавляем роли к организации
-	if err := bh.orgRepo.AddOrganizationRoles(ctx, int(in.Id), convertInt32SliceToInt(in.RoleIds)); err != nil {
-		log.Printf("add organization roles failed, err:%v\n", err)
-		return nil, status.Error(codes.Internal, "Failed to add organization roles")
+	// Проверяем срок действия выдачи
+	validity, err := parseGrantValidity(in.ValidFrom, in.ValidUntil)
+	if err != nil {
+		return nil, err
 	}
 
+	// Ограничения разделения обязанностей для участников организации проверяются в одной транзакции с выдачей
+	roleIDs := convertInt32SliceToInt(in.RoleIds)
+	err = bh.inTx(ctx, func(ctx context.Context) error {
+		if err := bh.checkSoD(ctx, models.GrantOwnerOrganization, int(in.Id), roleIDs); err != nil {
+			return err
+		}
+
+		// Добавляем роли к организации
+		if err := bh.orgRepo.AddOrganizationRoles(ctx, int(in.Id), roleIDs, validity); err != nil {
+			log.Printf("add organization roles failed, err:%v\n", err)
+			return status.Error(codes.Internal, "Failed to add organization roles")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		Action:  "organization.roles_add",
+		Details: fmt.Sprintf("organization %d: roles %v%s", in.Id, in.RoleIds, grantValidityDetails(validity)),
+	})
+
 	// Получаем обновленную организацию с ролями
 	roles, err := bh.orgRepo.GetOrganizationRoles(ctx, int(in.Id))
 	if err != nil {
@@ -39,6 +66,8 @@ func (bh *BaseHandler) AddOrganizationRoles(
 			Name:        role.Name,
 			Code:        role.Code,
 			Description: role.Description,
+			ValidFrom:   formatGrantTime(role.ValidFrom),
+			ValidUntil:  formatGrantTime(role.ValidUntil),
 		})
 	}
 
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetOrganizationAdmins получает администраторов организации
+func (bh *BaseHandler) GetOrganizationAdmins(ctx context.Context, in *api_pb.Id) (out *api_pb.OrganizationAdmins, err error) {
+	// Администраторов организации назначает только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	if _, err := bh.orgRepo.GetOrganizationByID(ctx, int(in.Id)); err != nil {
+		return nil, status.Error(codes.NotFound, "Organization not found")
+	}
+
+	userIDs, err := bh.orgRepo.GetOrganizationAdmins(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to get organization admins, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get organization admins")
+	}
+
+	return &api_pb.OrganizationAdmins{Id: in.Id, UserIds: convertIntSliceToInt32(userIDs)}, nil
+}
+
+// UpdateOrganizationAdmins заменяет список администраторов организации.
+// Администратором может быть только участник организации
+func (bh *BaseHandler) UpdateOrganizationAdmins(
+	ctx context.Context,
+	in *api_pb.OrganizationAdmins,
+) (out *api_pb.OrganizationAdmins, err error) {
+	// Администраторов организации назначает только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	if _, err := bh.orgRepo.GetOrganizationByID(ctx, int(in.Id)); err != nil {
+		return nil, status.Error(codes.NotFound, "Organization not found")
+	}
+
+	for _, userID := range in.UserIds {
+		// Репозиторий возвращает ошибку и для неизвестного пользователя
+		user, err := bh.userRepo.GetUserByID(ctx, int(userID))
+		if err != nil || user == nil {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Unknown user %d", userID))
+		}
+
+		if user.Organization == nil || user.Organization.ID != int(in.Id) {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("User %d is not a member of organization", userID))
+		}
+	}
+
+	if err := bh.orgRepo.SetOrganizationAdmins(ctx, int(in.Id), convertInt32SliceToInt(in.UserIds)); err != nil {
+		log.Printf("Failed to set organization admins, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to update organization admins")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		Action:  "organization.admins_update",
+		Details: fmt.Sprintf("organization %d: admins %v", in.Id, in.UserIds),
+	})
+
+	return bh.GetOrganizationAdmins(ctx, &api_pb.Id{Id: in.Id})
+}
@@ -39,6 +39,8 @@ func (bh *BaseHandler) DeleteOrganizationRoles(
 			Name:        role.Name,
 			Code:        role.Code,
 			Description: role.Description,
+			ValidFrom:   formatGrantTime(role.ValidFrom),
+			ValidUntil:  formatGrantTime(role.ValidUntil),
 		})
 	}
 
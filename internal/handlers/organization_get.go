@@ -37,6 +37,8 @@ func (bh *BaseHandler) GetOrganization(ctx context.Context, in *api_pb.Id) (out
 			Name:        permission.Name,
 			Code:        permission.Code,
 			Description: permission.Description,
+			ValidFrom:   formatGrantTime(permission.ValidFrom),
+			ValidUntil:  formatGrantTime(permission.ValidUntil),
 		})
 	}
 
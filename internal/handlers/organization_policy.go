@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// userOrganizationSettings возвращает настройки организации пользователя.
+// Для пользователей без организации возвращается nil
+func (bh *BaseHandler) userOrganizationSettings(ctx context.Context, user *models.User) (*models.OrganizationSettings, error) {
+	if user.Organization == nil {
+		return nil, nil
+	}
+
+	settings, err := bh.orgRepo.GetOrganizationSettings(ctx, user.Organization.ID)
+	if err != nil {
+		log.Printf("Failed to get organization settings, err:%v\n", err)
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// checkOrganizationLogin проверяет вход участника организации по ее настройкам безопасности:
+// способ входа, адрес клиента и привязку токена к ключу клиента
+func (bh *BaseHandler) checkOrganizationLogin(ctx context.Context, user *models.User, method, dpopJKT string) error {
+	settings, err := bh.userOrganizationSettings(ctx, user)
+	if err != nil {
+		return status.Error(codes.Internal, "Authentication failed")
+	}
+
+	if settings == nil {
+		return nil
+	}
+
+	if !settings.LoginMethodAllowed(method) {
+		log.Printf("Login rejected for user %d: method %s is not allowed\n", user.ID, method)
+		return status.Error(codes.PermissionDenied, "Login method is not allowed")
+	}
+
+	if !settings.IPAllowed(auth.ClientIP(ctx)) {
+		log.Printf("Login rejected for user %d: address %q is not allowed\n", user.ID, auth.ClientIP(ctx))
+		return status.Error(codes.PermissionDenied, "Login from this address is not allowed")
+	}
+
+	// Организация может требовать, чтобы токены ее участников были привязаны к ключу клиента
+	if settings.RequireDPoP && dpopJKT == "" {
+		return status.Error(codes.FailedPrecondition, "DPoP key is required")
+	}
+
+	return nil
+}
+
+// sessionTTL возвращает срок жизни сессии пользователя с учетом ограничения организации
+func (bh *BaseHandler) sessionTTL(ctx context.Context, user *models.User) (time.Duration, error) {
+	settings, err := bh.userOrganizationSettings(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	if settings != nil && settings.MaxSessionTTL > 0 && settings.MaxSessionTTL < utils.JWTTTL {
+		return settings.MaxSessionTTL, nil
+	}
+
+	return utils.JWTTTL, nil
+}
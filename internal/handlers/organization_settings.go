@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// checkOrganizationAdmin проверяет, что пользователь - администратор или назначенный администратор
+// организации orgID, остающийся ее участником
+func (bh *BaseHandler) checkOrganizationAdmin(ctx context.Context, orgID int) error {
+	if checkAdmin(ctx) == nil {
+		return nil
+	}
+
+	userID := currentUserID(ctx)
+	if userID == nil || orgID == 0 || auth.OrganizationID(ctx) != orgID {
+		return errors.New("checkOrganizationAdmin.PermissionDenied")
+	}
+
+	ok, err := bh.orgRepo.IsOrganizationAdmin(ctx, orgID, *userID)
+	if err != nil {
+		log.Printf("Failed to check organization admin, err:%v\n", err)
+		return err
+	}
+
+	if !ok {
+		return errors.New("checkOrganizationAdmin.PermissionDenied")
+	}
+
+	return nil
+}
+
+// GetOrganizationSettings получает настройки организации
+func (bh *BaseHandler) GetOrganizationSettings(ctx context.Context, in *api_pb.Id) (out *api_pb.OrganizationSettings, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Настройки доступны администратору и администратору организации
+	if err := bh.checkOrganizationAdmin(ctx, int(in.Id)); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем, существует ли организация
+	if _, err := bh.orgRepo.GetOrganizationByID(ctx, int(in.Id)); err != nil {
+		return nil, status.Error(codes.NotFound, "Organization not found")
+	}
+
+	settings, err := bh.orgRepo.GetOrganizationSettings(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to get organization settings, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get organization settings")
+	}
+
+	return organizationSettingsResponse(settings), nil
+}
+
+// UpdateOrganizationSettings обновляет настройки организации
+func (bh *BaseHandler) UpdateOrganizationSettings(
+	ctx context.Context,
+	in *api_pb.OrganizationSettings,
+) (out *api_pb.OrganizationSettings, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Менять настройки может администратор и администратор организации
+	if err := bh.checkOrganizationAdmin(ctx, int(in.Id)); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем, существует ли организация
+	if _, err := bh.orgRepo.GetOrganizationByID(ctx, int(in.Id)); err != nil {
+		return nil, status.Error(codes.NotFound, "Organization not found")
+	}
+
+	settings := &models.OrganizationSettings{
+		OrganizationID:   int(in.Id),
+		Require2FA:       in.Require_2Fa,
+		MagicLinkEnabled: in.MagicLinkEnabled,
+		RequireDPoP:      in.RequireDpop,
+		MaxSessionTTL:    time.Duration(in.MaxSessionTtlSeconds) * time.Second,
+	}
+
+	// Разрешенные диапазоны адресов хранятся в каноническом виде
+	for _, cidr := range in.AllowedCidrs {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "Invalid CIDR: "+cidr)
+		}
+		settings.AllowedCIDRs = append(settings.AllowedCIDRs, network.String())
+	}
+
+	for _, method := range in.AllowedLoginMethods {
+		if !models.IsValidLoginMethod(method) {
+			return nil, status.Error(codes.InvalidArgument, "Invalid login method: "+method)
+		}
+		if !containsCode(settings.AllowedLoginMethods, method) {
+			settings.AllowedLoginMethods = append(settings.AllowedLoginMethods, method)
+		}
+	}
+
+	if in.MaxSessionTtlSeconds < 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid session lifetime")
+	}
+
+	// Администратор организации не может закрыть доступ с адреса, с которого меняет настройки
+	if auth.OrganizationID(ctx) == settings.OrganizationID && !settings.IPAllowed(auth.ClientIP(ctx)) {
+		return nil, status.Error(codes.FailedPrecondition, "Current address is not in allowed CIDRs")
+	}
+
+	// Собственная политика паролей, без нее используется глобальная
+	if p := in.PasswordPolicy; p != nil {
+		settings.PasswordPolicy = &models.PasswordPolicy{
+			MinLength:          int(p.MinLength),
+			MaxBytes:           int(p.MaxBytes),
+			RequireUpper:       p.RequireUpper,
+			RequireLower:       p.RequireLower,
+			RequireDigit:       p.RequireDigit,
+			RequireSymbol:      p.RequireSymbol,
+			ForbidPersonalInfo: p.ForbidPersonalInfo,
+		}
+	}
+
+	if !bh.validatePasswordPolicy(settings.PasswordPolicy) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid password policy")
+	}
+
+	if err := bh.orgRepo.UpdateOrganizationSettings(ctx, settings); err != nil {
+		log.Printf("Failed to update organization settings, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to update organization settings")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		Action:  "organization.settings_update",
+		Details: fmt.Sprintf(
+			"organization %d: require_2fa=%t, password_policy=%t, magic_link_enabled=%t, require_dpop=%t, "+
+				"allowed_cidrs=%v, max_session_ttl=%s, allowed_login_methods=%v",
+			settings.OrganizationID, settings.Require2FA, settings.PasswordPolicy != nil, settings.MagicLinkEnabled,
+			settings.RequireDPoP, settings.AllowedCIDRs, settings.MaxSessionTTL, settings.AllowedLoginMethods,
+		),
+	})
+
+	return organizationSettingsResponse(settings), nil
+}
+
+// organizationSettingsResponse формирует ответ с настройками организации
+func organizationSettingsResponse(settings *models.OrganizationSettings) *api_pb.OrganizationSettings {
+	out := &api_pb.OrganizationSettings{
+		Id:                   int32(settings.OrganizationID),
+		Require_2Fa:          settings.Require2FA,
+		MagicLinkEnabled:     settings.MagicLinkEnabled,
+		RequireDpop:          settings.RequireDPoP,
+		AllowedCidrs:         settings.AllowedCIDRs,
+		MaxSessionTtlSeconds: int64(settings.MaxSessionTTL / time.Second),
+		AllowedLoginMethods:  settings.AllowedLoginMethods,
+	}
+
+	if p := settings.PasswordPolicy; p != nil {
+		out.PasswordPolicy = &api_pb.PasswordPolicy{
+			MinLength:          int32(p.MinLength),
+			MaxBytes:           int32(p.MaxBytes),
+			RequireUpper:       p.RequireUpper,
+			RequireLower:       p.RequireLower,
+			RequireDigit:       p.RequireDigit,
+			RequireSymbol:      p.RequireSymbol,
+			ForbidPersonalInfo: p.ForbidPersonalInfo,
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_UpdateOrganizationSettings тестирует метод UpdateOrganizationSettings базового обработчика
+func TestBaseHandler_UpdateOrganizationSettings(t *testing.T) {
+	// Test 1: Успешное обновление настроек администратором
+	t.Run("UpdateOrganizationSettingsSuccess", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.IsAdminKey, true)
+
+		// Создаем мок репозиторий
+		orgRepo := new(mocks.MockOrganizationRepository)
+
+		// Определяем ожидаемое поведение мока
+		settings := &models.OrganizationSettings{OrganizationID: 1, Require2FA: true}
+		orgRepo.On("GetOrganizationByID", ctx, 1).Return(&models.Organization{ID: 1}, nil)
+		orgRepo.On("UpdateOrganizationSettings", ctx, settings).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			orgRepo: orgRepo,
+		}
+
+		// Вызываем метод UpdateOrganizationSettings
+		result, err := baseHandler.UpdateOrganizationSettings(ctx, &grpc.OrganizationSettings{Id: 1, Require_2Fa: true})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.True(t, result.Require_2Fa)
+
+		// Проверяем, что мок был вызван правильно
+		orgRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Обычный пользователь не может менять настройки
+	t.Run("UpdateOrganizationSettingsNotAdmin", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.IsAdminKey, false)
+		baseHandler := &BaseHandler{}
+
+		result, err := baseHandler.UpdateOrganizationSettings(ctx, &grpc.OrganizationSettings{Id: 1, Require_2Fa: true})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = PermissionDenied desc = Permission denied", err.Error())
+	})
+
+	// Test 3: Администратор организации меняет настройки безопасности только своей организации
+	t.Run("UpdateOrganizationSettingsOrganizationAdmin", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 5)
+		ctx = context.WithValue(ctx, auth.OrganizationIDKey, 1)
+		ctx = context.WithValue(ctx, auth.ClientIPKey, "10.1.2.3")
+
+		// Создаем мок репозиторий
+		orgRepo := new(mocks.MockOrganizationRepository)
+
+		// Определяем ожидаемое поведение мока: диапазон адресов сохраняется в каноническом виде
+		settings := &models.OrganizationSettings{
+			OrganizationID:      1,
+			AllowedCIDRs:        []string{"10.0.0.0/8"},
+			MaxSessionTTL:       time.Hour,
+			AllowedLoginMethods: []string{models.LoginMethodSSO},
+		}
+		orgRepo.On("GetOrganizationByID", ctx, 1).Return(&models.Organization{ID: 1}, nil)
+		orgRepo.On("IsOrganizationAdmin", ctx, 1, 5).Return(true, nil)
+		orgRepo.On("UpdateOrganizationSettings", ctx, settings).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			orgRepo: orgRepo,
+		}
+
+		// Вызываем метод UpdateOrganizationSettings
+		result, err := baseHandler.UpdateOrganizationSettings(ctx, &grpc.OrganizationSettings{
+			Id:                   1,
+			AllowedCidrs:         []string{"10.1.2.3/8"},
+			MaxSessionTtlSeconds: 3600,
+			AllowedLoginMethods:  []string{models.LoginMethodSSO},
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.0/8"}, result.AllowedCidrs)
+
+		// Настройки другой организации недоступны
+		_, err = baseHandler.UpdateOrganizationSettings(ctx, &grpc.OrganizationSettings{Id: 2})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		// Проверяем, что мок был вызван правильно
+		orgRepo.AssertExpectations(t)
+	})
+
+	// Test 4: Администратор организации не может закрыть доступ с текущего адреса
+	t.Run("UpdateOrganizationSettingsLockout", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 5)
+		ctx = context.WithValue(ctx, auth.OrganizationIDKey, 1)
+		ctx = context.WithValue(ctx, auth.ClientIPKey, "192.168.1.10")
+
+		// Создаем мок репозиторий
+		orgRepo := new(mocks.MockOrganizationRepository)
+		orgRepo.On("IsOrganizationAdmin", ctx, 1, 5).Return(true, nil)
+		orgRepo.On("GetOrganizationByID", ctx, 1).Return(&models.Organization{ID: 1}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			orgRepo: orgRepo,
+		}
+
+		// Вызываем метод UpdateOrganizationSettings
+		result, err := baseHandler.UpdateOrganizationSettings(ctx, &grpc.OrganizationSettings{
+			Id:           1,
+			AllowedCidrs: []string{"10.0.0.0/8"},
+		})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		orgRepo.AssertNotCalled(t, "UpdateOrganizationSettings", ctx, mock.Anything)
+	})
+
+	// Test 5: Участник организации без назначения администратором не получает доступ к настройкам,
+	// даже имея право organization.admin
+	t.Run("UpdateOrganizationSettingsNotOrganizationAdmin", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 6)
+		ctx = context.WithValue(ctx, auth.OrganizationIDKey, 1)
+		ctx = context.WithValue(ctx, auth.PermissionsKey, []string{"organization.admin"})
+
+		// Создаем мок репозиторий
+		orgRepo := new(mocks.MockOrganizationRepository)
+
+		// Определяем ожидаемое поведение мока
+		orgRepo.On("IsOrganizationAdmin", ctx, 1, 6).Return(false, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			orgRepo: orgRepo,
+		}
+
+		// Вызываем метод UpdateOrganizationSettings
+		result, err := baseHandler.UpdateOrganizationSettings(ctx, &grpc.OrganizationSettings{Id: 1, Require_2Fa: true})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		orgRepo.AssertNotCalled(t, "UpdateOrganizationSettings", ctx, mock.Anything)
+
+		// Проверяем, что мок был вызван правильно
+		orgRepo.AssertExpectations(t)
+	})
+}
+
+// TestBaseHandler_OrganizationLoginPolicy тестирует ограничения входа участников организации
+func TestBaseHandler_OrganizationLoginPolicy(t *testing.T) {
+	user := &models.User{
+		ID:            1,
+		Email:         "test@example.com",
+		Status:        models.UserStatusActive,
+		EmailVerified: true,
+		Organization:  &models.Organization{ID: 3},
+	}
+
+	// newHandler создает обработчик для входа пользователя по паролю с настройками организации settings
+	newHandler := func(ctx context.Context, settings *models.OrganizationSettings) (*BaseHandler, *mocks.MockUserRepository) {
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		userRepo.On("GetUserByEmail", ctx, "test@example.com").Return(user, nil)
+		userRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+		userRepo.On("GetUserPermissions", ctx, 1).Return([]*models.Permission(nil), nil)
+		orgRepo.On("GetOrganizationByID", ctx, 3).Return(&models.Organization{ID: 3, Name: "Org"}, nil)
+		orgRepo.On("GetOrganizationSettings", ctx, 3).Return(settings, nil)
+
+		return &BaseHandler{userRepo: userRepo, orgRepo: orgRepo}, userRepo
+	}
+
+	// Test 1: Вход по паролю запрещен, если организация разрешает только SSO
+	t.Run("LoginMethodNotAllowed", func(t *testing.T) {
+		ctx := context.Background()
+		baseHandler, _ := newHandler(ctx, &models.OrganizationSettings{
+			OrganizationID:      3,
+			AllowedLoginMethods: []string{models.LoginMethodSSO},
+		})
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{Email: "test@example.com", Password: "password123"})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	// Test 2: Вход с адреса вне разрешенных диапазонов запрещен
+	t.Run("LoginAddressNotAllowed", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.ClientIPKey, "192.168.1.10")
+		baseHandler, _ := newHandler(ctx, &models.OrganizationSettings{
+			OrganizationID: 3,
+			AllowedCIDRs:   []string{"10.0.0.0/8"},
+		})
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{Email: "test@example.com", Password: "password123"})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	// Test 3: Срок токена ограничен временем жизни сессии организации
+	t.Run("LoginSessionTTL", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.ClientIPKey, "10.1.2.3")
+		baseHandler, userRepo := newHandler(ctx, &models.OrganizationSettings{
+			OrganizationID: 3,
+			AllowedCIDRs:   []string{"10.0.0.0/8"},
+			MaxSessionTTL:  time.Hour,
+		})
+
+		var tokenClaims *utils.Claims
+		baseHandler.jwtFunc = func(s string, claims *utils.Claims) (string, error) {
+			tokenClaims = claims
+			return "test-test-test", nil
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{Email: "test@example.com", Password: "password123"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, "test-test-test", result.Token)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), tokenClaims.ExpiresAt.Time, time.Minute)
+		userRepo.AssertExpectations(t)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 
+	"github.com/LiFeAiR/crud-ai/internal/models"
 	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -33,11 +34,26 @@ func (bh *BaseHandler) AddOrganizationTariff(ctx context.Context, in *api_pb.Org
 		return nil, status.Error(codes.NotFound, "Tariff not found")
 	}
 
-	// Устанавливаем тариф организации
-	err = bh.orgRepo.SetOrganizationTariff(ctx, int(in.Id), &in.TariffId)
+	// Роли тарифа не должны нарушать ограничения разделения обязанностей для участников организации,
+	// проверка выполняется в одной транзакции с изменением тарифа
+	err = bh.inTx(ctx, func(ctx context.Context) error {
+		err := bh.checkSoDMembership(
+			ctx, models.GrantOwnerOrganization, int(in.Id), models.GrantOwnerTariff, int(in.TariffId),
+		)
+		if err != nil {
+			return err
+		}
+
+		// Устанавливаем тариф организации
+		if err := bh.orgRepo.SetOrganizationTariff(ctx, int(in.Id), &in.TariffId); err != nil {
+			log.Printf("Failed to set organization tariff, err:%v\n", err)
+			return status.Error(codes.Internal, "Failed to set organization tariff")
+		}
+
+		return nil
+	})
 	if err != nil {
-		log.Printf("Failed to set organization tariff, err:%v\n", err)
-		return nil, status.Error(codes.Internal, "Failed to set organization tariff")
+		return nil, err
 	}
 
 	// Получаем тариф для ответа
@@ -82,11 +98,26 @@ func (bh *BaseHandler) UpdateOrganizationTariff(ctx context.Context, in *api_pb.
 		return nil, status.Error(codes.NotFound, "Tariff not found")
 	}
 
-	// Обновляем тариф организации
-	err = bh.orgRepo.SetOrganizationTariff(ctx, int(in.Id), &in.TariffId)
+	// Роли тарифа не должны нарушать ограничения разделения обязанностей для участников организации,
+	// проверка выполняется в одной транзакции с изменением тарифа
+	err = bh.inTx(ctx, func(ctx context.Context) error {
+		err := bh.checkSoDMembership(
+			ctx, models.GrantOwnerOrganization, int(in.Id), models.GrantOwnerTariff, int(in.TariffId),
+		)
+		if err != nil {
+			return err
+		}
+
+		// Обновляем тариф организации
+		if err := bh.orgRepo.SetOrganizationTariff(ctx, int(in.Id), &in.TariffId); err != nil {
+			log.Printf("Failed to update organization tariff, err:%v\n", err)
+			return status.Error(codes.Internal, "Failed to update organization tariff")
+		}
+
+		return nil
+	})
 	if err != nil {
-		log.Printf("Failed to update organization tariff, err:%v\n", err)
-		return nil, status.Error(codes.Internal, "Failed to update organization tariff")
+		return nil, err
 	}
 
 	// Получаем тариф для ответа
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/passwordpolicy"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// passwordPolicy возвращает политику паролей организации, если она задана, иначе глобальную
+func (bh *BaseHandler) passwordPolicy(ctx context.Context, orgID int) models.PasswordPolicy {
+	if orgID == 0 {
+		return bh.cfg.PasswordPolicy.Policy
+	}
+
+	settings, err := bh.orgRepo.GetOrganizationSettings(ctx, orgID)
+	if err != nil {
+		log.Printf("Failed to get organization settings, err:%v\n", err)
+		return bh.cfg.PasswordPolicy.Policy
+	}
+
+	if settings == nil || settings.PasswordPolicy == nil {
+		return bh.cfg.PasswordPolicy.Policy
+	}
+
+	// Политики, сохраненные до проверки границ, не ослабляют глобальную длину пароля
+	policy := *settings.PasswordPolicy
+	global := bh.cfg.PasswordPolicy.Policy
+	if policy.MaxBytes <= 0 || policy.MaxBytes > passwordpolicy.MaxBytes {
+		policy.MaxBytes = global.MaxBytes
+	}
+	if policy.MinLength < global.MinLength {
+		policy.MinLength = global.MinLength
+	}
+
+	return policy
+}
+
+// validatePassword проверяет пароль по политике паролей.
+// Каждое нарушенное правило возвращается отдельным нарушением поля field
+func (bh *BaseHandler) validatePassword(
+	ctx context.Context,
+	field, password string,
+	subject passwordpolicy.Subject,
+	orgID int,
+) error {
+	policy := bh.passwordPolicy(ctx, orgID)
+	violations := passwordpolicy.Check(policy, bh.cfg.PasswordPolicy.Breached, password, subject)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	badRequest := &errdetails.BadRequest{}
+	for _, v := range violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: v.Rule + ": " + v.Description,
+		})
+	}
+
+	st, err := status.New(codes.InvalidArgument, "Password does not meet policy").WithDetails(badRequest)
+	if err != nil {
+		log.Printf("Failed to attach password policy violations, err:%v\n", err)
+		return status.Error(codes.InvalidArgument, "Password does not meet policy")
+	}
+
+	return st.Err()
+}
+
+// validatePasswordPolicy проверяет, что политику паролей организации можно применить: длина ограничена
+// от 1 до 72 байт, а минимальная длина не меньше глобальной
+func (bh *BaseHandler) validatePasswordPolicy(policy *models.PasswordPolicy) bool {
+	if policy == nil {
+		return true
+	}
+
+	if policy.MaxBytes < 1 || policy.MaxBytes > passwordpolicy.MaxBytes {
+		return false
+	}
+
+	if policy.MinLength < bh.cfg.PasswordPolicy.Policy.MinLength {
+		return false
+	}
+
+	return policy.MinLength <= policy.MaxBytes
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/passwordpolicy"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_PasswordPolicy тестирует проверку паролей по политике
+func TestBaseHandler_PasswordPolicy(t *testing.T) {
+	ctx := context.Background()
+	subject := passwordpolicy.Subject{Email: "john.smith@example.com", Name: "John Smith"}
+
+	// Test 1: Каждое нарушенное правило возвращается отдельным нарушением поля
+	t.Run("PasswordPolicyViolations", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.PasswordPolicy.Breached = passwordpolicy.NewBreachedList("Smith")
+
+		baseHandler := &BaseHandler{cfg: cfg}
+
+		err := baseHandler.validatePassword(ctx, "password", "smith", subject, 0)
+
+		st, _ := status.FromError(err)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		assert.Equal(t, "Password does not meet policy", st.Message())
+		assert.Len(t, st.Details(), 1)
+
+		badRequest := st.Details()[0].(*errdetails.BadRequest)
+		var descriptions []string
+		for _, v := range badRequest.FieldViolations {
+			assert.Equal(t, "password", v.Field)
+			descriptions = append(descriptions, v.Description)
+		}
+		assert.Equal(t, []string{
+			"min_length: must be at least 8 characters long",
+			"require_digit: must contain a digit",
+			"contains_name: must not contain the name",
+			"breached: has appeared in a data breach",
+		}, descriptions)
+	})
+
+	// Test 2: Пароль, соответствующий политике, принимается
+	t.Run("PasswordPolicyValid", func(t *testing.T) {
+		baseHandler := &BaseHandler{cfg: DefaultConfig()}
+
+		err := baseHandler.validatePassword(ctx, "password", "correct horse 42", subject, 0)
+
+		assert.NoError(t, err)
+	})
+
+	// Test 3: Пароль не может содержать email и имя и превышать ограничение bcrypt
+	t.Run("PasswordPolicyEmailAndMaxBytes", func(t *testing.T) {
+		policy := DefaultConfig().PasswordPolicy.Policy
+
+		violations := passwordpolicy.Check(policy, nil, "x1john.smith@example.com"+strings.Repeat("a", 60), subject)
+
+		var rules []string
+		for _, v := range violations {
+			rules = append(rules, v.Rule)
+		}
+		assert.Equal(t, []string{passwordpolicy.RuleMaxLength, passwordpolicy.RuleEmail, passwordpolicy.RuleName}, rules)
+	})
+
+	// Test 4: Политика организации заменяет глобальную
+	t.Run("PasswordPolicyOrganizationOverride", func(t *testing.T) {
+		orgRepo := new(mocks.MockOrganizationRepository)
+		orgRepo.On("GetOrganizationSettings", ctx, 1).Return(&models.OrganizationSettings{
+			OrganizationID: 1,
+			PasswordPolicy: &models.PasswordPolicy{MinLength: 12, RequireSymbol: true},
+		}, nil)
+
+		baseHandler := &BaseHandler{orgRepo: orgRepo, cfg: DefaultConfig()}
+
+		// Проходит глобальную политику, но не политику организации
+		err := baseHandler.validatePassword(ctx, "password", "correct42", subject, 1)
+		assert.Error(t, err)
+
+		err = baseHandler.validatePassword(ctx, "password", "correct-horse", subject, 1)
+		assert.NoError(t, err)
+
+		orgRepo.AssertExpectations(t)
+	})
+
+	// Test 5: Политика организации с ограничением длины больше 72 байт отклоняется
+	t.Run("UpdateOrganizationSettingsInvalidPolicy", func(t *testing.T) {
+		adminCtx := context.WithValue(ctx, auth.IsAdminKey, true)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		orgRepo.On("GetOrganizationByID", adminCtx, 1).Return(&models.Organization{ID: 1}, nil)
+
+		baseHandler := &BaseHandler{orgRepo: orgRepo}
+
+		result, err := baseHandler.UpdateOrganizationSettings(adminCtx, &grpc.OrganizationSettings{
+			Id:             1,
+			PasswordPolicy: &grpc.PasswordPolicy{MinLength: 8, MaxBytes: 100},
+		})
+
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = InvalidArgument desc = Invalid password policy", err.Error())
+		orgRepo.AssertNotCalled(t, "UpdateOrganizationSettings", mock.Anything, mock.Anything)
+	})
+
+	// Test 6: Политика организации не может ослабить ограничения длины глобальной
+	t.Run("UpdateOrganizationSettingsWeakerPolicy", func(t *testing.T) {
+		adminCtx := context.WithValue(ctx, auth.IsAdminKey, true)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		orgRepo.On("GetOrganizationByID", adminCtx, 1).Return(&models.Organization{ID: 1}, nil)
+
+		baseHandler := &BaseHandler{orgRepo: orgRepo, cfg: DefaultConfig()}
+
+		// Без ограничения длины в байтах
+		result, err := baseHandler.UpdateOrganizationSettings(adminCtx, &grpc.OrganizationSettings{
+			Id:             1,
+			PasswordPolicy: &grpc.PasswordPolicy{MinLength: 8, MaxBytes: 0},
+		})
+		assert.Nil(t, result)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		// Минимальная длина меньше глобальной
+		result, err = baseHandler.UpdateOrganizationSettings(adminCtx, &grpc.OrganizationSettings{
+			Id:             1,
+			PasswordPolicy: &grpc.PasswordPolicy{MinLength: 0, MaxBytes: 72},
+		})
+		assert.Nil(t, result)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		orgRepo.AssertNotCalled(t, "UpdateOrganizationSettings", mock.Anything, mock.Anything)
+	})
+
+	// Test 7: Сохраненная ранее слабая политика организации дополняется глобальными ограничениями
+	t.Run("PasswordPolicyOrganizationStoredWeak", func(t *testing.T) {
+		orgRepo := new(mocks.MockOrganizationRepository)
+		orgRepo.On("GetOrganizationSettings", ctx, 1).Return(&models.OrganizationSettings{
+			OrganizationID: 1,
+			PasswordPolicy: &models.PasswordPolicy{MinLength: 0, MaxBytes: 0},
+		}, nil)
+
+		baseHandler := &BaseHandler{orgRepo: orgRepo, cfg: DefaultConfig()}
+
+		// Вызываем метод passwordPolicy
+		policy := baseHandler.passwordPolicy(ctx, 1)
+
+		// Проверяем результат
+		assert.Equal(t, 8, policy.MinLength)
+		assert.Equal(t, 72, policy.MaxBytes)
+		orgRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/passwordpolicy"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequestPasswordReset отправляет пользователю ссылку для сброса пароля.
+// Ответ не зависит от того, существует ли пользователь с таким email: поиск пользователя
+// и отправка выполняются в фоне, поэтому не различается и время ответа
+func (bh *BaseHandler) RequestPasswordReset(ctx context.Context, in *api_pb.PasswordResetRequest) (out *api_pb.Empty, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	email := in.Email
+	bh.runBackground(ctx, func(ctx context.Context) {
+		bh.requestPasswordReset(ctx, email)
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// requestPasswordReset отправляет ссылку для сброса пароля активному пользователю с email
+func (bh *BaseHandler) requestPasswordReset(ctx context.Context, email string) {
+	user, err := bh.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Printf("Failed to get user by email, err:%v\n", err)
+		return
+	}
+
+	// Неизвестный email, неактивный пользователь и сервисный аккаунт не раскрываются в ответе
+	if user == nil || user.Status != models.UserStatusActive || user.Kind == models.UserKindService {
+		return
+	}
+
+	if err := bh.sendPasswordReset(ctx, user); err != nil {
+		log.Printf("Failed to send password reset, err:%v\n", err)
+		return
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		UserID: utils.Ptr(user.ID),
+		Action: "user.password_reset_request",
+	})
+}
+
+// ResetPassword устанавливает новый пароль по одноразовому токену
+// и делает недействительными все ранее выпущенные токены и сессии пользователя
+func (bh *BaseHandler) ResetPassword(ctx context.Context, in *api_pb.ResetPasswordRequest) (out *api_pb.Empty, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Token == "" || in.NewPassword == "" {
+		log.Println("Failed to validate password reset request")
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	tokenHash := utils.HashToken(in.Token)
+	token, err := bh.tokenRepo.GetToken(ctx, models.TokenPurposePasswordReset, tokenHash)
+	if err != nil {
+		log.Printf("Failed to get password reset token, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to reset password")
+	}
+
+	if token == nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid or expired token")
+	}
+
+	user, err := bh.userRepo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		log.Printf("Failed to get user, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to reset password")
+	}
+
+	// Проверяем пароль по политике до использования токена, чтобы его можно было ввести повторно
+	var orgID int
+	if user.Organization != nil {
+		orgID = user.Organization.ID
+	}
+	subject := passwordpolicy.Subject{Email: user.Email, Name: user.Name}
+	if err := bh.validatePassword(ctx, "new_password", in.NewPassword, subject, orgID); err != nil {
+		return nil, err
+	}
+
+	hash, err := utils.HashPassword(in.NewPassword)
+	if err != nil {
+		log.Printf("Failed to hash password, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to reset password")
+	}
+
+	token, err = bh.tokenRepo.ConsumeToken(ctx, models.TokenPurposePasswordReset, tokenHash)
+	if err != nil {
+		log.Printf("Failed to consume password reset token, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to reset password")
+	}
+
+	if token == nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid or expired token")
+	}
+
+	if err := bh.userRepo.ResetUserPassword(ctx, token.UserID, hash); err != nil {
+		log.Printf("Failed to reset user password, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to reset password")
+	}
+
+	bh.revokeUserSessions(ctx, token.UserID)
+
+	bh.audit(ctx, &models.AuditEntry{
+		UserID: utils.Ptr(token.UserID),
+		Action: "user.password_reset",
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// sendPasswordReset выпускает новый токен сброса пароля и отправляет ссылку пользователю
+func (bh *BaseHandler) sendPasswordReset(ctx context.Context, user *models.User) error {
+	cfg := bh.cfg.PasswordReset
+	token, err := bh.issueToken(ctx, user.ID, models.TokenPurposePasswordReset, "", cfg.TokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return bh.notifier.Notify(ctx, notifier.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"To reset your password follow the link: %s?token=%s\nThe link expires in %s.",
+			cfg.URL, url.QueryEscape(token), cfg.TokenTTL,
+		),
+	})
+}
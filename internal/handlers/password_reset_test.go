@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_PasswordReset тестирует методы RequestPasswordReset и ResetPassword базового обработчика
+func TestBaseHandler_PasswordReset(t *testing.T) {
+	ctx := context.Background()
+
+	// Test 1: Запрос сброса и установка нового пароля по токену из сообщения
+	t.Run("PasswordResetSuccess", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		tokenRepo := new(mocks.MockTokenRepository)
+		sessionRepo := new(mocks.MockSessionRepository)
+		n := new(mocks.MockNotifier)
+
+		// Определяем ожидаемое поведение мока
+		var sent notifier.Message
+		var stored *models.OneTimeToken
+		// Письмо отправляется в фоне с контекстом, не зависящим от запроса
+		userRepo.On("GetUserByEmail", mock.Anything, "test@example.com").Return(&models.User{
+			ID:     1,
+			Email:  "test@example.com",
+			Status: models.UserStatusActive,
+		}, nil)
+		tokenRepo.On("DeleteUserTokens", mock.Anything, 1, models.TokenPurposePasswordReset).Return(nil)
+		tokenRepo.On("CreateToken", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.OneTimeToken)
+		}).Return(nil)
+		n.On("Notify", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			sent = args.Get(1).(notifier.Message)
+		}).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:    userRepo,
+			tokenRepo:   tokenRepo,
+			sessionRepo: sessionRepo,
+			notifier:    n,
+			cfg:         DefaultConfig(),
+		}
+
+		// Вызываем метод RequestPasswordReset
+		_, err := baseHandler.RequestPasswordReset(ctx, &grpc.PasswordResetRequest{Email: "test@example.com"})
+		assert.NoError(t, err)
+		baseHandler.background.Wait()
+
+		// Токен в сообщении не совпадает с хранимым хешем
+		assert.Equal(t, "test@example.com", sent.To)
+		match := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(sent.Body)
+		assert.Len(t, match, 2)
+		token, err := url.QueryUnescape(match[1])
+		assert.NoError(t, err)
+		assert.NotEqual(t, token, stored.TokenHash)
+		assert.Equal(t, utils.HashToken(token), stored.TokenHash)
+
+		// Используем токен из сообщения
+		tokenRepo.On("GetToken", ctx, models.TokenPurposePasswordReset, stored.TokenHash).
+			Return(&models.OneTimeToken{UserID: 1}, nil)
+		userRepo.On("GetUserByID", ctx, 1).Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+		tokenRepo.On("ConsumeToken", ctx, models.TokenPurposePasswordReset, stored.TokenHash).
+			Return(&models.OneTimeToken{UserID: 1}, nil)
+		userRepo.On("ResetUserPassword", ctx, 1, mock.Anything).Return(nil)
+		sessionRepo.On("RevokeUserSessions", ctx, 1, "").Return(1, nil)
+
+		// Вызываем метод ResetPassword
+		_, err = baseHandler.ResetPassword(ctx, &grpc.ResetPasswordRequest{Token: token, NewPassword: "new-password1"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		tokenRepo.AssertExpectations(t)
+		sessionRepo.AssertExpectations(t)
+		n.AssertExpectations(t)
+	})
+
+	// Test 2: Для неизвестного email ответ такой же, сообщение не отправляется
+	t.Run("PasswordResetUnknownEmail", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		n := new(mocks.MockNotifier)
+		userRepo.On("GetUserByEmail", mock.Anything, "unknown@example.com").Return((*models.User)(nil), nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			notifier: n,
+		}
+
+		// Вызываем метод RequestPasswordReset
+		result, err := baseHandler.RequestPasswordReset(ctx, &grpc.PasswordResetRequest{Email: "unknown@example.com"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.NotNil(t, result)
+		baseHandler.background.Wait()
+		n.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+	})
+
+	// Test 3: Использованный или истекший токен отклоняется
+	t.Run("ResetPasswordInvalidToken", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		tokenRepo := new(mocks.MockTokenRepository)
+		tokenRepo.On("GetToken", ctx, models.TokenPurposePasswordReset, utils.HashToken("used-token")).
+			Return((*models.OneTimeToken)(nil), nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			tokenRepo: tokenRepo,
+		}
+
+		// Вызываем метод ResetPassword
+		result, err := baseHandler.ResetPassword(ctx, &grpc.ResetPasswordRequest{Token: "used-token", NewPassword: "new-password1"})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = InvalidArgument desc = Invalid or expired token", err.Error())
+		userRepo.AssertNotCalled(t, "ResetUserPassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	// Test 4: Пароль, не соответствующий политике, не расходует токен
+	t.Run("ResetPasswordWeakPassword", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		tokenRepo := new(mocks.MockTokenRepository)
+		tokenRepo.On("GetToken", ctx, models.TokenPurposePasswordReset, utils.HashToken("valid-token")).
+			Return(&models.OneTimeToken{UserID: 1}, nil)
+		userRepo.On("GetUserByID", ctx, 1).Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			tokenRepo: tokenRepo,
+			cfg:       DefaultConfig(),
+		}
+
+		// Вызываем метод ResetPassword
+		result, err := baseHandler.ResetPassword(ctx, &grpc.ResetPasswordRequest{Token: "valid-token", NewPassword: "short"})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		tokenRepo.AssertNotCalled(t, "ConsumeToken", mock.Anything, mock.Anything, mock.Anything)
+		userRepo.AssertNotCalled(t, "ResetUserPassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreatePersonalToken выпускает персональный токен доступа пользователя.
+// Токен ограничен выбранными правами пользователя и возвращается только в ответе на этот запрос
+func (bh *BaseHandler) CreatePersonalToken(
+	ctx context.Context,
+	in *api_pb.PersonalTokenCreateRequest,
+) (out *api_pb.PersonalTokenCreateResponse, err error) {
+	// Проверяем входные данные
+	if in == nil || in.UserId == 0 || in.Name == "" || len(in.Scopes) == 0 || in.ExpiresInDays < 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Токен выпускает только сам пользователь и только по полноценному токену входа,
+	// иначе токен с узкими правами мог бы выпустить токен с более широкими,
+	// а администратор - сохранить доступ после окончания имперсонации
+	userID, ok := ctx.Value(auth.UserIDKey).(int)
+	if !ok || userID != int(in.UserId) || auth.APIKeyID(ctx) != 0 || auth.ImpersonatorID(ctx) != 0 ||
+		auth.IsLimited(ctx) || auth.IsDelegated(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	if _, err := bh.getTokenOwner(ctx, in.UserId); err != nil {
+		return nil, err
+	}
+
+	// Токен не может получить права, которых нет у текущего токена пользователя
+	for _, code := range in.Scopes {
+		if !auth.HasPermission(ctx, code) {
+			return nil, status.Errorf(codes.InvalidArgument, "Scope %q is not granted", code)
+		}
+	}
+
+	var expiresAt *time.Time
+	if in.ExpiresInDays > 0 {
+		expiresAt = utils.Ptr(time.Now().Add(time.Duration(in.ExpiresInDays) * 24 * time.Hour))
+	}
+
+	key, secret, err := bh.issueAPIKey(ctx, auth.PersonalTokenPrefix, userID, in.Name, in.Scopes, expiresAt)
+	if err != nil {
+		log.Printf("Failed to create personal token, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create personal token")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: utils.Ptr(userID),
+		UserID:  utils.Ptr(userID),
+		Action:  "user.personal_token_create",
+		Details: fmt.Sprintf("token %d", key.ID),
+	})
+
+	return &api_pb.PersonalTokenCreateResponse{PersonalToken: personalTokenToPb(key), Token: secret}, nil
+}
+
+// GetPersonalTokens получает персональные токены пользователя, включая отозванные и истекшие
+func (bh *BaseHandler) GetPersonalTokens(ctx context.Context, in *api_pb.Id) (out *api_pb.PersonalTokensResponse, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Токены доступны самому пользователю и администратору
+	if err := checkPermissions(ctx, in.Id); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	user, err := bh.getTokenOwner(ctx, in.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := bh.apiKeyRepo.GetAPIKeys(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to get personal tokens, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get personal tokens")
+	}
+
+	data := make([]*api_pb.PersonalToken, len(keys))
+	for i, key := range keys {
+		data[i] = personalTokenToPb(key)
+	}
+
+	return &api_pb.PersonalTokensResponse{Data: data}, nil
+}
+
+// RevokePersonalToken отзывает персональный токен, запросы с ним сразу перестают проходить аутентификацию
+func (bh *BaseHandler) RevokePersonalToken(
+	ctx context.Context,
+	in *api_pb.PersonalTokenRequest,
+) (out *api_pb.Empty, err error) {
+	// Проверяем входные данные
+	if in == nil || in.UserId == 0 || in.TokenId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Отзывать токены может сам пользователь и администратор
+	if err := checkPermissions(ctx, in.UserId); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	if _, err := bh.getTokenOwner(ctx, in.UserId); err != nil {
+		return nil, err
+	}
+
+	ok, err := bh.apiKeyRepo.RevokeAPIKey(ctx, int(in.UserId), int(in.TokenId))
+	if err != nil {
+		log.Printf("Failed to revoke personal token, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to revoke personal token")
+	}
+
+	if !ok {
+		return nil, status.Error(codes.NotFound, "Personal token not found")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(int(in.UserId)),
+		Action:  "user.personal_token_revoke",
+		Details: fmt.Sprintf("token %d", in.TokenId),
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// getTokenOwner получает пользователя, которому принадлежат персональные токены.
+// Ключи сервисных аккаунтов управляются отдельными методами
+func (bh *BaseHandler) getTokenOwner(ctx context.Context, id int32) (*models.User, error) {
+	user, err := bh.userRepo.GetUserByID(ctx, int(id))
+	if err != nil {
+		log.Printf("Failed to get user by id, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get user")
+	}
+
+	if user == nil || user.Kind == models.UserKindService {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	return user, nil
+}
+
+// personalTokenToPb преобразует персональный токен в ответ API
+func personalTokenToPb(key *models.APIKey) *api_pb.PersonalToken {
+	out := &api_pb.PersonalToken{
+		Id:        int32(key.ID),
+		UserId:    int32(key.UserID),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt.Format(time.RFC3339),
+	}
+	if key.ExpiresAt != nil {
+		out.ExpiresAt = key.ExpiresAt.Format(time.RFC3339)
+	}
+	if key.LastUsedAt != nil {
+		out.LastUsedAt = key.LastUsedAt.Format(time.RFC3339)
+	}
+	if key.RevokedAt != nil {
+		out.RevokedAt = key.RevokedAt.Format(time.RFC3339)
+	}
+
+	return out
+}
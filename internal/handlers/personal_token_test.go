@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_PersonalTokens тестирует методы работы с персональными токенами доступа
+func TestBaseHandler_PersonalTokens(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+	userCtx = context.WithValue(userCtx, auth.IsAdminKey, false)
+	userCtx = context.WithValue(userCtx, auth.PermissionsKey, []string{"reports.read", "reports.write"})
+
+	user := &models.User{
+		ID:     1,
+		Email:  "test@example.com",
+		Status: models.UserStatusActive,
+		Kind:   models.UserKindHuman,
+	}
+
+	// Test 1: Пользователь выпускает бессрочный токен с частью своих прав
+	t.Run("CreatePersonalToken", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		apiKeyRepo := new(mocks.MockAPIKeyRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", userCtx, 1).Return(user, nil)
+		var stored *models.APIKey
+		apiKeyRepo.On("CreateAPIKey", userCtx, mock.Anything).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.APIKey)
+			stored.ID = 3
+		}).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			apiKeyRepo: apiKeyRepo,
+		}
+
+		// Вызываем метод CreatePersonalToken
+		result, err := baseHandler.CreatePersonalToken(userCtx, &grpc.PersonalTokenCreateRequest{
+			UserId: 1,
+			Name:   "scripts",
+			Scopes: []string{"reports.read"},
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(result.Token, "pat_"))
+		assert.Equal(t, utils.HashToken(result.Token), stored.KeyHash)
+		assert.Equal(t, []string{"reports.read"}, stored.Scopes)
+		assert.Nil(t, stored.ExpiresAt)
+		assert.Empty(t, result.PersonalToken.ExpiresAt)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		apiKeyRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Токен не может получить права, которых нет у пользователя
+	t.Run("CreatePersonalTokenScopeNotGranted", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		apiKeyRepo := new(mocks.MockAPIKeyRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", userCtx, 1).Return(user, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			apiKeyRepo: apiKeyRepo,
+		}
+
+		// Вызываем метод CreatePersonalToken
+		result, err := baseHandler.CreatePersonalToken(userCtx, &grpc.PersonalTokenCreateRequest{
+			UserId: 1,
+			Name:   "scripts",
+			Scopes: []string{"admin"},
+		})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		apiKeyRepo.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+	})
+
+	// Test 3: Токен нельзя выпустить по другому токену
+	t.Run("CreatePersonalTokenWithAPIKey", func(t *testing.T) {
+		ctx := context.WithValue(userCtx, auth.APIKeyIDKey, 3)
+
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{}
+
+		// Вызываем метод CreatePersonalToken
+		result, err := baseHandler.CreatePersonalToken(ctx, &grpc.PersonalTokenCreateRequest{
+			UserId: 1,
+			Name:   "scripts",
+			Scopes: []string{"reports.read"},
+		})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	// Test 4: Токен нельзя выпустить по токену с ограниченными правами
+	restricted := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"CreatePersonalTokenWithLimitedToken", context.WithValue(userCtx, auth.LimitedKey, true)},
+		{"CreatePersonalTokenWithDelegatedToken", context.WithValue(userCtx, auth.DelegatedKey, true)},
+		{"CreatePersonalTokenWithImpersonation", context.WithValue(userCtx, auth.ImpersonatorIDKey, 2)},
+	}
+	for _, tc := range restricted {
+		t.Run(tc.name, func(t *testing.T) {
+			// Создаем базовый обработчик
+			baseHandler := &BaseHandler{}
+
+			// Вызываем метод CreatePersonalToken
+			result, err := baseHandler.CreatePersonalToken(tc.ctx, &grpc.PersonalTokenCreateRequest{
+				UserId: 1,
+				Name:   "scripts",
+				Scopes: []string{"reports.read"},
+			})
+
+			// Проверяем результат
+			assert.Nil(t, result)
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		})
+	}
+
+	// Test 5: Администратор может отозвать токен пользователя
+	t.Run("RevokePersonalTokenByAdmin", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 2)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, true)
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		apiKeyRepo := new(mocks.MockAPIKeyRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", ctx, 1).Return(user, nil)
+		apiKeyRepo.On("RevokeAPIKey", ctx, 1, 3).Return(true, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:   userRepo,
+			apiKeyRepo: apiKeyRepo,
+		}
+
+		// Вызываем метод RevokePersonalToken
+		_, err := baseHandler.RevokePersonalToken(ctx, &grpc.PersonalTokenRequest{UserId: 1, TokenId: 3})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		apiKeyRepo.AssertExpectations(t)
+	})
+}
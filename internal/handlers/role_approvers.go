@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetRoleApprovers получает пользователей, утверждающих запросы роли
+func (bh *BaseHandler) GetRoleApprovers(ctx context.Context, in *api_pb.Id) (out *api_pb.RoleApprovers, err error) {
+	// Утверждающих назначает только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	if _, err := bh.roleRepo.GetRoleByID(ctx, int(in.Id)); err != nil {
+		return nil, status.Error(codes.NotFound, "Role not found")
+	}
+
+	userIDs, err := bh.accessRepo.GetRoleApprovers(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to get role approvers, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get role approvers")
+	}
+
+	return &api_pb.RoleApprovers{Id: in.Id, UserIds: convertIntSliceToInt32(userIDs)}, nil
+}
+
+// UpdateRoleApprovers заменяет список пользователей, утверждающих запросы роли
+func (bh *BaseHandler) UpdateRoleApprovers(
+	ctx context.Context,
+	in *api_pb.RoleApprovers,
+) (out *api_pb.RoleApprovers, err error) {
+	// Утверждающих назначает только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	if _, err := bh.roleRepo.GetRoleByID(ctx, int(in.Id)); err != nil {
+		return nil, status.Error(codes.NotFound, "Role not found")
+	}
+
+	for _, userID := range in.UserIds {
+		// Репозиторий возвращает ошибку и для неизвестного пользователя
+		user, err := bh.userRepo.GetUserByID(ctx, int(userID))
+		if err != nil || user == nil {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Unknown user %d", userID))
+		}
+	}
+
+	if err := bh.accessRepo.SetRoleApprovers(ctx, int(in.Id), convertInt32SliceToInt(in.UserIds)); err != nil {
+		log.Printf("Failed to set role approvers, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to update role approvers")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		Action:  "role.approvers_update",
+		Details: fmt.Sprintf("role %d: approvers %v", in.Id, in.UserIds),
+	})
+
+	return bh.GetRoleApprovers(ctx, &api_pb.Id{Id: in.Id})
+}
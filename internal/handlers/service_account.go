@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"regexp"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serviceAccountDomain домен синтетических email сервисных аккаунтов.
+// Домен .invalid зарезервирован, письма на такие адреса не отправляются
+const serviceAccountDomain = "service-accounts.invalid"
+
+// serviceAccountName допустимое имя сервисного аккаунта
+var serviceAccountName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// CreateServiceAccount создает сервисный аккаунт для межсервисного доступа.
+// Сервисный аккаунт не может войти по паролю и аутентифицируется только API ключами
+func (bh *BaseHandler) CreateServiceAccount(
+	ctx context.Context,
+	in *api_pb.ServiceAccountCreateRequest,
+) (out *api_pb.ServiceAccount, err error) {
+	// Управлять сервисными аккаунтами может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || !serviceAccountName.MatchString(in.Name) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid service account name")
+	}
+
+	email := in.Name + "@" + serviceAccountDomain
+	existing, err := bh.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		log.Printf("Failed to get user by email, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create service account")
+	}
+
+	if existing != nil {
+		return nil, status.Error(codes.AlreadyExists, "Service account already exists")
+	}
+
+	var org *models.Organization
+	if in.OrganizationId != 0 {
+		org = &models.Organization{ID: int(in.OrganizationId)}
+	}
+
+	account, err := bh.userRepo.CreateUser(ctx, &models.User{
+		Name:          in.Name,
+		Email:         email,
+		Organization:  org,
+		Status:        models.UserStatusActive,
+		EmailVerified: true,
+		Kind:          models.UserKindService,
+	})
+	if err != nil {
+		log.Printf("Failed to create service account, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create service account")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(account.ID),
+		Action:  "service_account.create",
+	})
+
+	return serviceAccountToPb(account), nil
+}
+
+// GetServiceAccounts получает список сервисных аккаунтов
+func (bh *BaseHandler) GetServiceAccounts(
+	ctx context.Context,
+	in *api_pb.ListRequest,
+) (out *api_pb.ServiceAccountsResponse, err error) {
+	// Управлять сервисными аккаунтами может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Устанавливаем значения по умолчанию
+	limit := 10
+	offset := 0
+
+	// Парсим limit
+	if in.Limit > 0 && in.Limit < 100 {
+		limit = int(in.GetLimit())
+	}
+
+	// Парсим offset
+	if in.Offset > 0 {
+		offset = int(in.GetOffset())
+	}
+
+	accounts, err := bh.userRepo.GetServiceAccounts(ctx, limit, offset)
+	if err != nil {
+		log.Printf("Failed to get service accounts: %v", err)
+		return nil, status.Error(codes.Internal, "Failed to get service accounts")
+	}
+
+	data := make([]*api_pb.ServiceAccount, len(accounts))
+	for i, account := range accounts {
+		data[i] = serviceAccountToPb(account)
+	}
+
+	return &api_pb.ServiceAccountsResponse{Data: data}, nil
+}
+
+// getServiceAccount получает сервисный аккаунт по идентификатору.
+// Обычные пользователи не считаются сервисными аккаунтами
+func (bh *BaseHandler) getServiceAccount(ctx context.Context, id int32) (*models.User, error) {
+	if id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	account, err := bh.userRepo.GetUserByID(ctx, int(id))
+	if err != nil {
+		log.Printf("Failed to get service account, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get service account")
+	}
+
+	if account == nil || account.Kind != models.UserKindService {
+		return nil, status.Error(codes.NotFound, "Service account not found")
+	}
+
+	return account, nil
+}
+
+// serviceAccountToPb преобразует сервисный аккаунт в ответ API
+func serviceAccountToPb(account *models.User) *api_pb.ServiceAccount {
+	out := &api_pb.ServiceAccount{
+		Id:     int32(account.ID),
+		Name:   account.Name,
+		Status: string(account.Status),
+	}
+	if account.Organization != nil {
+		out.OrganizationId = int32(account.Organization.ID)
+	}
+
+	return out
+}
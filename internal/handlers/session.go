@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListSessions получает активные сессии пользователя и сессии, открытые администратором от его имени
+func (bh *BaseHandler) ListSessions(ctx context.Context, in *api_pb.SessionsRequest) (out *api_pb.SessionsResponse, err error) {
+	// Проверяем входные данные
+	if in == nil || in.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Сессии доступны самому пользователю и администратору
+	if err := checkPermissions(ctx, in.UserId); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	sessions, err := bh.sessionRepo.GetUserSessions(ctx, int(in.UserId))
+	if err != nil {
+		log.Printf("Failed to get user sessions, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get sessions")
+	}
+
+	currentID := auth.SessionID(ctx)
+	out = &api_pb.SessionsResponse{Sessions: make([]*api_pb.Session, 0, len(sessions))}
+	for _, session := range sessions {
+		item := &api_pb.Session{
+			Id:         session.ID,
+			UserId:     int32(session.UserID),
+			Ip:         session.IP,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt.Format(time.RFC3339),
+			LastUsedAt: session.LastUsedAt.Format(time.RFC3339),
+			ExpiresAt:  session.ExpiresAt.Format(time.RFC3339),
+			Current:    session.ID == currentID,
+		}
+		if session.RevokedAt != nil {
+			item.RevokedAt = session.RevokedAt.Format(time.RFC3339)
+		}
+		if session.ImpersonatorID != nil {
+			item.ImpersonatorId = int32(*session.ImpersonatorID)
+			item.ImpersonationReason = session.ImpersonationReason
+		}
+		out.Sessions = append(out.Sessions, item)
+	}
+
+	return out, nil
+}
+
+// RevokeSession отзывает сессию пользователя, токен этой сессии перестает действовать
+func (bh *BaseHandler) RevokeSession(ctx context.Context, in *api_pb.RevokeSessionRequest) (out *api_pb.Empty, err error) {
+	// Проверяем входные данные
+	if in == nil || in.UserId == 0 || in.SessionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Отзывать сессии может сам пользователь и администратор
+	if err := checkPermissions(ctx, in.UserId); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	ok, err := bh.sessionRepo.RevokeSession(ctx, int(in.UserId), in.SessionId)
+	if err != nil {
+		log.Printf("Failed to revoke session, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to revoke session")
+	}
+
+	if !ok {
+		return nil, status.Error(codes.NotFound, "Session not found")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(int(in.UserId)),
+		Action:  "user.session_revoke",
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// RevokeAllSessions отзывает все сессии пользователя.
+// С keep_current текущая сессия пользователя сохраняется
+func (bh *BaseHandler) RevokeAllSessions(
+	ctx context.Context,
+	in *api_pb.RevokeAllSessionsRequest,
+) (out *api_pb.RevokeAllSessionsResponse, err error) {
+	// Проверяем входные данные
+	if in == nil || in.UserId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Отзывать сессии может сам пользователь и администратор
+	if err := checkPermissions(ctx, in.UserId); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	var exceptID string
+	if in.KeepCurrent {
+		exceptID = auth.SessionID(ctx)
+	}
+
+	revoked, err := bh.sessionRepo.RevokeUserSessions(ctx, int(in.UserId), exceptID)
+	if err != nil {
+		log.Printf("Failed to revoke user sessions, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to revoke sessions")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(int(in.UserId)),
+		Action:  "user.sessions_revoke",
+		Details: fmt.Sprintf("revoked %d sessions", revoked),
+	})
+
+	return &api_pb.RevokeAllSessionsResponse{Revoked: int32(revoked)}, nil
+}
+
+// createSession сохраняет сессию для выпущенного токена, сведения о клиенте берутся из контекста.
+// Без репозитория сессий токены не привязываются к сессиям
+func (bh *BaseHandler) createSession(ctx context.Context, session *models.Session) error {
+	if bh.sessionRepo == nil {
+		return nil
+	}
+
+	session.IP = auth.ClientIP(ctx)
+	session.UserAgent = auth.UserAgent(ctx)
+	if session.ExpiresAt.IsZero() {
+		session.ExpiresAt = time.Now().Add(utils.JWTTTL)
+	}
+
+	return bh.sessionRepo.CreateSession(ctx, session)
+}
+
+// revokeUserSessions завершает все сессии пользователя после смены пароля или приостановки.
+// Ошибка не прерывает запрос: выпущенные токены уже недействительны после сдвига tokens_valid_after
+func (bh *BaseHandler) revokeUserSessions(ctx context.Context, userID int) {
+	if bh.sessionRepo == nil {
+		return
+	}
+
+	if _, err := bh.sessionRepo.RevokeUserSessions(ctx, userID, ""); err != nil {
+		log.Printf("Failed to revoke user %d sessions, err:%v\n", userID, err)
+	}
+}
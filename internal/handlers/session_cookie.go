@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// SetCookieMetadata ключ метаданных ответа, значения которого gateway передает в заголовке Set-Cookie
+const SetCookieMetadata = "set-cookie"
+
+// errDPoPWithCookie токен из cookie передается без DPoP доказательства, поэтому его нельзя привязать к ключу клиента
+var errDPoPWithCookie = status.Error(codes.InvalidArgument, "DPoP key can not be used with session cookie")
+
+// sessionCookies формирует cookie браузерной сессии: токен доступа недоступен скриптам страницы,
+// а CSRF токен страница читает и передает в заголовке X-CSRF-Token.
+// Пустой token удаляет cookie
+func (bh *BaseHandler) sessionCookies(token string) []*http.Cookie {
+	cfg := bh.cfg.SessionCookie
+	maxAge, csrfToken := -1, ""
+	if token != "" {
+		maxAge = int(utils.JWTTTL.Seconds())
+		csrfToken = utils.CSRFToken(bh.secretKey, token)
+	}
+
+	return []*http.Cookie{
+		{
+			Name:     auth.SessionCookie,
+			Value:    token,
+			Path:     "/",
+			Domain:   cfg.Domain,
+			MaxAge:   maxAge,
+			Secure:   cfg.Secure,
+			HttpOnly: true,
+			SameSite: cfg.SameSite,
+		},
+		{
+			Name:     auth.CSRFCookie,
+			Value:    csrfToken,
+			Path:     "/",
+			Domain:   cfg.Domain,
+			MaxAge:   maxAge,
+			Secure:   cfg.Secure,
+			SameSite: cfg.SameSite,
+		},
+	}
+}
+
+// setSessionCookie передает выпущенный токен в cookie браузерной сессии вместо тела ответа.
+// Ответ с запросом второго фактора не изменяется: cookie устанавливается после Login2FA
+func (bh *BaseHandler) setSessionCookie(ctx context.Context, out *api_pb.LoginResponse) (*api_pb.LoginResponse, error) {
+	if out.Token == "" {
+		return out, nil
+	}
+
+	if err := sendCookies(ctx, bh.sessionCookies(out.Token)); err != nil {
+		log.Printf("Failed to set session cookie, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	out.CsrfToken = utils.CSRFToken(bh.secretKey, out.Token)
+	out.Token = ""
+
+	return out, nil
+}
+
+// sendCookies добавляет cookie в метаданные ответа, gateway передает их в заголовке Set-Cookie
+func sendCookies(ctx context.Context, cookies []*http.Cookie) error {
+	pairs := make([]string, 0, 2*len(cookies))
+	for _, cookie := range cookies {
+		pairs = append(pairs, SetCookieMetadata, cookie.String())
+	}
+
+	return grpc.SetHeader(ctx, metadata.Pairs(pairs...))
+}
+
+// Logout завершает текущую сессию: сессия отзывается, а cookie браузерной сессии удаляются
+func (bh *BaseHandler) Logout(ctx context.Context, in *api_pb.Empty) (out *api_pb.Empty, err error) {
+	userID, ok := ctx.Value(auth.UserIDKey).(int)
+	sessionID := auth.SessionID(ctx)
+	if !ok || sessionID == "" {
+		return nil, status.Error(codes.Unauthenticated, "Unauthenticated")
+	}
+
+	if _, err := bh.sessionRepo.RevokeSession(ctx, userID, sessionID); err != nil {
+		log.Printf("Failed to revoke session, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to revoke session")
+	}
+
+	if err := sendCookies(ctx, bh.sessionCookies("")); err != nil {
+		log.Printf("Failed to clear session cookie, err:%v\n", err)
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(userID),
+		Action:  "user.logout",
+	})
+
+	return &api_pb.Empty{}, nil
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/LiFeAiR/crud-ai/internal/dpop"
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+)
+
+// TestBaseHandler_SessionCookie тестирует вход с cookie браузерной сессии и выход
+func TestBaseHandler_SessionCookie(t *testing.T) {
+	f := func(s string, claims *utils.Claims) (string, error) {
+		return "test-test-test", nil
+	}
+
+	// Test 1: Токен передается в HttpOnly cookie, а в ответе остается только CSRF токен
+	t.Run("LoginSessionCookie", func(t *testing.T) {
+		// Метаданные ответа, которые gateway передает в заголовках
+		var stream runtime.ServerTransportStream
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), &stream)
+
+		// Создаем мок репозиторий
+		mockRepo := new(mocks.MockUserRepository)
+
+		// Определяем ожидаемое поведение мока
+		mockRepo.On("GetUserByEmail", ctx, "test@example.com").Return(&models.User{
+			ID:            1,
+			Email:         "test@example.com",
+			Status:        models.UserStatusActive,
+			EmailVerified: true,
+		}, nil)
+		mockRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+		mockRepo.On("GetUserPermissions", ctx, 1).Return([]*models.Permission(nil), nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  mockRepo,
+			jwtFunc:   f,
+			secretKey: "secret",
+			cfg:       DefaultConfig(),
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &api_pb.LoginRequest{
+			Email:         "test@example.com",
+			Password:      "password123",
+			SessionCookie: true,
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Empty(t, result.Token)
+		assert.Equal(t, utils.CSRFToken("secret", "test-test-test"), result.CsrfToken)
+
+		cookies := stream.Header().Get(SetCookieMetadata)
+		assert.Len(t, cookies, 2)
+		assert.Contains(t, cookies[0], auth.SessionCookie+"=test-test-test")
+		assert.Contains(t, cookies[0], "HttpOnly")
+		assert.Contains(t, cookies[0], "Secure")
+		assert.Contains(t, cookies[0], "SameSite=Strict")
+		assert.Contains(t, cookies[1], auth.CSRFCookie+"="+result.CsrfToken)
+		assert.NotContains(t, cookies[1], "HttpOnly")
+
+		// Проверяем, что моки были вызваны правильно
+		mockRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Токен из cookie нельзя привязать к ключу клиента
+	t.Run("LoginSessionCookieWithDPoP", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		rawKey, err := json.Marshal(&dpop.JWK{
+			Kty: "EC",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		})
+		assert.NoError(t, err)
+
+		// Создаем базовый обработчик
+		baseHandler := &BaseHandler{}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(context.Background(), &api_pb.LoginRequest{
+			Email:         "test@example.com",
+			Password:      "password123",
+			DpopKey:       string(rawKey),
+			SessionCookie: true,
+		})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, errDPoPWithCookie, err)
+	})
+
+	// Test 3: Выход отзывает текущую сессию и удаляет cookie
+	t.Run("Logout", func(t *testing.T) {
+		var stream runtime.ServerTransportStream
+		ctx := grpc.NewContextWithServerTransportStream(context.Background(), &stream)
+		ctx = context.WithValue(ctx, auth.UserIDKey, 1)
+		ctx = context.WithValue(ctx, auth.SessionIDKey, "current")
+
+		// Создаем мок репозиторий
+		sessionRepo := new(mocks.MockSessionRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+
+		// Определяем ожидаемое поведение мока
+		sessionRepo.On("RevokeSession", ctx, 1, "current").Return(true, nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "user.logout"
+		})).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			sessionRepo: sessionRepo,
+			auditRepo:   auditRepo,
+			cfg:         DefaultConfig(),
+		}
+
+		// Вызываем метод Logout
+		_, err := baseHandler.Logout(ctx, &api_pb.Empty{})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		cookies := stream.Header().Get(SetCookieMetadata)
+		assert.Len(t, cookies, 2)
+		assert.Contains(t, cookies[0], auth.SessionCookie+"=;")
+		assert.Contains(t, cookies[0], "Max-Age=0")
+
+		// Проверяем, что моки были вызваны правильно
+		sessionRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+	})
+}
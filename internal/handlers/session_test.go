@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestBaseHandler_Sessions тестирует методы работы с сессиями базового обработчика
+func TestBaseHandler_Sessions(t *testing.T) {
+	// Test 1: При входе создается сессия, идентификатор которой сохраняется в jti токена
+	t.Run("LoginCreatesSession", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.ClientIPKey, "10.0.0.1")
+		ctx = context.WithValue(ctx, auth.UserAgentKey, "test-agent")
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		sessionRepo := new(mocks.MockSessionRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByEmail", ctx, "test@example.com").Return(&models.User{
+			ID:            1,
+			Email:         "test@example.com",
+			Status:        models.UserStatusActive,
+			EmailVerified: true,
+		}, nil)
+		userRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+		userRepo.On("GetUserPermissions", ctx, 1).Return([]*models.Permission(nil), nil)
+
+		var tokenID string
+		f := func(s string, claims *utils.Claims) (string, error) {
+			tokenID = claims.ID
+			return "token", nil
+		}
+		var session *models.Session
+		sessionRepo.On("CreateSession", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			session = args.Get(1).(*models.Session)
+		}).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:    userRepo,
+			sessionRepo: sessionRepo,
+			jwtFunc:     f,
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{Email: "test@example.com", Password: "password123"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, "token", result.Token)
+		assert.NotEmpty(t, tokenID)
+		assert.Equal(t, tokenID, session.ID)
+		assert.Equal(t, 1, session.UserID)
+		assert.Equal(t, "10.0.0.1", session.IP)
+		assert.Equal(t, "test-agent", session.UserAgent)
+		assert.True(t, session.ExpiresAt.After(time.Now()))
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		sessionRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Пользователь видит свои сессии, текущая сессия отмечена
+	t.Run("ListSessions", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+		ctx = context.WithValue(ctx, auth.SessionIDKey, "current")
+
+		// Создаем мок репозиторий
+		sessionRepo := new(mocks.MockSessionRepository)
+		sessionRepo.On("GetUserSessions", ctx, 1).Return([]*models.Session{
+			{ID: "current", UserID: 1, IP: "10.0.0.1"},
+			{ID: "other", UserID: 1, IP: "10.0.0.2"},
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{sessionRepo: sessionRepo}
+
+		// Вызываем метод ListSessions
+		result, err := baseHandler.ListSessions(ctx, &grpc.SessionsRequest{UserId: 1})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Len(t, result.Sessions, 2)
+		assert.True(t, result.Sessions[0].Current)
+		assert.False(t, result.Sessions[1].Current)
+		assert.Equal(t, "10.0.0.2", result.Sessions[1].Ip)
+	})
+
+	// Test 3: Чужие сессии доступны только администратору
+	t.Run("ListSessionsPermissionDenied", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 2)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+
+		// Создаем мок репозиторий
+		sessionRepo := new(mocks.MockSessionRepository)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{sessionRepo: sessionRepo}
+
+		// Вызываем метод ListSessions
+		result, err := baseHandler.ListSessions(ctx, &grpc.SessionsRequest{UserId: 1})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = PermissionDenied desc = Permission denied", err.Error())
+		sessionRepo.AssertNotCalled(t, "GetUserSessions", mock.Anything, mock.Anything)
+	})
+
+	// Test 4: Отзыв неизвестной сессии
+	t.Run("RevokeSessionNotFound", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+
+		// Создаем мок репозиторий
+		sessionRepo := new(mocks.MockSessionRepository)
+		sessionRepo.On("RevokeSession", ctx, 1, "unknown").Return(false, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{sessionRepo: sessionRepo}
+
+		// Вызываем метод RevokeSession
+		result, err := baseHandler.RevokeSession(ctx, &grpc.RevokeSessionRequest{UserId: 1, SessionId: "unknown"})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = NotFound desc = Session not found", err.Error())
+	})
+
+	// Test 5: Отзыв всех сессий, кроме текущей
+	t.Run("RevokeAllSessionsKeepCurrent", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+		ctx = context.WithValue(ctx, auth.SessionIDKey, "current")
+
+		// Создаем мок репозиторий
+		sessionRepo := new(mocks.MockSessionRepository)
+		sessionRepo.On("RevokeUserSessions", ctx, 1, "current").Return(3, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{sessionRepo: sessionRepo}
+
+		// Вызываем метод RevokeAllSessions
+		result, err := baseHandler.RevokeAllSessions(ctx, &grpc.RevokeAllSessionsRequest{UserId: 1, KeepCurrent: true})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), result.Revoked)
+		sessionRepo.AssertExpectations(t)
+	})
+	// Test 6: Своими сессиями пользователь управляет только по токену входа с полными правами
+	restricted := []struct {
+		name string
+		key  any
+		val  any
+	}{
+		{"ListSessionsWithAPIKey", auth.APIKeyIDKey, 3},
+		{"ListSessionsWithDelegatedToken", auth.DelegatedKey, true},
+		{"ListSessionsWithLimitedToken", auth.LimitedKey, true},
+	}
+	for _, tc := range restricted {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+			ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+			ctx = context.WithValue(ctx, tc.key, tc.val)
+
+			// Создаем мок репозиторий
+			sessionRepo := new(mocks.MockSessionRepository)
+
+			// Создаем базовый обработчик с моком
+			baseHandler := &BaseHandler{sessionRepo: sessionRepo}
+
+			// Вызываем метод ListSessions
+			result, err := baseHandler.ListSessions(ctx, &grpc.SessionsRequest{UserId: 1})
+
+			// Проверяем результат
+			assert.Nil(t, result)
+			assert.Equal(t, "rpc error: code = PermissionDenied desc = Permission denied", err.Error())
+			sessionRepo.AssertNotCalled(t, "GetUserSessions", mock.Anything, mock.Anything)
+		})
+	}
+}
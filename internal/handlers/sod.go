@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/repository"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateSoDConstraint создает ограничение разделения обязанностей: набор ролей,
+// больше одной из которых пользователь иметь не может
+func (bh *BaseHandler) CreateSoDConstraint(
+	ctx context.Context,
+	in *api_pb.SoDConstraintCreateRequest,
+) (out *api_pb.SoDConstraint, err error) {
+	// Ограничения задает только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || strings.TrimSpace(in.Name) == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Повторы ролей не учитываются, ограничение имеет смысл минимум для двух ролей
+	var roleIDs []int
+	seen := make(map[int]bool)
+	for _, id := range in.RoleIds {
+		if id == 0 || seen[int(id)] {
+			continue
+		}
+		seen[int(id)] = true
+		roleIDs = append(roleIDs, int(id))
+	}
+
+	if len(roleIDs) < 2 {
+		return nil, status.Error(codes.InvalidArgument, "Constraint requires at least two roles")
+	}
+
+	for _, id := range roleIDs {
+		if _, err := bh.roleRepo.GetRoleByID(ctx, id); err != nil {
+			if errors.Is(err, repository.ErrRoleNotFound) {
+				return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Unknown role %d", id))
+			}
+			log.Printf("Failed to get role, err:%v\n", err)
+			return nil, status.Error(codes.Internal, "Failed to create constraint")
+		}
+	}
+
+	constraint := &models.SoDConstraint{
+		Name:        strings.TrimSpace(in.Name),
+		Description: in.Description,
+		RoleIDs:     roleIDs,
+	}
+	if err := bh.sodRepo.CreateSoDConstraint(ctx, constraint); err != nil {
+		log.Printf("Failed to create sod constraint, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to create constraint")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		Action:  "sod_constraint.create",
+		Details: fmt.Sprintf("constraint %d: %s, roles %v", constraint.ID, constraint.Name, constraint.RoleIDs),
+	})
+
+	return sodConstraintToPb(constraint), nil
+}
+
+// GetSoDConstraints получает список ограничений разделения обязанностей
+func (bh *BaseHandler) GetSoDConstraints(
+	ctx context.Context,
+	in *api_pb.ListRequest,
+) (out *api_pb.SoDConstraintsResponse, err error) {
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Устанавливаем значения по умолчанию
+	limit := 10
+	offset := 0
+
+	// Парсим limit
+	if in.Limit > 0 && in.Limit < 100 {
+		limit = int(in.GetLimit())
+	}
+
+	// Парсим offset
+	if in.Offset > 0 {
+		offset = int(in.GetOffset())
+	}
+
+	constraints, err := bh.sodRepo.GetSoDConstraints(ctx, limit, offset)
+	if err != nil {
+		log.Printf("Failed to get sod constraints, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get constraints")
+	}
+
+	data := make([]*api_pb.SoDConstraint, 0, len(constraints))
+	for _, constraint := range constraints {
+		data = append(data, sodConstraintToPb(constraint))
+	}
+
+	return &api_pb.SoDConstraintsResponse{Data: data}, nil
+}
+
+// DeleteSoDConstraint удаляет ограничение разделения обязанностей
+func (bh *BaseHandler) DeleteSoDConstraint(ctx context.Context, in *api_pb.Id) (out *api_pb.Empty, err error) {
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	ok, err := bh.sodRepo.DeleteSoDConstraint(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to delete sod constraint, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to delete constraint")
+	}
+
+	if !ok {
+		return nil, status.Error(codes.NotFound, "Constraint not found")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		Action:  "sod_constraint.delete",
+		Details: fmt.Sprintf("constraint %d", in.Id),
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// GetSoDViolations получает пользователей, которые уже нарушают ограничения, например,
+// получили роли до добавления ограничения. constraint_id 0 - все ограничения
+func (bh *BaseHandler) GetSoDViolations(
+	ctx context.Context,
+	in *api_pb.SoDViolationsRequest,
+) (out *api_pb.SoDViolationsResponse, err error) {
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем входные данные
+	if in == nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Устанавливаем значения по умолчанию
+	limit := 10
+	offset := 0
+
+	// Парсим limit
+	if in.Limit > 0 && in.Limit < 100 {
+		limit = int(in.GetLimit())
+	}
+
+	// Парсим offset
+	if in.Offset > 0 {
+		offset = int(in.GetOffset())
+	}
+
+	violations, err := bh.sodRepo.GetSoDViolations(ctx, int(in.ConstraintId), limit, offset)
+	if err != nil {
+		log.Printf("Failed to get sod violations, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to get violations")
+	}
+
+	data := make([]*api_pb.SoDViolation, 0, len(violations))
+	for _, violation := range violations {
+		data = append(data, &api_pb.SoDViolation{
+			ConstraintId:   int32(violation.ConstraintID),
+			ConstraintName: violation.ConstraintName,
+			UserId:         int32(violation.UserID),
+			UserEmail:      violation.UserEmail,
+			RoleIds:        convertIntSliceToInt32(violation.RoleIDs),
+			RoleCodes:      violation.RoleCodes,
+		})
+	}
+
+	return &api_pb.SoDViolationsResponse{Data: data}, nil
+}
+
+// checkSoD проверяет, что выдача ролей roleIDs владельцу не нарушит ограничения разделения обязанностей
+// ни для одного пользователя, получающего роли владельца. Нарушение возвращается ошибкой FailedPrecondition.
+// Проверку и выдачу нужно выполнять в одной транзакции: строки проверенных пользователей блокируются до ее завершения
+func (bh *BaseHandler) checkSoD(ctx context.Context, owner models.GrantOwner, ownerID int, roleIDs []int) error {
+	if bh.sodRepo == nil {
+		return nil
+	}
+
+	conflicts, err := bh.sodRepo.GetSoDConflicts(ctx, owner, ownerID, roleIDs)
+	if err != nil {
+		log.Printf("Failed to check sod constraints, err:%v\n", err)
+		return status.Error(codes.Internal, "Failed to check separation of duties")
+	}
+
+	return sodConflictsError(conflicts)
+}
+
+// checkSoDMembership проверяет, что переход пользователей владельца в организацию или на тариф target
+// не нарушит ограничения разделения обязанностей. Как и checkSoD, выполняется в транзакции с изменением
+func (bh *BaseHandler) checkSoDMembership(
+	ctx context.Context,
+	owner models.GrantOwner,
+	ownerID int,
+	target models.GrantOwner,
+	targetID int,
+) error {
+	if bh.sodRepo == nil {
+		return nil
+	}
+
+	conflicts, err := bh.sodRepo.GetSoDMembershipConflicts(ctx, owner, ownerID, target, targetID)
+	if err != nil {
+		log.Printf("Failed to check sod constraints, err:%v\n", err)
+		return status.Error(codes.Internal, "Failed to check separation of duties")
+	}
+
+	return sodConflictsError(conflicts)
+}
+
+// sodConflictsError описывает первое нарушение ограничений ошибкой FailedPrecondition, без нарушений возвращает nil
+func sodConflictsError(conflicts []*models.SoDViolation) error {
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	conflict := conflicts[0]
+	msg := fmt.Sprintf(
+		"Separation of duties constraint %q violated: user %s would hold roles %s",
+		conflict.ConstraintName, conflict.UserEmail, strings.Join(conflict.RoleCodes, ", "),
+	)
+	if len(conflicts) > 1 {
+		msg += fmt.Sprintf(" (and %d more conflicts)", len(conflicts)-1)
+	}
+
+	return status.Error(codes.FailedPrecondition, msg)
+}
+
+// sodConstraintToPb преобразует ограничение разделения обязанностей в ответ API
+func sodConstraintToPb(constraint *models.SoDConstraint) *api_pb.SoDConstraint {
+	return &api_pb.SoDConstraint{
+		Id:          int32(constraint.ID),
+		Name:        constraint.Name,
+		Description: constraint.Description,
+		RoleIds:     convertIntSliceToInt32(constraint.RoleIDs),
+		CreatedAt:   constraint.CreatedAt.Format(time.RFC3339),
+	}
+}
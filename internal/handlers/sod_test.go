@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/repository"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_SoD тестирует ограничения разделения обязанностей между ролями
+func TestBaseHandler_SoD(t *testing.T) {
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, 100)
+	ctx = context.WithValue(ctx, auth.IsAdminKey, true)
+	conflict := &models.SoDViolation{
+		ConstraintID:   1,
+		ConstraintName: "Payments",
+		UserID:         7,
+		UserEmail:      "user@example.com",
+		RoleIDs:        []int{2, 3},
+		RoleCodes:      []string{"payments_approver", "payments_creator"},
+	}
+
+	// Test 1: Выдача роли, нарушающая ограничение, отклоняется с указанием конфликта.
+	// Проверка выполняется в транзакции с выдачей
+	t.Run("AddUserRolesConflict", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		sodRepo := new(mocks.MockSoDRepository)
+		tx := new(mocks.MockTransactor)
+
+		// Определяем ожидаемое поведение мока
+		tx.On("InTx", ctx).Return()
+		sodRepo.On("GetSoDConflicts", ctx, models.GrantOwnerUser, 7, []int{3}).Return([]*models.SoDViolation{conflict}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			sodRepo:  sodRepo,
+			tx:       tx,
+		}
+
+		// Вызываем метод AddUserRoles
+		result, err := baseHandler.AddUserRoles(ctx, &grpc.UserRolesRequest{Id: 7, RoleIds: []int32{3}})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Equal(t,
+			`Separation of duties constraint "Payments" violated: user user@example.com would hold roles `+
+				`payments_approver, payments_creator`,
+			status.Convert(err).Message(),
+		)
+		userRepo.AssertNotCalled(t, "AddUserRoles", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		sodRepo.AssertExpectations(t)
+		tx.AssertExpectations(t)
+	})
+
+	// Test 2: Роль организации проверяется для всех ее участников
+	t.Run("AddOrganizationRolesConflict", func(t *testing.T) {
+		// Создаем мок репозиторий
+		orgRepo := new(mocks.MockOrganizationRepository)
+		sodRepo := new(mocks.MockSoDRepository)
+
+		// Определяем ожидаемое поведение мока
+		second := *conflict
+		second.UserID = 8
+		sodRepo.On("GetSoDConflicts", ctx, models.GrantOwnerOrganization, 4, []int{3}).
+			Return([]*models.SoDViolation{conflict, &second}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			orgRepo: orgRepo,
+			sodRepo: sodRepo,
+		}
+
+		// Вызываем метод AddOrganizationRoles
+		result, err := baseHandler.AddOrganizationRoles(ctx, &grpc.OrganizationRolesRequest{Id: 4, RoleIds: []int32{3}})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), "(and 1 more conflicts)")
+		orgRepo.AssertNotCalled(t, "AddOrganizationRoles", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		sodRepo.AssertExpectations(t)
+	})
+
+	// Test 3: Ограничение создается минимум для двух разных ролей
+	t.Run("CreateSoDConstraint", func(t *testing.T) {
+		// Создаем мок репозиторий
+		roleRepo := new(mocks.MockRoleRepository)
+		sodRepo := new(mocks.MockSoDRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+
+		// Определяем ожидаемое поведение мока
+		roleRepo.On("GetRoleByID", ctx, 2).Return(&models.Role{ID: 2}, nil)
+		roleRepo.On("GetRoleByID", ctx, 3).Return(&models.Role{ID: 3}, nil)
+		sodRepo.On("CreateSoDConstraint", ctx, mock.MatchedBy(func(constraint *models.SoDConstraint) bool {
+			return constraint.Name == "Payments" && assert.ObjectsAreEqual([]int{2, 3}, constraint.RoleIDs)
+		})).Run(func(args mock.Arguments) {
+			args.Get(1).(*models.SoDConstraint).ID = 1
+		}).Return(nil)
+		auditRepo.On("AddAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == "sod_constraint.create" && entry.Details == "constraint 1: Payments, roles [2 3]"
+		})).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			roleRepo:  roleRepo,
+			sodRepo:   sodRepo,
+			auditRepo: auditRepo,
+		}
+
+		// Повторы не учитываются, одной роли недостаточно
+		result, err := baseHandler.CreateSoDConstraint(ctx, &grpc.SoDConstraintCreateRequest{
+			Name:    "Payments",
+			RoleIds: []int32{2, 2},
+		})
+		assert.Nil(t, result)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		// Вызываем метод CreateSoDConstraint
+		result, err = baseHandler.CreateSoDConstraint(ctx, &grpc.SoDConstraintCreateRequest{
+			Name:    "Payments",
+			RoleIds: []int32{2, 3, 2},
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), result.Id)
+		assert.Equal(t, []int32{2, 3}, result.RoleIds)
+
+		// Проверяем, что моки были вызваны правильно
+		roleRepo.AssertExpectations(t)
+		sodRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+	})
+
+	// Test 4: Отчет содержит пользователей, уже нарушающих ограничение
+	t.Run("GetSoDViolations", func(t *testing.T) {
+		// Создаем мок репозиторий
+		sodRepo := new(mocks.MockSoDRepository)
+
+		// Определяем ожидаемое поведение мока
+		sodRepo.On("GetSoDViolations", ctx, 1, 10, 0).Return([]*models.SoDViolation{conflict}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{sodRepo: sodRepo}
+
+		// Вызываем метод GetSoDViolations
+		result, err := baseHandler.GetSoDViolations(ctx, &grpc.SoDViolationsRequest{ConstraintId: 1})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Len(t, result.Data, 1)
+		assert.Equal(t, int32(7), result.Data[0].UserId)
+		assert.Equal(t, []int32{2, 3}, result.Data[0].RoleIds)
+		assert.Equal(t, []string{"payments_approver", "payments_creator"}, result.Data[0].RoleCodes)
+
+		// Проверяем, что моки были вызваны правильно
+		sodRepo.AssertExpectations(t)
+	})
+	// Test 5: Переход в организацию, роли которой нарушают ограничение, отклоняется
+	t.Run("UpdateUserOrganizationConflict", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		sodRepo := new(mocks.MockSoDRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", ctx, 7).Return(&models.User{
+			ID:           7,
+			Email:        "user@example.com",
+			Organization: &models.Organization{ID: 1},
+		}, nil)
+		sodRepo.On("GetSoDMembershipConflicts", ctx, models.GrantOwnerUser, 7, models.GrantOwnerOrganization, 4).
+			Return([]*models.SoDViolation{conflict}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			sodRepo:  sodRepo,
+		}
+
+		// Вызываем метод UpdateUser
+		result, err := baseHandler.UpdateUser(ctx, &grpc.UserUpdateRequest{Id: 7, Name: "User", OrganizationId: 4})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		userRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		sodRepo.AssertExpectations(t)
+	})
+
+	// Test 6: Новый участник организации проверяется в транзакции создания
+	t.Run("CreateUserOrganizationConflict", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		sodRepo := new(mocks.MockSoDRepository)
+		tx := new(mocks.MockTransactor)
+
+		// Определяем ожидаемое поведение мока
+		tx.On("InTx", ctx).Return()
+		orgRepo.On("GetOrganizationSettings", ctx, 4).Return((*models.OrganizationSettings)(nil), nil)
+		userRepo.On("CreateUser", ctx, mock.Anything).Return(&models.User{ID: 9, Email: "new@example.com"}, nil)
+		sodRepo.On("GetSoDMembershipConflicts", ctx, models.GrantOwnerUser, 9, models.GrantOwnerOrganization, 4).
+			Return([]*models.SoDViolation{conflict}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			orgRepo:  orgRepo,
+			sodRepo:  sodRepo,
+			tx:       tx,
+			cfg:      DefaultConfig(),
+		}
+
+		// Вызываем метод CreateUser
+		result, err := baseHandler.CreateUser(ctx, &grpc.UserCreateRequest{
+			Name:           "User",
+			Email:          "new@example.com",
+			Password:       "Str0ng!Passw0rd",
+			OrganizationId: 4,
+		})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		sodRepo.AssertExpectations(t)
+		tx.AssertExpectations(t)
+	})
+
+	// Test 7: Неизвестная роль отличается от ошибки чтения роли
+	t.Run("CreateSoDConstraintRoleLookup", func(t *testing.T) {
+		// Создаем мок репозиторий
+		roleRepo := new(mocks.MockRoleRepository)
+		sodRepo := new(mocks.MockSoDRepository)
+
+		// Определяем ожидаемое поведение мока
+		roleRepo.On("GetRoleByID", ctx, 2).Return((*models.Role)(nil), repository.ErrRoleNotFound)
+		roleRepo.On("GetRoleByID", ctx, 4).Return((*models.Role)(nil), errors.New("connection refused"))
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			roleRepo: roleRepo,
+			sodRepo:  sodRepo,
+		}
+
+		// Вызываем метод CreateSoDConstraint с несуществующей ролью
+		result, err := baseHandler.CreateSoDConstraint(ctx, &grpc.SoDConstraintCreateRequest{
+			Name:    "Payments",
+			RoleIds: []int32{2, 3},
+		})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		// Вызываем метод CreateSoDConstraint при ошибке БД
+		result, err = baseHandler.CreateSoDConstraint(ctx, &grpc.SoDConstraintCreateRequest{
+			Name:    "Payments",
+			RoleIds: []int32{4, 3},
+		})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.Internal, status.Code(err))
+		sodRepo.AssertNotCalled(t, "CreateSoDConstraint", mock.Anything, mock.Anything)
+
+		// Проверяем, что моки были вызваны правильно
+		roleRepo.AssertExpectations(t)
+	})
+}
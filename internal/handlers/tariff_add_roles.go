@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 
+	"github.com/LiFeAiR/crud-ai/internal/models"
 	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -19,10 +20,24 @@ func (bh *BaseHandler) AddTariffRoles(
 		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
 	}
 
-	// Добавляем роли к тарифу
-	if err := bh.tariffRepo.AddTariffRoles(ctx, int(in.Id), convertInt32SliceToInt(in.RoleIds)); err != nil {
-		log.Printf("add tariff roles failed, err:%v\n", err)
-		return nil, status.Error(codes.Internal, "Failed to add tariff roles")
+	// Ограничения разделения обязанностей для пользователей и организаций с тарифом
+	// проверяются в одной транзакции с выдачей
+	roleIDs := convertInt32SliceToInt(in.RoleIds)
+	err = bh.inTx(ctx, func(ctx context.Context) error {
+		if err := bh.checkSoD(ctx, models.GrantOwnerTariff, int(in.Id), roleIDs); err != nil {
+			return err
+		}
+
+		// Добавляем роли к тарифу
+		if err := bh.tariffRepo.AddTariffRoles(ctx, int(in.Id), roleIDs); err != nil {
+			log.Printf("add tariff roles failed, err:%v\n", err)
+			return status.Error(codes.Internal, "Failed to add tariff roles")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Получаем обновленный тариф с ролями
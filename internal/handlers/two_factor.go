@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Enroll2FA создает секрет TOTP для текущего пользователя.
+// Двухфакторная аутентификация включается после подтверждения кода в Confirm2FA
+func (bh *BaseHandler) Enroll2FA(ctx context.Context, _ *api_pb.Empty) (out *api_pb.Enroll2FAResponse, err error) {
+	userID := currentUserID(ctx)
+	if userID == nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+	}
+
+	// Подключить 2FA можно и по токену без прав: его выдает вход, пока обязательная 2FA не подключена
+	if err := requireLoginToken(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	user, err := bh.userRepo.GetUserByID(ctx, *userID)
+	if err != nil {
+		log.Printf("Failed to get user, err:%v\n", err)
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	tf, err := bh.twoFARepo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to get two factor, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to enroll two-factor authentication")
+	}
+
+	if tf != nil && tf.Enabled {
+		return nil, status.Error(codes.FailedPrecondition, "Two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("Failed to generate totp secret, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to enroll two-factor authentication")
+	}
+
+	if err := bh.twoFARepo.SaveTwoFactorSecret(ctx, user.ID, secret); err != nil {
+		log.Printf("Failed to save totp secret, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to enroll two-factor authentication")
+	}
+
+	return &api_pb.Enroll2FAResponse{
+		Secret:     secret,
+		OtpauthUri: utils.TOTPURI(bh.cfg.TwoFactor.Issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm2FA включает двухфакторную аутентификацию по коду из приложения
+// и возвращает коды восстановления. Коды показываются только один раз
+func (bh *BaseHandler) Confirm2FA(ctx context.Context, in *api_pb.TwoFactorCodeRequest) (out *api_pb.RecoveryCodesResponse, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	userID := currentUserID(ctx)
+	if userID == nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+	}
+
+	if err := requireLoginToken(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	tf, err := bh.twoFARepo.GetTwoFactor(ctx, *userID)
+	if err != nil {
+		log.Printf("Failed to get two factor, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to confirm two-factor authentication")
+	}
+
+	if tf == nil || tf.Enabled {
+		return nil, status.Error(codes.FailedPrecondition, "Two-factor authentication is not enrolled")
+	}
+
+	step, ok := utils.ValidateTOTP(tf.Secret, strings.TrimSpace(in.Code), time.Now())
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "Invalid code")
+	}
+
+	if _, err := bh.twoFARepo.UseTOTPStep(ctx, tf.UserID, step); err != nil {
+		log.Printf("Failed to use totp step, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to confirm two-factor authentication")
+	}
+
+	// Генерируем коды восстановления, в БД сохраняются только их хеши
+	recoveryCodes := make([]string, 0, bh.cfg.TwoFactor.RecoveryCodes)
+	hashes := make([]string, 0, bh.cfg.TwoFactor.RecoveryCodes)
+	for i := 0; i < bh.cfg.TwoFactor.RecoveryCodes; i++ {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			log.Printf("Failed to generate recovery code, err:%v\n", err)
+			return nil, status.Error(codes.Internal, "Failed to confirm two-factor authentication")
+		}
+		recoveryCodes = append(recoveryCodes, code)
+		hashes = append(hashes, utils.HashToken(normalizeRecoveryCode(code)))
+	}
+
+	if err := bh.twoFARepo.EnableTwoFactor(ctx, tf.UserID, hashes); err != nil {
+		log.Printf("Failed to enable two factor, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to confirm two-factor authentication")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: userID,
+		UserID:  userID,
+		Action:  "user.2fa_enable",
+	})
+
+	return &api_pb.RecoveryCodesResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+// Disable2FA выключает двухфакторную аутентификацию по коду TOTP или коду восстановления
+func (bh *BaseHandler) Disable2FA(ctx context.Context, in *api_pb.TwoFactorCodeRequest) (out *api_pb.Empty, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	userID := currentUserID(ctx)
+	if userID == nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+	}
+
+	if err := requireInteractiveSession(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	user, err := bh.userRepo.GetUserByID(ctx, *userID)
+	if err != nil {
+		log.Printf("Failed to get user, err:%v\n", err)
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	tf, err := bh.twoFARepo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to get two factor, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to disable two-factor authentication")
+	}
+
+	if tf == nil || !tf.Enabled {
+		return nil, status.Error(codes.FailedPrecondition, "Two-factor authentication is not enabled")
+	}
+
+	// Участники организации, которая требует 2FA, не могут ее выключить
+	required, err := bh.organizationRequires2FA(ctx, user)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to disable two-factor authentication")
+	}
+	if required {
+		return nil, status.Error(codes.FailedPrecondition, "Organization requires two-factor authentication")
+	}
+
+	valid, err := bh.verifySecondFactor(ctx, tf, in.Code)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Failed to disable two-factor authentication")
+	}
+	if !valid {
+		return nil, status.Error(codes.InvalidArgument, "Invalid code")
+	}
+
+	if err := bh.twoFARepo.DisableTwoFactor(ctx, user.ID); err != nil {
+		log.Printf("Failed to disable two factor, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to disable two-factor authentication")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: userID,
+		UserID:  userID,
+		Action:  "user.2fa_disable",
+	})
+
+	return &api_pb.Empty{}, nil
+}
+
+// Login2FA завершает вход по токену, выданному Login, и коду второго фактора
+func (bh *BaseHandler) Login2FA(ctx context.Context, in *api_pb.Login2FARequest) (out *api_pb.LoginResponse, err error) {
+	// Проверяем входные данные
+	if in == nil || in.ChallengeToken == "" || in.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	challengeHash := utils.HashToken(in.ChallengeToken)
+	challenge, err := bh.tokenRepo.GetToken(ctx, models.TokenPurposeLoginChallenge, challengeHash)
+	if err != nil {
+		log.Printf("Failed to get login challenge, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	if challenge == nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+	}
+
+	if in.SessionCookie && challenge.Payload != "" {
+		return nil, errDPoPWithCookie
+	}
+
+	user, err := bh.userRepo.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		log.Printf("Failed to get user, err:%v\n", err)
+		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+	}
+
+	// Неверные коды учитываются вместе с неудачными попытками входа по паролю
+	ip := auth.ClientIP(ctx)
+	if err := bh.checkLoginThrottle(ctx, user.Email, ip); err != nil {
+		return nil, err
+	}
+
+	tf, err := bh.twoFARepo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to get two factor, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	if tf == nil || !tf.Enabled {
+		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+	}
+
+	valid, err := bh.verifySecondFactor(ctx, tf, in.Code)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+	if !valid {
+		bh.recordLoginAttempt(ctx, user.Email, ip, &user.ID, false)
+		return nil, status.Error(codes.Unauthenticated, "Invalid code")
+	}
+
+	// Токен одноразовый, при параллельных запросах войти сможет только один
+	consumed, err := bh.tokenRepo.ConsumeToken(ctx, models.TokenPurposeLoginChallenge, challengeHash)
+	if err != nil {
+		log.Printf("Failed to consume login challenge, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Authentication failed")
+	}
+	if consumed == nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+	}
+
+	bh.recordLoginAttempt(ctx, user.Email, ip, &user.ID, true)
+
+	// Статус мог измениться, пока пользователь вводил код
+	limited, err := bh.loginRestrictions(user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Токен привязывается к ключу клиента, переданному при входе
+	out, err = bh.issueLoginResponse(ctx, user, limited, consumed.Payload)
+	if err != nil || !in.SessionCookie {
+		return out, err
+	}
+
+	return bh.setSessionCookie(ctx, out)
+}
+
+// checkSecondFactor проверяет двухфакторную аутентификацию после верного пароля.
+// Если она включена, возвращает ответ с токеном для Login2FA.
+// setupRequired означает, что организация требует 2FA, а пользователь ее еще не подключил.
+// Отпечаток ключа клиента сохраняется в токене для Login2FA
+func (bh *BaseHandler) checkSecondFactor(
+	ctx context.Context,
+	user *models.User,
+	dpopJKT string,
+) (challenge *api_pb.LoginResponse, setupRequired bool, err error) {
+	if bh.twoFARepo == nil {
+		return nil, false, nil
+	}
+
+	tf, err := bh.twoFARepo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		log.Printf("Failed to get two factor, err:%v\n", err)
+		return nil, false, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	if tf != nil && tf.Enabled {
+		token, err := bh.issueToken(ctx, user.ID, models.TokenPurposeLoginChallenge, dpopJKT, bh.cfg.TwoFactor.ChallengeTTL)
+		if err != nil {
+			log.Printf("Failed to issue login challenge, err:%v\n", err)
+			return nil, false, status.Error(codes.Internal, "Authentication failed")
+		}
+
+		return &api_pb.LoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    token,
+		}, false, nil
+	}
+
+	required, err := bh.organizationRequires2FA(ctx, user)
+	if err != nil {
+		return nil, false, status.Error(codes.Internal, "Authentication failed")
+	}
+
+	return nil, required, nil
+}
+
+// organizationRequires2FA проверяет, требует ли организация пользователя двухфакторную аутентификацию
+func (bh *BaseHandler) organizationRequires2FA(ctx context.Context, user *models.User) (bool, error) {
+	if user.Organization == nil {
+		return false, nil
+	}
+
+	settings, err := bh.orgRepo.GetOrganizationSettings(ctx, user.Organization.ID)
+	if err != nil {
+		log.Printf("Failed to get organization settings, err:%v\n", err)
+		return false, err
+	}
+
+	return settings.Require2FA, nil
+}
+
+// verifySecondFactor проверяет код TOTP или код восстановления.
+// Каждый код принимается только один раз
+func (bh *BaseHandler) verifySecondFactor(ctx context.Context, tf *models.TwoFactor, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if step, ok := utils.ValidateTOTP(tf.Secret, code, time.Now()); ok {
+		used, err := bh.twoFARepo.UseTOTPStep(ctx, tf.UserID, step)
+		if err != nil {
+			log.Printf("Failed to use totp step, err:%v\n", err)
+		}
+		return used, err
+	}
+
+	used, err := bh.twoFARepo.UseRecoveryCode(ctx, tf.UserID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		log.Printf("Failed to use recovery code, err:%v\n", err)
+	}
+	return used, err
+}
+
+// normalizeRecoveryCode приводит код восстановления к виду, в котором хранится его хеш
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
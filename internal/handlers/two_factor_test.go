@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestBaseHandler_Login2FA тестирует вход с двухфакторной аутентификацией
+func TestBaseHandler_Login2FA(t *testing.T) {
+	ctx := context.Background()
+	f := func(s string, claims *utils.Claims) (string, error) {
+		return "test-test-test", nil
+	}
+	secret, err := utils.GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	testUser := &models.User{
+		ID:            1,
+		Name:          "Test User",
+		Email:         "test@example.com",
+		Status:        models.UserStatusActive,
+		EmailVerified: true,
+	}
+
+	// Test 1: При включенной 2FA вместо JWT выдается токен для ввода кода
+	t.Run("LoginReturnsChallenge", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		tokenRepo := new(mocks.MockTokenRepository)
+		twoFARepo := new(mocks.MockTwoFactorRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByEmail", ctx, "test@example.com").Return(testUser, nil)
+		userRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+		twoFARepo.On("GetTwoFactor", ctx, 1).Return(&models.TwoFactor{UserID: 1, Secret: secret, Enabled: true}, nil)
+		tokenRepo.On("DeleteUserTokens", ctx, 1, models.TokenPurposeLoginChallenge).Return(nil)
+		tokenRepo.On("CreateToken", ctx, mock.Anything).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			tokenRepo: tokenRepo,
+			twoFARepo: twoFARepo,
+			jwtFunc:   f,
+			cfg:       DefaultConfig(),
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{Email: "test@example.com", Password: "password123"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.True(t, result.TwoFactorRequired)
+		assert.NotEmpty(t, result.ChallengeToken)
+		assert.Empty(t, result.Token)
+		assert.Nil(t, result.User)
+		userRepo.AssertNotCalled(t, "GetUserPermissions", mock.Anything, mock.Anything)
+	})
+
+	// Test 2: Успешное завершение входа по коду TOTP
+	t.Run("Login2FASuccess", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		tokenRepo := new(mocks.MockTokenRepository)
+		twoFARepo := new(mocks.MockTwoFactorRepository)
+
+		// Определяем ожидаемое поведение мока
+		challengeHash := utils.HashToken("challenge")
+		tokenRepo.On("GetToken", ctx, models.TokenPurposeLoginChallenge, challengeHash).Return(&models.OneTimeToken{UserID: 1}, nil)
+		tokenRepo.On("ConsumeToken", ctx, models.TokenPurposeLoginChallenge, challengeHash).Return(&models.OneTimeToken{UserID: 1}, nil)
+		userRepo.On("GetUserByID", ctx, 1).Return(testUser, nil)
+		userRepo.On("GetUserPermissions", ctx, 1).Return([]*models.Permission(nil), nil)
+		twoFARepo.On("GetTwoFactor", ctx, 1).Return(&models.TwoFactor{UserID: 1, Secret: secret, Enabled: true}, nil)
+		twoFARepo.On("UseTOTPStep", ctx, 1, mock.Anything).Return(true, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			tokenRepo: tokenRepo,
+			twoFARepo: twoFARepo,
+			jwtFunc:   f,
+		}
+
+		// Вызываем метод Login2FA с текущим кодом
+		code, err := utils.TOTPCode(secret, time.Now())
+		assert.NoError(t, err)
+		result, err := baseHandler.Login2FA(ctx, &grpc.Login2FARequest{ChallengeToken: "challenge", Code: code})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, "test-test-test", result.Token)
+		assert.Equal(t, int32(1), result.User.Id)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		tokenRepo.AssertExpectations(t)
+		twoFARepo.AssertExpectations(t)
+	})
+
+	// Test 3: Неверный код не завершает вход и не использует токен
+	t.Run("Login2FAInvalidCode", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		tokenRepo := new(mocks.MockTokenRepository)
+		twoFARepo := new(mocks.MockTwoFactorRepository)
+
+		// Определяем ожидаемое поведение мока
+		tokenRepo.On("GetToken", ctx, models.TokenPurposeLoginChallenge, utils.HashToken("challenge")).
+			Return(&models.OneTimeToken{UserID: 1}, nil)
+		userRepo.On("GetUserByID", ctx, 1).Return(testUser, nil)
+		twoFARepo.On("GetTwoFactor", ctx, 1).Return(&models.TwoFactor{UserID: 1, Secret: secret, Enabled: true}, nil)
+		twoFARepo.On("UseRecoveryCode", ctx, 1, mock.Anything).Return(false, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			tokenRepo: tokenRepo,
+			twoFARepo: twoFARepo,
+		}
+
+		// Вызываем метод Login2FA с неверным кодом
+		result, err := baseHandler.Login2FA(ctx, &grpc.Login2FARequest{ChallengeToken: "challenge", Code: "abcde-fghij"})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = Unauthenticated desc = Invalid code", err.Error())
+		tokenRepo.AssertNotCalled(t, "ConsumeToken", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	// Test 4: Организация требует 2FA, а пользователь ее не подключил
+	t.Run("LoginOrganizationRequires2FA", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		twoFARepo := new(mocks.MockTwoFactorRepository)
+
+		// Определяем ожидаемое поведение мока
+		member := *testUser
+		member.Organization = &models.Organization{ID: 7}
+		userRepo.On("GetUserByEmail", ctx, "test@example.com").Return(&member, nil)
+		userRepo.On("CheckPassword", ctx, 1, "password123").Return(true, nil)
+		twoFARepo.On("GetTwoFactor", ctx, 1).Return((*models.TwoFactor)(nil), nil)
+		orgRepo.On("GetOrganizationSettings", ctx, 7).Return(&models.OrganizationSettings{OrganizationID: 7, Require2FA: true}, nil)
+		orgRepo.On("GetOrganizationByID", ctx, 7).Return(&models.Organization{ID: 7, Name: "Org"}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			orgRepo:   orgRepo,
+			twoFARepo: twoFARepo,
+			jwtFunc:   f,
+		}
+
+		// Вызываем метод Login
+		result, err := baseHandler.Login(ctx, &grpc.LoginRequest{Email: "test@example.com", Password: "password123"})
+
+		// Проверяем результат: выдан токен без прав
+		assert.NoError(t, err)
+		assert.True(t, result.TwoFactorSetupRequired)
+		assert.NotEmpty(t, result.Token)
+		assert.Empty(t, result.User.Permissions)
+		userRepo.AssertNotCalled(t, "GetUserPermissions", mock.Anything, mock.Anything)
+	})
+}
+
+// TestBaseHandler_Confirm2FA тестирует метод Confirm2FA базового обработчика
+func TestBaseHandler_Confirm2FA(t *testing.T) {
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+	secret, err := utils.GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	// Создаем мок репозиторий
+	twoFARepo := new(mocks.MockTwoFactorRepository)
+
+	// Определяем ожидаемое поведение мока
+	var hashes []string
+	twoFARepo.On("GetTwoFactor", ctx, 1).Return(&models.TwoFactor{UserID: 1, Secret: secret}, nil)
+	twoFARepo.On("UseTOTPStep", ctx, 1, mock.Anything).Return(true, nil)
+	twoFARepo.On("EnableTwoFactor", ctx, 1, mock.Anything).Run(func(args mock.Arguments) {
+		hashes = args.Get(2).([]string)
+	}).Return(nil)
+
+	// Создаем базовый обработчик с моком
+	baseHandler := &BaseHandler{
+		twoFARepo: twoFARepo,
+		cfg:       DefaultConfig(),
+	}
+
+	// Вызываем метод Confirm2FA с текущим кодом
+	code, err := utils.TOTPCode(secret, time.Now())
+	assert.NoError(t, err)
+	result, err := baseHandler.Confirm2FA(ctx, &grpc.TwoFactorCodeRequest{Code: code})
+
+	// Проверяем результат: коды выдаются пользователю, а хранятся только хеши
+	assert.NoError(t, err)
+	assert.Len(t, result.RecoveryCodes, 10)
+	assert.Len(t, hashes, 10)
+	assert.Equal(t, utils.HashToken(normalizeRecoveryCode(result.RecoveryCodes[0])), hashes[0])
+	assert.NotContains(t, hashes, result.RecoveryCodes[0])
+
+	// Проверяем, что моки были вызваны правильно
+	twoFARepo.AssertExpectations(t)
+}
+
+// TestBaseHandler_Enroll2FA тестирует метод Enroll2FA базового обработчика
+func TestBaseHandler_Enroll2FA(t *testing.T) {
+	// Test 1: Обязательную 2FA можно подключить по токену без прав, выданному при входе
+	t.Run("Enroll2FAWithLimitedToken", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+		ctx = context.WithValue(ctx, auth.LimitedKey, true)
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		twoFARepo := new(mocks.MockTwoFactorRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", ctx, 1).Return(&models.User{ID: 1, Email: "test@example.com"}, nil)
+		twoFARepo.On("GetTwoFactor", ctx, 1).Return((*models.TwoFactor)(nil), nil)
+		twoFARepo.On("SaveTwoFactorSecret", ctx, 1, mock.Anything).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			twoFARepo: twoFARepo,
+			cfg:       DefaultConfig(),
+		}
+
+		// Вызываем метод Enroll2FA
+		result, err := baseHandler.Enroll2FA(ctx, &grpc.Empty{})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.NotEmpty(t, result.Secret)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		twoFARepo.AssertExpectations(t)
+	})
+
+	// Test 2: Персональный токен не может подключить 2FA за пользователя
+	t.Run("Enroll2FAWithAPIKey", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 1)
+		ctx = context.WithValue(ctx, auth.APIKeyIDKey, 3)
+
+		// Создаем мок репозиторий
+		twoFARepo := new(mocks.MockTwoFactorRepository)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{twoFARepo: twoFARepo}
+
+		// Вызываем метод Enroll2FA
+		result, err := baseHandler.Enroll2FA(ctx, &grpc.Empty{})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		twoFARepo.AssertNotCalled(t, "SaveTwoFactorSecret", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"log"
 
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
 	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -19,12 +22,25 @@ func (bh *BaseHandler) AddUserPermissions(
 		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
 	}
 
+	// Проверяем срок действия выдачи
+	validity, err := parseGrantValidity(in.ValidFrom, in.ValidUntil)
+	if err != nil {
+		return nil, err
+	}
+
 	// Добавляем права пользователю
-	if err := bh.userRepo.AddUserPermissions(ctx, int(in.Id), convertInt32SliceToInt(in.PermissionIds)); err != nil {
+	if err := bh.userRepo.AddUserPermissions(ctx, int(in.Id), convertInt32SliceToInt(in.PermissionIds), validity); err != nil {
 		log.Printf("add user permissions failed, err:%v\n", err)
 		return nil, status.Error(codes.Internal, "Failed to add user permissions")
 	}
 
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(int(in.Id)),
+		Action:  "user.permissions_add",
+		Details: fmt.Sprintf("permissions %v%s", in.PermissionIds, grantValidityDetails(validity)),
+	})
+
 	// Получаем обновленного пользователя с правами
 	permissions, err := bh.userRepo.GetUserPermissions(ctx, int(in.Id))
 	if err != nil {
@@ -39,6 +55,8 @@ func (bh *BaseHandler) AddUserPermissions(
 			Name:        permission.Name,
 			Code:        permission.Code,
 			Description: permission.Description,
+			ValidFrom:   formatGrantTime(permission.ValidFrom),
+			ValidUntil:  formatGrantTime(permission.ValidUntil),
 		})
 	}
 
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"log"
 
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
 	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -19,12 +22,39 @@ func (bh *BaseHandler) AddUserRoles(
 		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
 	}
 
-	// Добавляем роли пользователю
-	if err := bh.userRepo.AddUserRoles(ctx, int(in.Id), convertInt32SliceToInt(in.RoleIds)); err != nil {
-		log.Printf("add user roles failed, err:%v\n", err)
-		return nil, status.Error(codes.Internal, "Failed to add user roles")
+	// Проверяем срок действия выдачи
+	validity, err := parseGrantValidity(in.ValidFrom, in.ValidUntil)
+	if err != nil {
+		return nil, err
 	}
 
+	// Ограничения разделения обязанностей проверяются в одной транзакции с выдачей,
+	// чтобы параллельная выдача тому же пользователю не прошла ту же проверку
+	roleIDs := convertInt32SliceToInt(in.RoleIds)
+	err = bh.inTx(ctx, func(ctx context.Context) error {
+		if err := bh.checkSoD(ctx, models.GrantOwnerUser, int(in.Id), roleIDs); err != nil {
+			return err
+		}
+
+		// Добавляем роли пользователю
+		if err := bh.userRepo.AddUserRoles(ctx, int(in.Id), roleIDs, validity); err != nil {
+			log.Printf("add user roles failed, err:%v\n", err)
+			return status.Error(codes.Internal, "Failed to add user roles")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(int(in.Id)),
+		Action:  "user.roles_add",
+		Details: fmt.Sprintf("roles %v%s", in.RoleIds, grantValidityDetails(validity)),
+	})
+
 	// Получаем обновленного пользователя с ролями
 	roles, err := bh.userRepo.GetUserRoles(ctx, int(in.Id))
 	if err != nil {
@@ -39,6 +69,8 @@ func (bh *BaseHandler) AddUserRoles(
 			Name:        role.Name,
 			Code:        role.Code,
 			Description: role.Description,
+			ValidFrom:   formatGrantTime(role.ValidFrom),
+			ValidUntil:  formatGrantTime(role.ValidUntil),
 		})
 	}
 
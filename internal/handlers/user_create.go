@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/passwordpolicy"
 	"github.com/LiFeAiR/crud-ai/internal/utils"
 	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
 	"google.golang.org/grpc/codes"
@@ -15,16 +16,25 @@ import (
 func (bh *BaseHandler) CreateUser(ctx context.Context, in *api_pb.UserCreateRequest) (out *api_pb.User, err error) {
 	var org *models.Organization
 	if in.GetOrganizationId() != 0 {
+		// Участником организации пользователя делает только администратор,
+		// иначе при регистрации можно войти в любую организацию
+		if err := checkAdmin(ctx); err != nil {
+			return nil, status.Error(codes.PermissionDenied, "Only administrator can set organization")
+		}
 		org = &models.Organization{ID: int(in.GetOrganizationId())}
 	}
 
 	// Validate request
-	// TODO валидация на сложный пароль
-	if in.Password == "" || len(in.Password) < 5 {
+	if in.Password == "" {
 		log.Println("Failed to validate password")
 		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
 	}
 
+	subject := passwordpolicy.Subject{Email: in.Email, Name: in.Name}
+	if err := bh.validatePassword(ctx, "password", in.Password, subject, int(in.GetOrganizationId())); err != nil {
+		return nil, err
+	}
+
 	// Хешируем пароль перед сохранением
 	hash, err := utils.HashPassword(in.Password)
 	if err != nil {
@@ -37,13 +47,34 @@ func (bh *BaseHandler) CreateUser(ctx context.Context, in *api_pb.UserCreateRequ
 		Email:        in.Email,
 		PasswordHash: hash,
 		Organization: org,
+		Status:       models.UserStatusActive,
 	}
 
-	// Используем репозиторий для создания пользователя
-	dbUser, err := bh.userRepo.CreateUser(ctx, &user)
+	// Роли организации не должны нарушать ограничения разделения обязанностей,
+	// пользователь создается и проверяется в одной транзакции
+	var dbUser *models.User
+	err = bh.inTx(ctx, func(ctx context.Context) error {
+		// Используем репозиторий для создания пользователя
+		created, err := bh.userRepo.CreateUser(ctx, &user)
+		if err != nil {
+			log.Printf("Failed to create user, err:%v\n", err)
+			return status.Error(codes.Internal, "Failed to create user")
+		}
+		dbUser = created
+
+		if org != nil {
+			return bh.checkSoDMembership(ctx, models.GrantOwnerUser, dbUser.ID, models.GrantOwnerOrganization, org.ID)
+		}
+
+		return nil
+	})
 	if err != nil {
-		log.Printf("Failed to create user, err:%v\n", err)
-		return nil, status.Error(codes.Internal, "Failed to create user")
+		return nil, err
+	}
+
+	// Отправляем письмо для подтверждения email, при ошибке его можно запросить повторно
+	if err := bh.sendEmailVerification(ctx, dbUser.ID, dbUser.Email); err != nil {
+		log.Printf("Failed to send email verification, err:%v\n", err)
 	}
 
 	var orgOut *api_pb.Organization
@@ -59,9 +90,11 @@ func (bh *BaseHandler) CreateUser(ctx context.Context, in *api_pb.UserCreateRequ
 
 	// Send response
 	return &api_pb.User{
-		Id:           int32(dbUser.ID),
-		Name:         user.Name,
-		Email:        user.Email,
-		Organization: orgOut,
+		Id:            int32(dbUser.ID),
+		Name:          user.Name,
+		Email:         user.Email,
+		Organization:  orgOut,
+		Status:        string(dbUser.Status),
+		EmailVerified: dbUser.EmailVerified,
 	}, nil
 }
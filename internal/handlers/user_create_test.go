@@ -7,9 +7,13 @@ import (
 
 	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
 	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
 	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // TestBaseHandler_CreateUser тестирует метод CreateUser базового обработчика
@@ -29,11 +33,26 @@ func TestBaseHandler_CreateUser(t *testing.T) {
 		}
 
 		// Определяем ожидаемое поведение мока
-		mockRepo.On("CreateUser", ctx, mock.Anything).Return(expectedUser, nil)
+		mockRepo.On("CreateUser", ctx, mock.MatchedBy(func(u *models.User) bool {
+			return !u.EmailVerified
+		})).Return(expectedUser, nil)
+
+		// Новому пользователю отправляется письмо для подтверждения email
+		tokenRepo := new(mocks.MockTokenRepository)
+		tokenRepo.On("DeleteUserTokens", ctx, 1, models.TokenPurposeEmailVerification).Return(nil)
+		tokenRepo.On("CreateToken", ctx, mock.MatchedBy(func(token *models.OneTimeToken) bool {
+			return token.UserID == 1 && token.Payload == "test@example.com"
+		})).Return(nil)
+		n := new(mocks.MockNotifier)
+		n.On("Notify", ctx, mock.MatchedBy(func(msg notifier.Message) bool {
+			return msg.To == "test@example.com"
+		})).Return(nil)
 
 		// Создаем базовый обработчик с моком
 		baseHandler := &BaseHandler{
-			userRepo: mockRepo,
+			userRepo:  mockRepo,
+			tokenRepo: tokenRepo,
+			notifier:  n,
 		}
 
 		// Вызываем метод CreateUser
@@ -49,10 +68,13 @@ func TestBaseHandler_CreateUser(t *testing.T) {
 		assert.Equal(t, int32(1), result.Id)
 		assert.Equal(t, "Test User", result.Name)
 		assert.Equal(t, "test@example.com", result.Email)
+		assert.False(t, result.EmailVerified)
 		//assert.Equal(t, nil, result.Organization)
 
 		// Проверяем, что мок был вызван правильно
 		mockRepo.AssertExpectations(t)
+		tokenRepo.AssertExpectations(t)
+		n.AssertExpectations(t)
 	})
 
 	// Test 2: Ошибка при неудачном создании в репозитории
@@ -83,4 +105,58 @@ func TestBaseHandler_CreateUser(t *testing.T) {
 		// Проверяем, что мок был вызван правильно
 		mockRepo.AssertExpectations(t)
 	})
+
+	// Test 3: Пароль сохраняется хешем текущего алгоритма
+	t.Run("CreateUserPasswordHash", func(t *testing.T) {
+		// Создаем мок репозиторий
+		mockRepo := new(mocks.MockUserRepository)
+
+		// Определяем ожидаемое поведение мока
+		var hash string
+		mockRepo.On("CreateUser", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			hash = args.Get(1).(*models.User).PasswordHash
+		}).Return((*models.User)(nil), errors.New("create failed"))
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: mockRepo,
+		}
+
+		// Вызываем метод CreateUser
+		_, _ = baseHandler.CreateUser(ctx, &grpc.UserCreateRequest{
+			Name:     "Test User",
+			Email:    "test@example.com",
+			Password: "password123",
+		})
+
+		// Алгоритм и параметры сохраняются в хеше
+		assert.Regexp(t, `^\$argon2id\$v=19\$m=65536,t=3,p=4\$`, hash)
+		assert.True(t, utils.CheckPassword("password123", hash))
+		assert.False(t, utils.CheckPassword("password124", hash))
+		assert.False(t, utils.PasswordNeedsRehash(hash))
+	})
+
+	// Test 4: При регистрации нельзя стать участником организации, организацию назначает администратор
+	t.Run("CreateUserOrganizationDenied", func(t *testing.T) {
+		// Создаем мок репозиторий
+		mockRepo := new(mocks.MockUserRepository)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: mockRepo,
+		}
+
+		// Вызываем метод CreateUser
+		result, err := baseHandler.CreateUser(ctx, &grpc.UserCreateRequest{
+			Name:           "Test User",
+			Email:          "test@example.com",
+			Password:       "password123",
+			OrganizationId: 3,
+		})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		mockRepo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+	})
 }
@@ -39,6 +39,8 @@ func (bh *BaseHandler) DeleteUserPermissions(
 			Name:        permission.Name,
 			Code:        permission.Code,
 			Description: permission.Description,
+			ValidFrom:   formatGrantTime(permission.ValidFrom),
+			ValidUntil:  formatGrantTime(permission.ValidUntil),
 		})
 	}
 
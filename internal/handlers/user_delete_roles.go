@@ -39,6 +39,8 @@ func (bh *BaseHandler) DeleteUserRoles(
 			Name:        role.Name,
 			Code:        role.Code,
 			Description: role.Description,
+			ValidFrom:   formatGrantTime(role.ValidFrom),
+			ValidUntil:  formatGrantTime(role.ValidUntil),
 		})
 	}
 
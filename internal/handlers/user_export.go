@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExportUserData выгружает все данные, которые хранятся о пользователе
+func (bh *BaseHandler) ExportUserData(
+	ctx context.Context,
+	in *api_pb.ExportUserDataRequest,
+) (out *api_pb.ExportUserDataResponse, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Выгрузку может получить сам пользователь или администратор
+	err = checkPermissions(ctx, in.Id)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
+	}
+
+	// Проверяем, существует ли пользователь
+	_, err = bh.userRepo.GetUserByID(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to get user, err:%v\n", err)
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	bundle, err := bh.exporter.Collect(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to collect user data, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to export user data")
+	}
+
+	// Формируем ответ в запрошенном формате
+	if in.Zip {
+		data, err := bundle.Zip()
+		if err != nil {
+			log.Printf("Failed to zip user data, err:%v\n", err)
+			return nil, status.Error(codes.Internal, "Failed to export user data")
+		}
+
+		return &api_pb.ExportUserDataResponse{
+			FileName:    bundle.FileName() + ".zip",
+			ContentType: "application/zip",
+			Data:        data,
+		}, nil
+	}
+
+	data, err := bundle.JSON()
+	if err != nil {
+		log.Printf("Failed to marshal user data, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to export user data")
+	}
+
+	return &api_pb.ExportUserDataResponse{
+		FileName:    bundle.FileName() + ".json",
+		ContentType: "application/json",
+		Data:        data,
+	}, nil
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/export"
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBaseHandler_ExportUserData тестирует метод ExportUserData базового обработчика
+func TestBaseHandler_ExportUserData(t *testing.T) {
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, auth.UserIDKey, 1)
+	ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+
+	revokedAt := time.Now()
+
+	// Подготавливаем тестового пользователя
+	testUser := &models.User{
+		ID:           1,
+		Name:         "Test User",
+		Email:        "test@example.com",
+		PasswordHash: "secret-hash",
+	}
+
+	// Test 1: Успешная выгрузка в JSON
+	t.Run("ExportUserDataJSON", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		roleRepo := new(mocks.MockRoleRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+		sessionRepo := new(mocks.MockSessionRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", ctx, 1).Return(testUser, nil)
+		userRepo.On("GetUserRoles", ctx, 1).Return([]*models.Role{{ID: 2, Name: "Manager", Code: "manager"}}, nil)
+		roleRepo.On("GetRoleWithPermissions", ctx, 2).Return(&models.Role{
+			ID:          2,
+			Name:        "Manager",
+			Code:        "manager",
+			Permissions: []models.Permission{{ID: 3, Code: "read"}},
+		}, nil)
+		userRepo.On("GetUserDirectPermissions", ctx, 1).Return([]*models.Permission{{ID: 4, Code: "write"}}, nil)
+		userRepo.On("GetUserPermissions", ctx, 1).Return([]*models.Permission{{ID: 3, Code: "read"}, {ID: 4, Code: "write"}}, nil)
+		auditRepo.On("GetUserAuditEntries", ctx, 1).Return([]*models.AuditEntry{
+			{ID: 5, Action: "user.suspend"},
+			{ID: 6, Action: "user.tariff_set", Details: "tariff 3"},
+		}, nil)
+		sessionRepo.On("GetUserSessionHistory", ctx, 1).Return([]*models.Session{
+			{ID: "active-jti", UserID: 1, IP: "10.0.0.1"},
+			{ID: "revoked-jti", UserID: 1, IP: "10.0.0.2", RevokedAt: &revokedAt},
+		}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			exporter: export.NewExporter(export.UserSections(userRepo, orgRepo, roleRepo, auditRepo, sessionRepo)...),
+		}
+
+		// Вызываем метод ExportUserData
+		result, err := baseHandler.ExportUserData(ctx, &grpc.ExportUserDataRequest{Id: 1})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.NotNil(t, result)
+		assert.Equal(t, "application/json", result.ContentType)
+		assert.Equal(t, "user_1_export.json", result.FileName)
+		assert.NotContains(t, string(result.Data), "secret-hash")
+
+		var bundle map[string]any
+		assert.NoError(t, json.Unmarshal(result.Data, &bundle))
+		sections, ok := bundle["sections"].(map[string]any)
+		assert.True(t, ok)
+		for _, name := range []string{"profile", "organizations", "roles", "permissions", "tariffs", "sessions", "audit"} {
+			assert.Contains(t, sections, name)
+		}
+
+		// История тарифов строится по журналу аудита
+		tariffs, ok := sections["tariffs"].(map[string]any)
+		assert.True(t, ok)
+		assert.Nil(t, tariffs["current"])
+		history, ok := tariffs["history"].([]any)
+		assert.True(t, ok)
+		assert.Len(t, history, 1)
+
+		// Выгружаются и завершенные сессии
+		sessions, ok := sections["sessions"].([]any)
+		assert.True(t, ok)
+		assert.Len(t, sessions, 2)
+		assert.Equal(t, "revoked-jti", sessions[1].(map[string]any)["id"])
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		roleRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+		sessionRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Разделы, которые добавляют подсистемы, хранящие данные пользователя
+	t.Run("ExportUserDataRegisteredSections", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+		twoFARepo := new(mocks.MockTwoFactorRepository)
+		oidcRepo := new(mocks.MockOIDCRepository)
+		apiKeyRepo := new(mocks.MockAPIKeyRepository)
+		accessRepo := new(mocks.MockAccessRequestRepository)
+		reviewRepo := new(mocks.MockAccessReviewRepository)
+		attemptRepo := new(mocks.MockLoginAttemptRepository)
+		oauthRepo := new(mocks.MockOAuthRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", ctx, 1).Return(testUser, nil)
+		twoFARepo.On("GetTwoFactor", ctx, 1).Return(&models.TwoFactor{UserID: 1, Secret: "JBSWY3DPEHPK3PXP", Enabled: true}, nil)
+		twoFARepo.On("GetRecoveryCodes", ctx, 1).Return([]*models.RecoveryCode{
+			{ID: 1, CodeHash: "hash-1"},
+			{ID: 2, CodeHash: "hash-2", UsedAt: &revokedAt},
+		}, nil)
+		oidcRepo.On("GetUserOIDCIdentities", ctx, 1).Return([]*models.OIDCIdentity{
+			{ID: 3, ProviderID: 1, UserID: 1, Subject: "idp-subject"},
+		}, nil)
+		apiKeyRepo.On("GetAPIKeys", ctx, 1).Return([]*models.APIKey{
+			{ID: 4, UserID: 1, Name: "ci", Prefix: "pat_abc", KeyHash: "key-hash"},
+		}, nil)
+		accessRepo.On("GetUserAccessRequests", ctx, 1).Return([]*models.AccessRequest{
+			{ID: 5, UserID: 1, RoleID: 2, Status: models.AccessRequestPending},
+		}, nil)
+		reviewRepo.On("GetUserAccessReviewItems", ctx, 1).Return([]*models.AccessReviewItem{
+			{ID: 6, ReviewID: 1, OwnerType: models.GrantOwnerUser, OwnerID: 1},
+		}, nil)
+		attemptRepo.On("GetUserLoginAttempts", ctx, 1).Return([]*models.LoginAttempt{
+			{ID: 7, Email: "test@example.com", IP: "10.0.0.1", Success: false},
+		}, nil)
+		oauthRepo.On("GetUserOAuthConsents", ctx, 1).Return([]*models.OAuthConsent(nil), nil)
+		orgRepo.On("GetUserAdminOrganizations", ctx, 1).Return([]int{8}, nil)
+
+		// Создаем базовый обработчик с моком, разделы добавляются так же, как в NewBaseHandler
+		exporter := export.NewExporter()
+		exporter.Register(
+			export.TwoFactorSection(twoFARepo),
+			export.OIDCIdentitySection(oidcRepo),
+			export.APIKeySection(apiKeyRepo),
+			export.AccessRequestSection(accessRepo),
+			export.AccessReviewSection(reviewRepo),
+			export.LoginAttemptSection(attemptRepo),
+			export.OAuthConsentSection(oauthRepo),
+			export.OrganizationAdminSection(orgRepo),
+		)
+		baseHandler := &BaseHandler{userRepo: userRepo, exporter: exporter}
+
+		// Вызываем метод ExportUserData
+		result, err := baseHandler.ExportUserData(ctx, &grpc.ExportUserDataRequest{Id: 1})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.NotContains(t, string(result.Data), "key-hash")
+
+		var bundle map[string]any
+		assert.NoError(t, json.Unmarshal(result.Data, &bundle))
+		sections, ok := bundle["sections"].(map[string]any)
+		assert.True(t, ok)
+
+		// Секрет и хеши резервных кодов выгружаются, хотя в API не отдаются
+		twoFactor, ok := sections["two_factor"].(map[string]any)
+		assert.True(t, ok)
+		assert.Equal(t, "JBSWY3DPEHPK3PXP", twoFactor["secret"])
+		assert.Len(t, twoFactor["recovery_codes"], 2)
+
+		for name, count := range map[string]int{
+			"oidc_identities":     1,
+			"api_keys":            1,
+			"access_requests":     1,
+			"access_review_items": 1,
+			"login_attempts":      1,
+			"oauth_consents":      0,
+			"organization_admins": 1,
+		} {
+			items, ok := sections[name].([]any)
+			assert.True(t, ok, name)
+			assert.Len(t, items, count, name)
+		}
+
+		// Проверяем, что моки были вызваны правильно
+		twoFARepo.AssertExpectations(t)
+		oidcRepo.AssertExpectations(t)
+		apiKeyRepo.AssertExpectations(t)
+		accessRepo.AssertExpectations(t)
+		reviewRepo.AssertExpectations(t)
+		attemptRepo.AssertExpectations(t)
+		oauthRepo.AssertExpectations(t)
+		orgRepo.AssertExpectations(t)
+	})
+
+	// Test 3: Успешная выгрузка в zip архиве
+	t.Run("ExportUserDataZip", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		userRepo.On("GetUserByID", ctx, 1).Return(testUser, nil)
+
+		// Создаем базовый обработчик с одним разделом выгрузки
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			exporter: export.NewExporter(export.Section{
+				Name: "custom",
+				Collect: func(ctx context.Context, userID int) (any, error) {
+					return []string{"value"}, nil
+				},
+			}),
+		}
+
+		// Вызываем метод ExportUserData
+		result, err := baseHandler.ExportUserData(ctx, &grpc.ExportUserDataRequest{Id: 1, Zip: true})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, "application/zip", result.ContentType)
+
+		zr, err := zip.NewReader(bytes.NewReader(result.Data), int64(len(result.Data)))
+		assert.NoError(t, err)
+		assert.Len(t, zr.File, 1)
+		assert.Equal(t, "user_1_export.json", zr.File[0].Name)
+	})
+
+	// Test 4: Чужие данные недоступны без прав администратора
+	t.Run("ExportUserDataForbidden", func(t *testing.T) {
+		// Создаем базовый обработчик без мока (не нужен для этого теста)
+		baseHandler := &BaseHandler{}
+
+		// Вызываем метод ExportUserData для другого пользователя
+		result, err := baseHandler.ExportUserData(ctx, &grpc.ExportUserDataRequest{Id: 2})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = Unauthenticated desc = Invalid credentials", err.Error())
+	})
+}
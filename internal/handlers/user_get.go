@@ -52,16 +52,21 @@ func (bh *BaseHandler) GetUser(ctx context.Context, in *api_pb.Id) (out *api_pb.
 			Name:        permission.Name,
 			Code:        permission.Code,
 			Description: permission.Description,
+			ValidFrom:   formatGrantTime(permission.ValidFrom),
+			ValidUntil:  formatGrantTime(permission.ValidUntil),
 		})
 	}
 
 	// Возвращаем ответ
 	return &api_pb.User{
-		Id:           int32(user.ID),
-		Name:         user.Name,
-		Email:        user.Email,
-		Organization: orgOut,
-		TariffId:     int32(utils.FromPtr(user.TariffID)),
-		Permissions:  permissionsOut,
+		Id:            int32(user.ID),
+		Name:          user.Name,
+		Email:         user.Email,
+		Organization:  orgOut,
+		TariffId:      int32(utils.FromPtr(user.TariffID)),
+		Permissions:   permissionsOut,
+		Status:        string(user.Status),
+		EmailVerified: user.EmailVerified,
+		PendingEmail:  utils.FromPtr(user.PendingEmail),
 	}, nil
 }
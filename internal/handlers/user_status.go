@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SuspendUser приостанавливает учетную запись пользователя
+func (bh *BaseHandler) SuspendUser(ctx context.Context, in *api_pb.UserStatusRequest) (out *api_pb.User, err error) {
+	return bh.changeUserStatus(ctx, in, models.UserStatusSuspended, "user.suspend")
+}
+
+// ReactivateUser возвращает учетную запись пользователя в активный статус
+func (bh *BaseHandler) ReactivateUser(ctx context.Context, in *api_pb.UserStatusRequest) (out *api_pb.User, err error) {
+	return bh.changeUserStatus(ctx, in, models.UserStatusActive, "user.reactivate")
+}
+
+// UnlockUser снимает временную блокировку входа после неудачных попыток, не дожидаясь ее окончания
+func (bh *BaseHandler) UnlockUser(ctx context.Context, in *api_pb.UserStatusRequest) (out *api_pb.User, err error) {
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 || strings.TrimSpace(in.Reason) == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Снимать блокировку может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Проверяем, существует ли пользователь
+	user, err := bh.userRepo.GetUserByID(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to get user, err:%v\n", err)
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	// Сбрасываем счетчик неудачных попыток входа
+	if err := bh.attemptRepo.ClearFailedLoginAttempts(ctx, throttleKey(user.Email)); err != nil {
+		log.Printf("Failed to clear failed login attempts, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to unlock user")
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(user.ID),
+		Action:  "user.unlock",
+		Reason:  in.Reason,
+	})
+
+	return userStatusResponse(user, user.Status), nil
+}
+
+// changeUserStatus переводит пользователя в новый статус с проверкой допустимости перехода
+func (bh *BaseHandler) changeUserStatus(
+	ctx context.Context,
+	in *api_pb.UserStatusRequest,
+	to models.UserStatus,
+	action string,
+) (*api_pb.User, error) {
+	// Проверяем входные данные
+	if in == nil || in.Id == 0 || strings.TrimSpace(in.Reason) == "" {
+		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	}
+
+	// Менять статус может только администратор
+	if err := checkAdmin(ctx); err != nil {
+		return nil, status.Error(codes.PermissionDenied, "Permission denied")
+	}
+
+	// Администратор не может изменить статус самому себе
+	actorID := currentUserID(ctx)
+	if actorID != nil && *actorID == int(in.Id) {
+		return nil, status.Error(codes.FailedPrecondition, "Cannot change own status")
+	}
+
+	// Проверяем, существует ли пользователь
+	user, err := bh.userRepo.GetUserByID(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to get user, err:%v\n", err)
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	// Проверяем, допустим ли переход
+	if !user.Status.CanTransitionTo(to) {
+		return nil, status.Errorf(codes.FailedPrecondition, "Cannot change status from %s to %s", user.Status, to)
+	}
+
+	if err := bh.userRepo.SetUserStatus(ctx, user.ID, to, in.Reason); err != nil {
+		log.Printf("Failed to set user status, err:%v\n", err)
+		return nil, status.Error(codes.Internal, "Failed to change user status")
+	}
+
+	// Пользователь, потерявший доступ, выходит из всех сессий
+	if to != models.UserStatusActive {
+		bh.revokeUserSessions(ctx, user.ID)
+	}
+
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: actorID,
+		UserID:  utils.Ptr(user.ID),
+		Action:  action,
+		Reason:  in.Reason,
+		Details: string(user.Status) + " -> " + string(to),
+	})
+
+	// Возвращаем ответ
+	return userStatusResponse(user, to), nil
+}
+
+// userStatusResponse формирует ответ с пользователем в новом статусе
+func userStatusResponse(user *models.User, userStatus models.UserStatus) *api_pb.User {
+	var orgOut *api_pb.Organization
+	if user.Organization != nil {
+		orgOut = &api_pb.Organization{
+			Id: int32(user.Organization.ID),
+		}
+	}
+
+	return &api_pb.User{
+		Id:           int32(user.ID),
+		Name:         user.Name,
+		Email:        user.Email,
+		Organization: orgOut,
+		TariffId:     int32(utils.FromPtr(user.TariffID)),
+		Status:       string(userStatus),
+	}
+}
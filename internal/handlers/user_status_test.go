@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
+	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestBaseHandler_SuspendUser тестирует методы SuspendUser и ReactivateUser базового обработчика
+func TestBaseHandler_SuspendUser(t *testing.T) {
+	adminCtx := context.Background()
+	adminCtx = context.WithValue(adminCtx, auth.UserIDKey, 100)
+	adminCtx = context.WithValue(adminCtx, auth.IsAdminKey, true)
+
+	// Test 1: Успешная приостановка пользователя
+	t.Run("SuspendUserSuccess", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+		sessionRepo := new(mocks.MockSessionRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", adminCtx, 1).Return(&models.User{ID: 1, Name: "Test User", Status: models.UserStatusActive}, nil)
+		userRepo.On("SetUserStatus", adminCtx, 1, models.UserStatusSuspended, "fraud").Return(nil)
+		sessionRepo.On("RevokeUserSessions", adminCtx, 1, "").Return(2, nil)
+		auditRepo.On("AddAuditEntry", adminCtx, mock.MatchedBy(func(e *models.AuditEntry) bool {
+			return e.Action == "user.suspend" && *e.ActorID == 100 && *e.UserID == 1 && e.Reason == "fraud"
+		})).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:    userRepo,
+			auditRepo:   auditRepo,
+			sessionRepo: sessionRepo,
+		}
+
+		// Вызываем метод SuspendUser
+		result, err := baseHandler.SuspendUser(adminCtx, &grpc.UserStatusRequest{Id: 1, Reason: "fraud"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, "suspended", result.Status)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+		sessionRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Недопустимый переход статуса
+	t.Run("ReactivateActiveUser", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		userRepo.On("GetUserByID", adminCtx, 1).Return(&models.User{ID: 1, Status: models.UserStatusActive}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+		}
+
+		// Вызываем метод ReactivateUser
+		result, err := baseHandler.ReactivateUser(adminCtx, &grpc.UserStatusRequest{Id: 1, Reason: "ok"})
+
+		// Проверяем результат
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = FailedPrecondition desc = Cannot change status from active to active", err.Error())
+		userRepo.AssertNotCalled(t, "SetUserStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	// Test 3: Без причины запрос отклоняется
+	t.Run("SuspendUserWithoutReason", func(t *testing.T) {
+		baseHandler := &BaseHandler{}
+
+		result, err := baseHandler.SuspendUser(adminCtx, &grpc.UserStatusRequest{Id: 1, Reason: " "})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = InvalidArgument desc = Invalid argument", err.Error())
+	})
+
+	// Test 4: Обычный пользователь не может менять статус
+	t.Run("SuspendUserNotAdmin", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 2)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+		baseHandler := &BaseHandler{}
+
+		result, err := baseHandler.SuspendUser(ctx, &grpc.UserStatusRequest{Id: 1, Reason: "fraud"})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = PermissionDenied desc = Permission denied", err.Error())
+	})
+}
+
+// TestBaseHandler_UnlockUser тестирует метод UnlockUser базового обработчика
+func TestBaseHandler_UnlockUser(t *testing.T) {
+	adminCtx := context.Background()
+	adminCtx = context.WithValue(adminCtx, auth.UserIDKey, 100)
+	adminCtx = context.WithValue(adminCtx, auth.IsAdminKey, true)
+
+	// Test 1: Разблокировка сбрасывает неудачные попытки входа, статус пользователя не меняется
+	t.Run("UnlockUser", func(t *testing.T) {
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		auditRepo := new(mocks.MockAuditRepository)
+		attemptRepo := new(mocks.MockLoginAttemptRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", adminCtx, 1).Return(&models.User{ID: 1, Email: "Test@Example.com", Status: models.UserStatusActive}, nil)
+		attemptRepo.On("ClearFailedLoginAttempts", adminCtx, "test@example.com").Return(nil)
+		auditRepo.On("AddAuditEntry", adminCtx, mock.MatchedBy(func(e *models.AuditEntry) bool {
+			return e.Action == "user.unlock" && *e.UserID == 1
+		})).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:    userRepo,
+			auditRepo:   auditRepo,
+			attemptRepo: attemptRepo,
+		}
+
+		// Вызываем метод UnlockUser
+		result, err := baseHandler.UnlockUser(adminCtx, &grpc.UserStatusRequest{Id: 1, Reason: "verified"})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, "active", result.Status)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		auditRepo.AssertExpectations(t)
+		attemptRepo.AssertExpectations(t)
+	})
+
+	// Test 2: Обычный пользователь не может снимать блокировку
+	t.Run("UnlockUserNotAdmin", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, 2)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+		baseHandler := &BaseHandler{}
+
+		result, err := baseHandler.UnlockUser(ctx, &grpc.UserStatusRequest{Id: 1, Reason: "verified"})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Equal(t, "rpc error: code = PermissionDenied desc = Permission denied", err.Error())
+	})
+}
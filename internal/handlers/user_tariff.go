@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"log"
 
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
 	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -33,13 +36,33 @@ func (bh *BaseHandler) AddUserTariff(ctx context.Context, in *api_pb.UserTariffR
 		return nil, status.Error(codes.NotFound, "Tariff not found")
 	}
 
-	// Устанавливаем тариф пользователю
-	err = bh.userRepo.SetUserTariff(ctx, int(in.Id), &in.TariffId)
+	// Роли тарифа не должны нарушать ограничения разделения обязанностей,
+	// проверка выполняется в одной транзакции с изменением тарифа
+	err = bh.inTx(ctx, func(ctx context.Context) error {
+		err := bh.checkSoDMembership(ctx, models.GrantOwnerUser, int(in.Id), models.GrantOwnerTariff, int(in.TariffId))
+		if err != nil {
+			return err
+		}
+
+		// Устанавливаем тариф пользователю
+		if err := bh.userRepo.SetUserTariff(ctx, int(in.Id), &in.TariffId); err != nil {
+			log.Printf("Failed to set user tariff, err:%v\n", err)
+			return status.Error(codes.Internal, "Failed to set user tariff")
+		}
+
+		return nil
+	})
 	if err != nil {
-		log.Printf("Failed to set user tariff, err:%v\n", err)
-		return nil, status.Error(codes.Internal, "Failed to set user tariff")
+		return nil, err
 	}
 
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(int(in.Id)),
+		Action:  "user.tariff_set",
+		Details: fmt.Sprintf("tariff %d", in.TariffId),
+	})
+
 	// Получаем тариф для ответа
 	tariff, err := bh.tariffRepo.GetTariffByID(ctx, int(in.TariffId))
 	if err != nil {
@@ -82,13 +105,33 @@ func (bh *BaseHandler) UpdateUserTariff(ctx context.Context, in *api_pb.UserTari
 		return nil, status.Error(codes.NotFound, "Tariff not found")
 	}
 
-	// Обновляем тариф пользователя
-	err = bh.userRepo.SetUserTariff(ctx, int(in.Id), &in.TariffId)
+	// Роли тарифа не должны нарушать ограничения разделения обязанностей,
+	// проверка выполняется в одной транзакции с изменением тарифа
+	err = bh.inTx(ctx, func(ctx context.Context) error {
+		err := bh.checkSoDMembership(ctx, models.GrantOwnerUser, int(in.Id), models.GrantOwnerTariff, int(in.TariffId))
+		if err != nil {
+			return err
+		}
+
+		// Обновляем тариф пользователя
+		if err := bh.userRepo.SetUserTariff(ctx, int(in.Id), &in.TariffId); err != nil {
+			log.Printf("Failed to update user tariff, err:%v\n", err)
+			return status.Error(codes.Internal, "Failed to update user tariff")
+		}
+
+		return nil
+	})
 	if err != nil {
-		log.Printf("Failed to update user tariff, err:%v\n", err)
-		return nil, status.Error(codes.Internal, "Failed to update user tariff")
+		return nil, err
 	}
 
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(int(in.Id)),
+		Action:  "user.tariff_set",
+		Details: fmt.Sprintf("tariff %d", in.TariffId),
+	})
+
 	// Получаем тариф для ответа
 	tariff, err := bh.tariffRepo.GetTariffByID(ctx, int(in.TariffId))
 	if err != nil {
@@ -128,5 +171,11 @@ func (bh *BaseHandler) DeleteUserTariff(ctx context.Context, in *api_pb.UserTari
 		return nil, status.Error(codes.Internal, "Failed to delete user tariff")
 	}
 
+	bh.audit(ctx, &models.AuditEntry{
+		ActorID: currentUserID(ctx),
+		UserID:  utils.Ptr(int(in.Id)),
+		Action:  "user.tariff_delete",
+	})
+
 	return &api_pb.Empty{}, nil
 }
@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/passwordpolicy"
 	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
 	"github.com/LiFeAiR/crud-ai/internal/utils"
 	api_pb "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
@@ -25,20 +26,51 @@ func (bh *BaseHandler) UpdateUser(ctx context.Context, in *api_pb.UserUpdateRequ
 		return nil, status.Error(codes.Unauthenticated, "Invalid credentials")
 	}
 
+	// Получаем текущие данные пользователя
+	current, err := bh.userRepo.GetUserByID(ctx, int(in.Id))
+	if err != nil {
+		log.Printf("Failed to get user, err:%v\n", err)
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
 	var org *models.Organization
 	if in.GetOrganizationId() != 0 {
 		org = &models.Organization{ID: int(in.GetOrganizationId())}
 	}
 
-	// TODO валидация на сложный пароль
-	if in.Password != "" && len(in.Password) < 5 {
-		log.Println("Failed to validate password")
-		return nil, status.Error(codes.InvalidArgument, "Invalid argument")
+	// Организацию пользователя меняет только администратор: от нее зависят права и администраторы,
+	// утверждающие запросы доступа. Без organization_id пользователь остается в текущей организации
+	if checkAdmin(ctx) != nil {
+		currentOrgID := 0
+		if current.Organization != nil {
+			currentOrgID = current.Organization.ID
+		}
+
+		if org != nil && org.ID != currentOrgID {
+			return nil, status.Error(codes.PermissionDenied, "Only administrator can change organization")
+		}
+
+		if currentOrgID != 0 {
+			org = &models.Organization{ID: currentOrgID}
+		}
 	}
 
-	// Если указан пароль, хешируем его перед сохранением
+	// Если указан пароль, проверяем его по политике и хешируем перед сохранением
 	var passwordHash string
 	if in.Password != "" {
+		orgID := int(in.GetOrganizationId())
+		if orgID == 0 && current.Organization != nil {
+			orgID = current.Organization.ID
+		}
+
+		subject := passwordpolicy.Subject{Email: current.Email, Name: in.Name}
+		if subject.Name == "" {
+			subject.Name = current.Name
+		}
+		if err := bh.validatePassword(ctx, "password", in.Password, subject, orgID); err != nil {
+			return nil, err
+		}
+
 		hash, err := utils.HashPassword(in.Password)
 		if err != nil {
 			log.Printf("Failed to hash password, err:%v\n", err)
@@ -47,19 +79,60 @@ func (bh *BaseHandler) UpdateUser(ctx context.Context, in *api_pb.UserUpdateRequ
 		passwordHash = hash
 	}
 
+	// Новый email вступает в силу только после подтверждения
+	pendingEmail := current.PendingEmail
+	if in.Email != "" && in.Email != current.Email {
+		existing, err := bh.userRepo.GetUserByEmail(ctx, in.Email)
+		if err != nil {
+			log.Printf("Failed to get user by email, err:%v\n", err)
+			return nil, status.Error(codes.Internal, "Failed to update user")
+		}
+		if existing != nil {
+			return nil, status.Error(codes.AlreadyExists, "Email already in use")
+		}
+		pendingEmail = &in.Email
+	}
+
 	// Преобразуем запрос в модель
 	user := models.User{
 		ID:           int(in.Id),
 		Name:         in.Name,
-		Email:        in.Email,
+		Email:        current.Email,
 		PasswordHash: passwordHash,
 		Organization: org,
 	}
 
-	// Используем репозиторий для обновления пользователя
-	if err := bh.userRepo.UpdateUser(ctx, &user); err != nil {
-		log.Printf("update user failed, err:%v\n", err)
-		return nil, status.Error(codes.Internal, "Failed to update user")
+	// Роли новой организации не должны нарушать ограничения разделения обязанностей,
+	// проверка выполняется в одной транзакции с переходом
+	err = bh.inTx(ctx, func(ctx context.Context) error {
+		if org != nil && (current.Organization == nil || current.Organization.ID != org.ID) {
+			err := bh.checkSoDMembership(ctx, models.GrantOwnerUser, user.ID, models.GrantOwnerOrganization, org.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Используем репозиторий для обновления пользователя
+		if err := bh.userRepo.UpdateUser(ctx, &user); err != nil {
+			log.Printf("update user failed, err:%v\n", err)
+			return status.Error(codes.Internal, "Failed to update user")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if pendingEmail != current.PendingEmail {
+		if err := bh.userRepo.SetUserPendingEmail(ctx, user.ID, *pendingEmail); err != nil {
+			log.Printf("Failed to set pending email, err:%v\n", err)
+			return nil, status.Error(codes.Internal, "Failed to update user")
+		}
+
+		if err := bh.sendEmailVerification(ctx, user.ID, *pendingEmail); err != nil {
+			log.Printf("Failed to send email verification, err:%v\n", err)
+		}
 	}
 
 	var orgOut *api_pb.Organization
@@ -75,13 +148,17 @@ func (bh *BaseHandler) UpdateUser(ctx context.Context, in *api_pb.UserUpdateRequ
 
 	// Возвращаем ответ
 	return &api_pb.User{
-		Id:           int32(user.ID),
-		Name:         user.Name,
-		Email:        user.Email,
-		Organization: orgOut,
+		Id:            int32(user.ID),
+		Name:          user.Name,
+		Email:         user.Email,
+		Organization:  orgOut,
+		EmailVerified: current.EmailVerified,
+		PendingEmail:  utils.FromPtr(pendingEmail),
 	}, nil
 }
 
+// checkPermissions проверяет, что запрос выполняет администратор или сам пользователь id.
+// Сам пользователь управляет учетной записью только по токену входа с полными правами
 func checkPermissions(ctx context.Context, id int32) error {
 	userID, ok := ctx.Value(auth.UserIDKey).(int)
 	if !ok {
@@ -93,9 +170,13 @@ func checkPermissions(ctx context.Context, id int32) error {
 		return errors.New("auth.IsAdminKey")
 	}
 
-	if userID == int(id) || isAdmin {
+	if isAdmin {
 		return nil
 	}
 
+	if userID == int(id) {
+		return requireInteractiveSession(ctx)
+	}
+
 	return errors.New("checkPermissions.Unauthenticated")
 }
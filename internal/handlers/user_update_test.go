@@ -7,10 +7,13 @@ import (
 
 	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
 	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
 	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
 	"github.com/LiFeAiR/crud-ai/pkg/server/grpc"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // TestBaseHandler_UpdateUser тестирует метод UpdateUser базового обработчика
@@ -26,14 +29,22 @@ func TestBaseHandler_UpdateUser(t *testing.T) {
 		orgRepo := new(mocks.MockOrganizationRepository)
 
 		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", ctx, 1).Return(&models.User{
+			ID:            1,
+			Email:         "updated@example.com",
+			EmailVerified: true,
+			Organization:  &models.Organization{ID: 1},
+		}, nil)
 		userRepo.On("UpdateUser", ctx, mock.Anything).Return(nil)
 		var org *models.Organization
 		orgRepo.On("GetOrganizationByID", ctx, mock.Anything).Return(org, nil)
+		orgRepo.On("GetOrganizationSettings", ctx, 1).Return(&models.OrganizationSettings{OrganizationID: 1}, nil)
 
 		// Создаем базовый обработчик с моком
 		baseHandler := &BaseHandler{
 			userRepo: userRepo,
 			orgRepo:  orgRepo,
+			cfg:      DefaultConfig(),
 		}
 
 		// Вызываем метод UpdateUser
@@ -57,7 +68,58 @@ func TestBaseHandler_UpdateUser(t *testing.T) {
 		userRepo.AssertExpectations(t)
 	})
 
-	// Test 2: Ошибка при отсутствии ID в запросе
+	// Test 2: Новый email вступает в силу только после подтверждения
+	t.Run("UpdateUserEmailChange", func(t *testing.T) {
+		ctx := context.Background()
+		ctx = context.WithValue(ctx, auth.UserIDKey, 1)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		tokenRepo := new(mocks.MockTokenRepository)
+		n := new(mocks.MockNotifier)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", ctx, 1).Return(&models.User{ID: 1, Email: "old@example.com", EmailVerified: true}, nil)
+		userRepo.On("GetUserByEmail", ctx, "new@example.com").Return((*models.User)(nil), nil)
+		userRepo.On("UpdateUser", ctx, mock.MatchedBy(func(u *models.User) bool {
+			return u.Email == "old@example.com"
+		})).Return(nil)
+		userRepo.On("SetUserPendingEmail", ctx, 1, "new@example.com").Return(nil)
+		tokenRepo.On("DeleteUserTokens", ctx, 1, models.TokenPurposeEmailVerification).Return(nil)
+		tokenRepo.On("CreateToken", ctx, mock.MatchedBy(func(token *models.OneTimeToken) bool {
+			return token.Payload == "new@example.com"
+		})).Return(nil)
+		n.On("Notify", ctx, mock.MatchedBy(func(msg notifier.Message) bool {
+			return msg.To == "new@example.com"
+		})).Return(nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo:  userRepo,
+			tokenRepo: tokenRepo,
+			notifier:  n,
+		}
+
+		// Вызываем метод UpdateUser
+		result, err := baseHandler.UpdateUser(ctx, &grpc.UserUpdateRequest{
+			Id:    1,
+			Name:  "Updated User",
+			Email: "new@example.com",
+		})
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, "old@example.com", result.Email)
+		assert.Equal(t, "new@example.com", result.PendingEmail)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		tokenRepo.AssertExpectations(t)
+		n.AssertExpectations(t)
+	})
+
+	// Test 3: Ошибка при отсутствии ID в запросе
 	t.Run("UpdateUserMissingID", func(t *testing.T) {
 		ctx := context.Background()
 		ctx = context.WithValue(ctx, auth.UserIDKey, 0)
@@ -80,7 +142,7 @@ func TestBaseHandler_UpdateUser(t *testing.T) {
 		assert.Nil(t, result)
 	})
 
-	// Test 3: Ошибка при отсутствии самого запроса
+	// Test 4: Ошибка при отсутствии самого запроса
 	t.Run("UpdateUserNilRequest", func(t *testing.T) {
 		ctx := context.Background()
 		ctx = context.WithValue(ctx, auth.UserIDKey, 1)
@@ -97,7 +159,7 @@ func TestBaseHandler_UpdateUser(t *testing.T) {
 		assert.Nil(t, result)
 	})
 
-	// Test 4: Ошибка при неудачном обновлении в репозитории
+	// Test 5: Ошибка при неудачном обновлении в репозитории
 	t.Run("UpdateUserRepositoryError", func(t *testing.T) {
 		ctx := context.Background()
 		ctx = context.WithValue(ctx, auth.UserIDKey, 1)
@@ -107,12 +169,21 @@ func TestBaseHandler_UpdateUser(t *testing.T) {
 		mockRepo := new(mocks.MockUserRepository)
 
 		// Определяем ожидаемое поведение мока - возвращаем ошибку
+		mockRepo.On("GetUserByID", ctx, 1).Return(&models.User{
+			ID:           1,
+			Email:        "updated@example.com",
+			Organization: &models.Organization{ID: 1},
+		}, nil)
 		mockRepo.On("UpdateUser", ctx, mock.Anything).
 			Return(errors.New("update failed"))
+		orgRepo := new(mocks.MockOrganizationRepository)
+		orgRepo.On("GetOrganizationSettings", ctx, 1).Return(&models.OrganizationSettings{OrganizationID: 1}, nil)
 
 		// Создаем базовый обработчик с моком
 		baseHandler := &BaseHandler{
 			userRepo: mockRepo,
+			orgRepo:  orgRepo,
+			cfg:      DefaultConfig(),
 		}
 
 		// Вызываем метод UpdateUser
@@ -131,4 +202,52 @@ func TestBaseHandler_UpdateUser(t *testing.T) {
 		// Проверяем, что мок был вызван правильно
 		mockRepo.AssertExpectations(t)
 	})
+
+	// Test 6: Пользователь не может сам перейти в другую организацию, без organization_id остается в текущей
+	t.Run("UpdateUserOrganizationChangeDenied", func(t *testing.T) {
+		ctx := context.Background()
+		ctx = context.WithValue(ctx, auth.UserIDKey, 1)
+		ctx = context.WithValue(ctx, auth.IsAdminKey, false)
+
+		// Создаем мок репозиторий
+		userRepo := new(mocks.MockUserRepository)
+		orgRepo := new(mocks.MockOrganizationRepository)
+
+		// Определяем ожидаемое поведение мока
+		userRepo.On("GetUserByID", ctx, 1).Return(&models.User{
+			ID:           1,
+			Email:        "user@example.com",
+			Organization: &models.Organization{ID: 1},
+		}, nil)
+		userRepo.On("UpdateUser", ctx, mock.MatchedBy(func(u *models.User) bool {
+			return u.Organization != nil && u.Organization.ID == 1
+		})).Return(nil).Once()
+		orgRepo.On("GetOrganizationByID", ctx, 1).Return(&models.Organization{ID: 1, Name: "Org"}, nil)
+
+		// Создаем базовый обработчик с моком
+		baseHandler := &BaseHandler{
+			userRepo: userRepo,
+			orgRepo:  orgRepo,
+		}
+
+		// Вызываем метод UpdateUser
+		result, err := baseHandler.UpdateUser(ctx, &grpc.UserUpdateRequest{
+			Id:             1,
+			Name:           "User",
+			OrganizationId: 2,
+		})
+
+		// Проверяем результат
+		assert.Nil(t, result)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		// Без organization_id организация сохраняется
+		result, err = baseHandler.UpdateUser(ctx, &grpc.UserUpdateRequest{Id: 1, Name: "User"})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), result.Organization.Id)
+
+		// Проверяем, что моки были вызваны правильно
+		userRepo.AssertExpectations(t)
+		orgRepo.AssertExpectations(t)
+	})
 }
@@ -17,7 +17,7 @@ type UsersResponse struct {
 // GetUsersHandler обработчик для получения списка пользователей
 func (h *BaseHandler) GetUsers(
 	ctx context.Context,
-	in *api_pb.ListRequest,
+	in *api_pb.UsersListRequest,
 ) (out *api_pb.UsersResponse, err error) {
 	// Устанавливаем значения по умолчанию
 	limit := 10
@@ -33,8 +33,14 @@ func (h *BaseHandler) GetUsers(
 		offset = int(in.GetOffset())
 	}
 
+	// Парсим статус
+	userStatus := models.UserStatus(in.GetStatus())
+	if userStatus != "" && !userStatus.IsValid() {
+		return nil, status.Error(codes.InvalidArgument, "Invalid status")
+	}
+
 	// Получаем список пользователей из репозитория
-	users, err := h.userRepo.GetUsers(ctx, limit, offset)
+	users, err := h.userRepo.GetUsers(ctx, limit, offset, userStatus)
 	if err != nil {
 		log.Printf("Failed to get users: %v", err)
 		return nil, status.Error(codes.Internal, "Failed to get users")
@@ -55,6 +61,7 @@ func (h *BaseHandler) GetUsers(
 			Name:         user.Name,
 			Email:        user.Email,
 			Organization: orgOut,
+			Status:       string(user.Status),
 		}
 	}
 
@@ -25,7 +25,7 @@ func TestBaseHandler_GetUsers(t *testing.T) {
 		}
 
 		// Определяем ожидаемое поведение мока
-		mockRepo.On("GetUsers", ctx, 10, 0).Return([]*models.User{testOrg}, nil)
+		mockRepo.On("GetUsers", ctx, 10, 0, models.UserStatus("")).Return([]*models.User{testOrg}, nil)
 
 		// Создаем базовый обработчик с моком
 		baseHandler := &BaseHandler{
@@ -33,7 +33,7 @@ func TestBaseHandler_GetUsers(t *testing.T) {
 		}
 
 		// Вызываем метод GetUsers
-		orgs, err := baseHandler.GetUsers(ctx, &grpc.ListRequest{})
+		orgs, err := baseHandler.GetUsers(ctx, &grpc.UsersListRequest{})
 
 		// Проверяем результат
 		assert.NoError(t, err)
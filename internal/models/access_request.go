@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// AccessRequestStatus состояние запроса доступа
+type AccessRequestStatus string
+
+const (
+	// AccessRequestPending запрос ожидает решения
+	AccessRequestPending AccessRequestStatus = "pending"
+	// AccessRequestApproved запрос одобрен, роль выдана
+	AccessRequestApproved AccessRequestStatus = "approved"
+	// AccessRequestDenied запрос отклонен
+	AccessRequestDenied AccessRequestStatus = "denied"
+)
+
+// AccessRequest запрос пользователя на выдачу роли
+type AccessRequest struct {
+	ID     int `json:"id"`
+	UserID int `json:"user_id"`
+	// OrganizationID организация пользователя на момент запроса, ее администраторы могут решить запрос
+	OrganizationID *int `json:"organization_id,omitempty"`
+	RoleID         int  `json:"role_id"`
+	// Justification обоснование запроса
+	Justification string `json:"justification"`
+	// ValidFrom и ValidUntil запрошенный срок действия роли
+	ValidFrom  *time.Time          `json:"valid_from,omitempty"`
+	ValidUntil *time.Time          `json:"valid_until,omitempty"`
+	Status     AccessRequestStatus `json:"status"`
+	// DecidedBy пользователь, одобривший или отклонивший запрос, и причина решения
+	DecidedBy      *int       `json:"decided_by,omitempty"`
+	DecisionReason string     `json:"decision_reason,omitempty"`
+	DecidedAt      *time.Time `json:"decided_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Validity возвращает запрошенный срок действия роли
+func (r *AccessRequest) Validity() GrantValidity {
+	return GrantValidity{ValidFrom: r.ValidFrom, ValidUntil: r.ValidUntil}
+}
+
+// AccessApprover пользователь, которому направляются запросы доступа
+type AccessApprover struct {
+	// UserID получает запросы ролей, для которых он назначен утверждающим
+	UserID int
+	// OrganizationID получает запросы участников организации, 0 - пользователь не администратор организации
+	OrganizationID int
+	// Admin получает все запросы
+	Admin bool
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// AccessReviewStatus состояние кампании пересмотра доступа
+type AccessReviewStatus string
+
+const (
+	// AccessReviewOpen кампания ожидает решений утверждающих
+	AccessReviewOpen AccessReviewStatus = "open"
+	// AccessReviewClosed кампания закрыта, отозванные выдачи удалены
+	AccessReviewClosed AccessReviewStatus = "closed"
+)
+
+// AccessReviewDecision решение по выдаче в кампании пересмотра доступа
+type AccessReviewDecision string
+
+const (
+	// AccessReviewUndecided решение еще не принято, выдача сохраняется
+	AccessReviewUndecided AccessReviewDecision = ""
+	// AccessReviewKeep выдача подтверждена
+	AccessReviewKeep AccessReviewDecision = "keep"
+	// AccessReviewRevoke выдача отзывается при закрытии кампании
+	AccessReviewRevoke AccessReviewDecision = "revoke"
+)
+
+// AccessReview кампания пересмотра доступа: снимок выдач ролей и прав, каждую из которых
+// утверждающий подтверждает или отзывает
+type AccessReview struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// RoleIDs, PermissionIDs и OrganizationID область кампании.
+	// Без ролей и прав в область входят все выдачи организации
+	RoleIDs        []int `json:"role_ids,omitempty"`
+	PermissionIDs  []int `json:"permission_ids,omitempty"`
+	OrganizationID *int  `json:"organization_id,omitempty"`
+	// ReviewerID утверждающий выдач, для ролей которых не назначены утверждающие
+	ReviewerID int                `json:"reviewer_id"`
+	Status     AccessReviewStatus `json:"status"`
+	CreatedBy  *int               `json:"created_by,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	ClosedBy   *int               `json:"closed_by,omitempty"`
+	ClosedAt   *time.Time         `json:"closed_at,omitempty"`
+	// ItemCount и DecidedCount число выдач в кампании и выдач с принятым решением
+	ItemCount    int `json:"item_count"`
+	DecidedCount int `json:"decided_count"`
+}
+
+// AccessReviewItem выдача роли или права в кампании пересмотра доступа
+type AccessReviewItem struct {
+	ID        int        `json:"id"`
+	ReviewID  int        `json:"review_id"`
+	Kind      GrantKind  `json:"kind"`
+	OwnerType GrantOwner `json:"owner_type"`
+	OwnerID   int        `json:"owner_id"`
+	// OwnerName email пользователя или название организации на момент снимка
+	OwnerName string `json:"owner_name"`
+	ObjectID  int    `json:"object_id"`
+	// ObjectCode код роли или права на момент снимка
+	ObjectCode string     `json:"object_code"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	ReviewerID *int       `json:"reviewer_id,omitempty"`
+	// Decision, Reason и DecidedBy решение утверждающего
+	Decision  AccessReviewDecision `json:"decision"`
+	Reason    string               `json:"reason,omitempty"`
+	DecidedBy *int                 `json:"decided_by,omitempty"`
+	DecidedAt *time.Time           `json:"decided_at,omitempty"`
+	// Applied отзыв выдачи выполнен при закрытии кампании
+	Applied bool `json:"applied"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// APIKey API ключ сервисного аккаунта или персональный токен доступа пользователя.
+// Хранится только хеш ключа, сам ключ показывается один раз при создании
+type APIKey struct {
+	ID int `json:"id"`
+	// UserID идентификатор сервисного аккаунта или пользователя
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	// Prefix начало ключа, по которому его можно узнать в списке
+	Prefix  string `json:"prefix"`
+	KeyHash string `json:"-"`
+	// Scopes коды прав, которыми ограничен ключ. Пустой список - все права сервисного аккаунта
+	Scopes []string `json:"scopes"`
+	// ExpiresAt срок действия ключа, nil - бессрочный ключ
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// AuditEntry запись журнала аудита
+type AuditEntry struct {
+	ID int `json:"id"`
+	// ActorID пользователь, совершивший действие
+	ActorID *int `json:"actor_id,omitempty"`
+	// UserID пользователь, к которому относится действие
+	UserID *int `json:"user_id,omitempty"`
+	// ImpersonatorID администратор, выполнивший действие от имени ActorID
+	ImpersonatorID *int      `json:"impersonator_id,omitempty"`
+	Action         string    `json:"action"`
+	Reason         string    `json:"reason,omitempty"`
+	Details        string    `json:"details,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// GrantValidity срок действия выдачи роли или права. Пустая граница не ограничивает срок
+type GrantValidity struct {
+	ValidFrom  *time.Time
+	ValidUntil *time.Time
+}
+
+// GrantKind вид выдачи
+type GrantKind string
+
+const (
+	// GrantKindRole выдача роли
+	GrantKindRole GrantKind = "role"
+	// GrantKindPermission выдача права
+	GrantKindPermission GrantKind = "permission"
+)
+
+// ExpiredGrant выдача роли или права, удаленная по истечении срока
+type ExpiredGrant struct {
+	Kind GrantKind
+	// OwnerID пользователь или организация, которой была выдана роль или право
+	OwnerID int
+	// ObjectID роль или право
+	ObjectID   int
+	ValidUntil time.Time
+}
+
+// GrantOwner вид владельца выдачи
+type GrantOwner string
+
+const (
+	// GrantOwnerUser выдача пользователю
+	GrantOwnerUser GrantOwner = "user"
+	// GrantOwnerOrganization выдача организации
+	GrantOwnerOrganization GrantOwner = "organization"
+	// GrantOwnerTariff выдача ролей тарифу, их получают пользователи и организации с тарифом
+	GrantOwnerTariff GrantOwner = "tariff"
+)
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// LoginAttempt попытка входа в систему
+type LoginAttempt struct {
+	ID    int    `json:"id"`
+	Email string `json:"email"`
+	IP    string `json:"ip"`
+	// UserID заполняется, если пользователь с таким email существует
+	UserID    *int      `json:"user_id,omitempty"`
+	Success   bool      `json:"success"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoginFailureStats статистика неудачных попыток входа по аккаунту и по IP
+type LoginFailureStats struct {
+	AccountFailures    int
+	AccountLastFailure *time.Time
+	IPFailures         int
+	IPLastFailure      *time.Time
+}
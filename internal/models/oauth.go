@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// OAuthClient зарегистрированный клиент OAuth2, которому сервис выдает токены
+type OAuthClient struct {
+	ID int `json:"id"`
+	// ClientID публичный идентификатор клиента, передается в параметре client_id
+	ClientID string `json:"client_id"`
+	Name     string `json:"name"`
+	// SecretHash хеш секрета клиента, сам секрет показывается только при создании
+	SecretHash   string   `json:"-"`
+	RedirectURIs []string `json:"redirect_uris"`
+	// Scopes коды прав, которые клиент может запрашивать
+	Scopes []string `json:"scopes"`
+	// ServiceAccountID сервисный аккаунт, от имени которого выдаются токены client_credentials
+	ServiceAccountID *int      `json:"service_account_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// OAuthConsent согласие пользователя на доступ клиента к перечисленным правам
+type OAuthConsent struct {
+	UserID        int `json:"user_id"`
+	OAuthClientID int `json:"oauth_client_id"`
+	// ClientID и ClientName публичный идентификатор и название клиента
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// OAuthCode данные authorization code, которые хранятся в payload одноразового токена
+type OAuthCode struct {
+	OAuthClientID int    `json:"oauth_client_id"`
+	RedirectURI   string `json:"redirect_uri"`
+	Scope         string `json:"scope"`
+	CodeChallenge string `json:"code_challenge"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// OIDCProvider внешний провайдер OpenID Connect, через который входят пользователи организации
+type OIDCProvider struct {
+	ID             int    `json:"id"`
+	OrganizationID int    `json:"organization_id"`
+	Issuer         string `json:"issuer"`
+	ClientID       string `json:"client_id"`
+	// ClientSecret секрет клиента у провайдера, никогда не возвращается в API
+	ClientSecret string   `json:"-"`
+	Scopes       []string `json:"scopes"`
+	// EmailClaim и NameClaim claim'ы ID токена, из которых берутся email и имя пользователя
+	EmailClaim string `json:"email_claim"`
+	NameClaim  string `json:"name_claim"`
+	// AutoProvision создавать пользователя при первом входе, если он не найден
+	AutoProvision bool      `json:"auto_provision"`
+	Enabled       bool      `json:"enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// OIDCIdentity связь пользователя с учетной записью у провайдера OpenID Connect
+type OIDCIdentity struct {
+	ID         int `json:"id"`
+	ProviderID int `json:"provider_id"`
+	UserID     int `json:"user_id"`
+	// Subject идентификатор пользователя у провайдера (claim sub)
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OIDCLoginState незавершенный вход через провайдера OpenID Connect.
+// В БД хранится только хеш параметра state, который передается провайдеру
+type OIDCLoginState struct {
+	StateHash      string `json:"-"`
+	OrganizationID int    `json:"organization_id"`
+	Nonce          string `json:"-"`
+	CodeVerifier   string `json:"-"`
+	// DPoPJKT отпечаток ключа клиента, к которому будет привязан выданный токен
+	DPoPJKT string `json:"-"`
+	// SessionCookie токен передается в cookie браузерной сессии
+	SessionCookie bool      `json:"-"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
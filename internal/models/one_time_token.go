@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// TokenPurpose назначение одноразового токена
+type TokenPurpose string
+
+const (
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	TokenPurposeLoginChallenge    TokenPurpose = "login_challenge"
+	TokenPurposeOAuthCode         TokenPurpose = "oauth_code"
+	TokenPurposeMagicLink         TokenPurpose = "magic_link"
+)
+
+// OneTimeToken одноразовый токен с ограниченным сроком действия.
+// В БД хранится только хеш токена, сам токен отправляется пользователю
+type OneTimeToken struct {
+	ID        int          `json:"id"`
+	UserID    int          `json:"user_id"`
+	Purpose   TokenPurpose `json:"purpose"`
+	TokenHash string       `json:"-"`
+	// Payload дополнительные данные, привязанные к токену
+	Payload   string     `json:"payload,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
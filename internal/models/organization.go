@@ -1,8 +1,86 @@
 package models
 
+import (
+	"net"
+	"time"
+)
+
 // Organization represents an organization data structure
 type Organization struct {
 	ID       int    `json:"id"`
 	Name     string `json:"name"`
 	TariffID *int   `json:"tariff_id,omitempty"`
 }
+
+// OrganizationSettings настройки организации
+type OrganizationSettings struct {
+	OrganizationID int `json:"organization_id"`
+	// Require2FA все участники организации обязаны использовать двухфакторную аутентификацию
+	Require2FA bool `json:"require_2fa"`
+	// PasswordPolicy политика паролей организации, nil - используется глобальная политика
+	PasswordPolicy *PasswordPolicy `json:"password_policy,omitempty"`
+	// MagicLinkEnabled участники организации могут входить по ссылке из письма без пароля
+	MagicLinkEnabled bool `json:"magic_link_enabled"`
+	// RequireDPoP участники организации получают только токены, привязанные к ключу клиента (DPoP)
+	RequireDPoP bool `json:"require_dpop"`
+	// AllowedCIDRs диапазоны адресов, с которых участники организации могут входить и выполнять запросы.
+	// Пустой список - ограничений нет
+	AllowedCIDRs []string `json:"allowed_cidrs"`
+	// MaxSessionTTL максимальное время жизни сессий и токенов участников, 0 - используется срок токена
+	MaxSessionTTL time.Duration `json:"max_session_ttl"`
+	// AllowedLoginMethods способы входа участников организации (LoginMethod*). Пустой список - разрешены все
+	AllowedLoginMethods []string `json:"allowed_login_methods"`
+}
+
+const (
+	// LoginMethodPassword вход по email и паролю
+	LoginMethodPassword = "password"
+	// LoginMethodSSO вход через провайдера OpenID Connect организации
+	LoginMethodSSO = "sso"
+	// LoginMethodMagicLink вход по ссылке из письма
+	LoginMethodMagicLink = "magic_link"
+)
+
+// IsValidLoginMethod проверяет, что способ входа известен
+func IsValidLoginMethod(method string) bool {
+	switch method {
+	case LoginMethodPassword, LoginMethodSSO, LoginMethodMagicLink:
+		return true
+	}
+	return false
+}
+
+// LoginMethodAllowed проверяет, разрешен ли участникам организации способ входа
+func (s *OrganizationSettings) LoginMethodAllowed(method string) bool {
+	if len(s.AllowedLoginMethods) == 0 {
+		return true
+	}
+
+	for _, allowed := range s.AllowedLoginMethods {
+		if allowed == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IPAllowed проверяет, входит ли адрес клиента в разрешенные диапазоны организации
+func (s *OrganizationSettings) IPAllowed(ip string) bool {
+	if len(s.AllowedCIDRs) == 0 {
+		return true
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, cidr := range s.AllowedCIDRs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
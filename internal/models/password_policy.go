@@ -0,0 +1,16 @@
+package models
+
+// PasswordPolicy правила, которым должен соответствовать пароль.
+// Нулевые значения отключают соответствующую проверку
+type PasswordPolicy struct {
+	// MinLength минимальная длина пароля в символах
+	MinLength int `json:"min_length"`
+	// MaxBytes максимальная длина пароля в байтах (bcrypt учитывает только первые 72 байта)
+	MaxBytes      int  `json:"max_bytes"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	// ForbidPersonalInfo пароль не может совпадать с email или именем пользователя или содержать их
+	ForbidPersonalInfo bool `json:"forbid_personal_info"`
+}
@@ -1,9 +1,14 @@
 package models
 
+import "time"
+
 // Permission представляет сущность права доступа
 type Permission struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name"`
 	Code        string `json:"code"`
 	Description string `json:"description"`
-}
\ No newline at end of file
+	// Срок действия выдачи права, заполняется для прав пользователя или организации
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
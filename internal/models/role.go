@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Role представляет сущность роли
 type Role struct {
 	ID          int    `json:"id"`
@@ -8,4 +10,7 @@ type Role struct {
 	Description string `json:"description"`
 	// Связь один ко многим с Permission
 	Permissions []Permission `json:"permissions,omitempty"`
-}
\ No newline at end of file
+	// Срок действия выдачи роли, заполняется для ролей пользователя или организации
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Session сессия пользователя, созданная при входе.
+// ID совпадает с jti выданного токена
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     int        `json:"user_id"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	// ImpersonatorID администратор, открывший сессию от имени пользователя
+	ImpersonatorID      *int   `json:"impersonator_id,omitempty"`
+	ImpersonationReason string `json:"impersonation_reason,omitempty"`
+	// OAuthClientID OAuth клиент, которому выдан токен сессии
+	OAuthClientID *int `json:"oauth_client_id,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// SoDConstraint ограничение разделения обязанностей: один пользователь не может одновременно
+// иметь больше одной роли из набора
+type SoDConstraint struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	RoleIDs     []int     `json:"role_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SoDViolation нарушение ограничения разделения обязанностей пользователем
+type SoDViolation struct {
+	ConstraintID   int    `json:"constraint_id"`
+	ConstraintName string `json:"constraint_name"`
+	UserID         int    `json:"user_id"`
+	UserEmail      string `json:"user_email"`
+	// RoleIDs и RoleCodes роли набора, которые пользователь имеет напрямую, через организацию или тариф
+	RoleIDs   []int    `json:"role_ids"`
+	RoleCodes []string `json:"role_codes"`
+}
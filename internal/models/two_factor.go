@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// TwoFactor настройки двухфакторной аутентификации пользователя
+type TwoFactor struct {
+	UserID int `json:"user_id"`
+	// Secret секрет TOTP в кодировке base32
+	Secret  string `json:"-"`
+	Enabled bool   `json:"enabled"`
+	// LastStep номер последнего принятого шага TOTP, повторно коды этого шага не принимаются
+	LastStep  *int64     `json:"-"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RecoveryCode резервный код двухфакторной аутентификации. Сам код не хранится, только его хеш
+type RecoveryCode struct {
+	ID       int        `json:"id"`
+	CodeHash string     `json:"code_hash"`
+	UsedAt   *time.Time `json:"used_at,omitempty"`
+}
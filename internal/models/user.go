@@ -1,5 +1,50 @@
 package models
 
+import "time"
+
+// UserStatus статус учетной записи пользователя
+type UserStatus string
+
+const (
+	// UserStatusActive пользователь может входить в систему
+	UserStatusActive UserStatus = "active"
+	// UserStatusSuspended учетная запись приостановлена администратором
+	UserStatusSuspended UserStatus = "suspended"
+)
+
+// userStatusTransitions допустимые переходы между статусами.
+// Блокировка входа после неудачных попыток временная и статус не меняет, см. LoginThrottleConfig
+var userStatusTransitions = map[UserStatus][]UserStatus{
+	UserStatusActive:    {UserStatusSuspended},
+	UserStatusSuspended: {UserStatusActive},
+}
+
+// IsValid проверяет, что статус известен
+func (s UserStatus) IsValid() bool {
+	_, ok := userStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo проверяет, допустим ли переход в указанный статус
+func (s UserStatus) CanTransitionTo(to UserStatus) bool {
+	for _, allowed := range userStatusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// UserKind тип учетной записи
+type UserKind string
+
+const (
+	// UserKindHuman пользователь, который входит по паролю
+	UserKindHuman UserKind = "human"
+	// UserKindService сервисный аккаунт, который обращается к API только по API ключам
+	UserKindService UserKind = "service"
+)
+
 // User represents a user data structure
 type User struct {
 	ID           int           `json:"id"`
@@ -8,4 +53,24 @@ type User struct {
 	PasswordHash string        `json:"password_hash"`
 	Organization *Organization `json:"organization"`
 	TariffID     *int          `json:"tariff_id,omitempty"`
+	Status       UserStatus    `json:"status"`
+	// EmailVerified email подтвержден пользователем
+	EmailVerified bool `json:"email_verified"`
+	// PendingEmail новый email, который вступит в силу после подтверждения
+	PendingEmail *string `json:"pending_email,omitempty"`
+	// GrantVersion версия прав, повышается при любом изменении прав пользователя
+	GrantVersion int64 `json:"-"`
+	// Kind тип учетной записи, пустое значение соответствует UserKindHuman
+	Kind UserKind `json:"kind,omitempty"`
+}
+
+// UserAuthState состояние пользователя, необходимое для проверки токена
+type UserAuthState struct {
+	Status UserStatus
+	// TokensValidAfter токены, выпущенные раньше этого момента, недействительны
+	TokensValidAfter *time.Time
+	// GrantVersion текущая версия прав пользователя
+	GrantVersion int64
+	// OrganizationID организация пользователя
+	OrganizationID *int
 }
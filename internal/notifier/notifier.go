@@ -0,0 +1,74 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message сообщение для пользователя
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier доставляет сообщения пользователям
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// logNotifier выводит сообщения в лог, используется при разработке
+type logNotifier struct{}
+
+// NewLogNotifier создает Notifier, который пишет сообщения в лог
+func NewLogNotifier() Notifier {
+	return logNotifier{}
+}
+
+// Notify выводит сообщение в лог
+func (logNotifier) Notify(_ context.Context, msg Message) error {
+	log.Printf("Notification to %s: %s\n%s\n", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// fileNotifier дописывает сообщения в файл построчно в формате JSON
+type fileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileNotifier создает Notifier, который сохраняет сообщения в файл
+func NewFileNotifier(path string) Notifier {
+	return &fileNotifier{path: path}
+}
+
+// Notify дописывает сообщение в файл
+func (n *fileNotifier) Notify(_ context.Context, msg Message) error {
+	line, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{Message: msg, SentAt: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// maxResponseBytes максимальный размер ответа провайдера
+const maxResponseBytes = 1 << 20
+
+var (
+	ErrIssuerMismatch = errors.New("issuer does not match discovery document")
+	ErrInvalidNonce   = errors.New("id token nonce mismatch")
+	ErrUnknownKey     = errors.New("id token signed with unknown key")
+	ErrNoIDToken      = errors.New("token response has no id_token")
+)
+
+// Discovery метаданные провайдера (OpenID Connect Discovery 1.0)
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Client клиент провайдеров OpenID Connect, выполняющий вход по authorization code с PKCE
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient создает клиент с таймаутом запросов к провайдеру
+func NewClient(timeout time.Duration) *Client {
+	return &Client{httpClient: &http.Client{Timeout: timeout}}
+}
+
+// Discover загружает метаданные провайдера. Issuer в документе должен совпадать с настроенным
+func (c *Client) Discover(ctx context.Context, issuer string) (*Discovery, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Discovery{}
+	if err := c.doJSON(req, d); err != nil {
+		return nil, fmt.Errorf("failed to load discovery document: %w", err)
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != issuer {
+		return nil, ErrIssuerMismatch
+	}
+
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("discovery document is incomplete")
+	}
+
+	return d, nil
+}
+
+// AuthCodeURL формирует адрес страницы входа провайдера
+func (d *Discovery) AuthCodeURL(clientID, redirectURI string, scopes []string, state, nonce, codeVerifier string) string {
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return d.AuthorizationEndpoint + sep + params.Encode()
+}
+
+// CodeChallenge вычисляет code_challenge метода S256 (RFC 7636)
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Exchange обменивает authorization code на ID токен и возвращает его проверенные claims.
+// Клиент аутентифицируется методом client_secret_basic
+func (c *Client) Exchange(
+	ctx context.Context,
+	d *Discovery,
+	clientID, clientSecret, redirectURI, code, codeVerifier, nonce string,
+) (jwt.MapClaims, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+
+	var resp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := c.doJSON(req, &resp); err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	if resp.IDToken == "" {
+		return nil, ErrNoIDToken
+	}
+
+	return c.verifyIDToken(ctx, d, clientID, resp.IDToken, nonce)
+}
+
+// verifyIDToken проверяет подпись ID токена ключами провайдера, issuer, audience, срок действия и nonce
+func (c *Client) verifyIDToken(ctx context.Context, d *Discovery, clientID, rawIDToken, nonce string) (jwt.MapClaims, error) {
+	keys, err := c.keys(ctx, d.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := keys[kid]; ok {
+			return key, nil
+		}
+		// Провайдер с единственным ключом может не указывать kid
+		if kid == "" && len(keys) == 1 {
+			for _, key := range keys {
+				return key, nil
+			}
+		}
+		return nil, ErrUnknownKey
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, ErrInvalidNonce
+	}
+
+	return claims, nil
+}
+
+// keys загружает RSA ключи провайдера (RFC 7517)
+func (c *Client) keys(ctx context.Context, jwksURI string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := c.doJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("failed to load jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+// doJSON выполняет запрос к провайдеру и разбирает JSON ответ
+func (c *Client) doJSON(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+// StringClaim возвращает строковый claim или пустую строку
+func StringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// EmailVerified проверяет claim email_verified. Некоторые провайдеры передают его строкой
+func EmailVerified(claims jwt.MapClaims) bool {
+	switch value := claims["email_verified"].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// BreachedList список паролей, попавших в утечки
+type BreachedList struct {
+	passwords map[string]struct{}
+}
+
+// LoadBreachedList загружает список утекших паролей из файла, по одному паролю в строке.
+// Пустые строки и строки, начинающиеся с #, пропускаются
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer f.Close()
+
+	list := &BreachedList{passwords: make(map[string]struct{})}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list.passwords[strings.ToLower(line)] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return list, nil
+}
+
+// NewBreachedList создает список утекших паролей из переданных значений
+func NewBreachedList(passwords ...string) *BreachedList {
+	list := &BreachedList{passwords: make(map[string]struct{}, len(passwords))}
+	for _, password := range passwords {
+		list.passwords[strings.ToLower(password)] = struct{}{}
+	}
+
+	return list
+}
+
+// Contains проверяет, есть ли пароль в списке без учета регистра
+func (l *BreachedList) Contains(password string) bool {
+	if l == nil {
+		return false
+	}
+
+	_, ok := l.passwords[strings.ToLower(password)]
+	return ok
+}
+
+// Len возвращает количество паролей в списке
+func (l *BreachedList) Len() int {
+	if l == nil {
+		return 0
+	}
+
+	return len(l.passwords)
+}
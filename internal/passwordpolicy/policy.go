@@ -0,0 +1,136 @@
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+)
+
+// Названия правил, которые возвращаются в нарушениях
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleUpper     = "require_upper"
+	RuleLower     = "require_lower"
+	RuleDigit     = "require_digit"
+	RuleSymbol    = "require_symbol"
+	RuleEmail     = "contains_email"
+	RuleName      = "contains_name"
+	RuleBreached  = "breached"
+)
+
+// MaxBytes наибольшая допустимая длина пароля в байтах: bcrypt учитывает только первые 72 байта
+const MaxBytes = 72
+
+// minPersonalInfoLength части email и имени короче этой длины не проверяются
+const minPersonalInfoLength = 3
+
+// Violation нарушенное правило политики паролей
+type Violation struct {
+	Rule        string
+	Description string
+}
+
+// Subject данные пользователя, которые не должны встречаться в пароле
+type Subject struct {
+	Email string
+	Name  string
+}
+
+// Check проверяет пароль по политике и списку утекших паролей и возвращает все нарушенные правила
+func Check(policy models.PasswordPolicy, breached *BreachedList, password string, subject Subject) []Violation {
+	var violations []Violation
+	add := func(rule, format string, args ...any) {
+		violations = append(violations, Violation{Rule: rule, Description: fmt.Sprintf(format, args...)})
+	}
+
+	if policy.MinLength > 0 && utf8.RuneCountInString(password) < policy.MinLength {
+		add(RuleMinLength, "must be at least %d characters long", policy.MinLength)
+	}
+
+	if policy.MaxBytes > 0 && len(password) > policy.MaxBytes {
+		add(RuleMaxLength, "must be at most %d bytes long", policy.MaxBytes)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if policy.RequireUpper && !hasUpper {
+		add(RuleUpper, "must contain an uppercase letter")
+	}
+	if policy.RequireLower && !hasLower {
+		add(RuleLower, "must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !hasDigit {
+		add(RuleDigit, "must contain a digit")
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		add(RuleSymbol, "must contain a symbol")
+	}
+
+	if policy.ForbidPersonalInfo {
+		lower := strings.ToLower(password)
+		if containsAny(lower, emailParts(subject.Email)) {
+			add(RuleEmail, "must not contain the email")
+		}
+		if containsAny(lower, nameParts(subject.Name)) {
+			add(RuleName, "must not contain the name")
+		}
+	}
+
+	if breached.Contains(password) {
+		add(RuleBreached, "has appeared in a data breach")
+	}
+
+	return violations
+}
+
+// emailParts возвращает email целиком и его локальную часть
+func emailParts(email string) []string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil
+	}
+
+	parts := []string{email}
+	if local, _, ok := strings.Cut(email, "@"); ok {
+		parts = append(parts, local)
+	}
+
+	return parts
+}
+
+// nameParts возвращает имя целиком и отдельные слова имени
+func nameParts(name string) []string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil
+	}
+
+	return append([]string{name}, strings.Fields(name)...)
+}
+
+// containsAny проверяет, содержит ли пароль хотя бы одну из достаточно длинных частей
+func containsAny(password string, parts []string) bool {
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= minPersonalInfoLength && strings.Contains(password, part) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,325 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// accessRequestColumns столбцы, из которых читается запрос доступа
+const accessRequestColumns = `id, user_id, organization_id, role_id, justification, valid_from, valid_until, status,
+	decided_by, decision_reason, decided_at, created_at`
+
+// accessRequestRepository реализация интерфейса AccessRequestRepository
+type accessRequestRepository struct {
+	db *DB
+}
+
+// NewAccessRequestRepository создает новый репозиторий запросов доступа
+func NewAccessRequestRepository(db *DB) AccessRequestRepository {
+	return &accessRequestRepository{db: db}
+}
+
+// CreateAccessRequest сохраняет новый запрос доступа.
+// Возвращает false, если у пользователя уже есть запрос этой роли, ожидающий решения
+func (r *accessRequestRepository) CreateAccessRequest(ctx context.Context, request *models.AccessRequest) (bool, error) {
+	query := `INSERT INTO access_requests (user_id, organization_id, role_id, justification, valid_from, valid_until)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          ON CONFLICT (user_id, role_id) WHERE status = 'pending' DO NOTHING
+	          RETURNING id, status, created_at`
+	var organizationID sql.NullInt32
+	if request.OrganizationID != nil {
+		organizationID = utils.NewNullInt32(int32(*request.OrganizationID))
+	}
+	err := r.db.GetConnection().QueryRow(ctx, query,
+		request.UserID, organizationID, request.RoleID, request.Justification, request.ValidFrom, request.ValidUntil,
+	).Scan(&request.ID, &request.Status, &request.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create access request: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetAccessRequest получает запрос доступа по ID.
+// Если запрос не найден, возвращается nil
+func (r *accessRequestRepository) GetAccessRequest(ctx context.Context, id int) (*models.AccessRequest, error) {
+	query := `SELECT ` + accessRequestColumns + ` FROM access_requests WHERE id = $1`
+
+	request, err := scanAccessRequest(r.db.GetConnection().QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get access request: %w", err)
+	}
+
+	return request, nil
+}
+
+// GetPendingAccessRequests получает запросы, ожидающие решения, которые направлены approver:
+// запросы ролей, для которых он назначен утверждающим, и запросы участников организации, которой он управляет
+func (r *accessRequestRepository) GetPendingAccessRequests(
+	ctx context.Context,
+	approver models.AccessApprover,
+	limit, offset int,
+) ([]*models.AccessRequest, error) {
+	query := `SELECT ` + accessRequestColumns + ` FROM access_requests
+	          WHERE status = 'pending' AND user_id <> $2 AND (
+	              $1::boolean
+	              OR role_id IN (SELECT role_id FROM role_approvers WHERE user_id = $2)
+	              OR ($3 <> 0 AND organization_id = $3)
+	          )
+	          ORDER BY id LIMIT $4 OFFSET $5`
+
+	rows, err := r.db.GetConnection().Query(ctx, query,
+		approver.Admin, approver.UserID, approver.OrganizationID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access requests: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []*models.AccessRequest
+	for rows.Next() {
+		request, err := scanAccessRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan access request: %w", err)
+		}
+		requests = append(requests, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating access requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// GetUserAccessRequests получает запросы доступа, которые пользователь создал или решил
+func (r *accessRequestRepository) GetUserAccessRequests(ctx context.Context, userID int) ([]*models.AccessRequest, error) {
+	query := `SELECT ` + accessRequestColumns + ` FROM access_requests
+	          WHERE user_id = $1 OR decided_by = $1
+	          ORDER BY id`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access requests: %w", err)
+	}
+	defer rows.Close()
+
+	var requests []*models.AccessRequest
+	for rows.Next() {
+		request, err := scanAccessRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan access request: %w", err)
+		}
+		requests = append(requests, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating access requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// DecideAccessRequest сохраняет решение по запросу: Status, DecidedBy и DecisionReason.
+// Возвращает false, если запрос уже решен
+func (r *accessRequestRepository) DecideAccessRequest(ctx context.Context, request *models.AccessRequest) (bool, error) {
+	query := `UPDATE access_requests SET status = $1, decided_by = $2, decision_reason = $3, decided_at = now()
+	          WHERE id = $4 AND status = 'pending'
+	          RETURNING decided_at`
+	var decidedBy sql.NullInt32
+	if request.DecidedBy != nil {
+		decidedBy = utils.NewNullInt32(int32(*request.DecidedBy))
+	}
+	var decidedAt sql.NullTime
+	err := r.db.GetConnection().QueryRow(ctx, query,
+		request.Status, decidedBy, request.DecisionReason, request.ID,
+	).Scan(&decidedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to decide access request: %w", err)
+	}
+
+	request.DecidedAt = &decidedAt.Time
+
+	return true, nil
+}
+
+// ReopenAccessRequest возвращает одобренный запрос в ожидание решения, если роль по нему выдать не удалось
+func (r *accessRequestRepository) ReopenAccessRequest(ctx context.Context, id int) error {
+	query := `UPDATE access_requests SET status = 'pending', decided_by = NULL, decision_reason = '', decided_at = NULL
+	          WHERE id = $1 AND status = 'approved'`
+	_, err := r.db.GetConnection().Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to reopen access request: %w", err)
+	}
+
+	return nil
+}
+
+// GetRoleApprovers получает пользователей, утверждающих запросы роли
+func (r *accessRequestRepository) GetRoleApprovers(ctx context.Context, roleID int) ([]int, error) {
+	query := `SELECT user_id FROM role_approvers WHERE role_id = $1 ORDER BY user_id`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role approvers: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan role approver: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating role approvers: %w", err)
+	}
+
+	return userIDs, nil
+}
+
+// SetRoleApprovers заменяет список пользователей, утверждающих запросы роли
+func (r *accessRequestRepository) SetRoleApprovers(ctx context.Context, roleID int, userIDs []int) error {
+	if userIDs == nil {
+		userIDs = []int{}
+	}
+
+	query := `WITH removed AS (
+	              DELETE FROM role_approvers WHERE role_id = $1 AND user_id <> ALL($2)
+	          )
+	          INSERT INTO role_approvers (role_id, user_id)
+	          SELECT $1, unnest($2::integer[])
+	          ON CONFLICT DO NOTHING`
+	_, err := r.db.GetConnection().Exec(ctx, query, roleID, userIDs)
+	if err != nil {
+		return fmt.Errorf("failed to set role approvers: %w", err)
+	}
+
+	return nil
+}
+
+// GetAccessApprovers получает активных пользователей, которым направляется запрос роли roleID
+// от участника организации organizationID: утверждающих роли и администраторов организации
+func (r *accessRequestRepository) GetAccessApprovers(
+	ctx context.Context,
+	roleID, organizationID int,
+) ([]*models.User, error) {
+	query := `SELECT u.id, u.name, u.email FROM users u
+	          WHERE u.status = 'active' AND (
+	              u.id IN (SELECT user_id FROM role_approvers WHERE role_id = $1)
+	              OR u.organization_id = $2 AND u.id IN (
+	                  SELECT user_id FROM organization_admins WHERE organization_id = $2
+	              )
+	          )
+	          ORDER BY u.id`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, roleID, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access approvers: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user := &models.User{}
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email); err != nil {
+			return nil, fmt.Errorf("failed to scan access approver: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating access approvers: %w", err)
+	}
+
+	return users, nil
+}
+
+// scanAccessRequest читает запрос доступа из строки результата
+func scanAccessRequest(row pgx.Row) (*models.AccessRequest, error) {
+	request := &models.AccessRequest{}
+	var organizationID, decidedBy sql.NullInt32
+	var validFrom, validUntil, decidedAt sql.NullTime
+	err := row.Scan(
+		&request.ID, &request.UserID, &organizationID, &request.RoleID, &request.Justification,
+		&validFrom, &validUntil, &request.Status, &decidedBy, &request.DecisionReason, &decidedAt,
+		&request.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем, было ли значение NULL
+	if organizationID.Valid {
+		request.OrganizationID = utils.Ptr(int(organizationID.Int32))
+	}
+	if validFrom.Valid {
+		request.ValidFrom = &validFrom.Time
+	}
+	if validUntil.Valid {
+		request.ValidUntil = &validUntil.Time
+	}
+	if decidedBy.Valid {
+		request.DecidedBy = utils.Ptr(int(decidedBy.Int32))
+	}
+	if decidedAt.Valid {
+		request.DecidedAt = &decidedAt.Time
+	}
+
+	return request, nil
+}
+
+// InitDB инициализирует таблицы в БД для запросов доступа
+func (r *accessRequestRepository) InitDB() error {
+	query := `
+CREATE TABLE IF NOT EXISTS access_requests (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL,
+	role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+	justification TEXT NOT NULL,
+	valid_from TIMESTAMPTZ,
+	valid_until TIMESTAMPTZ,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	decision_reason TEXT NOT NULL DEFAULT '',
+	decided_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- У пользователя может быть только один запрос роли, ожидающий решения
+CREATE UNIQUE INDEX IF NOT EXISTS access_requests_pending_idx
+	ON access_requests (user_id, role_id) WHERE status = 'pending';
+
+-- Пользователи, утверждающие запросы роли
+CREATE TABLE IF NOT EXISTS role_approvers (
+	role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	PRIMARY KEY (role_id, user_id)
+);`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize access request tables: %w", err)
+	}
+
+	log.Println("AccessRequestRepository initialized successfully")
+	return nil
+}
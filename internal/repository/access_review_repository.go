@@ -0,0 +1,486 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// accessReviewColumns столбцы, из которых читается кампания пересмотра доступа
+const accessReviewColumns = `r.id, r.name, r.role_ids, r.permission_ids, r.organization_id, r.reviewer_id, r.status,
+	r.created_by, r.created_at, r.closed_by, r.closed_at,
+	(SELECT count(*) FROM access_review_items i WHERE i.review_id = r.id),
+	(SELECT count(*) FROM access_review_items i WHERE i.review_id = r.id AND i.decision <> '')`
+
+// accessReviewItemColumns столбцы, из которых читается выдача в кампании пересмотра доступа
+const accessReviewItemColumns = `id, review_id, kind, owner_type, owner_id, owner_name, object_id, object_code,
+	valid_until, reviewer_id, decision, reason, decided_by, decided_at, applied`
+
+// accessReviewRepository реализация интерфейса AccessReviewRepository
+type accessReviewRepository struct {
+	db *DB
+}
+
+// NewAccessReviewRepository создает новый репозиторий кампаний пересмотра доступа
+func NewAccessReviewRepository(db *DB) AccessReviewRepository {
+	return &accessReviewRepository{db: db}
+}
+
+// reviewSnapshot запрос выдач таблицы, входящих в область кампании, параметры:
+// роли, права, организация и утверждающий по умолчанию.
+// Выдача роли назначается утверждающему роли, кроме самого владельца, остальные - утверждающему по умолчанию
+func (t grantTable) reviewSnapshot() string {
+	ownerName := `(SELECT email FROM users WHERE id = g.user_id)`
+	inOrganization := `g.user_id IN (SELECT id FROM users WHERE organization_id = $3::integer)`
+	ownerUser := `g.user_id`
+	if t.ownerType == models.GrantOwnerOrganization {
+		ownerName = `(SELECT name FROM organizations WHERE id = g.organization_id)`
+		inOrganization = `g.organization_id = $3::integer`
+		ownerUser = `0`
+	}
+
+	objectCode := `(SELECT code FROM roles WHERE id = g.role_id)`
+	inScope := `g.role_id = ANY($1::integer[])`
+	reviewer := `COALESCE((SELECT min(ra.user_id) FROM role_approvers ra
+		WHERE ra.role_id = g.role_id AND ra.user_id <> ` + ownerUser + `), $4::integer)`
+	if t.kind == models.GrantKindPermission {
+		objectCode = `(SELECT code FROM permissions WHERE id = g.permission_id)`
+		inScope = `g.permission_id = ANY($2::integer[])`
+		reviewer = `$4::integer`
+	}
+
+	return `SELECT '` + string(t.kind) + `' AS kind, '` + string(t.ownerType) + `' AS owner_type,
+			g.` + t.owner + ` AS owner_id, COALESCE(` + ownerName + `, '') AS owner_name,
+			g.` + t.object + ` AS object_id, COALESCE(` + objectCode + `, '') AS object_code,
+			g.valid_until, ` + reviewer + ` AS reviewer_id
+		FROM ` + t.name + ` g
+		WHERE ` + unexpiredGrant("g") + `
+			AND (cardinality($1::integer[]) + cardinality($2::integer[]) = 0 OR ` + inScope + `)
+			AND ($3::integer IS NULL OR ` + inOrganization + `)`
+}
+
+// CreateAccessReview создает кампанию и сохраняет снимок выдач ролей и прав, входящих в ее область
+func (r *accessReviewRepository) CreateAccessReview(ctx context.Context, review *models.AccessReview) error {
+	tables := []grantTable{userRoleGrants, userPermissionGrants, organizationRoleGrants, organizationPermissionGrants}
+	snapshots := make([]string, 0, len(tables))
+	for _, t := range tables {
+		snapshots = append(snapshots, t.reviewSnapshot())
+	}
+
+	query := `
+		WITH review AS (
+			INSERT INTO access_reviews (name, role_ids, permission_ids, organization_id, reviewer_id, created_by)
+			VALUES ($5, $1, $2, $3, $4, $6)
+			RETURNING id, status, created_at
+		), items AS (
+			INSERT INTO access_review_items (review_id, kind, owner_type, owner_id, owner_name, object_id, object_code,
+				valid_until, reviewer_id)
+			SELECT review.id, g.kind, g.owner_type, g.owner_id, g.owner_name, g.object_id, g.object_code,
+				g.valid_until, g.reviewer_id
+			FROM review, (` + strings.Join(snapshots, "\n\t\t\tUNION ALL\n") + `) g
+			RETURNING 1
+		)
+		SELECT id, status, created_at, (SELECT count(*) FROM items) FROM review`
+
+	roleIDs, permissionIDs := review.RoleIDs, review.PermissionIDs
+	if roleIDs == nil {
+		roleIDs = []int{}
+	}
+	if permissionIDs == nil {
+		permissionIDs = []int{}
+	}
+	var organizationID, createdBy sql.NullInt32
+	if review.OrganizationID != nil {
+		organizationID = utils.NewNullInt32(int32(*review.OrganizationID))
+	}
+	if review.CreatedBy != nil {
+		createdBy = utils.NewNullInt32(int32(*review.CreatedBy))
+	}
+
+	err := r.db.GetConnection().QueryRow(ctx, query,
+		roleIDs, permissionIDs, organizationID, review.ReviewerID, review.Name, createdBy,
+	).Scan(&review.ID, &review.Status, &review.CreatedAt, &review.ItemCount)
+	if err != nil {
+		return fmt.Errorf("failed to create access review: %w", err)
+	}
+
+	return nil
+}
+
+// GetAccessReview получает кампанию по ID.
+// Если кампания не найдена, возвращается nil
+func (r *accessReviewRepository) GetAccessReview(ctx context.Context, id int) (*models.AccessReview, error) {
+	query := `SELECT ` + accessReviewColumns + ` FROM access_reviews r WHERE r.id = $1`
+
+	review, err := scanAccessReview(r.db.GetConnection().QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get access review: %w", err)
+	}
+
+	return review, nil
+}
+
+// GetAccessReviews получает список кампаний, в которых reviewerID назначен утверждающим выдач.
+// reviewerID 0 - все кампании
+func (r *accessReviewRepository) GetAccessReviews(
+	ctx context.Context,
+	reviewerID, limit, offset int,
+) ([]*models.AccessReview, error) {
+	query := `SELECT ` + accessReviewColumns + ` FROM access_reviews r
+	          WHERE $1 = 0 OR EXISTS (
+	              SELECT 1 FROM access_review_items i WHERE i.review_id = r.id AND i.reviewer_id = $1
+	          )
+	          ORDER BY r.id LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, reviewerID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access reviews: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []*models.AccessReview
+	for rows.Next() {
+		review, err := scanAccessReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan access review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating access reviews: %w", err)
+	}
+
+	return reviews, nil
+}
+
+// GetAccessReviewItems получает выдачи кампании, назначенные reviewerID.
+// reviewerID 0 - все выдачи, limit 0 - без ограничения
+func (r *accessReviewRepository) GetAccessReviewItems(
+	ctx context.Context,
+	reviewID, reviewerID, limit, offset int,
+) ([]*models.AccessReviewItem, error) {
+	query := `SELECT ` + accessReviewItemColumns + ` FROM access_review_items
+	          WHERE review_id = $1 AND ($2 = 0 OR reviewer_id = $2)
+	          ORDER BY id LIMIT NULLIF($3::integer, 0) OFFSET $4`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, reviewID, reviewerID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access review items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.AccessReviewItem
+	for rows.Next() {
+		item, err := scanAccessReviewItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan access review item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating access review items: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetUserAccessReviewItems получает выдачи пользователя в кампаниях пересмотра,
+// а также выдачи, которые он пересматривал или решил
+func (r *accessReviewRepository) GetUserAccessReviewItems(
+	ctx context.Context,
+	userID int,
+) ([]*models.AccessReviewItem, error) {
+	query := `SELECT ` + accessReviewItemColumns + ` FROM access_review_items
+	          WHERE (owner_type = $2 AND owner_id = $1) OR reviewer_id = $1 OR decided_by = $1
+	          ORDER BY id`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, userID, models.GrantOwnerUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access review items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.AccessReviewItem
+	for rows.Next() {
+		item, err := scanAccessReviewItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan access review item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating access review items: %w", err)
+	}
+
+	return items, nil
+}
+
+// GetAccessReviewItem получает выдачу в кампании по ID.
+// Если выдача не найдена, возвращается nil
+func (r *accessReviewRepository) GetAccessReviewItem(ctx context.Context, id int) (*models.AccessReviewItem, error) {
+	query := `SELECT ` + accessReviewItemColumns + ` FROM access_review_items WHERE id = $1`
+
+	item, err := scanAccessReviewItem(r.db.GetConnection().QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get access review item: %w", err)
+	}
+
+	return item, nil
+}
+
+// DecideAccessReviewItem сохраняет решение по выдаче: Decision, Reason и DecidedBy.
+// Возвращает false, если кампания уже закрыта
+func (r *accessReviewRepository) DecideAccessReviewItem(ctx context.Context, item *models.AccessReviewItem) (bool, error) {
+	query := `UPDATE access_review_items SET decision = $1, reason = $2, decided_by = $3, decided_at = now()
+	          WHERE id = $4 AND review_id IN (SELECT id FROM access_reviews WHERE status = 'open')
+	          RETURNING decided_at`
+	var decidedBy sql.NullInt32
+	if item.DecidedBy != nil {
+		decidedBy = utils.NewNullInt32(int32(*item.DecidedBy))
+	}
+	var decidedAt sql.NullTime
+	err := r.db.GetConnection().QueryRow(ctx, query, item.Decision, item.Reason, decidedBy, item.ID).Scan(&decidedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to decide access review item: %w", err)
+	}
+
+	item.DecidedAt = &decidedAt.Time
+
+	return true, nil
+}
+
+// ReassignAccessReviewItem назначает выдаче другого утверждающего.
+// Возвращает false, если кампания уже закрыта
+func (r *accessReviewRepository) ReassignAccessReviewItem(ctx context.Context, id, reviewerID int) (bool, error) {
+	query := `UPDATE access_review_items SET reviewer_id = $1
+	          WHERE id = $2 AND review_id IN (SELECT id FROM access_reviews WHERE status = 'open')`
+	result, err := r.db.GetConnection().Exec(ctx, query, reviewerID, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to reassign access review item: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// CloseAccessReview закрывает кампанию, после закрытия решения не принимаются.
+// Возвращает false, если кампания уже закрыта
+func (r *accessReviewRepository) CloseAccessReview(ctx context.Context, review *models.AccessReview) (bool, error) {
+	query := `UPDATE access_reviews SET status = 'closed', closed_by = $1, closed_at = now()
+	          WHERE id = $2 AND status = 'open'
+	          RETURNING status, closed_at`
+	var closedBy sql.NullInt32
+	if review.ClosedBy != nil {
+		closedBy = utils.NewNullInt32(int32(*review.ClosedBy))
+	}
+	var closedAt sql.NullTime
+	err := r.db.GetConnection().QueryRow(ctx, query, closedBy, review.ID).Scan(&review.Status, &closedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to close access review: %w", err)
+	}
+
+	review.ClosedAt = &closedAt.Time
+
+	return true, nil
+}
+
+// RevokeAccessReviewGrants удаляет выдачи, отозванные в кампании, и отмечает решения выполненными.
+// Возвращает удаленные выдачи, выдачи, удаленные ранее, только отмечаются
+func (r *accessReviewRepository) RevokeAccessReviewGrants(
+	ctx context.Context,
+	reviewID int,
+) ([]*models.AccessReviewItem, error) {
+	var revoked []*models.AccessReviewItem
+	for _, t := range []grantTable{
+		userRoleGrants, userPermissionGrants, organizationRoleGrants, organizationPermissionGrants,
+	} {
+		items, err := revokeReviewedGrants(ctx, r.db, t, reviewID)
+		if err != nil {
+			return revoked, err
+		}
+		revoked = append(revoked, items...)
+	}
+
+	return revoked, nil
+}
+
+// revokeReviewedGrants удаляет из таблицы выдачи, отозванные в кампании reviewID.
+// Версия прав затронутых пользователей увеличивается в том же запросе
+func revokeReviewedGrants(
+	ctx context.Context,
+	db *DB,
+	t grantTable,
+	reviewID int,
+) ([]*models.AccessReviewItem, error) {
+	query := `
+		WITH revoked AS (
+			DELETE FROM ` + t.name + ` g USING access_review_items i
+			WHERE i.review_id = $1 AND i.decision = 'revoke' AND NOT i.applied
+				AND i.kind = $2 AND i.owner_type = $3
+				AND g.` + t.owner + ` = i.owner_id AND g.` + t.object + ` = i.object_id
+			RETURNING i.id, i.owner_id, i.object_id
+		), applied AS (
+			UPDATE access_review_items SET applied = true
+			WHERE review_id = $1 AND decision = 'revoke' AND NOT applied AND kind = $2 AND owner_type = $3
+		), bumped AS (
+			UPDATE users SET grant_version = grant_version + 1
+			WHERE ` + t.users + ` IN (SELECT owner_id FROM revoked)
+		)
+		SELECT id, owner_id, object_id FROM revoked`
+	rows, err := db.GetConnection().Query(ctx, query, reviewID, t.kind, t.ownerType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke %s grants: %w", t.name, err)
+	}
+	defer rows.Close()
+
+	var revoked []*models.AccessReviewItem
+	for rows.Next() {
+		item := &models.AccessReviewItem{
+			ReviewID:  reviewID,
+			Kind:      t.kind,
+			OwnerType: t.ownerType,
+			Decision:  models.AccessReviewRevoke,
+			Applied:   true,
+		}
+		if err := rows.Scan(&item.ID, &item.OwnerID, &item.ObjectID); err != nil {
+			return nil, fmt.Errorf("failed to scan revoked grant: %w", err)
+		}
+		revoked = append(revoked, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate revoked grants: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// scanAccessReview читает кампанию пересмотра доступа из строки результата
+func scanAccessReview(row pgx.Row) (*models.AccessReview, error) {
+	review := &models.AccessReview{}
+	var organizationID, reviewerID, createdBy, closedBy sql.NullInt32
+	var closedAt sql.NullTime
+	err := row.Scan(
+		&review.ID, &review.Name, &review.RoleIDs, &review.PermissionIDs, &organizationID, &reviewerID,
+		&review.Status, &createdBy, &review.CreatedAt, &closedBy, &closedAt, &review.ItemCount, &review.DecidedCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем, было ли значение NULL
+	if organizationID.Valid {
+		review.OrganizationID = utils.Ptr(int(organizationID.Int32))
+	}
+	if reviewerID.Valid {
+		review.ReviewerID = int(reviewerID.Int32)
+	}
+	if createdBy.Valid {
+		review.CreatedBy = utils.Ptr(int(createdBy.Int32))
+	}
+	if closedBy.Valid {
+		review.ClosedBy = utils.Ptr(int(closedBy.Int32))
+	}
+	if closedAt.Valid {
+		review.ClosedAt = &closedAt.Time
+	}
+
+	return review, nil
+}
+
+// scanAccessReviewItem читает выдачу в кампании пересмотра доступа из строки результата
+func scanAccessReviewItem(row pgx.Row) (*models.AccessReviewItem, error) {
+	item := &models.AccessReviewItem{}
+	var reviewerID, decidedBy sql.NullInt32
+	var validUntil, decidedAt sql.NullTime
+	err := row.Scan(
+		&item.ID, &item.ReviewID, &item.Kind, &item.OwnerType, &item.OwnerID, &item.OwnerName, &item.ObjectID,
+		&item.ObjectCode, &validUntil, &reviewerID, &item.Decision, &item.Reason, &decidedBy, &decidedAt,
+		&item.Applied,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем, было ли значение NULL
+	if validUntil.Valid {
+		item.ValidUntil = &validUntil.Time
+	}
+	if reviewerID.Valid {
+		item.ReviewerID = utils.Ptr(int(reviewerID.Int32))
+	}
+	if decidedBy.Valid {
+		item.DecidedBy = utils.Ptr(int(decidedBy.Int32))
+	}
+	if decidedAt.Valid {
+		item.DecidedAt = &decidedAt.Time
+	}
+
+	return item, nil
+}
+
+// InitDB инициализирует таблицы в БД для кампаний пересмотра доступа
+func (r *accessReviewRepository) InitDB() error {
+	query := `
+CREATE TABLE IF NOT EXISTS access_reviews (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	role_ids INTEGER[] NOT NULL DEFAULT '{}',
+	permission_ids INTEGER[] NOT NULL DEFAULT '{}',
+	organization_id INTEGER REFERENCES organizations(id) ON DELETE SET NULL,
+	reviewer_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'open',
+	created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	closed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	closed_at TIMESTAMPTZ
+);
+
+-- Снимок выдач хранит владельца и объект без внешних ключей, чтобы отчет сохранялся после их удаления
+CREATE TABLE IF NOT EXISTS access_review_items (
+	id SERIAL PRIMARY KEY,
+	review_id INTEGER NOT NULL REFERENCES access_reviews(id) ON DELETE CASCADE,
+	kind VARCHAR(16) NOT NULL,
+	owner_type VARCHAR(16) NOT NULL,
+	owner_id INTEGER NOT NULL,
+	owner_name VARCHAR(255) NOT NULL DEFAULT '',
+	object_id INTEGER NOT NULL,
+	object_code VARCHAR(255) NOT NULL DEFAULT '',
+	valid_until TIMESTAMPTZ,
+	reviewer_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	decision VARCHAR(16) NOT NULL DEFAULT '',
+	reason TEXT NOT NULL DEFAULT '',
+	decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	decided_at TIMESTAMPTZ,
+	applied BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS access_review_items_review_idx ON access_review_items (review_id, reviewer_id);`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize access review tables: %w", err)
+	}
+
+	log.Println("AccessReviewRepository initialized successfully")
+	return nil
+}
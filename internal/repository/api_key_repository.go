@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// apiKeyTouchInterval время последнего использования ключа обновляется не чаще этого интервала
+const apiKeyTouchInterval = "1 minute"
+
+// apiKeyColumns столбцы, из которых читается API ключ
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+// apiKeyRepository реализация интерфейса APIKeyRepository
+type apiKeyRepository struct {
+	db *DB
+}
+
+// NewAPIKeyRepository создает новый репозиторий API ключей
+func NewAPIKeyRepository(db *DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// CreateAPIKey сохраняет новый API ключ
+func (r *apiKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	query := `INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          RETURNING id, created_at`
+	if key.Scopes == nil {
+		key.Scopes = []string{}
+	}
+	err := r.db.GetConnection().QueryRow(
+		ctx, query, key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKey получает API ключ сервисного аккаунта по идентификатору, включая отозванные и истекшие.
+// Если ключ не найден, возвращается nil
+func (r *apiKeyRepository) GetAPIKey(ctx context.Context, userID, id int) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1 AND user_id = $2`
+
+	key, err := scanAPIKey(r.db.GetConnection().QueryRow(ctx, query, id, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetAPIKeyByHash получает действующий API ключ по хешу.
+// Если ключ не найден, отозван или истек, возвращается nil
+func (r *apiKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys
+	          WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())`
+
+	key, err := scanAPIKey(r.db.GetConnection().QueryRow(ctx, query, keyHash))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetAPIKeys получает все API ключи сервисного аккаунта или пользователя
+func (r *apiKeyRepository) GetAPIKeys(ctx context.Context, userID int) ([]*models.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE user_id = $1 ORDER BY id`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает API ключ.
+// Возвращает false, если ключ не найден или уже отозван
+func (r *apiKeyRepository) RevokeAPIKey(ctx context.Context, userID, id int) (bool, error) {
+	query := `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	tag, err := r.db.GetConnection().Exec(ctx, query, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// ExpireAPIKey сокращает срок действия API ключа, более поздний срок не продлевается.
+// Бессрочный ключ получает указанный срок, LEAST пропускает NULL
+func (r *apiKeyRepository) ExpireAPIKey(ctx context.Context, userID, id int, expiresAt time.Time) error {
+	query := `UPDATE api_keys SET expires_at = LEAST(expires_at, $1) WHERE id = $2 AND user_id = $3`
+	_, err := r.db.GetConnection().Exec(ctx, query, expiresAt, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to expire api key: %w", err)
+	}
+
+	return nil
+}
+
+// TouchAPIKey обновляет время последнего использования API ключа
+func (r *apiKeyRepository) TouchAPIKey(ctx context.Context, id int) error {
+	query := `UPDATE api_keys SET last_used_at = now()
+	          WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '` + apiKeyTouchInterval + `')`
+	_, err := r.db.GetConnection().Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to touch api key: %w", err)
+	}
+
+	return nil
+}
+
+// scanAPIKey читает API ключ из строки результата
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &key.Scopes,
+		&expiresAt, &lastUsedAt, &revokedAt, &key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем, было ли значение NULL
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return key, nil
+}
+
+// InitDB инициализирует таблицы в БД для API ключей
+func (r *apiKeyRepository) InitDB() error {
+	query := `
+CREATE TABLE IF NOT EXISTS api_keys (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	prefix VARCHAR(16) NOT NULL,
+	key_hash CHAR(64) UNIQUE NOT NULL,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	expires_at TIMESTAMPTZ NOT NULL,
+	last_used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
+
+-- Персональные токены доступа могут быть бессрочными
+ALTER TABLE api_keys ALTER COLUMN expires_at DROP NOT NULL;`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize api_keys table: %w", err)
+	}
+
+	log.Println("APIKeyRepository initialized successfully")
+	return nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+)
+
+// auditRepository реализация интерфейса AuditRepository
+type auditRepository struct {
+	db *DB
+}
+
+// NewAuditRepository создает новый репозиторий журнала аудита
+func NewAuditRepository(db *DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// AddAuditEntry добавляет запись в журнал аудита
+func (r *auditRepository) AddAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	query := `INSERT INTO audit_log (actor_id, user_id, impersonator_id, action, reason, details)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          RETURNING id, created_at`
+	var actorID, userID, impersonatorID sql.NullInt32
+	if entry.ActorID != nil {
+		actorID = utils.NewNullInt32(int32(*entry.ActorID))
+	}
+	if entry.UserID != nil {
+		userID = utils.NewNullInt32(int32(*entry.UserID))
+	}
+	if entry.ImpersonatorID != nil {
+		impersonatorID = utils.NewNullInt32(int32(*entry.ImpersonatorID))
+	}
+
+	err := r.db.GetConnection().QueryRow(
+		ctx, query, actorID, userID, impersonatorID, entry.Action, entry.Reason, entry.Details,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserAuditEntries получает записи журнала аудита, относящиеся к пользователю
+func (r *auditRepository) GetUserAuditEntries(ctx context.Context, userID int) ([]*models.AuditEntry, error) {
+	query := `SELECT id, actor_id, user_id, impersonator_id, action, reason, details, created_at
+	          FROM audit_log
+	          WHERE user_id = $1
+	          ORDER BY id`
+	rows, err := r.db.GetConnection().Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		entry := &models.AuditEntry{}
+		var actorID, subjectID, impersonatorID sql.NullInt32
+		err := rows.Scan(
+			&entry.ID, &actorID, &subjectID, &impersonatorID,
+			&entry.Action, &entry.Reason, &entry.Details, &entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+
+		// Проверяем, было ли значение NULL
+		if actorID.Valid {
+			entry.ActorID = utils.Ptr(int(actorID.Int32))
+		}
+		if subjectID.Valid {
+			entry.UserID = utils.Ptr(int(subjectID.Int32))
+		}
+		if impersonatorID.Valid {
+			entry.ImpersonatorID = utils.Ptr(int(impersonatorID.Int32))
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// InitDB инициализирует таблицы в БД для журнала аудита
+func (r *auditRepository) InitDB() error {
+	// Ссылки на пользователей не ограничены внешним ключом,
+	// чтобы записи сохранялись после удаления пользователя
+	query := `
+CREATE TABLE IF NOT EXISTS audit_log (
+	id SERIAL PRIMARY KEY,
+	actor_id INTEGER,
+	user_id INTEGER,
+	action VARCHAR(64) NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	details TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+alter table audit_log add IF NOT EXISTS impersonator_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS audit_log_user_id_idx ON audit_log (user_id);`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize audit_log table: %w", err)
+	}
+
+	log.Println("AuditRepository initialized successfully")
+	return nil
+}
@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -34,6 +37,52 @@ func (d *DB) GetConnection() *pgxpool.Pool {
 	return d.conn
 }
 
+// querier выполняет запросы через пул подключений или в транзакции
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// txKey ключ контекста, в котором InTx передает транзакцию
+type txKey struct{}
+
+// InTx выполняет fn в транзакции и фиксирует ее, если fn не вернула ошибку.
+// Методы репозиториев, получающие подключение через Conn, с контекстом fn выполняются в этой транзакции.
+// Вложенный вызов использует внешнюю транзакцию. Ошибка fn возвращается без изменений
+func (d *DB) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := d.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+			log.Printf("Failed to rollback transaction: %v", rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Conn возвращает транзакцию InTx из контекста, а вне транзакции - пул подключений
+func (d *DB) Conn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+
+	return d.conn
+}
+
 // Close закрывает подключение к БД
 func (d *DB) Close() error {
 	if d.conn != nil {
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// dpopRepository реализация интерфейса DPoPRepository
+type dpopRepository struct {
+	db *DB
+}
+
+// NewDPoPRepository создает новый репозиторий использованных DPoP доказательств
+func NewDPoPRepository(db *DB) DPoPRepository {
+	return &dpopRepository{db: db}
+}
+
+// RememberDPoPProof сохраняет идентификатор доказательства до expiresAt. Истекшие доказательства при этом удаляются.
+// Возвращает false, если доказательство уже использовано. Доказательства хранятся в БД,
+// поэтому повтор обнаруживается на всех репликах сервера
+func (r *dpopRepository) RememberDPoPProof(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	query := `WITH expired AS (DELETE FROM dpop_proofs WHERE expires_at <= now() AND id <> $1)
+	          INSERT INTO dpop_proofs (id, expires_at) VALUES ($1, $2)
+	          ON CONFLICT (id) DO UPDATE SET expires_at = EXCLUDED.expires_at
+	          WHERE dpop_proofs.expires_at <= now()`
+	tag, err := r.db.GetConnection().Exec(ctx, query, id, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to remember dpop proof: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// InitDB инициализирует таблицы в БД для использованных DPoP доказательств
+func (r *dpopRepository) InitDB() error {
+	query := `
+CREATE TABLE IF NOT EXISTS dpop_proofs (
+	id VARCHAR(64) PRIMARY KEY,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS dpop_proofs_expires_at_idx ON dpop_proofs (expires_at);`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize dpop_proofs table: %w", err)
+	}
+
+	log.Println("DPoPRepository initialized successfully")
+	return nil
+}
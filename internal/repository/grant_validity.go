@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+)
+
+// activeGrant условие действующей выдачи в таблице alias: срок начался и еще не истек
+func activeGrant(alias string) string {
+	return `(` + alias + `.valid_from IS NULL OR ` + alias + `.valid_from <= now())
+		AND (` + alias + `.valid_until IS NULL OR ` + alias + `.valid_until > now())`
+}
+
+// unexpiredGrant условие выдачи в таблице alias, срок которой не истек: действующей или запланированной
+func unexpiredGrant(alias string) string {
+	return `(` + alias + `.valid_until IS NULL OR ` + alias + `.valid_until > now())`
+}
+
+// grantTable таблица выдачи ролей или прав пользователям или организациям
+type grantTable struct {
+	kind      models.GrantKind
+	ownerType models.GrantOwner
+	// name имя таблицы
+	name string
+	// owner колонка пользователя или организации
+	owner string
+	// object колонка роли или права
+	object string
+	// users колонка таблицы users, по которой выбираются пользователи владельца
+	users string
+}
+
+// Таблицы выдачи ролей и прав
+var (
+	userRoleGrants = grantTable{
+		kind: models.GrantKindRole, ownerType: models.GrantOwnerUser,
+		name: "user_roles", owner: "user_id", object: "role_id", users: "id",
+	}
+	userPermissionGrants = grantTable{
+		kind: models.GrantKindPermission, ownerType: models.GrantOwnerUser,
+		name: "user_permissions", owner: "user_id", object: "permission_id", users: "id",
+	}
+	organizationRoleGrants = grantTable{
+		kind: models.GrantKindRole, ownerType: models.GrantOwnerOrganization,
+		name: "organization_roles", owner: "organization_id", object: "role_id", users: "organization_id",
+	}
+	organizationPermissionGrants = grantTable{
+		kind: models.GrantKindPermission, ownerType: models.GrantOwnerOrganization,
+		name: "organization_permissions", owner: "organization_id", object: "permission_id", users: "organization_id",
+	}
+)
+
+// insert запрос выдачи, параметры: владелец, объект, начало и окончание срока.
+// Повторная выдача заменяет срок действия.
+// Запланированная выдача отмечается pending, чтобы при наступлении срока обновить права пользователей
+func (t grantTable) insert() string {
+	return `INSERT INTO ` + t.name + ` (` + t.owner + `, ` + t.object + `, valid_from, valid_until, pending)
+		VALUES ($1, $2, $3::timestamptz, $4::timestamptz, COALESCE($3::timestamptz > now(), false))
+		ON CONFLICT (` + t.owner + `, ` + t.object + `) DO UPDATE
+		SET valid_from = EXCLUDED.valid_from, valid_until = EXCLUDED.valid_until, pending = EXCLUDED.pending`
+}
+
+// expireGrants удаляет выдачи с истекшим сроком и снимает отметку pending с выдач, срок которых начался.
+// Версия прав затронутых пользователей увеличивается в том же запросе
+func expireGrants(ctx context.Context, db *DB, t grantTable) ([]*models.ExpiredGrant, error) {
+	query := `
+		WITH activated AS (
+			UPDATE ` + t.name + ` SET pending = false
+			WHERE pending AND valid_from <= now() AND (valid_until IS NULL OR valid_until > now())
+			RETURNING ` + t.owner + `
+		), expired AS (
+			DELETE FROM ` + t.name + ` WHERE valid_until <= now()
+			RETURNING ` + t.owner + `, ` + t.object + `, valid_until
+		), bumped AS (
+			UPDATE users SET grant_version = grant_version + 1
+			WHERE ` + t.users + ` IN (SELECT ` + t.owner + ` FROM activated UNION SELECT ` + t.owner + ` FROM expired)
+		)
+		SELECT ` + t.owner + `, ` + t.object + `, valid_until FROM expired`
+	rows, err := db.GetConnection().Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire %s grants: %w", t.name, err)
+	}
+	defer rows.Close()
+
+	var expired []*models.ExpiredGrant
+	for rows.Next() {
+		grant := &models.ExpiredGrant{Kind: t.kind}
+		if err := rows.Scan(&grant.OwnerID, &grant.ObjectID, &grant.ValidUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan expired grant: %w", err)
+		}
+		expired = append(expired, grant)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate expired grants: %w", err)
+	}
+
+	return expired, nil
+}
+
+// expireGrantTables выполняет expireGrants для каждой таблицы
+func expireGrantTables(ctx context.Context, db *DB, tables ...grantTable) ([]*models.ExpiredGrant, error) {
+	var expired []*models.ExpiredGrant
+	for _, t := range tables {
+		grants, err := expireGrants(ctx, db, t)
+		if err != nil {
+			return expired, err
+		}
+		expired = append(expired, grants...)
+	}
+
+	return expired, nil
+}
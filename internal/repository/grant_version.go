@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+// Выборки пользователей, на права которых влияет изменение. Параметр $1 - идентификатор измененного объекта
+const (
+	// usersByID пользователь с идентификатором $1
+	usersByID = `SELECT $1::integer`
+	// usersInOrganization пользователи организации $1
+	usersInOrganization = `SELECT id FROM users WHERE organization_id = $1`
+	// usersWithTariff пользователи с тарифом $1, выданным напрямую или через организацию
+	usersWithTariff = `SELECT id FROM users WHERE tariff_id = $1
+		UNION SELECT u.id FROM users u JOIN organizations o ON o.id = u.organization_id WHERE o.tariff_id = $1`
+)
+
+// usersWithRoles пользователи, получающие роли из выборки roles напрямую, через организацию или через тариф
+func usersWithRoles(roles string) string {
+	return `SELECT user_id FROM user_roles WHERE role_id IN (` + roles + `)
+		UNION SELECT u.id FROM users u JOIN organization_roles r ON r.organization_id = u.organization_id
+			WHERE r.role_id IN (` + roles + `)
+		UNION SELECT u.id FROM users u JOIN tariff_roles t ON t.tariff_id = u.tariff_id
+			WHERE t.role_id IN (` + roles + `)
+		UNION SELECT u.id FROM users u JOIN organizations o ON o.id = u.organization_id
+			JOIN tariff_roles t ON t.tariff_id = o.tariff_id WHERE t.role_id IN (` + roles + `)`
+}
+
+var (
+	// usersWithRole пользователи с ролью $1
+	usersWithRole = usersWithRoles(`SELECT $1::integer`)
+	// usersWithPermission пользователи с правом $1, выданным напрямую, через организацию или через роль
+	usersWithPermission = `SELECT user_id FROM user_permissions WHERE permission_id = $1
+		UNION SELECT u.id FROM users u JOIN organization_permissions p ON p.organization_id = u.organization_id
+			WHERE p.permission_id = $1
+		UNION ` + usersWithRoles(`SELECT role_id FROM role_permissions WHERE permission_id = $1`)
+)
+
+// bumpGrantVersion увеличивает версию прав пользователей из выборки users.
+// Токены, выпущенные с прежней версией, перестают считаться актуальными
+func bumpGrantVersion(ctx context.Context, db *DB, users string, id int) error {
+	query := `UPDATE users SET grant_version = grant_version + 1 WHERE id IN (` + users + `)`
+	_, err := db.Conn(ctx).Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to bump grant version: %w", err)
+	}
+
+	return nil
+}
@@ -2,10 +2,16 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/LiFeAiR/crud-ai/internal/models"
 )
 
+// Transactor выполняет изменения нескольких репозиториев в одной транзакции
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 // UserRepository интерфейс для работы с пользователями
 type UserRepository interface {
 	CheckPassword(ctx context.Context, userID int, password string) (bool, error)
@@ -14,13 +20,21 @@ type UserRepository interface {
 	GetUserByID(ctx context.Context, id int) (*models.User, error)
 	UpdateUser(ctx context.Context, user *models.User) error
 	DeleteUser(ctx context.Context, id int) error
-	GetUsers(ctx context.Context, limit, offset int) ([]*models.User, error)
+	GetUsers(ctx context.Context, limit, offset int, status models.UserStatus) ([]*models.User, error)
+	GetServiceAccounts(ctx context.Context, limit, offset int) ([]*models.User, error)
+	SetUserStatus(ctx context.Context, userID int, status models.UserStatus, reason string) error
+	GetUserAuthState(ctx context.Context, userID int) (*models.UserAuthState, error)
+	ResetUserPassword(ctx context.Context, userID int, passwordHash string) error
+	SetUserPendingEmail(ctx context.Context, userID int, email string) error
+	VerifyUserEmail(ctx context.Context, userID int, email string) (bool, error)
 	GetUserPermissions(ctx context.Context, userID int) ([]*models.Permission, error)
-	AddUserPermissions(ctx context.Context, userID int, permissionIDs []int) error
+	GetUserDirectPermissions(ctx context.Context, userID int) ([]*models.Permission, error)
+	AddUserPermissions(ctx context.Context, userID int, permissionIDs []int, validity models.GrantValidity) error
 	DeleteUserPermissions(ctx context.Context, userID int, permissionIDs []int) error
 	GetUserRoles(ctx context.Context, userID int) ([]*models.Role, error)
-	AddUserRoles(ctx context.Context, userID int, roleIDs []int) error
+	AddUserRoles(ctx context.Context, userID int, roleIDs []int, validity models.GrantValidity) error
 	DeleteUserRoles(ctx context.Context, userID int, roleIDs []int) error
+	ExpireUserGrants(ctx context.Context) ([]*models.ExpiredGrant, error)
 	SetUserTariff(ctx context.Context, userID int, tariffID *int32) error
 	GetUserTariff(ctx context.Context, userID int) (*models.Tariff, error)
 	InitDB() error
@@ -34,13 +48,23 @@ type OrganizationRepository interface {
 	DeleteOrganization(ctx context.Context, id int) error
 	GetOrganizations(ctx context.Context, limit, offset int) ([]*models.Organization, error)
 	GetOrganizationPermissions(ctx context.Context, organizationID int) ([]*models.Permission, error)
-	AddOrganizationPermissions(ctx context.Context, organizationID int, permissionIDs []int) error
+	AddOrganizationPermissions(
+		ctx context.Context, organizationID int, permissionIDs []int, validity models.GrantValidity,
+	) error
 	DeleteOrganizationPermissions(ctx context.Context, organizationID int, permissionIDs []int) error
 	GetOrganizationRoles(ctx context.Context, organizationID int) ([]*models.Role, error)
-	AddOrganizationRoles(ctx context.Context, organizationID int, roleIDs []int) error
+	AddOrganizationRoles(ctx context.Context, organizationID int, roleIDs []int, validity models.GrantValidity) error
 	DeleteOrganizationRoles(ctx context.Context, organizationID int, roleIDs []int) error
+	ExpireOrganizationGrants(ctx context.Context) ([]*models.ExpiredGrant, error)
 	SetOrganizationTariff(ctx context.Context, orgID int, tariffID *int32) error
 	GetOrganizationTariff(ctx context.Context, orgID int) (*models.Tariff, error)
+	GetOrganizationSettings(ctx context.Context, orgID int) (*models.OrganizationSettings, error)
+	UpdateOrganizationSettings(ctx context.Context, settings *models.OrganizationSettings) error
+	GetOrganizationAdmins(ctx context.Context, orgID int) ([]int, error)
+	GetUserAdminOrganizations(ctx context.Context, userID int) ([]int, error)
+	SetOrganizationAdmins(ctx context.Context, orgID int, userIDs []int) error
+	IsOrganizationAdmin(ctx context.Context, orgID, userID int) (bool, error)
+	IsOrganizationRole(ctx context.Context, orgID, roleID int) (bool, error)
 	InitDB() error
 }
 
@@ -51,6 +75,7 @@ type PermissionRepository interface {
 	UpdatePermission(ctx context.Context, permission *models.Permission) error
 	DeletePermission(ctx context.Context, id int) error
 	GetPermissions(ctx context.Context, limit, offset int) ([]*models.Permission, error)
+	GetPermissionsByCodes(ctx context.Context, codes []string) ([]*models.Permission, error)
 	InitDB() error
 }
 
@@ -79,3 +104,146 @@ type TariffRepository interface {
 	DeleteTariffRoles(ctx context.Context, tariffID int, roleIDs []int) error
 	InitDB() error
 }
+
+// AuditRepository интерфейс для работы с журналом аудита
+type AuditRepository interface {
+	AddAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	GetUserAuditEntries(ctx context.Context, userID int) ([]*models.AuditEntry, error)
+	InitDB() error
+}
+
+// LoginAttemptRepository интерфейс для работы с попытками входа
+type LoginAttemptRepository interface {
+	AddLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error
+	GetUserLoginAttempts(ctx context.Context, userID int) ([]*models.LoginAttempt, error)
+	GetFailedLoginStats(ctx context.Context, email, ip string, since time.Time) (*models.LoginFailureStats, error)
+	ClearFailedLoginAttempts(ctx context.Context, email string) error
+	InitDB() error
+}
+
+// TokenRepository интерфейс для работы с одноразовыми токенами
+type TokenRepository interface {
+	CreateToken(ctx context.Context, token *models.OneTimeToken) error
+	GetToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.OneTimeToken, error)
+	ConsumeToken(ctx context.Context, purpose models.TokenPurpose, tokenHash string) (*models.OneTimeToken, error)
+	DeleteUserTokens(ctx context.Context, userID int, purpose models.TokenPurpose) error
+	InitDB() error
+}
+
+// TwoFactorRepository интерфейс для работы с двухфакторной аутентификацией
+type TwoFactorRepository interface {
+	GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error)
+	GetRecoveryCodes(ctx context.Context, userID int) ([]*models.RecoveryCode, error)
+	SaveTwoFactorSecret(ctx context.Context, userID int, secret string) error
+	EnableTwoFactor(ctx context.Context, userID int, recoveryCodeHashes []string) error
+	DisableTwoFactor(ctx context.Context, userID int) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+	InitDB() error
+}
+
+// SessionRepository интерфейс для работы с сессиями пользователей
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+	TouchSession(ctx context.Context, id string) error
+	GetUserSessions(ctx context.Context, userID int) ([]*models.Session, error)
+	GetUserSessionHistory(ctx context.Context, userID int) ([]*models.Session, error)
+	RevokeSession(ctx context.Context, userID int, id string) (bool, error)
+	RevokeUserSessions(ctx context.Context, userID int, exceptID string) (int, error)
+	RevokeOAuthClientSessions(ctx context.Context, clientID, userID int) (int, error)
+	InitDB() error
+}
+
+// APIKeyRepository интерфейс для работы с API ключами сервисных аккаунтов
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKey(ctx context.Context, userID, id int) (*models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	GetAPIKeys(ctx context.Context, userID int) ([]*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id int) (bool, error)
+	ExpireAPIKey(ctx context.Context, userID, id int, expiresAt time.Time) error
+	TouchAPIKey(ctx context.Context, id int) error
+	InitDB() error
+}
+
+// OIDCRepository интерфейс для работы с провайдерами OpenID Connect организаций
+type OIDCRepository interface {
+	GetOIDCProvider(ctx context.Context, orgID int) (*models.OIDCProvider, error)
+	SaveOIDCProvider(ctx context.Context, provider *models.OIDCProvider) error
+	DeleteOIDCProvider(ctx context.Context, orgID int) (bool, error)
+	CreateOIDCState(ctx context.Context, state *models.OIDCLoginState) error
+	ConsumeOIDCState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error)
+	GetOIDCIdentity(ctx context.Context, providerID int, subject string) (*models.OIDCIdentity, error)
+	GetUserOIDCIdentities(ctx context.Context, userID int) ([]*models.OIDCIdentity, error)
+	CreateOIDCIdentity(ctx context.Context, identity *models.OIDCIdentity) error
+	TouchOIDCIdentity(ctx context.Context, id int, email string) error
+	InitDB() error
+}
+
+// AccessRequestRepository интерфейс для работы с запросами доступа и утверждающими ролей
+type AccessRequestRepository interface {
+	CreateAccessRequest(ctx context.Context, request *models.AccessRequest) (bool, error)
+	GetAccessRequest(ctx context.Context, id int) (*models.AccessRequest, error)
+	GetPendingAccessRequests(
+		ctx context.Context, approver models.AccessApprover, limit, offset int,
+	) ([]*models.AccessRequest, error)
+	GetUserAccessRequests(ctx context.Context, userID int) ([]*models.AccessRequest, error)
+	DecideAccessRequest(ctx context.Context, request *models.AccessRequest) (bool, error)
+	ReopenAccessRequest(ctx context.Context, id int) error
+	GetRoleApprovers(ctx context.Context, roleID int) ([]int, error)
+	SetRoleApprovers(ctx context.Context, roleID int, userIDs []int) error
+	GetAccessApprovers(ctx context.Context, roleID, organizationID int) ([]*models.User, error)
+	InitDB() error
+}
+
+// AccessReviewRepository интерфейс для работы с кампаниями пересмотра доступа
+type AccessReviewRepository interface {
+	CreateAccessReview(ctx context.Context, review *models.AccessReview) error
+	GetAccessReview(ctx context.Context, id int) (*models.AccessReview, error)
+	GetAccessReviews(ctx context.Context, reviewerID, limit, offset int) ([]*models.AccessReview, error)
+	GetAccessReviewItems(
+		ctx context.Context, reviewID, reviewerID, limit, offset int,
+	) ([]*models.AccessReviewItem, error)
+	GetUserAccessReviewItems(ctx context.Context, userID int) ([]*models.AccessReviewItem, error)
+	GetAccessReviewItem(ctx context.Context, id int) (*models.AccessReviewItem, error)
+	DecideAccessReviewItem(ctx context.Context, item *models.AccessReviewItem) (bool, error)
+	ReassignAccessReviewItem(ctx context.Context, id, reviewerID int) (bool, error)
+	CloseAccessReview(ctx context.Context, review *models.AccessReview) (bool, error)
+	RevokeAccessReviewGrants(ctx context.Context, reviewID int) ([]*models.AccessReviewItem, error)
+	InitDB() error
+}
+
+// SoDRepository интерфейс для работы с ограничениями разделения обязанностей
+type SoDRepository interface {
+	CreateSoDConstraint(ctx context.Context, constraint *models.SoDConstraint) error
+	GetSoDConstraints(ctx context.Context, limit, offset int) ([]*models.SoDConstraint, error)
+	DeleteSoDConstraint(ctx context.Context, id int) (bool, error)
+	GetSoDConflicts(
+		ctx context.Context, owner models.GrantOwner, ownerID int, roleIDs []int,
+	) ([]*models.SoDViolation, error)
+	GetSoDMembershipConflicts(
+		ctx context.Context, owner models.GrantOwner, ownerID int, target models.GrantOwner, targetID int,
+	) ([]*models.SoDViolation, error)
+	GetSoDViolations(ctx context.Context, constraintID, limit, offset int) ([]*models.SoDViolation, error)
+	InitDB() error
+}
+
+// OAuthRepository интерфейс для работы с OAuth клиентами и согласиями пользователей
+type OAuthRepository interface {
+	CreateOAuthClient(ctx context.Context, client *models.OAuthClient) error
+	GetOAuthClient(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	GetOAuthClients(ctx context.Context, limit, offset int) ([]*models.OAuthClient, error)
+	DeleteOAuthClient(ctx context.Context, id int) (bool, error)
+	GetOAuthConsent(ctx context.Context, userID, oauthClientID int) (*models.OAuthConsent, error)
+	SaveOAuthConsent(ctx context.Context, consent *models.OAuthConsent) error
+	GetUserOAuthConsents(ctx context.Context, userID int) ([]*models.OAuthConsent, error)
+	DeleteOAuthConsent(ctx context.Context, userID, oauthClientID int) (bool, error)
+	InitDB() error
+}
+
+// DPoPRepository интерфейс для работы с использованными DPoP доказательствами
+type DPoPRepository interface {
+	RememberDPoPProof(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+	InitDB() error
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+)
+
+// loginAttemptRepository реализация интерфейса LoginAttemptRepository
+type loginAttemptRepository struct {
+	db *DB
+}
+
+// NewLoginAttemptRepository создает новый репозиторий попыток входа
+func NewLoginAttemptRepository(db *DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+// AddLoginAttempt сохраняет попытку входа
+func (r *loginAttemptRepository) AddLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	query := `INSERT INTO login_attempts (email, ip, user_id, success) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	var userID sql.NullInt32
+	if attempt.UserID != nil {
+		userID = utils.NewNullInt32(int32(*attempt.UserID))
+	}
+
+	err := r.db.GetConnection().QueryRow(ctx, query, attempt.Email, attempt.IP, userID, attempt.Success).
+		Scan(&attempt.ID, &attempt.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add login attempt: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserLoginAttempts получает попытки входа в аккаунт пользователя
+func (r *loginAttemptRepository) GetUserLoginAttempts(ctx context.Context, userID int) ([]*models.LoginAttempt, error) {
+	query := `SELECT id, email, ip, user_id, success, created_at FROM login_attempts WHERE user_id = $1 ORDER BY id`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []*models.LoginAttempt
+	for rows.Next() {
+		attempt := &models.LoginAttempt{}
+		var attemptUserID sql.NullInt32
+		err := rows.Scan(&attempt.ID, &attempt.Email, &attempt.IP, &attemptUserID, &attempt.Success, &attempt.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan login attempt: %w", err)
+		}
+		if attemptUserID.Valid {
+			attempt.UserID = utils.Ptr(int(attemptUserID.Int32))
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating login attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// GetFailedLoginStats получает количество и время последней неудачной попытки
+// для email и для IP начиная с момента since
+func (r *loginAttemptRepository) GetFailedLoginStats(
+	ctx context.Context,
+	email, ip string,
+	since time.Time,
+) (*models.LoginFailureStats, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE email = $1),
+			MAX(created_at) FILTER (WHERE email = $1),
+			COUNT(*) FILTER (WHERE ip = $2),
+			MAX(created_at) FILTER (WHERE ip = $2)
+		FROM login_attempts
+		WHERE NOT success AND created_at >= $3 AND (email = $1 OR ip = $2)`
+
+	stats := &models.LoginFailureStats{}
+	var accountLast, ipLast sql.NullTime
+	err := r.db.GetConnection().QueryRow(ctx, query, email, ip, since).
+		Scan(&stats.AccountFailures, &accountLast, &stats.IPFailures, &ipLast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failed login stats: %w", err)
+	}
+
+	// Проверяем, было ли значение NULL
+	if accountLast.Valid {
+		stats.AccountLastFailure = &accountLast.Time
+	}
+	if ipLast.Valid {
+		stats.IPLastFailure = &ipLast.Time
+	}
+
+	return stats, nil
+}
+
+// ClearFailedLoginAttempts удаляет неудачные попытки входа для email
+func (r *loginAttemptRepository) ClearFailedLoginAttempts(ctx context.Context, email string) error {
+	query := `DELETE FROM login_attempts WHERE email = $1 AND NOT success`
+	_, err := r.db.GetConnection().Exec(ctx, query, email)
+	if err != nil {
+		return fmt.Errorf("failed to clear failed login attempts: %w", err)
+	}
+
+	return nil
+}
+
+// InitDB инициализирует таблицы в БД для попыток входа
+func (r *loginAttemptRepository) InitDB() error {
+	query := `
+CREATE TABLE IF NOT EXISTS login_attempts (
+	id SERIAL PRIMARY KEY,
+	email VARCHAR(255) NOT NULL,
+	ip VARCHAR(64) NOT NULL DEFAULT '',
+	user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	success BOOLEAN NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_email_idx ON login_attempts (email, created_at);
+CREATE INDEX IF NOT EXISTS login_attempts_ip_idx ON login_attempts (ip, created_at);`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize login_attempts table: %w", err)
+	}
+
+	log.Println("LoginAttemptRepository initialized successfully")
+	return nil
+}
@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// oauthClientColumns столбцы, из которых читается OAuth клиент
+const oauthClientColumns = `id, client_id, name, secret_hash, redirect_uris, scopes, service_account_id, created_at`
+
+// oauthRepository реализация интерфейса OAuthRepository
+type oauthRepository struct {
+	db *DB
+}
+
+// NewOAuthRepository создает новый репозиторий OAuth клиентов
+func NewOAuthRepository(db *DB) OAuthRepository {
+	return &oauthRepository{db: db}
+}
+
+// CreateOAuthClient сохраняет нового OAuth клиента
+func (r *oauthRepository) CreateOAuthClient(ctx context.Context, client *models.OAuthClient) error {
+	query := `INSERT INTO oauth_clients (client_id, name, secret_hash, redirect_uris, scopes, service_account_id)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          RETURNING id, created_at`
+	if client.RedirectURIs == nil {
+		client.RedirectURIs = []string{}
+	}
+	var serviceAccountID sql.NullInt32
+	if client.ServiceAccountID != nil {
+		serviceAccountID = utils.NewNullInt32(int32(*client.ServiceAccountID))
+	}
+	err := r.db.GetConnection().QueryRow(ctx, query,
+		client.ClientID, client.Name, client.SecretHash, client.RedirectURIs, client.Scopes, serviceAccountID,
+	).Scan(&client.ID, &client.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create oauth client: %w", err)
+	}
+
+	return nil
+}
+
+// GetOAuthClient получает OAuth клиента по публичному идентификатору.
+// Если клиент не найден, возвращается nil
+func (r *oauthRepository) GetOAuthClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients WHERE client_id = $1`
+
+	client, err := scanOAuthClient(r.db.GetConnection().QueryRow(ctx, query, clientID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get oauth client: %w", err)
+	}
+
+	return client, nil
+}
+
+// GetOAuthClients получает список OAuth клиентов
+func (r *oauthRepository) GetOAuthClients(ctx context.Context, limit, offset int) ([]*models.OAuthClient, error) {
+	query := `SELECT ` + oauthClientColumns + ` FROM oauth_clients ORDER BY id LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth clients: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []*models.OAuthClient
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan oauth client: %w", err)
+		}
+		clients = append(clients, client)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating oauth clients: %w", err)
+	}
+
+	return clients, nil
+}
+
+// DeleteOAuthClient удаляет OAuth клиента вместе с согласиями пользователей.
+// Возвращает false, если клиент не найден
+func (r *oauthRepository) DeleteOAuthClient(ctx context.Context, id int) (bool, error) {
+	query := `DELETE FROM oauth_clients WHERE id = $1`
+	tag, err := r.db.GetConnection().Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete oauth client: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetOAuthConsent получает согласие пользователя для клиента.
+// Если согласие не давалось, возвращается nil
+func (r *oauthRepository) GetOAuthConsent(ctx context.Context, userID, oauthClientID int) (*models.OAuthConsent, error) {
+	query := `SELECT c.user_id, c.oauth_client_id, oc.client_id, oc.name, c.scopes, c.created_at, c.updated_at
+	          FROM oauth_consents c
+	          JOIN oauth_clients oc ON oc.id = c.oauth_client_id
+	          WHERE c.user_id = $1 AND c.oauth_client_id = $2`
+
+	consent, err := scanOAuthConsent(r.db.GetConnection().QueryRow(ctx, query, userID, oauthClientID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get oauth consent: %w", err)
+	}
+
+	return consent, nil
+}
+
+// SaveOAuthConsent сохраняет согласие пользователя. Права добавляются к ранее одобренным
+func (r *oauthRepository) SaveOAuthConsent(ctx context.Context, consent *models.OAuthConsent) error {
+	query := `INSERT INTO oauth_consents (user_id, oauth_client_id, scopes)
+	          VALUES ($1, $2, $3)
+	          ON CONFLICT (user_id, oauth_client_id) DO UPDATE
+	          SET scopes = ARRAY(SELECT DISTINCT unnest(oauth_consents.scopes || EXCLUDED.scopes) ORDER BY 1),
+	              updated_at = now()
+	          RETURNING scopes, created_at, updated_at`
+	err := r.db.GetConnection().QueryRow(ctx, query, consent.UserID, consent.OAuthClientID, consent.Scopes).
+		Scan(&consent.Scopes, &consent.CreatedAt, &consent.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save oauth consent: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserOAuthConsents получает все согласия пользователя
+func (r *oauthRepository) GetUserOAuthConsents(ctx context.Context, userID int) ([]*models.OAuthConsent, error) {
+	query := `SELECT c.user_id, c.oauth_client_id, oc.client_id, oc.name, c.scopes, c.created_at, c.updated_at
+	          FROM oauth_consents c
+	          JOIN oauth_clients oc ON oc.id = c.oauth_client_id
+	          WHERE c.user_id = $1
+	          ORDER BY c.created_at`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oauth consents: %w", err)
+	}
+	defer rows.Close()
+
+	var consents []*models.OAuthConsent
+	for rows.Next() {
+		consent, err := scanOAuthConsent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan oauth consent: %w", err)
+		}
+		consents = append(consents, consent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating oauth consents: %w", err)
+	}
+
+	return consents, nil
+}
+
+// DeleteOAuthConsent отзывает согласие пользователя.
+// Возвращает false, если согласие не давалось
+func (r *oauthRepository) DeleteOAuthConsent(ctx context.Context, userID, oauthClientID int) (bool, error) {
+	query := `DELETE FROM oauth_consents WHERE user_id = $1 AND oauth_client_id = $2`
+	tag, err := r.db.GetConnection().Exec(ctx, query, userID, oauthClientID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete oauth consent: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// scanOAuthClient читает OAuth клиента из строки результата
+func scanOAuthClient(row pgx.Row) (*models.OAuthClient, error) {
+	client := &models.OAuthClient{}
+	var serviceAccountID sql.NullInt32
+	err := row.Scan(
+		&client.ID, &client.ClientID, &client.Name, &client.SecretHash, &client.RedirectURIs, &client.Scopes,
+		&serviceAccountID, &client.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем, было ли значение NULL
+	if serviceAccountID.Valid {
+		client.ServiceAccountID = utils.Ptr(int(serviceAccountID.Int32))
+	}
+
+	return client, nil
+}
+
+// scanOAuthConsent читает согласие пользователя из строки результата
+func scanOAuthConsent(row pgx.Row) (*models.OAuthConsent, error) {
+	consent := &models.OAuthConsent{}
+	err := row.Scan(
+		&consent.UserID, &consent.OAuthClientID, &consent.ClientID, &consent.ClientName, &consent.Scopes,
+		&consent.CreatedAt, &consent.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return consent, nil
+}
+
+// InitDB инициализирует таблицы в БД для OAuth клиентов
+func (r *oauthRepository) InitDB() error {
+	query := `
+CREATE TABLE IF NOT EXISTS oauth_clients (
+	id SERIAL PRIMARY KEY,
+	client_id VARCHAR(64) UNIQUE NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	secret_hash CHAR(64) NOT NULL,
+	redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	service_account_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS oauth_consents (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	oauth_client_id INTEGER NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_id, oauth_client_id)
+);`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize oauth tables: %w", err)
+	}
+
+	log.Println("OAuthRepository initialized successfully")
+	return nil
+}
@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// oidcProviderColumns столбцы, из которых читается провайдер OpenID Connect
+const oidcProviderColumns = `id, organization_id, issuer, client_id, client_secret, scopes, email_claim, name_claim,
+	auto_provision, enabled, created_at, updated_at`
+
+// oidcRepository реализация интерфейса OIDCRepository
+type oidcRepository struct {
+	db *DB
+}
+
+// NewOIDCRepository создает новый репозиторий провайдеров OpenID Connect
+func NewOIDCRepository(db *DB) OIDCRepository {
+	return &oidcRepository{db: db}
+}
+
+// GetOIDCProvider получает провайдера OpenID Connect организации.
+// Если провайдер не настроен, возвращается nil
+func (r *oidcRepository) GetOIDCProvider(ctx context.Context, orgID int) (*models.OIDCProvider, error) {
+	query := `SELECT ` + oidcProviderColumns + ` FROM oidc_providers WHERE organization_id = $1`
+
+	provider := &models.OIDCProvider{}
+	err := r.db.GetConnection().QueryRow(ctx, query, orgID).Scan(
+		&provider.ID, &provider.OrganizationID, &provider.Issuer, &provider.ClientID, &provider.ClientSecret,
+		&provider.Scopes, &provider.EmailClaim, &provider.NameClaim, &provider.AutoProvision, &provider.Enabled,
+		&provider.CreatedAt, &provider.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get oidc provider: %w", err)
+	}
+
+	return provider, nil
+}
+
+// SaveOIDCProvider создает или обновляет провайдера OpenID Connect организации.
+// Пустой секрет клиента не заменяет сохраненный
+func (r *oidcRepository) SaveOIDCProvider(ctx context.Context, provider *models.OIDCProvider) error {
+	query := `INSERT INTO oidc_providers (organization_id, issuer, client_id, client_secret, scopes, email_claim,
+	              name_claim, auto_provision, enabled)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	          ON CONFLICT (organization_id) DO UPDATE
+	          SET issuer = EXCLUDED.issuer, client_id = EXCLUDED.client_id,
+	              client_secret = COALESCE(NULLIF(EXCLUDED.client_secret, ''), oidc_providers.client_secret),
+	              scopes = EXCLUDED.scopes, email_claim = EXCLUDED.email_claim, name_claim = EXCLUDED.name_claim,
+	              auto_provision = EXCLUDED.auto_provision, enabled = EXCLUDED.enabled, updated_at = now()
+	          RETURNING id, client_secret, created_at, updated_at`
+	if provider.Scopes == nil {
+		provider.Scopes = []string{}
+	}
+	err := r.db.GetConnection().QueryRow(ctx, query,
+		provider.OrganizationID, provider.Issuer, provider.ClientID, provider.ClientSecret, provider.Scopes,
+		provider.EmailClaim, provider.NameClaim, provider.AutoProvision, provider.Enabled,
+	).Scan(&provider.ID, &provider.ClientSecret, &provider.CreatedAt, &provider.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save oidc provider: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteOIDCProvider удаляет провайдера OpenID Connect организации вместе со связями пользователей.
+// Возвращает false, если провайдер не был настроен
+func (r *oidcRepository) DeleteOIDCProvider(ctx context.Context, orgID int) (bool, error) {
+	query := `DELETE FROM oidc_providers WHERE organization_id = $1`
+	tag, err := r.db.GetConnection().Exec(ctx, query, orgID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete oidc provider: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// CreateOIDCState сохраняет незавершенный вход. Истекшие входы при этом удаляются
+func (r *oidcRepository) CreateOIDCState(ctx context.Context, state *models.OIDCLoginState) error {
+	query := `WITH expired AS (DELETE FROM oidc_login_states WHERE expires_at <= now())
+	          INSERT INTO oidc_login_states
+	              (state_hash, organization_id, nonce, code_verifier, dpop_jkt, session_cookie, expires_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.db.GetConnection().Exec(ctx, query,
+		state.StateHash, state.OrganizationID, state.Nonce, state.CodeVerifier, state.DPoPJKT, state.SessionCookie,
+		state.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create oidc state: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeOIDCState удаляет незавершенный вход и возвращает его.
+// Если вход не найден или истек, возвращается nil
+func (r *oidcRepository) ConsumeOIDCState(ctx context.Context, stateHash string) (*models.OIDCLoginState, error) {
+	query := `DELETE FROM oidc_login_states
+	          WHERE state_hash = $1 AND expires_at > now()
+	          RETURNING state_hash, organization_id, nonce, code_verifier, dpop_jkt, session_cookie, expires_at`
+
+	state := &models.OIDCLoginState{}
+	err := r.db.GetConnection().QueryRow(ctx, query, stateHash).Scan(
+		&state.StateHash, &state.OrganizationID, &state.Nonce, &state.CodeVerifier, &state.DPoPJKT,
+		&state.SessionCookie, &state.ExpiresAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to consume oidc state: %w", err)
+	}
+
+	return state, nil
+}
+
+// GetOIDCIdentity получает связь с учетной записью провайдера по claim sub.
+// Если связь не найдена, возвращается nil
+func (r *oidcRepository) GetOIDCIdentity(ctx context.Context, providerID int, subject string) (*models.OIDCIdentity, error) {
+	query := `SELECT id, provider_id, user_id, subject, email, created_at, last_login_at
+	          FROM oidc_identities WHERE provider_id = $1 AND subject = $2`
+
+	identity := &models.OIDCIdentity{}
+	var lastLoginAt sql.NullTime
+	err := r.db.GetConnection().QueryRow(ctx, query, providerID, subject).Scan(
+		&identity.ID, &identity.ProviderID, &identity.UserID, &identity.Subject, &identity.Email,
+		&identity.CreatedAt, &lastLoginAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get oidc identity: %w", err)
+	}
+
+	// Проверяем, было ли значение NULL
+	if lastLoginAt.Valid {
+		identity.LastLoginAt = &lastLoginAt.Time
+	}
+
+	return identity, nil
+}
+
+// GetUserOIDCIdentities получает учетные записи провайдеров, связанные с пользователем
+func (r *oidcRepository) GetUserOIDCIdentities(ctx context.Context, userID int) ([]*models.OIDCIdentity, error) {
+	query := `SELECT id, provider_id, user_id, subject, email, created_at, last_login_at
+	          FROM oidc_identities WHERE user_id = $1 ORDER BY id`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oidc identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*models.OIDCIdentity
+	for rows.Next() {
+		identity := &models.OIDCIdentity{}
+		var lastLoginAt sql.NullTime
+		err := rows.Scan(
+			&identity.ID, &identity.ProviderID, &identity.UserID, &identity.Subject, &identity.Email,
+			&identity.CreatedAt, &lastLoginAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan oidc identity: %w", err)
+		}
+		if lastLoginAt.Valid {
+			identity.LastLoginAt = &lastLoginAt.Time
+		}
+		identities = append(identities, identity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating oidc identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+// CreateOIDCIdentity связывает пользователя с учетной записью провайдера
+func (r *oidcRepository) CreateOIDCIdentity(ctx context.Context, identity *models.OIDCIdentity) error {
+	query := `INSERT INTO oidc_identities (provider_id, user_id, subject, email, last_login_at)
+	          VALUES ($1, $2, $3, $4, now())
+	          RETURNING id, created_at`
+	err := r.db.GetConnection().QueryRow(ctx, query,
+		identity.ProviderID, identity.UserID, identity.Subject, identity.Email,
+	).Scan(&identity.ID, &identity.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create oidc identity: %w", err)
+	}
+
+	return nil
+}
+
+// TouchOIDCIdentity обновляет время последнего входа и email, полученный от провайдера
+func (r *oidcRepository) TouchOIDCIdentity(ctx context.Context, id int, email string) error {
+	query := `UPDATE oidc_identities SET last_login_at = now(), email = $2 WHERE id = $1`
+	_, err := r.db.GetConnection().Exec(ctx, query, id, email)
+	if err != nil {
+		return fmt.Errorf("failed to touch oidc identity: %w", err)
+	}
+
+	return nil
+}
+
+// InitDB инициализирует таблицы в БД для провайдеров OpenID Connect
+func (r *oidcRepository) InitDB() error {
+	query := `
+CREATE TABLE IF NOT EXISTS oidc_providers (
+	id SERIAL PRIMARY KEY,
+	organization_id INTEGER UNIQUE NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+	issuer VARCHAR(512) NOT NULL,
+	client_id VARCHAR(255) NOT NULL,
+	client_secret TEXT NOT NULL DEFAULT '',
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	email_claim VARCHAR(64) NOT NULL DEFAULT 'email',
+	name_claim VARCHAR(64) NOT NULL DEFAULT 'name',
+	auto_provision BOOLEAN NOT NULL DEFAULT false,
+	enabled BOOLEAN NOT NULL DEFAULT true,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS oidc_identities (
+	id SERIAL PRIMARY KEY,
+	provider_id INTEGER NOT NULL REFERENCES oidc_providers(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	subject VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_login_at TIMESTAMPTZ,
+	UNIQUE (provider_id, subject)
+);
+
+CREATE INDEX IF NOT EXISTS oidc_identities_user_id_idx ON oidc_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+	state_hash CHAR(64) PRIMARY KEY,
+	organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+	nonce VARCHAR(64) NOT NULL,
+	code_verifier VARCHAR(128) NOT NULL,
+	dpop_jkt VARCHAR(64) NOT NULL DEFAULT '',
+	session_cookie BOOLEAN NOT NULL DEFAULT false,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+alter table oidc_login_states
+    add IF NOT EXISTS dpop_jkt VARCHAR(64) NOT NULL DEFAULT '';
+
+alter table oidc_login_states
+    add IF NOT EXISTS session_cookie BOOLEAN NOT NULL DEFAULT false;`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize oidc tables: %w", err)
+	}
+
+	log.Println("OIDCRepository initialized successfully")
+	return nil
+}
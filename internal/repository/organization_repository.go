@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/LiFeAiR/crud-ai/internal/models"
 	"github.com/LiFeAiR/crud-ai/internal/utils"
@@ -66,6 +67,11 @@ func (r *organizationRepository) UpdateOrganization(ctx context.Context, org *mo
 
 // DeleteOrganization удаляет организацию
 func (r *organizationRepository) DeleteOrganization(ctx context.Context, id int) error {
+	// Версия прав повышается до удаления, пока связи еще существуют
+	if err := bumpGrantVersion(ctx, r.db, usersInOrganization, id); err != nil {
+		return err
+	}
+
 	query := `DELETE FROM organizations WHERE id = $1`
 	_, err := r.db.GetConnection().Exec(ctx, query, id)
 	if err != nil {
@@ -100,18 +106,26 @@ func (r *organizationRepository) GetOrganizations(ctx context.Context, limit, of
 	return organizations, nil
 }
 
-// GetOrganizationPermissions получает права организации
+// GetOrganizationPermissions получает действующие права организации, выданные напрямую или через роли.
+// ValidUntil права - наиболее поздний срок среди его выдач, пустой, если хотя бы одна выдача бессрочная
 func (r *organizationRepository) GetOrganizationPermissions(
 	ctx context.Context,
 	organizationID int,
 ) ([]*models.Permission, error) {
-	// TODO Сложно!!
-	query := `SELECT DISTINCT p.id, p.name, p.code, p.description
+	query := `SELECT p.id, p.name, p.code, p.description,
+				CASE WHEN bool_and(g.valid_until IS NOT NULL) THEN max(g.valid_until) END
 			  FROM permissions p
-				LEFT JOIN organization_permissions op ON p.id = op.permission_id and op.organization_id = $1
-				LEFT JOIN organization_roles ro ON ro.organization_id = $1
-				LEFT JOIN role_permissions rp ON rp.role_id = ro.role_id and p.id = op.permission_id
-			  WHERE ro.organization_id = $1 or op.organization_id = $1
+				JOIN (
+					SELECT op.permission_id, op.valid_until
+					FROM organization_permissions op
+					WHERE op.organization_id = $1 AND ` + activeGrant("op") + `
+					UNION ALL
+					SELECT rp.permission_id, ro.valid_until
+					FROM organization_roles ro
+						JOIN role_permissions rp ON rp.role_id = ro.role_id
+					WHERE ro.organization_id = $1 AND ` + activeGrant("ro") + `
+				) g ON g.permission_id = p.id
+			  GROUP BY p.id
 			  ORDER BY p.id`
 	rows, err := r.db.GetConnection().Query(ctx, query, organizationID)
 	if err != nil {
@@ -122,7 +136,9 @@ func (r *organizationRepository) GetOrganizationPermissions(
 	var permissions []*models.Permission
 	for rows.Next() {
 		permission := &models.Permission{}
-		err := rows.Scan(&permission.ID, &permission.Name, &permission.Code, &permission.Description)
+		err := rows.Scan(
+			&permission.ID, &permission.Name, &permission.Code, &permission.Description, &permission.ValidUntil,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan permission: %w", err)
 		}
@@ -136,8 +152,13 @@ func (r *organizationRepository) GetOrganizationPermissions(
 	return permissions, nil
 }
 
-// AddOrganizationPermissions добавляет права к организации
-func (r *organizationRepository) AddOrganizationPermissions(ctx context.Context, organizationID int, permissionIDs []int) error {
+// AddOrganizationPermissions добавляет права к организации на срок validity
+func (r *organizationRepository) AddOrganizationPermissions(
+	ctx context.Context,
+	organizationID int,
+	permissionIDs []int,
+	validity models.GrantValidity,
+) error {
 	// Проверяем, что организация существует
 	_, err := r.GetOrganizationByID(ctx, organizationID)
 	if err != nil {
@@ -154,13 +175,20 @@ func (r *organizationRepository) AddOrganizationPermissions(ctx context.Context,
 
 	// Добавляем права к организации
 	for _, permissionID := range permissionIDs {
-		query := `INSERT INTO organization_permissions (organization_id, permission_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
-		_, err := r.db.GetConnection().Exec(ctx, query, organizationID, permissionID)
+		query := organizationPermissionGrants.insert()
+		_, err := r.db.GetConnection().Exec(
+			ctx, query, organizationID, permissionID, validity.ValidFrom, validity.ValidUntil,
+		)
 		if err != nil {
 			return fmt.Errorf("failed to add permission to organization: %w", err)
 		}
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersInOrganization, organizationID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -181,15 +209,20 @@ func (r *organizationRepository) DeleteOrganizationPermissions(ctx context.Conte
 		}
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersInOrganization, organizationID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// GetOrganizationRoles получает роли организации
+// GetOrganizationRoles получает роли организации, включая запланированные выдачи
 func (r *organizationRepository) GetOrganizationRoles(ctx context.Context, organizationID int) ([]*models.Role, error) {
-	query := `SELECT r.id, r.name, r.code, r.description
+	query := `SELECT r.id, r.name, r.code, r.description, orl.valid_from, orl.valid_until
 	          FROM roles r
 	          JOIN organization_roles orl ON r.id = orl.role_id
-	          WHERE orl.organization_id = $1`
+	          WHERE orl.organization_id = $1 AND ` + unexpiredGrant("orl")
 	rows, err := r.db.GetConnection().Query(ctx, query, organizationID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get organization roles: %w", err)
@@ -199,7 +232,7 @@ func (r *organizationRepository) GetOrganizationRoles(ctx context.Context, organ
 	var roles []*models.Role
 	for rows.Next() {
 		role := &models.Role{}
-		err := rows.Scan(&role.ID, &role.Name, &role.Code, &role.Description)
+		err := rows.Scan(&role.ID, &role.Name, &role.Code, &role.Description, &role.ValidFrom, &role.ValidUntil)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan role: %w", err)
 		}
@@ -213,8 +246,13 @@ func (r *organizationRepository) GetOrganizationRoles(ctx context.Context, organ
 	return roles, nil
 }
 
-// AddOrganizationRoles добавляет роли к организации
-func (r *organizationRepository) AddOrganizationRoles(ctx context.Context, organizationID int, roleIDs []int) error {
+// AddOrganizationRoles добавляет роли к организации на срок validity
+func (r *organizationRepository) AddOrganizationRoles(
+	ctx context.Context,
+	organizationID int,
+	roleIDs []int,
+	validity models.GrantValidity,
+) error {
 	// Проверяем, что организация существует
 	_, err := r.GetOrganizationByID(ctx, organizationID)
 	if err != nil {
@@ -223,7 +261,7 @@ func (r *organizationRepository) AddOrganizationRoles(ctx context.Context, organ
 
 	// Проверяем, что все роли существуют
 	for _, roleID := range roleIDs {
-		_, err := r.db.GetConnection().Query(ctx, `SELECT id FROM roles WHERE id = $1`, roleID)
+		_, err := r.db.Conn(ctx).Query(ctx, `SELECT id FROM roles WHERE id = $1`, roleID)
 		if err != nil {
 			return fmt.Errorf("role not found: %w", err)
 		}
@@ -231,13 +269,18 @@ func (r *organizationRepository) AddOrganizationRoles(ctx context.Context, organ
 
 	// Добавляем роли к организации
 	for _, roleID := range roleIDs {
-		query := `INSERT INTO organization_roles (organization_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
-		_, err := r.db.GetConnection().Exec(ctx, query, organizationID, roleID)
+		query := organizationRoleGrants.insert()
+		_, err := r.db.Conn(ctx).Exec(ctx, query, organizationID, roleID, validity.ValidFrom, validity.ValidUntil)
 		if err != nil {
 			return fmt.Errorf("failed to add role to organization: %w", err)
 		}
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersInOrganization, organizationID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -258,9 +301,19 @@ func (r *organizationRepository) DeleteOrganizationRoles(ctx context.Context, or
 		}
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersInOrganization, organizationID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// ExpireOrganizationGrants удаляет роли и права организаций с истекшим сроком и возвращает удаленные выдачи
+func (r *organizationRepository) ExpireOrganizationGrants(ctx context.Context) ([]*models.ExpiredGrant, error) {
+	return expireGrantTables(ctx, r.db, organizationRoleGrants, organizationPermissionGrants)
+}
+
 // SetOrganizationTariff устанавливает тариф организации
 func (r *organizationRepository) SetOrganizationTariff(ctx context.Context, orgID int, tariffID *int32) error {
 	var tariffIDVal interface{}
@@ -271,11 +324,16 @@ func (r *organizationRepository) SetOrganizationTariff(ctx context.Context, orgI
 	}
 
 	query := `UPDATE organizations SET tariff_id = $1 WHERE id = $2`
-	_, err := r.db.GetConnection().Exec(ctx, query, tariffIDVal, orgID)
+	_, err := r.db.Conn(ctx).Exec(ctx, query, tariffIDVal, orgID)
 	if err != nil {
 		return fmt.Errorf("failed to set organization tariff: %w", err)
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersInOrganization, orgID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -300,6 +358,167 @@ func (r *organizationRepository) GetOrganizationTariff(ctx context.Context, orgI
 	return tariff, nil
 }
 
+// GetOrganizationSettings получает настройки организации.
+// Если настройки не сохранялись, возвращаются значения по умолчанию
+func (r *organizationRepository) GetOrganizationSettings(ctx context.Context, orgID int) (*models.OrganizationSettings, error) {
+	query := `SELECT organization_id, require_2fa, password_policy, magic_link_enabled, require_dpop,
+	                 allowed_cidrs, max_session_ttl_seconds, allowed_login_methods
+	          FROM organization_settings WHERE organization_id = $1`
+
+	var maxSessionTTL int64
+	settings := &models.OrganizationSettings{}
+	err := r.db.GetConnection().QueryRow(ctx, query, orgID).
+		Scan(
+			&settings.OrganizationID, &settings.Require2FA, &settings.PasswordPolicy, &settings.MagicLinkEnabled,
+			&settings.RequireDPoP, &settings.AllowedCIDRs, &maxSessionTTL, &settings.AllowedLoginMethods,
+		)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &models.OrganizationSettings{OrganizationID: orgID}, nil
+		}
+		return nil, fmt.Errorf("failed to get organization settings: %w", err)
+	}
+	settings.MaxSessionTTL = time.Duration(maxSessionTTL) * time.Second
+
+	return settings, nil
+}
+
+// UpdateOrganizationSettings сохраняет настройки организации
+func (r *organizationRepository) UpdateOrganizationSettings(ctx context.Context, settings *models.OrganizationSettings) error {
+	query := `INSERT INTO organization_settings
+	          (organization_id, require_2fa, password_policy, magic_link_enabled, require_dpop,
+	           allowed_cidrs, max_session_ttl_seconds, allowed_login_methods)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	          ON CONFLICT (organization_id) DO UPDATE
+	          SET require_2fa = EXCLUDED.require_2fa, password_policy = EXCLUDED.password_policy,
+	              magic_link_enabled = EXCLUDED.magic_link_enabled, require_dpop = EXCLUDED.require_dpop,
+	              allowed_cidrs = EXCLUDED.allowed_cidrs, max_session_ttl_seconds = EXCLUDED.max_session_ttl_seconds,
+	              allowed_login_methods = EXCLUDED.allowed_login_methods`
+	allowedCIDRs, loginMethods := settings.AllowedCIDRs, settings.AllowedLoginMethods
+	if allowedCIDRs == nil {
+		allowedCIDRs = []string{}
+	}
+	if loginMethods == nil {
+		loginMethods = []string{}
+	}
+	_, err := r.db.GetConnection().Exec(ctx, query,
+		settings.OrganizationID, settings.Require2FA, settings.PasswordPolicy, settings.MagicLinkEnabled,
+		settings.RequireDPoP, allowedCIDRs, int64(settings.MaxSessionTTL/time.Second), loginMethods,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update organization settings: %w", err)
+	}
+
+	return nil
+}
+
+// GetOrganizationAdmins получает администраторов организации
+func (r *organizationRepository) GetOrganizationAdmins(ctx context.Context, orgID int) ([]int, error) {
+	query := `SELECT user_id FROM organization_admins WHERE organization_id = $1 ORDER BY user_id`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization admins: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan organization admin: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating organization admins: %w", err)
+	}
+
+	return userIDs, nil
+}
+
+// GetUserAdminOrganizations получает идентификаторы организаций, администратором которых назначен пользователь
+func (r *organizationRepository) GetUserAdminOrganizations(ctx context.Context, userID int) ([]int, error) {
+	query := `SELECT organization_id FROM organization_admins WHERE user_id = $1 ORDER BY organization_id`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user admin organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgIDs []int
+	for rows.Next() {
+		var orgID int
+		if err := rows.Scan(&orgID); err != nil {
+			return nil, fmt.Errorf("failed to scan organization admin: %w", err)
+		}
+		orgIDs = append(orgIDs, orgID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating organization admins: %w", err)
+	}
+
+	return orgIDs, nil
+}
+
+// SetOrganizationAdmins заменяет список администраторов организации
+func (r *organizationRepository) SetOrganizationAdmins(ctx context.Context, orgID int, userIDs []int) error {
+	if userIDs == nil {
+		userIDs = []int{}
+	}
+
+	query := `WITH removed AS (
+	              DELETE FROM organization_admins WHERE organization_id = $1 AND user_id <> ALL($2)
+	          )
+	          INSERT INTO organization_admins (organization_id, user_id)
+	          SELECT $1, unnest($2::integer[])
+	          ON CONFLICT DO NOTHING`
+	_, err := r.db.GetConnection().Exec(ctx, query, orgID, userIDs)
+	if err != nil {
+		return fmt.Errorf("failed to set organization admins: %w", err)
+	}
+
+	return nil
+}
+
+// IsOrganizationAdmin проверяет, что пользователь назначен администратором организации и остается ее участником
+func (r *organizationRepository) IsOrganizationAdmin(ctx context.Context, orgID, userID int) (bool, error) {
+	query := `SELECT EXISTS (
+	              SELECT 1 FROM organization_admins a
+	                  JOIN users u ON u.id = a.user_id AND u.organization_id = a.organization_id
+	              WHERE a.organization_id = $1 AND a.user_id = $2
+	          )`
+
+	var ok bool
+	if err := r.db.GetConnection().QueryRow(ctx, query, orgID, userID).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed to check organization admin: %w", err)
+	}
+
+	return ok, nil
+}
+
+// IsOrganizationRole проверяет, что роль доступна организации: выдана ей, в том числе на срок, или входит в ее тариф
+func (r *organizationRepository) IsOrganizationRole(ctx context.Context, orgID, roleID int) (bool, error) {
+	query := `SELECT EXISTS (
+	              SELECT 1 FROM organization_roles r
+	              WHERE r.organization_id = $1 AND r.role_id = $2 AND ` + unexpiredGrant("r") + `
+	              UNION ALL
+	              SELECT 1 FROM organizations o
+	                  JOIN tariff_roles t ON t.tariff_id = o.tariff_id
+	              WHERE o.id = $1 AND t.role_id = $2
+	          )`
+
+	var ok bool
+	if err := r.db.GetConnection().QueryRow(ctx, query, orgID, roleID).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed to check organization role: %w", err)
+	}
+
+	return ok, nil
+}
+
 // InitDB инициализирует таблицы в БД для организаций
 func (r *organizationRepository) InitDB() error {
 	query := `
@@ -322,6 +541,61 @@ CREATE TABLE IF NOT EXISTS organization_roles (
 	organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE,
 	role_id INTEGER REFERENCES roles(id) ON DELETE CASCADE,
 	PRIMARY KEY (organization_id, role_id)
+);
+
+-- Срок действия выдачи, pending отмечает выдачи, срок которых еще не начался
+alter table organization_permissions
+    add IF NOT EXISTS valid_from TIMESTAMPTZ;
+
+alter table organization_permissions
+    add IF NOT EXISTS valid_until TIMESTAMPTZ;
+
+alter table organization_permissions
+    add IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT false;
+
+alter table organization_roles
+    add IF NOT EXISTS valid_from TIMESTAMPTZ;
+
+alter table organization_roles
+    add IF NOT EXISTS valid_until TIMESTAMPTZ;
+
+alter table organization_roles
+    add IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS organization_settings (
+	organization_id INTEGER PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+	require_2fa BOOLEAN NOT NULL DEFAULT false,
+	password_policy JSONB,
+	magic_link_enabled BOOLEAN NOT NULL DEFAULT false,
+	require_dpop BOOLEAN NOT NULL DEFAULT false,
+	allowed_cidrs TEXT[] NOT NULL DEFAULT '{}',
+	max_session_ttl_seconds BIGINT NOT NULL DEFAULT 0,
+	allowed_login_methods TEXT[] NOT NULL DEFAULT '{}'
+);
+
+alter table organization_settings
+    add IF NOT EXISTS password_policy JSONB;
+
+alter table organization_settings
+    add IF NOT EXISTS magic_link_enabled BOOLEAN NOT NULL DEFAULT false;
+
+alter table organization_settings
+    add IF NOT EXISTS require_dpop BOOLEAN NOT NULL DEFAULT false;
+
+alter table organization_settings
+    add IF NOT EXISTS allowed_cidrs TEXT[] NOT NULL DEFAULT '{}';
+
+alter table organization_settings
+    add IF NOT EXISTS max_session_ttl_seconds BIGINT NOT NULL DEFAULT 0;
+
+alter table organization_settings
+    add IF NOT EXISTS allowed_login_methods TEXT[] NOT NULL DEFAULT '{}';
+
+-- Администраторы организации управляют ее настройками и запросами доступа ее участников
+CREATE TABLE IF NOT EXISTS organization_admins (
+	organization_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE,
+	user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+	PRIMARY KEY (organization_id, user_id)
 );`
 	_, err := r.db.GetConnection().Exec(context.Background(), query)
 	if err != nil {
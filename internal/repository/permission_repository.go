@@ -53,11 +53,21 @@ func (r *permissionRepository) UpdatePermission(ctx context.Context, permission
 	if err != nil {
 		return fmt.Errorf("failed to update permission: %w", err)
 	}
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersWithPermission, permission.ID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // DeletePermission удаляет право
 func (r *permissionRepository) DeletePermission(ctx context.Context, id int) error {
+	// Версия прав повышается до удаления, пока связи еще существуют
+	if err := bumpGrantVersion(ctx, r.db, usersWithPermission, id); err != nil {
+		return err
+	}
+
 	query := `DELETE FROM permissions WHERE id = $1`
 	_, err := r.db.GetConnection().Exec(ctx, query, id)
 	if err != nil {
@@ -92,6 +102,32 @@ func (r *permissionRepository) GetPermissions(ctx context.Context, limit, offset
 	return permissions, nil
 }
 
+// GetPermissionsByCodes получает права по кодам. Неизвестные коды пропускаются
+func (r *permissionRepository) GetPermissionsByCodes(ctx context.Context, codes []string) ([]*models.Permission, error) {
+	query := `SELECT id, name, code, description FROM permissions WHERE code = ANY($1) ORDER BY id`
+	rows, err := r.db.GetConnection().Query(ctx, query, codes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	defer rows.Close()
+
+	var permissions []*models.Permission
+	for rows.Next() {
+		permission := &models.Permission{}
+		err := rows.Scan(&permission.ID, &permission.Name, &permission.Code, &permission.Description)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate permissions: %w", err)
+	}
+
+	return permissions, nil
+}
+
 // InitDB инициализирует таблицы в БД для прав
 func (r *permissionRepository) InitDB() error {
 	query := `
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
@@ -9,6 +10,9 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+// ErrRoleNotFound роль с указанным идентификатором не существует
+var ErrRoleNotFound = errors.New("role not found")
+
 // roleRepository реализация интерфейса RoleRepository
 type roleRepository struct {
 	db *DB
@@ -38,7 +42,7 @@ func (r *roleRepository) GetRoleByID(ctx context.Context, id int) (*models.Role,
 	err := row.Scan(&role.ID, &role.Name, &role.Code, &role.Description)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("role not found")
+			return nil, ErrRoleNotFound
 		}
 		return nil, fmt.Errorf("failed to get role: %w", err)
 	}
@@ -58,6 +62,11 @@ func (r *roleRepository) UpdateRole(ctx context.Context, role *models.Role) erro
 
 // DeleteRole удаляет роль
 func (r *roleRepository) DeleteRole(ctx context.Context, id int) error {
+	// Версия прав повышается до удаления, пока связи еще существуют
+	if err := bumpGrantVersion(ctx, r.db, usersWithRole, id); err != nil {
+		return err
+	}
+
 	query := `DELETE FROM roles WHERE id = $1`
 	_, err := r.db.GetConnection().Exec(ctx, query, id)
 	if err != nil {
@@ -154,6 +163,11 @@ func (r *roleRepository) AddRolePermissions(ctx context.Context, roleID int, per
 		}
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersWithRole, roleID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -174,6 +188,11 @@ func (r *roleRepository) DeleteRolePermissions(ctx context.Context, roleID int,
 		}
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersWithRole, roleID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/jackc/pgx/v5"
+)
+
+// sessionTouchInterval время последнего использования сессии обновляется не чаще этого интервала
+const sessionTouchInterval = "1 minute"
+
+// sessionColumns столбцы, из которых читается сессия
+const sessionColumns = `id, user_id, ip, user_agent, created_at, last_used_at, expires_at, revoked_at,
+	impersonator_id, impersonation_reason, oauth_client_id`
+
+// sessionRepository реализация интерфейса SessionRepository
+type sessionRepository struct {
+	db *DB
+}
+
+// NewSessionRepository создает новый репозиторий сессий
+func NewSessionRepository(db *DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// CreateSession сохраняет новую сессию
+func (r *sessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	query := `INSERT INTO user_sessions (id, user_id, ip, user_agent, expires_at, impersonator_id, impersonation_reason,
+	              oauth_client_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	          RETURNING created_at, last_used_at`
+	var impersonatorID, oauthClientID sql.NullInt32
+	if session.ImpersonatorID != nil {
+		impersonatorID = utils.NewNullInt32(int32(*session.ImpersonatorID))
+	}
+	if session.OAuthClientID != nil {
+		oauthClientID = utils.NewNullInt32(int32(*session.OAuthClientID))
+	}
+	err := r.db.GetConnection().QueryRow(
+		ctx, query, session.ID, session.UserID, session.IP, session.UserAgent, session.ExpiresAt,
+		impersonatorID, session.ImpersonationReason, oauthClientID,
+	).Scan(&session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+// GetSession получает сессию по идентификатору, включая отозванные и истекшие.
+// Если сессия не найдена, возвращается nil
+func (r *sessionRepository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions WHERE id = $1`
+
+	session, err := scanSession(r.db.GetConnection().QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return session, nil
+}
+
+// TouchSession обновляет время последнего использования сессии
+func (r *sessionRepository) TouchSession(ctx context.Context, id string) error {
+	query := `UPDATE user_sessions SET last_used_at = now()
+	          WHERE id = $1 AND last_used_at < now() - interval '` + sessionTouchInterval + `'`
+	_, err := r.db.GetConnection().Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserSessions получает активные сессии пользователя, начиная с последней использованной.
+// Сессии, открытые администратором от имени пользователя, возвращаются и после завершения,
+// чтобы пользователь видел историю таких входов
+func (r *sessionRepository) GetUserSessions(ctx context.Context, userID int) ([]*models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions
+	          WHERE user_id = $1 AND ((revoked_at IS NULL AND expires_at > now()) OR impersonator_id IS NOT NULL)
+	          ORDER BY last_used_at DESC`
+
+	return r.querySessions(ctx, query, userID)
+}
+
+// GetUserSessionHistory получает все сохраненные сессии пользователя, включая завершенные и истекшие,
+// начиная с последней открытой
+func (r *sessionRepository) GetUserSessionHistory(ctx context.Context, userID int) ([]*models.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions WHERE user_id = $1 ORDER BY created_at DESC`
+
+	return r.querySessions(ctx, query, userID)
+}
+
+// querySessions выполняет запрос сессий и читает результат
+func (r *sessionRepository) querySessions(ctx context.Context, query string, args ...any) ([]*models.Session, error) {
+	rows, err := r.db.GetConnection().Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession отзывает активную сессию пользователя.
+// Возвращает false, если сессия не найдена или уже отозвана
+func (r *sessionRepository) RevokeSession(ctx context.Context, userID int, id string) (bool, error) {
+	query := `UPDATE user_sessions SET revoked_at = now()
+	          WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	tag, err := r.db.GetConnection().Exec(ctx, query, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeUserSessions отзывает все активные сессии пользователя, кроме exceptID,
+// и возвращает количество отозванных сессий
+func (r *sessionRepository) RevokeUserSessions(ctx context.Context, userID int, exceptID string) (int, error) {
+	query := `UPDATE user_sessions SET revoked_at = now()
+	          WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL AND expires_at > now()`
+	tag, err := r.db.GetConnection().Exec(ctx, query, userID, exceptID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// RevokeOAuthClientSessions отзывает активные сессии, выданные OAuth клиенту.
+// Если userID не 0, отзываются только сессии этого пользователя
+func (r *sessionRepository) RevokeOAuthClientSessions(ctx context.Context, clientID, userID int) (int, error) {
+	query := `UPDATE user_sessions SET revoked_at = now()
+	          WHERE oauth_client_id = $1 AND ($2 = 0 OR user_id = $2) AND revoked_at IS NULL AND expires_at > now()`
+	tag, err := r.db.GetConnection().Exec(ctx, query, clientID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke oauth client sessions: %w", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// scanSession читает сессию из строки результата
+func scanSession(row pgx.Row) (*models.Session, error) {
+	session := &models.Session{}
+	var revokedAt sql.NullTime
+	var impersonatorID, oauthClientID sql.NullInt32
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.IP, &session.UserAgent,
+		&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &revokedAt,
+		&impersonatorID, &session.ImpersonationReason, &oauthClientID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем, было ли значение NULL
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	if impersonatorID.Valid {
+		session.ImpersonatorID = utils.Ptr(int(impersonatorID.Int32))
+	}
+	if oauthClientID.Valid {
+		session.OAuthClientID = utils.Ptr(int(oauthClientID.Int32))
+	}
+
+	return session, nil
+}
+
+// InitDB инициализирует таблицы в БД для сессий
+func (r *sessionRepository) InitDB() error {
+	query := `
+CREATE TABLE IF NOT EXISTS user_sessions (
+	id VARCHAR(64) PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	ip VARCHAR(64) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_used_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ NOT NULL,
+	revoked_at TIMESTAMPTZ
+);
+
+alter table user_sessions add IF NOT EXISTS impersonator_id INTEGER;
+alter table user_sessions add IF NOT EXISTS impersonation_reason TEXT NOT NULL DEFAULT '';
+alter table user_sessions add IF NOT EXISTS oauth_client_id INTEGER;
+
+CREATE INDEX IF NOT EXISTS user_sessions_user_id_idx ON user_sessions (user_id);`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize user_sessions table: %w", err)
+	}
+
+	log.Println("SessionRepository initialized successfully")
+	return nil
+}
@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+)
+
+// userMemberships организация и тарифы пользователей.
+// Столбцы: id, organization_id, tariff_id, organization_tariff_id
+const userMemberships = `SELECT u.id, u.organization_id, u.tariff_id, o.tariff_id AS organization_tariff_id
+	FROM users u LEFT JOIN organizations o ON o.id = u.organization_id`
+
+// effectiveRoles роли пользователей из выборки users со столбцами userMemberships, выданные напрямую,
+// через организацию или через тариф, включая запланированные выдачи. Столбцы: user_id, role_id
+func effectiveRoles(users string) string {
+	return `SELECT u.id AS user_id, ur.role_id FROM (` + users + `) u JOIN user_roles ur ON ur.user_id = u.id
+		WHERE ` + unexpiredGrant("ur") + `
+	UNION SELECT u.id, r.role_id FROM (` + users + `) u JOIN organization_roles r ON r.organization_id = u.organization_id
+		WHERE ` + unexpiredGrant("r") + `
+	UNION SELECT u.id, t.role_id FROM (` + users + `) u JOIN tariff_roles t ON t.tariff_id = u.tariff_id
+	UNION SELECT u.id, t.role_id FROM (` + users + `) u JOIN tariff_roles t ON t.tariff_id = u.organization_tariff_id`
+}
+
+// effectiveUserRoles роли всех пользователей. Столбцы: user_id, role_id
+var effectiveUserRoles = effectiveRoles(userMemberships)
+
+// sodViolations запрос нарушений ограничений пользователями по ролям из выборки held (user_id, role_id).
+// Пользователь нарушает ограничение, если имеет больше одной роли из его набора; having дополняет условие
+func sodViolations(held, having string) string {
+	return `SELECT c.id, c.name, h.user_id, u.email,
+			array_agg(h.role_id ORDER BY h.role_id), array_agg(COALESCE(r.code, '') ORDER BY h.role_id)
+		FROM sod_constraints c
+			JOIN (` + held + `) h ON h.role_id = ANY(c.role_ids)
+			JOIN users u ON u.id = h.user_id
+			LEFT JOIN roles r ON r.id = h.role_id
+		GROUP BY c.id, c.name, h.user_id, u.email
+		HAVING count(*) > 1` + having + `
+		ORDER BY c.id, h.user_id`
+}
+
+// sodHolderUsers выборки пользователей, получающих роли владельца. Параметр $1 - идентификатор владельца
+var sodHolderUsers = map[models.GrantOwner]string{
+	models.GrantOwnerUser:         usersByID,
+	models.GrantOwnerOrganization: usersInOrganization,
+	models.GrantOwnerTariff:       usersWithTariff,
+}
+
+// sodMembership переход пользователей владельца в организацию или на тариф
+type sodMembership struct {
+	owner  models.GrantOwner
+	target models.GrantOwner
+}
+
+// sodMemberships выборки пользователей владельца $1 со столбцами userMemberships после перехода в target $2
+var sodMemberships = map[sodMembership]string{
+	{models.GrantOwnerUser, models.GrantOwnerOrganization}: `SELECT u.id, o.id AS organization_id, u.tariff_id,
+			o.tariff_id AS organization_tariff_id
+		FROM users u LEFT JOIN organizations o ON o.id = $2 WHERE u.id = $1`,
+	{models.GrantOwnerUser, models.GrantOwnerTariff}: `SELECT m.id, m.organization_id, $2::integer AS tariff_id,
+			m.organization_tariff_id
+		FROM (` + userMemberships + `) m WHERE m.id = $1`,
+	{models.GrantOwnerOrganization, models.GrantOwnerTariff}: `SELECT u.id, u.organization_id, u.tariff_id,
+			$2::integer AS organization_tariff_id
+		FROM users u WHERE u.organization_id = $1`,
+}
+
+// sodTargetRoles роли, которые дает организация или тариф $2
+var sodTargetRoles = map[models.GrantOwner]string{
+	models.GrantOwnerOrganization: `SELECT r.role_id FROM organization_roles r WHERE r.organization_id = $2 AND ` +
+		unexpiredGrant("r") + `
+		UNION SELECT t.role_id FROM organizations o JOIN tariff_roles t ON t.tariff_id = o.tariff_id WHERE o.id = $2`,
+	models.GrantOwnerTariff: `SELECT role_id FROM tariff_roles WHERE tariff_id = $2`,
+}
+
+// sodRepository реализация интерфейса SoDRepository
+type sodRepository struct {
+	db *DB
+}
+
+// NewSoDRepository создает новый репозиторий ограничений разделения обязанностей
+func NewSoDRepository(db *DB) SoDRepository {
+	return &sodRepository{db: db}
+}
+
+// CreateSoDConstraint создает ограничение разделения обязанностей
+func (r *sodRepository) CreateSoDConstraint(ctx context.Context, constraint *models.SoDConstraint) error {
+	query := `INSERT INTO sod_constraints (name, description, role_ids) VALUES ($1, $2, $3)
+	          RETURNING id, created_at`
+	err := r.db.GetConnection().QueryRow(ctx, query,
+		constraint.Name, constraint.Description, constraint.RoleIDs,
+	).Scan(&constraint.ID, &constraint.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create sod constraint: %w", err)
+	}
+
+	return nil
+}
+
+// GetSoDConstraints получает список ограничений разделения обязанностей
+func (r *sodRepository) GetSoDConstraints(ctx context.Context, limit, offset int) ([]*models.SoDConstraint, error) {
+	query := `SELECT id, name, description, role_ids, created_at FROM sod_constraints ORDER BY id LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sod constraints: %w", err)
+	}
+	defer rows.Close()
+
+	var constraints []*models.SoDConstraint
+	for rows.Next() {
+		constraint := &models.SoDConstraint{}
+		err := rows.Scan(
+			&constraint.ID, &constraint.Name, &constraint.Description, &constraint.RoleIDs, &constraint.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sod constraint: %w", err)
+		}
+		constraints = append(constraints, constraint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sod constraints: %w", err)
+	}
+
+	return constraints, nil
+}
+
+// DeleteSoDConstraint удаляет ограничение разделения обязанностей.
+// Возвращает false, если ограничение не найдено
+func (r *sodRepository) DeleteSoDConstraint(ctx context.Context, id int) (bool, error) {
+	query := `DELETE FROM sod_constraints WHERE id = $1`
+	result, err := r.db.GetConnection().Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete sod constraint: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// GetSoDConflicts получает нарушения ограничений, которые возникнут, если владельцу owner выдать роли roleIDs.
+// Проверяются все пользователи, получающие роли владельца. Учитываются только нарушения с участием
+// выдаваемых ролей, чтобы уже существующие нарушения не блокировали несвязанные выдачи.
+// В транзакции строки проверяемых пользователей блокируются до ее завершения
+func (r *sodRepository) GetSoDConflicts(
+	ctx context.Context,
+	owner models.GrantOwner,
+	ownerID int,
+	roleIDs []int,
+) ([]*models.SoDViolation, error) {
+	users, ok := sodHolderUsers[owner]
+	if !ok {
+		return nil, fmt.Errorf("unknown role holder %s", owner)
+	}
+
+	if err := r.lockUsers(ctx, users, ownerID); err != nil {
+		return nil, err
+	}
+
+	held := `SELECT e.user_id, e.role_id FROM (` + effectiveUserRoles + `) e
+			WHERE e.user_id IN (SELECT id FROM affected)
+		UNION SELECT a.id, unnest($2::integer[]) FROM affected a`
+	query := `WITH affected (id) AS (` + users + `) ` +
+		sodViolations(held, ` AND bool_or(h.role_id = ANY($2::integer[]))`)
+
+	return r.queryViolations(ctx, query, ownerID, roleIDs)
+}
+
+// GetSoDMembershipConflicts получает нарушения ограничений, которые возникнут, если пользователи владельца owner
+// перейдут в организацию или на тариф target с идентификатором targetID. Учитываются только нарушения
+// с участием ролей target. В транзакции строки проверяемых пользователей блокируются до ее завершения
+func (r *sodRepository) GetSoDMembershipConflicts(
+	ctx context.Context,
+	owner models.GrantOwner,
+	ownerID int,
+	target models.GrantOwner,
+	targetID int,
+) ([]*models.SoDViolation, error) {
+	users, ok := sodMemberships[sodMembership{owner: owner, target: target}]
+	if !ok {
+		return nil, fmt.Errorf("unknown membership %s in %s", owner, target)
+	}
+
+	if err := r.lockUsers(ctx, sodHolderUsers[owner], ownerID); err != nil {
+		return nil, err
+	}
+
+	query := sodViolations(effectiveRoles(users), ` AND bool_or(h.role_id IN (`+sodTargetRoles[target]+`))`)
+
+	return r.queryViolations(ctx, query, ownerID, targetID)
+}
+
+// lockUsers блокирует строки пользователей из выборки users, чтобы параллельные выдачи тем же пользователям
+// дождались завершения транзакции и проверили ограничения с учетом ее изменений
+func (r *sodRepository) lockUsers(ctx context.Context, users string, id int) error {
+	query := `SELECT id FROM users WHERE id IN (` + users + `) ORDER BY id FOR UPDATE`
+	if _, err := r.db.Conn(ctx).Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("failed to lock users: %w", err)
+	}
+
+	return nil
+}
+
+// GetSoDViolations получает пользователей, которые уже нарушают ограничение constraintID.
+// constraintID 0 - все ограничения
+func (r *sodRepository) GetSoDViolations(
+	ctx context.Context,
+	constraintID, limit, offset int,
+) ([]*models.SoDViolation, error) {
+	query := sodViolations(effectiveUserRoles, ` AND ($1 = 0 OR c.id = $1)`) + ` LIMIT $2 OFFSET $3`
+
+	return r.queryViolations(ctx, query, constraintID, limit, offset)
+}
+
+// queryViolations выполняет запрос нарушений ограничений и читает результат
+func (r *sodRepository) queryViolations(ctx context.Context, query string, args ...any) ([]*models.SoDViolation, error) {
+	rows, err := r.db.Conn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sod violations: %w", err)
+	}
+	defer rows.Close()
+
+	var violations []*models.SoDViolation
+	for rows.Next() {
+		violation := &models.SoDViolation{}
+		err := rows.Scan(
+			&violation.ConstraintID, &violation.ConstraintName, &violation.UserID, &violation.UserEmail,
+			&violation.RoleIDs, &violation.RoleCodes,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sod violation: %w", err)
+		}
+		violations = append(violations, violation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sod violations: %w", err)
+	}
+
+	return violations, nil
+}
+
+// InitDB инициализирует таблицы в БД для ограничений разделения обязанностей
+func (r *sodRepository) InitDB() error {
+	query := `
+CREATE TABLE IF NOT EXISTS sod_constraints (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	role_ids INTEGER[] NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize sod_constraints table: %w", err)
+	}
+
+	log.Println("SoDRepository initialized successfully")
+	return nil
+}
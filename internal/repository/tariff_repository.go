@@ -58,6 +58,11 @@ func (r *tariffRepository) UpdateTariff(ctx context.Context, tariff *models.Tari
 
 // DeleteTariff удаляет тариф
 func (r *tariffRepository) DeleteTariff(ctx context.Context, id int) error {
+	// Версия прав повышается до удаления, пока связи еще существуют
+	if err := bumpGrantVersion(ctx, r.db, usersWithTariff, id); err != nil {
+		return err
+	}
+
 	query := `DELETE FROM tariffs WHERE id = $1`
 	_, err := r.db.GetConnection().Exec(ctx, query, id)
 	if err != nil {
@@ -139,7 +144,7 @@ func (r *tariffRepository) AddTariffRoles(ctx context.Context, tariffID int, rol
 
 	// Проверяем, что все роли существуют
 	for _, roleID := range roleIDs {
-		_, err := r.db.GetConnection().Query(ctx, `SELECT id FROM roles WHERE id = $1`, roleID)
+		_, err := r.db.Conn(ctx).Query(ctx, `SELECT id FROM roles WHERE id = $1`, roleID)
 		if err != nil {
 			return fmt.Errorf("role not found: %w", err)
 		}
@@ -148,12 +153,17 @@ func (r *tariffRepository) AddTariffRoles(ctx context.Context, tariffID int, rol
 	// Добавляем роли к тарифу
 	for _, roleID := range roleIDs {
 		query := `INSERT INTO tariff_roles (tariff_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
-		_, err := r.db.GetConnection().Exec(ctx, query, tariffID, roleID)
+		_, err := r.db.Conn(ctx).Exec(ctx, query, tariffID, roleID)
 		if err != nil {
 			return fmt.Errorf("failed to add role to tariff: %w", err)
 		}
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersWithTariff, tariffID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -174,6 +184,11 @@ func (r *tariffRepository) DeleteTariffRoles(ctx context.Context, tariffID int,
 		}
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersWithTariff, tariffID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
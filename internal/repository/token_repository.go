@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// tokenRepository реализация интерфейса TokenRepository
+type tokenRepository struct {
+	db *DB
+}
+
+// NewTokenRepository создает новый репозиторий одноразовых токенов
+func NewTokenRepository(db *DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+// CreateToken сохраняет одноразовый токен
+func (r *tokenRepository) CreateToken(ctx context.Context, token *models.OneTimeToken) error {
+	query := `INSERT INTO one_time_tokens (user_id, purpose, token_hash, payload, expires_at)
+	          VALUES ($1, $2, $3, $4, $5)
+	          RETURNING id, created_at`
+	err := r.db.GetConnection().QueryRow(ctx, query,
+		token.UserID, string(token.Purpose), token.TokenHash, token.Payload, token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	return nil
+}
+
+// GetToken получает действующий токен без его использования.
+// Если токен не найден, уже использован или истек, возвращается nil
+func (r *tokenRepository) GetToken(
+	ctx context.Context,
+	purpose models.TokenPurpose,
+	tokenHash string,
+) (*models.OneTimeToken, error) {
+	query := `SELECT id, user_id, purpose, token_hash, payload, expires_at, created_at
+	          FROM one_time_tokens
+	          WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()`
+
+	token := &models.OneTimeToken{}
+	err := r.db.GetConnection().QueryRow(ctx, query, tokenHash, string(purpose)).Scan(
+		&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.Payload, &token.ExpiresAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	return token, nil
+}
+
+// ConsumeToken помечает токен использованным и возвращает его.
+// Если токен не найден, уже использован или истек, возвращается nil
+func (r *tokenRepository) ConsumeToken(
+	ctx context.Context,
+	purpose models.TokenPurpose,
+	tokenHash string,
+) (*models.OneTimeToken, error) {
+	query := `UPDATE one_time_tokens
+	          SET used_at = now()
+	          WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > now()
+	          RETURNING id, user_id, purpose, token_hash, payload, expires_at, used_at, created_at`
+
+	token := &models.OneTimeToken{}
+	var usedAt sql.NullTime
+	err := r.db.GetConnection().QueryRow(ctx, query, tokenHash, string(purpose)).Scan(
+		&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.Payload,
+		&token.ExpiresAt, &usedAt, &token.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to consume token: %w", err)
+	}
+
+	// Проверяем, было ли значение NULL
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	return token, nil
+}
+
+// DeleteUserTokens удаляет все токены пользователя с указанным назначением
+func (r *tokenRepository) DeleteUserTokens(ctx context.Context, userID int, purpose models.TokenPurpose) error {
+	query := `DELETE FROM one_time_tokens WHERE user_id = $1 AND purpose = $2`
+	_, err := r.db.GetConnection().Exec(ctx, query, userID, string(purpose))
+	if err != nil {
+		return fmt.Errorf("failed to delete tokens: %w", err)
+	}
+
+	return nil
+}
+
+// InitDB инициализирует таблицы в БД для одноразовых токенов
+func (r *tokenRepository) InitDB() error {
+	query := `
+CREATE TABLE IF NOT EXISTS one_time_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	purpose VARCHAR(32) NOT NULL,
+	token_hash CHAR(64) UNIQUE NOT NULL,
+	payload TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS one_time_tokens_user_id_idx ON one_time_tokens (user_id, purpose);`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize one_time_tokens table: %w", err)
+	}
+
+	log.Println("TokenRepository initialized successfully")
+	return nil
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// twoFactorRepository реализация интерфейса TwoFactorRepository
+type twoFactorRepository struct {
+	db *DB
+}
+
+// NewTwoFactorRepository создает новый репозиторий двухфакторной аутентификации
+func NewTwoFactorRepository(db *DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+// GetTwoFactor получает настройки двухфакторной аутентификации пользователя.
+// Если пользователь не начинал подключение, возвращается nil
+func (r *twoFactorRepository) GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	query := `SELECT user_id, secret, enabled, last_step, enabled_at, created_at FROM user_two_factor WHERE user_id = $1`
+
+	tf := &models.TwoFactor{}
+	var (
+		lastStep  sql.NullInt64
+		enabledAt sql.NullTime
+	)
+	err := r.db.GetConnection().QueryRow(ctx, query, userID).
+		Scan(&tf.UserID, &tf.Secret, &tf.Enabled, &lastStep, &enabledAt, &tf.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get two factor: %w", err)
+	}
+
+	// Проверяем, было ли значение NULL
+	if lastStep.Valid {
+		tf.LastStep = &lastStep.Int64
+	}
+	if enabledAt.Valid {
+		tf.EnabledAt = &enabledAt.Time
+	}
+
+	return tf, nil
+}
+
+// GetRecoveryCodes получает резервные коды пользователя, в том числе использованные
+func (r *twoFactorRepository) GetRecoveryCodes(ctx context.Context, userID int) ([]*models.RecoveryCode, error) {
+	query := `SELECT id, code_hash, used_at FROM user_recovery_codes WHERE user_id = $1 ORDER BY id`
+
+	rows, err := r.db.GetConnection().Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recovery codes: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []*models.RecoveryCode
+	for rows.Next() {
+		code := &models.RecoveryCode{}
+		var usedAt sql.NullTime
+		if err := rows.Scan(&code.ID, &code.CodeHash, &usedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan recovery code: %w", err)
+		}
+		if usedAt.Valid {
+			code.UsedAt = &usedAt.Time
+		}
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// SaveTwoFactorSecret сохраняет новый секрет, двухфакторная аутентификация остается выключенной до подтверждения
+func (r *twoFactorRepository) SaveTwoFactorSecret(ctx context.Context, userID int, secret string) error {
+	query := `INSERT INTO user_two_factor (user_id, secret, enabled)
+	          VALUES ($1, $2, false)
+	          ON CONFLICT (user_id) DO UPDATE
+	          SET secret = EXCLUDED.secret, enabled = false, last_step = NULL, enabled_at = NULL, created_at = now()
+	          WHERE NOT user_two_factor.enabled`
+	_, err := r.db.GetConnection().Exec(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save two factor secret: %w", err)
+	}
+
+	return nil
+}
+
+// EnableTwoFactor включает двухфакторную аутентификацию и заменяет коды восстановления
+func (r *twoFactorRepository) EnableTwoFactor(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	return pgx.BeginFunc(ctx, r.db.GetConnection(), func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE user_two_factor SET enabled = true, enabled_at = now() WHERE user_id = $1`, userID)
+		if err != nil {
+			return fmt.Errorf("failed to enable two factor: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("two factor is not enrolled")
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+
+		for _, hash := range recoveryCodeHashes {
+			_, err := tx.Exec(ctx, `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash)
+			if err != nil {
+				return fmt.Errorf("failed to add recovery code: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// DisableTwoFactor выключает двухфакторную аутентификацию и удаляет коды восстановления
+func (r *twoFactorRepository) DisableTwoFactor(ctx context.Context, userID int) error {
+	query := `DELETE FROM user_two_factor WHERE user_id = $1`
+	_, err := r.db.GetConnection().Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable two factor: %w", err)
+	}
+
+	_, err = r.db.GetConnection().Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep запоминает использованный шаг TOTP.
+// Возвращает false, если код этого или более позднего шага уже был принят
+func (r *twoFactorRepository) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	query := `UPDATE user_two_factor SET last_step = $1 WHERE user_id = $2 AND (last_step IS NULL OR last_step < $1)`
+	tag, err := r.db.GetConnection().Exec(ctx, query, step, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp step: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// UseRecoveryCode помечает код восстановления использованным.
+// Возвращает false, если код не найден или уже использован
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	query := `UPDATE user_recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	tag, err := r.db.GetConnection().Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// InitDB инициализирует таблицы в БД для двухфакторной аутентификации
+func (r *twoFactorRepository) InitDB() error {
+	query := `
+CREATE TABLE IF NOT EXISTS user_two_factor (
+	user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	secret TEXT NOT NULL,
+	enabled BOOLEAN NOT NULL DEFAULT false,
+	last_step BIGINT,
+	enabled_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	code_hash CHAR(64) NOT NULL,
+	used_at TIMESTAMPTZ,
+	UNIQUE (user_id, code_hash)
+);`
+	_, err := r.db.GetConnection().Exec(context.Background(), query)
+	if err != nil {
+		return fmt.Errorf("failed to initialize user_two_factor table: %w", err)
+	}
+
+	log.Println("TwoFactorRepository initialized successfully")
+	return nil
+}
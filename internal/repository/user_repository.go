@@ -34,17 +34,51 @@ func (r *userRepository) CheckPassword(ctx context.Context, userID int, password
 	}
 
 	// Используем функцию из utils для сравнения
-	return utils.CheckPassword(password, hash), nil
+	if !utils.CheckPassword(password, hash) {
+		return false, nil
+	}
+
+	// Хеш устаревшего алгоритма или с устаревшими параметрами заменяется новым.
+	// Ошибка обновления не мешает входу, хеш будет обновлен при следующем входе
+	if utils.PasswordNeedsRehash(hash) {
+		if err := r.rehashPassword(ctx, userID, password, hash); err != nil {
+			log.Printf("Failed to rehash password for user %d, err:%v\n", userID, err)
+		}
+	}
+
+	return true, nil
+}
+
+// rehashPassword сохраняет новый хеш пароля, если хеш не изменился с момента проверки
+func (r *userRepository) rehashPassword(ctx context.Context, userID int, password, oldHash string) error {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	query := `UPDATE users SET password_hash = $1 WHERE id = $2 AND password_hash = $3`
+	if _, err := r.db.GetConnection().Exec(ctx, query, hash, userID, oldHash); err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+
+	return nil
 }
 
 // GetUserByEmail получает пользователя по email
 func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
-	query := `SELECT id, name, email, organization_id FROM users WHERE email = $1`
+	query := `SELECT id, name, email, organization_id, status, email_verified, pending_email, grant_version, kind
+	          FROM users WHERE email = $1`
 	row := r.db.GetConnection().QueryRow(ctx, query, email)
 
 	user := &models.User{}
-	var org sql.NullInt32
-	err := row.Scan(&user.ID, &user.Name, &user.Email, &org)
+	var (
+		org          sql.NullInt32
+		pendingEmail sql.NullString
+	)
+	err := row.Scan(
+		&user.ID, &user.Name, &user.Email, &org, &user.Status, &user.EmailVerified, &pendingEmail, &user.GrantVersion,
+		&user.Kind,
+	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -59,17 +93,31 @@ func (r *userRepository) GetUserByEmail(ctx context.Context, email string) (*mod
 		user.Organization = nil
 	}
 
+	// Проверяем, было ли значение NULL
+	if pendingEmail.Valid {
+		user.PendingEmail = &pendingEmail.String
+	}
+
 	return user, nil
 }
 
 // CreateUser создает нового пользователя
 func (r *userRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
-	query := `INSERT INTO users (name, email, password_hash, organization_id) VALUES ($1, $2, $3, $4) RETURNING id`
+	query := `INSERT INTO users (name, email, password_hash, organization_id, status, email_verified, kind)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
 	var org interface{}
 	if user.Organization != nil {
 		org = user.Organization.ID
 	}
-	err := r.db.GetConnection().QueryRow(ctx, query, user.Name, user.Email, user.PasswordHash, org).Scan(&user.ID)
+	if user.Status == "" {
+		user.Status = models.UserStatusActive
+	}
+	if user.Kind == "" {
+		user.Kind = models.UserKindHuman
+	}
+	err := r.db.Conn(ctx).QueryRow(ctx, query,
+		user.Name, user.Email, user.PasswordHash, org, user.Status, user.EmailVerified, user.Kind,
+	).Scan(&user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
@@ -78,15 +126,21 @@ func (r *userRepository) CreateUser(ctx context.Context, user *models.User) (*mo
 
 // GetUserByID получает пользователя по ID
 func (r *userRepository) GetUserByID(ctx context.Context, id int) (*models.User, error) {
-	query := `SELECT id, name, email, organization_id, tariff_id FROM users WHERE id = $1`
+	query := `SELECT id, name, email, organization_id, tariff_id, status, email_verified, pending_email,
+	          grant_version, kind
+	          FROM users WHERE id = $1`
 	row := r.db.GetConnection().QueryRow(ctx, query, id)
 
 	user := &models.User{}
 	var (
-		org    sql.NullInt32
-		tariff sql.NullInt32
+		org          sql.NullInt32
+		tariff       sql.NullInt32
+		pendingEmail sql.NullString
+	)
+	err := row.Scan(
+		&user.ID, &user.Name, &user.Email, &org, &tariff, &user.Status, &user.EmailVerified, &pendingEmail,
+		&user.GrantVersion, &user.Kind,
 	)
-	err := row.Scan(&user.ID, &user.Name, &user.Email, &org, &tariff)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -106,6 +160,11 @@ func (r *userRepository) GetUserByID(ctx context.Context, id int) (*models.User,
 		user.TariffID = utils.Ptr(int(tariff.Int32))
 	}
 
+	// Проверяем, было ли значение NULL
+	if pendingEmail.Valid {
+		user.PendingEmail = &pendingEmail.String
+	}
+
 	return user, nil
 }
 
@@ -113,23 +172,27 @@ func (r *userRepository) GetUserByID(ctx context.Context, id int) (*models.User,
 func (r *userRepository) UpdateUser(ctx context.Context, user *models.User) error {
 	// Если пароль не пустой, обновляем и хеш пароля
 	if user.PasswordHash != "" {
-		query := `UPDATE users SET name = $1, email = $2, password_hash = $3, organization_id = $4 WHERE id = $5`
+		query := `UPDATE users SET name = $1, email = $2, password_hash = $3, organization_id = $4,
+		          grant_version = grant_version + CASE WHEN organization_id IS DISTINCT FROM $4 THEN 1 ELSE 0 END
+		          WHERE id = $5`
 		var orgId sql.NullInt32
 		if user.Organization != nil {
 			orgId = utils.NewNullInt32(int32(user.Organization.ID))
 		}
-		_, err := r.db.GetConnection().Exec(ctx, query, user.Name, user.Email, user.PasswordHash, orgId, user.ID)
+		_, err := r.db.Conn(ctx).Exec(ctx, query, user.Name, user.Email, user.PasswordHash, orgId, user.ID)
 		if err != nil {
 			return fmt.Errorf("failed to update user: %w", err)
 		}
 	} else {
 		// Если пароль не указан, обновляем только остальные поля
-		query := `UPDATE users SET name = $1, email = $2, organization_id = $3 WHERE id = $4`
+		query := `UPDATE users SET name = $1, email = $2, organization_id = $3,
+		          grant_version = grant_version + CASE WHEN organization_id IS DISTINCT FROM $3 THEN 1 ELSE 0 END
+		          WHERE id = $4`
 		var orgId sql.NullInt32
 		if user.Organization != nil {
 			orgId = utils.NewNullInt32(int32(user.Organization.ID))
 		}
-		_, err := r.db.GetConnection().Exec(ctx, query, user.Name, user.Email, orgId, user.ID)
+		_, err := r.db.Conn(ctx).Exec(ctx, query, user.Name, user.Email, orgId, user.ID)
 		if err != nil {
 			return fmt.Errorf("failed to update user: %w", err)
 		}
@@ -147,15 +210,32 @@ func (r *userRepository) DeleteUser(ctx context.Context, id int) error {
 	return nil
 }
 
-// GetUsers получает список пользователей с ограничением и смещением
-func (r *userRepository) GetUsers(ctx context.Context, limit, offset int) ([]*models.User, error) {
+// GetUsers получает список пользователей с ограничением и смещением.
+// Если указан статус, возвращаются только пользователи в этом статусе
+func (r *userRepository) GetUsers(ctx context.Context, limit, offset int, status models.UserStatus) ([]*models.User, error) {
+	return r.getUsers(ctx, limit, offset, status, models.UserKindHuman)
+}
+
+// GetServiceAccounts получает список сервисных аккаунтов с ограничением и смещением
+func (r *userRepository) GetServiceAccounts(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	return r.getUsers(ctx, limit, offset, "", models.UserKindService)
+}
+
+// getUsers получает список учетных записей указанного типа
+func (r *userRepository) getUsers(
+	ctx context.Context,
+	limit, offset int,
+	status models.UserStatus,
+	kind models.UserKind,
+) ([]*models.User, error) {
 	query := `SELECT 
     				u.id, u.name, u.email, u.organization_id,
-    				o.name AS organization
+    				o.name AS organization, u.status, u.kind
 			  FROM users u
 			  LEFT JOIN organizations o ON o.id = u.organization_id
+			  WHERE ($3::text = '' OR u.status = $3::text) AND u.kind = $4
 			  ORDER BY id LIMIT $1 OFFSET $2`
-	rows, err := r.db.GetConnection().Query(ctx, query, limit, offset)
+	rows, err := r.db.GetConnection().Query(ctx, query, limit, offset, string(status), string(kind))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users: %w", err)
 	}
@@ -168,7 +248,7 @@ func (r *userRepository) GetUsers(ctx context.Context, limit, offset int) ([]*mo
 			orgId   sql.NullInt32
 			orgName sql.NullString
 		)
-		err := rows.Scan(&user.ID, &user.Name, &user.Email, &orgId, &orgName)
+		err := rows.Scan(&user.ID, &user.Name, &user.Email, &orgId, &orgName, &user.Status, &user.Kind)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -189,16 +269,116 @@ func (r *userRepository) GetUsers(ctx context.Context, limit, offset int) ([]*mo
 	return users, nil
 }
 
-// GetUserPermissions получает права пользователя
+// SetUserStatus устанавливает статус пользователя.
+// При переходе в любой статус, кроме активного, ранее выпущенные токены становятся недействительными
+func (r *userRepository) SetUserStatus(ctx context.Context, userID int, status models.UserStatus, reason string) error {
+	query := `UPDATE users
+	          SET status = $1,
+	              status_reason = $2,
+	              status_changed_at = now(),
+	              tokens_valid_after = CASE WHEN $1::text = 'active' THEN tokens_valid_after ELSE now() END
+	          WHERE id = $3`
+	tag, err := r.db.GetConnection().Exec(ctx, query, string(status), reason, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set user status: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// GetUserAuthState получает состояние пользователя для проверки токена
+func (r *userRepository) GetUserAuthState(ctx context.Context, userID int) (*models.UserAuthState, error) {
+	query := `SELECT status, tokens_valid_after, grant_version, organization_id FROM users WHERE id = $1`
+
+	state := &models.UserAuthState{}
+	var validAfter sql.NullTime
+	var orgID sql.NullInt32
+	err := r.db.GetConnection().QueryRow(ctx, query, userID).Scan(&state.Status, &validAfter, &state.GrantVersion, &orgID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user auth state: %w", err)
+	}
+
+	// Проверяем, было ли значение NULL
+	if validAfter.Valid {
+		state.TokensValidAfter = &validAfter.Time
+	}
+	if orgID.Valid {
+		state.OrganizationID = utils.Ptr(int(orgID.Int32))
+	}
+
+	return state, nil
+}
+
+// ResetUserPassword устанавливает новый хеш пароля и делает недействительными ранее выпущенные токены
+func (r *userRepository) ResetUserPassword(ctx context.Context, userID int, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $1, tokens_valid_after = now() WHERE id = $2`
+	tag, err := r.db.GetConnection().Exec(ctx, query, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to reset user password: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// SetUserPendingEmail сохраняет новый email, который вступит в силу после подтверждения
+func (r *userRepository) SetUserPendingEmail(ctx context.Context, userID int, email string) error {
+	query := `UPDATE users SET pending_email = $1 WHERE id = $2`
+	tag, err := r.db.GetConnection().Exec(ctx, query, email, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set pending email: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// VerifyUserEmail подтверждает email пользователя. Если это ожидающий подтверждения email,
+// он становится основным. Возвращает false, если email больше не относится к пользователю
+func (r *userRepository) VerifyUserEmail(ctx context.Context, userID int, email string) (bool, error) {
+	query := `UPDATE users
+	          SET email = $1, email_verified = true, pending_email = NULL
+	          WHERE id = $2 AND (email = $1 OR pending_email = $1)`
+	tag, err := r.db.GetConnection().Exec(ctx, query, email, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify user email: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetUserPermissions получает действующие на текущий момент права пользователя, выданные напрямую или через роли.
+// Выдачи, срок которых еще не начался или уже истек, не учитываются, даже если их еще не обработал RunGrantExpiry.
+// ValidUntil права - наиболее поздний срок среди его выдач, пустой, если хотя бы одна выдача бессрочная
 func (r *userRepository) GetUserPermissions(ctx context.Context, userID int) ([]*models.Permission, error) {
-	// TODO Сложно!!
 	query := `
-		SELECT DISTINCT p.id, p.name, p.code, p.description
+		SELECT p.id, p.name, p.code, p.description,
+			CASE WHEN bool_and(g.valid_until IS NOT NULL) THEN max(g.valid_until) END
 		FROM permissions p
-			LEFT JOIN user_permissions up ON p.id = up.permission_id and up.user_id = $1
-			LEFT JOIN user_roles ur ON ur.user_id = $1
-			LEFT JOIN role_permissions rp ON rp.role_id = ur.role_id and p.id = rp.permission_id
-		WHERE ur.user_id = $1 or up.user_id = $1
+			JOIN (
+				SELECT up.permission_id, up.valid_until
+				FROM user_permissions up
+				WHERE up.user_id = $1 AND ` + activeGrant("up") + `
+				UNION ALL
+				SELECT rp.permission_id, ur.valid_until
+				FROM user_roles ur
+					JOIN role_permissions rp ON rp.role_id = ur.role_id
+				WHERE ur.user_id = $1 AND ` + activeGrant("ur") + `
+			) g ON g.permission_id = p.id
+		GROUP BY p.id
 		ORDER BY p.id`
 	rows, err := r.db.GetConnection().Query(ctx, query, userID)
 	if err != nil {
@@ -209,7 +389,44 @@ func (r *userRepository) GetUserPermissions(ctx context.Context, userID int) ([]
 	var permissions []*models.Permission
 	for rows.Next() {
 		permission := &models.Permission{}
-		err := rows.Scan(&permission.ID, &permission.Name, &permission.Code, &permission.Description)
+		err := rows.Scan(
+			&permission.ID, &permission.Name, &permission.Code, &permission.Description, &permission.ValidUntil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate permissions: %w", err)
+	}
+
+	return permissions, nil
+}
+
+// GetUserDirectPermissions получает права, выданные пользователю напрямую (без учета ролей),
+// включая запланированные выдачи
+func (r *userRepository) GetUserDirectPermissions(ctx context.Context, userID int) ([]*models.Permission, error) {
+	query := `
+		SELECT p.id, p.name, p.code, p.description, up.valid_from, up.valid_until
+		FROM permissions p
+		JOIN user_permissions up ON p.id = up.permission_id
+		WHERE up.user_id = $1 AND ` + unexpiredGrant("up") + `
+		ORDER BY p.id`
+	rows, err := r.db.GetConnection().Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user direct permissions: %w", err)
+	}
+	defer rows.Close()
+
+	var permissions []*models.Permission
+	for rows.Next() {
+		permission := &models.Permission{}
+		err := rows.Scan(
+			&permission.ID, &permission.Name, &permission.Code, &permission.Description,
+			&permission.ValidFrom, &permission.ValidUntil,
+		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan permission: %w", err)
 		}
@@ -223,8 +440,13 @@ func (r *userRepository) GetUserPermissions(ctx context.Context, userID int) ([]
 	return permissions, nil
 }
 
-// AddUserPermissions добавляет права пользователю
-func (r *userRepository) AddUserPermissions(ctx context.Context, userID int, permissionIDs []int) error {
+// AddUserPermissions добавляет права пользователю на срок validity
+func (r *userRepository) AddUserPermissions(
+	ctx context.Context,
+	userID int,
+	permissionIDs []int,
+	validity models.GrantValidity,
+) error {
 	// Проверяем, существуют ли все указанные права
 	query := `SELECT COUNT(*) FROM permissions WHERE id = ANY($1)`
 	var count int
@@ -240,13 +462,18 @@ func (r *userRepository) AddUserPermissions(ctx context.Context, userID int, per
 
 	// Добавляем права пользователю
 	for _, permissionID := range permissionIDs {
-		query = `INSERT INTO user_permissions (user_id, permission_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
-		_, err := r.db.GetConnection().Exec(ctx, query, userID, permissionID)
+		query = userPermissionGrants.insert()
+		_, err := r.db.GetConnection().Exec(ctx, query, userID, permissionID, validity.ValidFrom, validity.ValidUntil)
 		if err != nil {
 			return fmt.Errorf("failed to add permission to user: %w", err)
 		}
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersByID, userID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -259,16 +486,21 @@ func (r *userRepository) DeleteUserPermissions(ctx context.Context, userID int,
 		return fmt.Errorf("failed to delete user permissions: %w", err)
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersByID, userID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// GetUserRoles получает роли пользователя
+// GetUserRoles получает роли пользователя, включая запланированные выдачи
 func (r *userRepository) GetUserRoles(ctx context.Context, userID int) ([]*models.Role, error) {
 	query := `
-		SELECT r.id, r.name, r.code, r.description
+		SELECT r.id, r.name, r.code, r.description, ur.valid_from, ur.valid_until
 		FROM roles r
 		JOIN user_roles ur ON r.id = ur.role_id
-		WHERE ur.user_id = $1
+		WHERE ur.user_id = $1 AND ` + unexpiredGrant("ur") + `
 		ORDER BY r.id
 	`
 	rows, err := r.db.GetConnection().Query(ctx, query, userID)
@@ -280,7 +512,7 @@ func (r *userRepository) GetUserRoles(ctx context.Context, userID int) ([]*model
 	var roles []*models.Role
 	for rows.Next() {
 		role := &models.Role{}
-		err := rows.Scan(&role.ID, &role.Name, &role.Code, &role.Description)
+		err := rows.Scan(&role.ID, &role.Name, &role.Code, &role.Description, &role.ValidFrom, &role.ValidUntil)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan role: %w", err)
 		}
@@ -294,12 +526,12 @@ func (r *userRepository) GetUserRoles(ctx context.Context, userID int) ([]*model
 	return roles, nil
 }
 
-// AddUserRoles добавляет роли пользователю
-func (r *userRepository) AddUserRoles(ctx context.Context, userID int, roleIDs []int) error {
+// AddUserRoles добавляет роли пользователю на срок validity
+func (r *userRepository) AddUserRoles(ctx context.Context, userID int, roleIDs []int, validity models.GrantValidity) error {
 	// Проверяем, существуют ли все указанные роли
 	query := `SELECT COUNT(*) FROM roles WHERE id = ANY($1)`
 	var count int
-	err := r.db.GetConnection().QueryRow(ctx, query, roleIDs).Scan(&count)
+	err := r.db.Conn(ctx).QueryRow(ctx, query, roleIDs).Scan(&count)
 	if err != nil {
 		return fmt.Errorf("failed to check roles: %w", err)
 	}
@@ -311,13 +543,18 @@ func (r *userRepository) AddUserRoles(ctx context.Context, userID int, roleIDs [
 
 	// Добавляем роли пользователю
 	for _, roleID := range roleIDs {
-		query = `INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
-		_, err := r.db.GetConnection().Exec(ctx, query, userID, roleID)
+		query = userRoleGrants.insert()
+		_, err := r.db.Conn(ctx).Exec(ctx, query, userID, roleID, validity.ValidFrom, validity.ValidUntil)
 		if err != nil {
 			return fmt.Errorf("failed to add role to user: %w", err)
 		}
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersByID, userID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -330,9 +567,19 @@ func (r *userRepository) DeleteUserRoles(ctx context.Context, userID int, roleID
 		return fmt.Errorf("failed to delete user roles: %w", err)
 	}
 
+	// Права затронутых пользователей изменились
+	if err := bumpGrantVersion(ctx, r.db, usersByID, userID); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// ExpireUserGrants удаляет роли и права пользователей с истекшим сроком и возвращает удаленные выдачи
+func (r *userRepository) ExpireUserGrants(ctx context.Context) ([]*models.ExpiredGrant, error) {
+	return expireGrantTables(ctx, r.db, userRoleGrants, userPermissionGrants)
+}
+
 // SetUserTariff устанавливает тариф пользователю
 func (r *userRepository) SetUserTariff(ctx context.Context, userID int, tariffID *int32) error {
 	var tariffIDVal interface{}
@@ -342,8 +589,8 @@ func (r *userRepository) SetUserTariff(ctx context.Context, userID int, tariffID
 		tariffIDVal = nil
 	}
 
-	query := `UPDATE users SET tariff_id = $1 WHERE id = $2`
-	_, err := r.db.GetConnection().Exec(ctx, query, tariffIDVal, userID)
+	query := `UPDATE users SET tariff_id = $1, grant_version = grant_version + 1 WHERE id = $2`
+	_, err := r.db.Conn(ctx).Exec(ctx, query, tariffIDVal, userID)
 	if err != nil {
 		return fmt.Errorf("failed to set user tariff: %w", err)
 	}
@@ -391,6 +638,23 @@ alter table users
     add IF NOT EXISTS password_hash TEXT;
 alter table users
     add IF NOT EXISTS tariff_id integer;
+alter table users
+    add IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active';
+alter table users
+    add IF NOT EXISTS status_reason TEXT NOT NULL DEFAULT '';
+alter table users
+    add IF NOT EXISTS status_changed_at TIMESTAMPTZ;
+alter table users
+    add IF NOT EXISTS tokens_valid_after TIMESTAMPTZ;
+-- Пользователи, созданные до подтверждения email, считаются подтвержденными
+alter table users
+    add IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT true;
+alter table users
+    add IF NOT EXISTS pending_email VARCHAR(255);
+alter table users
+    add IF NOT EXISTS grant_version BIGINT NOT NULL DEFAULT 0;
+alter table users
+    add IF NOT EXISTS kind VARCHAR(16) NOT NULL DEFAULT 'human';
 
 -- Таблица для связи пользователей и прав
 CREATE TABLE IF NOT EXISTS user_permissions (
@@ -405,6 +669,20 @@ CREATE TABLE IF NOT EXISTS user_roles (
 	role_id INTEGER REFERENCES roles(id) ON DELETE CASCADE,
 	PRIMARY KEY (user_id, role_id)
 );
+
+-- Срок действия выдачи, pending отмечает выдачи, срок которых еще не начался
+alter table user_permissions
+    add IF NOT EXISTS valid_from TIMESTAMPTZ;
+alter table user_permissions
+    add IF NOT EXISTS valid_until TIMESTAMPTZ;
+alter table user_permissions
+    add IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT false;
+alter table user_roles
+    add IF NOT EXISTS valid_from TIMESTAMPTZ;
+alter table user_roles
+    add IF NOT EXISTS valid_until TIMESTAMPTZ;
+alter table user_roles
+    add IF NOT EXISTS pending BOOLEAN NOT NULL DEFAULT false;
 `
 	_, err := r.db.GetConnection().Exec(context.Background(), query)
 	if err != nil {
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testDB подключается к тестовой БД из TEST_DATABASE_URL и инициализирует таблицы пользователей.
+// Без переменной окружения тест пропускается
+func testDB(t *testing.T) *DB {
+	t.Helper()
+
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := NewDB(dbURL)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	for _, repo := range []interface{ InitDB() error }{
+		NewPermissionRepository(db),
+		NewRoleRepository(db),
+		NewUserRepository(db),
+	} {
+		if err := repo.InitDB(); err != nil {
+			t.Fatalf("failed to initialize test database: %v", err)
+		}
+	}
+
+	return db
+}
+
+// TestUserRepository_CheckPassword тестирует прозрачное обновление хеша пароля при входе
+func TestUserRepository_CheckPassword(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	repo := NewUserRepository(db)
+
+	// Пользователь с хешем bcrypt, созданным до перехода на argon2id
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	assert.NoError(t, err)
+
+	var userID int
+	email := fmt.Sprintf("rehash-%d@example.com", time.Now().UnixNano())
+	err = db.GetConnection().QueryRow(ctx,
+		`INSERT INTO users (name, email, password_hash) VALUES ($1, $2, $3) RETURNING id`,
+		"Rehash User", email, string(legacy),
+	).Scan(&userID)
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_, _ = db.GetConnection().Exec(context.Background(), `DELETE FROM users WHERE id = $1`, userID)
+	})
+
+	storedHash := func() string {
+		var hash string
+		err := db.GetConnection().QueryRow(ctx, `SELECT password_hash FROM users WHERE id = $1`, userID).Scan(&hash)
+		assert.NoError(t, err)
+		return hash
+	}
+
+	// Test 1: Неверный пароль не обновляет хеш
+	t.Run("CheckPasswordInvalid", func(t *testing.T) {
+		ok, err := repo.CheckPassword(ctx, userID, "password124")
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, string(legacy), storedHash())
+	})
+
+	// Test 2: Верный пароль заменяет устаревший хеш хешем текущего алгоритма
+	t.Run("CheckPasswordRehash", func(t *testing.T) {
+		ok, err := repo.CheckPassword(ctx, userID, "password123")
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		hash := storedHash()
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$"))
+		assert.False(t, utils.PasswordNeedsRehash(hash))
+		assert.True(t, utils.CheckPassword("password123", hash))
+
+		// Повторный вход проходит с новым хешем и не меняет его
+		ok, err = repo.CheckPassword(ctx, userID, "password123")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, hash, storedHash())
+	})
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+)
+
+const (
+	// APIKeyPrefix префикс API ключей сервисных аккаунтов
+	APIKeyPrefix = "ak_"
+	// PersonalTokenPrefix префикс персональных токенов доступа пользователей
+	PersonalTokenPrefix = "pat_"
+)
+
+// APIKeyProvider источник API ключей сервисных аккаунтов и персональных токенов
+type APIKeyProvider interface {
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	TouchAPIKey(ctx context.Context, id int) error
+}
+
+// extractAPIKey извлекает API ключ из заголовка Authorization: ApiKey ... или X-Api-Key.
+// Персональный токен доступа также принимается в заголовке Authorization: Bearer pat_...
+func extractAPIKey(r *http.Request) string {
+	if key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey "); ok {
+		return strings.TrimSpace(key)
+	}
+
+	if token := extractBearerToken(r); strings.HasPrefix(token, PersonalTokenPrefix) {
+		return token
+	}
+
+	return strings.TrimSpace(r.Header.Get("X-Api-Key"))
+}
+
+// verifyAPIKey проверяет API ключ или персональный токен доступа.
+// Права владельца определяются при каждом запросе, поэтому отзыв права сразу сужает ключ
+func (v *Verifier) verifyAPIKey(ctx context.Context, rawKey string) (*Identity, error) {
+	if v.apiKeys == nil || v.users == nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := v.apiKeys.GetAPIKeyByHash(ctx, utils.HashToken(rawKey))
+	if err != nil || key == nil {
+		return nil, ErrInvalidToken
+	}
+
+	state, err := v.users.GetUserAuthState(ctx, key.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if state.Status != models.UserStatusActive {
+		return nil, ErrUserInactive
+	}
+
+	// Максимальное время жизни сессии организации ограничивает и API ключи, и персональные токены ее участников
+	if err := v.checkSessionLifetime(ctx, state, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	// Права владельца ограничиваются scopes ключа
+	permissions, err := v.permissionCodes(ctx, key.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	codes := make([]string, 0, len(permissions))
+	for _, code := range permissions {
+		if len(key.Scopes) == 0 || containsString(key.Scopes, code) {
+			codes = append(codes, code)
+		}
+	}
+
+	if err := v.apiKeys.TouchAPIKey(ctx, key.ID); err != nil {
+		log.Printf("middleware.auth, failed to touch api key: %v", err)
+	}
+
+	return &Identity{
+		UserID:      key.UserID,
+		Permissions: codes,
+		APIKeyID:    key.ID,
+		State:       state,
+		ExpiresAt:   key.ExpiresAt,
+	}, nil
+}
+
+// APIKeyID возвращает идентификатор API ключа из контекста, если запрос выполнен по API ключу
+func APIKeyID(ctx context.Context) int {
+	id, _ := ctx.Value(APIKeyIDKey).(int)
+	return id
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -6,62 +6,237 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/LiFeAiR/crud-ai/internal/models"
 )
 
 type ctxKey string
 
 var (
-	ErrInvalidToken = errors.New("invalid token")
-
-	UserIDKey  ctxKey = "UserID"
-	ErrorKey   ctxKey = "Error"
-	IsAdminKey ctxKey = "IsAdmin"
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrUserInactive    = errors.New("user is not active")
+	ErrTokenRevoked    = errors.New("token revoked")
+	ErrActorInvalid    = errors.New("impersonating user is no longer allowed")
+	ErrInvalidAudience = errors.New("token issued for another audience")
+	ErrDPoPRequired    = errors.New("token is bound to a key and requires dpop proof")
+	ErrDPoPInvalid     = errors.New("invalid dpop proof")
+	ErrCSRFInvalid     = errors.New("missing or invalid csrf token")
+	ErrIPNotAllowed    = errors.New("client ip is not allowed by organization")
+	ErrSessionExpired  = errors.New("session lifetime exceeded")
+
+	UserIDKey    ctxKey = "UserID"
+	ErrorKey     ctxKey = "Error"
+	IsAdminKey   ctxKey = "IsAdmin"
+	ClientIPKey  ctxKey = "ClientIP"
+	UserAgentKey ctxKey = "UserAgent"
+	SessionIDKey ctxKey = "SessionID"
+	APIKeyIDKey  ctxKey = "APIKeyID"
+	// PermissionsKey действующие коды прав авторизованного пользователя
+	PermissionsKey ctxKey = "Permissions"
+	// ImpersonatorIDKey администратор, действующий от имени пользователя UserIDKey
+	ImpersonatorIDKey ctxKey = "ImpersonatorID"
+	// OrganizationIDKey организация авторизованного пользователя
+	OrganizationIDKey ctxKey = "OrganizationID"
+	// LimitedKey токен без прав, выданный до подтверждения email или настройки 2FA
+	LimitedKey ctxKey = "Limited"
+	// DelegatedKey токен, полученный обменом или выданный OAuth клиенту
+	DelegatedKey ctxKey = "Delegated"
 
 	IsAdmin = "admin"
 )
 
+// UserStateProvider источник актуального состояния и прав пользователя
+type UserStateProvider interface {
+	GetUserAuthState(ctx context.Context, userID int) (*models.UserAuthState, error)
+	GetUserPermissions(ctx context.Context, userID int) ([]*models.Permission, error)
+}
+
+// SessionProvider источник сессий, к которым привязаны токены
+type SessionProvider interface {
+	GetSession(ctx context.Context, id string) (*models.Session, error)
+	TouchSession(ctx context.Context, id string) error
+}
+
+// AuditRecorder журнал аудита, в который записываются запросы по токенам имперсонации
+type AuditRecorder interface {
+	AddAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+}
+
 // New creates new auth middleware.
-func New(
-	appSecret string,
-) func(next http.Handler) http.Handler {
+// Токены, привязанные к ключу клиента, проверяются dpopChecker. Каждый запрос по токену имперсонации
+// записывается в audit, без журнала аудита такие запросы только пишутся в лог
+func New(verifier *Verifier, dpopChecker *DPoPChecker, audit AuditRecorder) func(next http.Handler) http.Handler {
 	const op = "middleware.auth"
 
 	// Возвращаем функцию-обработчик
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Получаем JWT-токен из запроса
+			// Сведения о клиенте нужны и для неавторизованных запросов
+			r = r.WithContext(withClientInfo(r))
+
+			// Сервисные аккаунты и персональные токены передают API ключ вместо JWT-токена
+			if apiKey := extractAPIKey(r); apiKey != "" {
+				identity, err := verifier.verifyAPIKey(r.Context(), apiKey)
+				if err == nil {
+					err = verifier.checkClientIP(r.Context(), identity)
+				}
+				if err != nil {
+					log.Printf("%s, api key rejected: %v", op, err)
+
+					ctx := context.WithValue(r.Context(), ErrorKey, err)
+					next.ServeHTTP(w, r.WithContext(ctx))
+
+					return
+				}
+
+				log.Printf("%s, api key authorized: %v", op, identity.UserID)
+				next.ServeHTTP(w, r.WithContext(identity.withContext(r.Context())))
+
+				return
+			}
+
+			// Получаем JWT-токен из запроса, браузер передает его в cookie сессии
 			tokenStr := extractBearerToken(r)
+			dpopScheme, fromCookie := false, false
+			if tokenStr == "" {
+				tokenStr = extractDPoPToken(r)
+				dpopScheme = tokenStr != ""
+			}
+			if tokenStr == "" {
+				tokenStr = extractCookieToken(r)
+				fromCookie = tokenStr != ""
+			}
 			if tokenStr == "" {
 				// It's ok, if user is not authorized
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			// Парсим и валидируем токен, использая appSecret
-			claims, err := utils.ValidateJWT(tokenStr, appSecret)
+			// Проверяем токен, состояние пользователя, сессию и доказательство владения ключом
+			identity, err := verifier.verifyJWT(r.Context(), tokenStr)
+			if err == nil {
+				err = dpopChecker.check(r, tokenStr, identity.Claims, dpopScheme)
+			}
+			if err == nil && fromCookie {
+				err = verifier.checkCSRF(r, tokenStr)
+			}
+			if err == nil {
+				err = verifier.checkClientIP(r.Context(), identity)
+			}
 			if err != nil {
-				log.Printf("%s, failed to parse token: %v", op, err)
+				log.Printf("%s, token rejected: %v", op, err)
 
 				// But if token is invalid, we shouldn't handle request
-				ctx := context.WithValue(r.Context(), ErrorKey, ErrInvalidToken)
+				ctx := context.WithValue(r.Context(), ErrorKey, err)
 				next.ServeHTTP(w, r.WithContext(ctx))
 
 				return
 			}
 
-			log.Printf("%s, user authorized: %v", op, claims.UserID)
+			if act := identity.Claims.Act; act != nil {
+				log.Printf("%s, user %v impersonated by %v: %s %s",
+					op, identity.UserID, act.UserID, r.Method, r.URL.Path)
+				auditImpersonatedRequest(r, audit, identity.UserID, act.UserID)
+			} else {
+				log.Printf("%s, user authorized: %v", op, identity.UserID)
+			}
 
 			// Полученны данные сохраняем в контекст,
 			// откуда его смогут получить следующие хэндлеры.
-			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-			ctx = context.WithValue(ctx, IsAdminKey, isAdmin(claims.Permissions))
-
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(identity.withContext(r.Context())))
 		})
 	}
 }
 
+// auditImpersonatedRequest записывает в журнал аудита запрос администратора impersonatorID
+// от имени пользователя userID. Ошибка записи не прерывает запрос
+func auditImpersonatedRequest(r *http.Request, audit AuditRecorder, userID, impersonatorID int) {
+	if audit == nil {
+		return
+	}
+
+	entry := &models.AuditEntry{
+		ActorID:        &impersonatorID,
+		UserID:         &userID,
+		ImpersonatorID: &impersonatorID,
+		Action:         "user.impersonated_request",
+		Details:        r.Method + " " + r.URL.Path,
+	}
+	if err := audit.AddAuditEntry(r.Context(), entry); err != nil {
+		log.Printf("middleware.auth, failed to audit impersonated request: %v", err)
+	}
+}
+
+// withContext сохраняет данные проверенного токена в контекст запроса
+func (i *Identity) withContext(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, i.UserID)
+	ctx = context.WithValue(ctx, IsAdminKey, i.IsAdmin())
+	ctx = context.WithValue(ctx, PermissionsKey, i.Permissions)
+	if i.APIKeyID != 0 {
+		ctx = context.WithValue(ctx, APIKeyIDKey, i.APIKeyID)
+	}
+	if i.State != nil && i.State.OrganizationID != nil {
+		ctx = context.WithValue(ctx, OrganizationIDKey, *i.State.OrganizationID)
+	}
+	if i.Claims != nil {
+		ctx = context.WithValue(ctx, SessionIDKey, i.Claims.ID)
+		if i.Claims.Act != nil {
+			ctx = context.WithValue(ctx, ImpersonatorIDKey, i.Claims.Act.UserID)
+		}
+		if i.Claims.Limited {
+			ctx = context.WithValue(ctx, LimitedKey, true)
+		}
+		if i.Claims.Scope != "" || len(i.Claims.Audience) > 0 {
+			ctx = context.WithValue(ctx, DelegatedKey, true)
+		}
+	}
+
+	return ctx
+}
+
+// HasPermission проверяет, есть ли у авторизованного пользователя право с указанным кодом.
+// Администратору доступны все права
+func HasPermission(ctx context.Context, code string) bool {
+	if admin, _ := ctx.Value(IsAdminKey).(bool); admin {
+		return true
+	}
+
+	permissions, _ := ctx.Value(PermissionsKey).([]string)
+	return containsString(permissions, code)
+}
+
+// ImpersonatorID возвращает идентификатор администратора, действующего от имени пользователя.
+// Для обычных запросов возвращается 0
+func ImpersonatorID(ctx context.Context) int {
+	id, _ := ctx.Value(ImpersonatorIDKey).(int)
+	return id
+}
+
+// IsLimited сообщает, выполнен ли запрос по токену без прав
+func IsLimited(ctx context.Context) bool {
+	limited, _ := ctx.Value(LimitedKey).(bool)
+	return limited
+}
+
+// IsDelegated сообщает, выполнен ли запрос по токену, полученному обменом или выданному OAuth клиенту.
+// Такой токен ограничен scope или привязан к audience другого сервиса
+func IsDelegated(ctx context.Context) bool {
+	delegated, _ := ctx.Value(DelegatedKey).(bool)
+	return delegated
+}
+
+// OrganizationID возвращает организацию авторизованного пользователя из контекста.
+// Для пользователей без организации возвращается 0
+func OrganizationID(ctx context.Context) int {
+	id, _ := ctx.Value(OrganizationIDKey).(int)
+	return id
+}
+
+// SessionID возвращает идентификатор сессии текущего токена из контекста
+func SessionID(ctx context.Context) string {
+	sessionID, _ := ctx.Value(SessionIDKey).(string)
+	return sessionID
+}
+
 func isAdmin(permissions []string) bool {
 	for _, permission := range permissions {
 		if permission == IsAdmin {
@@ -0,0 +1,401 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/handlers/mocks"
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// testAudience audience сервиса в тестах
+const testAudience = "crud-ai"
+
+// testSecretKey генерирует RSA ключ в PEM, которым подписываются токены в тестах
+func testSecretKey(t *testing.T) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+}
+
+// testRepos моки источников состояния, которые использует middleware
+type testRepos struct {
+	users    *mocks.MockUserRepository
+	sessions *mocks.MockSessionRepository
+	apiKeys  *mocks.MockAPIKeyRepository
+	orgs     *mocks.MockOrganizationRepository
+}
+
+func (r *testRepos) assertExpectations(t *testing.T) {
+	r.users.AssertExpectations(t)
+	r.sessions.AssertExpectations(t)
+	r.apiKeys.AssertExpectations(t)
+	r.orgs.AssertExpectations(t)
+}
+
+// serve пропускает запрос через middleware и возвращает контекст, с которым был вызван следующий обработчик
+func serve(mw func(http.Handler) http.Handler, r *http.Request) context.Context {
+	var got context.Context
+	mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	return got
+}
+
+// TestNew тестирует проверку токенов и API ключей в auth middleware
+func TestNew(t *testing.T) {
+	secretKey := testSecretKey(t)
+
+	// newToken выпускает токен пользователя 2 с сессией s1
+	newToken := func(t *testing.T, audience ...string) string {
+		claims := &utils.Claims{UserID: 2, Email: "user@example.com"}
+		claims.ID = "s1"
+		claims.Audience = audience
+		token, err := utils.GenerateJWT(secretKey, claims)
+		assert.NoError(t, err)
+		return token
+	}
+
+	activeState := &models.UserAuthState{Status: models.UserStatusActive}
+	orgState := &models.UserAuthState{Status: models.UserStatusActive, OrganizationID: utils.Ptr(7)}
+	permissions := []*models.Permission{{ID: 1, Code: "reports.read"}, {ID: 2, Code: "reports.write"}}
+	// apiKey ключ пользователя 2, ограниченный правом reports.read
+	apiKey := &models.APIKey{ID: 9, UserID: 2, Scopes: []string{"reports.read"}, CreatedAt: time.Now()}
+
+	// validSession ожидаемые обращения к источникам для действующего токена пользователя state
+	validSession := func(state *models.UserAuthState) func(r *testRepos) {
+		return func(r *testRepos) {
+			r.users.On("GetUserAuthState", mock.Anything, 2).Return(state, nil)
+			r.users.On("GetUserPermissions", mock.Anything, 2).Return(permissions, nil)
+			r.sessions.On("GetSession", mock.Anything, "s1").Return(&models.Session{ID: "s1", UserID: 2}, nil)
+			r.sessions.On("TouchSession", mock.Anything, "s1").Return(nil)
+		}
+	}
+
+	// allowedCIDRs настройки организации 7 с разрешенным диапазоном адресов
+	allowedCIDRs := func(r *testRepos) {
+		r.orgs.On("GetOrganizationSettings", mock.Anything, 7).Return(&models.OrganizationSettings{
+			OrganizationID: 7,
+			AllowedCIDRs:   []string{"10.0.0.0/8"},
+		}, nil)
+	}
+
+	tests := []struct {
+		name    string
+		request func(t *testing.T) *http.Request
+		setup   func(r *testRepos)
+		// wantErr ошибка в контексте, nil - запрос авторизован
+		wantErr         error
+		wantPermissions []string
+	}{
+		{
+			name: "ValidToken",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+				r.Header.Set("Authorization", "Bearer "+newToken(t))
+				return r
+			},
+			setup:           validSession(activeState),
+			wantPermissions: []string{"reports.read", "reports.write"},
+		},
+		{
+			name: "RevokedSession",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+				r.Header.Set("Authorization", "Bearer "+newToken(t))
+				return r
+			},
+			setup: func(r *testRepos) {
+				r.users.On("GetUserAuthState", mock.Anything, 2).Return(activeState, nil)
+				r.users.On("GetUserPermissions", mock.Anything, 2).Return(permissions, nil)
+				r.sessions.On("GetSession", mock.Anything, "s1").Return(&models.Session{
+					ID:        "s1",
+					UserID:    2,
+					RevokedAt: utils.Ptr(time.Now()),
+				}, nil)
+			},
+			wantErr: ErrTokenRevoked,
+		},
+		{
+			name: "SuspendedUser",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+				r.Header.Set("Authorization", "Bearer "+newToken(t))
+				return r
+			},
+			setup: func(r *testRepos) {
+				r.users.On("GetUserAuthState", mock.Anything, 2).
+					Return(&models.UserAuthState{Status: models.UserStatusSuspended}, nil)
+			},
+			wantErr: ErrUserInactive,
+		},
+		{
+			name: "ForeignAudience",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+				r.Header.Set("Authorization", "Bearer "+newToken(t, "billing"))
+				return r
+			},
+			setup:   func(r *testRepos) {},
+			wantErr: ErrInvalidAudience,
+		},
+		{
+			name: "OwnAudience",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+				r.Header.Set("Authorization", "Bearer "+newToken(t, "billing", testAudience))
+				return r
+			},
+			setup:           validSession(activeState),
+			wantPermissions: []string{"reports.read", "reports.write"},
+		},
+		{
+			name: "CookieGetWithoutCSRF",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+				r.AddCookie(&http.Cookie{Name: SessionCookie, Value: newToken(t)})
+				return r
+			},
+			setup:           validSession(activeState),
+			wantPermissions: []string{"reports.read", "reports.write"},
+		},
+		{
+			name: "CookiePostWithoutCSRF",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/api/user", nil)
+				r.AddCookie(&http.Cookie{Name: SessionCookie, Value: newToken(t)})
+				return r
+			},
+			setup:   validSession(activeState),
+			wantErr: ErrCSRFInvalid,
+		},
+		{
+			name: "CookiePostWithForeignCSRF",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/api/user", nil)
+				r.AddCookie(&http.Cookie{Name: SessionCookie, Value: newToken(t)})
+				r.Header.Set(CSRFHeader, utils.CSRFToken(secretKey, "another-token"))
+				return r
+			},
+			setup:   validSession(activeState),
+			wantErr: ErrCSRFInvalid,
+		},
+		{
+			name: "CookiePostWithCSRF",
+			request: func(t *testing.T) *http.Request {
+				token := newToken(t)
+				r := httptest.NewRequest(http.MethodPost, "/api/user", nil)
+				r.AddCookie(&http.Cookie{Name: SessionCookie, Value: token})
+				r.Header.Set(CSRFHeader, utils.CSRFToken(secretKey, token))
+				return r
+			},
+			setup:           validSession(activeState),
+			wantPermissions: []string{"reports.read", "reports.write"},
+		},
+		{
+			name: "ClientIPAllowed",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+				r.RemoteAddr = "10.1.2.3:5000"
+				r.Header.Set("Authorization", "Bearer "+newToken(t))
+				return r
+			},
+			setup: func(r *testRepos) {
+				validSession(orgState)(r)
+				allowedCIDRs(r)
+			},
+			wantPermissions: []string{"reports.read", "reports.write"},
+		},
+		{
+			name: "ClientIPNotAllowed",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+				r.RemoteAddr = "192.168.1.10:5000"
+				r.Header.Set("Authorization", "Bearer "+newToken(t))
+				return r
+			},
+			setup: func(r *testRepos) {
+				validSession(orgState)(r)
+				allowedCIDRs(r)
+			},
+			wantErr: ErrIPNotAllowed,
+		},
+		{
+			name: "APIKeyScopes",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+				r.Header.Set("Authorization", "ApiKey "+APIKeyPrefix+"secret")
+				return r
+			},
+			setup: func(r *testRepos) {
+				r.apiKeys.On("GetAPIKeyByHash", mock.Anything, utils.HashToken(APIKeyPrefix+"secret")).Return(apiKey, nil)
+				r.apiKeys.On("TouchAPIKey", mock.Anything, apiKey.ID).Return(nil)
+				r.users.On("GetUserAuthState", mock.Anything, 2).Return(activeState, nil)
+				r.users.On("GetUserPermissions", mock.Anything, 2).Return(permissions, nil)
+			},
+			// Права владельца ограничены scopes ключа
+			wantPermissions: []string{"reports.read"},
+		},
+		{
+			name: "APIKeyClientIPNotAllowed",
+			request: func(t *testing.T) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+				r.RemoteAddr = "192.168.1.10:5000"
+				r.Header.Set("Authorization", "ApiKey "+APIKeyPrefix+"secret")
+				return r
+			},
+			setup: func(r *testRepos) {
+				r.apiKeys.On("GetAPIKeyByHash", mock.Anything, utils.HashToken(APIKeyPrefix+"secret")).Return(apiKey, nil)
+				r.apiKeys.On("TouchAPIKey", mock.Anything, apiKey.ID).Return(nil)
+				r.users.On("GetUserAuthState", mock.Anything, 2).Return(orgState, nil)
+				r.users.On("GetUserPermissions", mock.Anything, 2).Return(permissions, nil)
+				allowedCIDRs(r)
+			},
+			wantErr: ErrIPNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Создаем мок репозиторий
+			repos := &testRepos{
+				users:    new(mocks.MockUserRepository),
+				sessions: new(mocks.MockSessionRepository),
+				apiKeys:  new(mocks.MockAPIKeyRepository),
+				orgs:     new(mocks.MockOrganizationRepository),
+			}
+
+			// Определяем ожидаемое поведение мока
+			tt.setup(repos)
+
+			// Создаем middleware с моком
+			verifier := NewVerifier(secretKey, testAudience, repos.users, repos.sessions, repos.apiKeys, repos.orgs)
+			mw := New(verifier, NewDPoPChecker(time.Minute, "", nil), nil)
+
+			// Выполняем запрос
+			ctx := serve(mw, tt.request(t))
+
+			// Проверяем результат
+			err, _ := ctx.Value(ErrorKey).(error)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, ctx.Value(UserIDKey))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, 2, ctx.Value(UserIDKey))
+				assert.Equal(t, tt.wantPermissions, ctx.Value(PermissionsKey))
+			}
+
+			// Проверяем, что моки были вызваны правильно
+			repos.assertExpectations(t)
+		})
+	}
+}
+
+// TestNew_GrantVersionBump тестирует обновление прав из кеша после изменения версии прав пользователя
+func TestNew_GrantVersionBump(t *testing.T) {
+	secretKey := testSecretKey(t)
+
+	// Создаем мок репозиторий: до изменения прав у пользователя версия 1 и право reports.read,
+	// после - версия 2 и право reports.write
+	users := new(mocks.MockUserRepository)
+	users.On("GetUserAuthState", mock.Anything, 2).
+		Return(&models.UserAuthState{Status: models.UserStatusActive, GrantVersion: 1}, nil).Once()
+	users.On("GetUserPermissions", mock.Anything, 2).
+		Return([]*models.Permission{{ID: 1, Code: "reports.read"}}, nil).Once()
+	users.On("GetUserAuthState", mock.Anything, 2).
+		Return(&models.UserAuthState{Status: models.UserStatusActive, GrantVersion: 2}, nil).Once()
+	users.On("GetUserPermissions", mock.Anything, 2).
+		Return([]*models.Permission{{ID: 2, Code: "reports.write"}}, nil).Once()
+
+	// Создаем middleware с кешем, запись которого не истекает за время теста
+	cache := NewStateCache(users, nil, time.Hour)
+	mw := New(NewVerifier(secretKey, testAudience, cache, nil, nil, nil), nil, nil)
+
+	request := func(t *testing.T, grantVersion int64) context.Context {
+		token, err := utils.GenerateJWT(secretKey, &utils.Claims{UserID: 2, GrantVersion: grantVersion})
+		assert.NoError(t, err)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return serve(mw, r)
+	}
+
+	// Test 1: Права первого запроса берутся из источника и кешируются
+	ctx := request(t, 1)
+	assert.Nil(t, ctx.Value(ErrorKey))
+	assert.Equal(t, []string{"reports.read"}, ctx.Value(PermissionsKey))
+
+	// Test 2: Старый токен в пределах срока кеша получает права из кеша
+	ctx = request(t, 1)
+	assert.Equal(t, []string{"reports.read"}, ctx.Value(PermissionsKey))
+
+	// Test 3: Токен, выпущенный после изменения прав, сбрасывает кеш и получает новые права
+	ctx = request(t, 2)
+	assert.Nil(t, ctx.Value(ErrorKey))
+	assert.Equal(t, []string{"reports.write"}, ctx.Value(PermissionsKey))
+
+	// Test 4: После обновления кеша новые права действуют и для старого токена
+	ctx = request(t, 1)
+	assert.Equal(t, []string{"reports.write"}, ctx.Value(PermissionsKey))
+
+	// Проверяем, что моки были вызваны правильно
+	users.AssertExpectations(t)
+}
+
+// TestStateCache_GetUserPermissions тестирует сброс кеша прав при изменении версии прав
+func TestStateCache_GetUserPermissions(t *testing.T) {
+	ctx := context.Background()
+
+	// Создаем мок репозиторий
+	users := new(mocks.MockUserRepository)
+	users.On("GetUserAuthState", ctx, 2).
+		Return(&models.UserAuthState{Status: models.UserStatusActive, GrantVersion: 1}, nil).Once()
+	users.On("GetUserPermissions", ctx, 2).
+		Return([]*models.Permission{{ID: 1, Code: "reports.read"}}, nil).Once()
+	users.On("GetUserAuthState", ctx, 2).
+		Return(&models.UserAuthState{Status: models.UserStatusActive, GrantVersion: 2}, nil).Once()
+	users.On("GetUserPermissions", ctx, 2).
+		Return([]*models.Permission{{ID: 2, Code: "reports.write"}}, nil).Once()
+
+	cache := NewStateCache(users, nil, time.Hour)
+
+	// Кешируем состояние и права версии 1
+	_, err := cache.GetUserAuthState(ctx, 2)
+	assert.NoError(t, err)
+	permissions, err := cache.GetUserPermissions(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "reports.read", permissions[0].Code)
+
+	// Состояние истекло раньше прав, и источник вернул новую версию прав
+	cache.mu.Lock()
+	delete(cache.states, 2)
+	cache.mu.Unlock()
+	_, err = cache.GetUserAuthState(ctx, 2)
+	assert.NoError(t, err)
+
+	// Права версии 1 больше не используются, хотя запись кеша прав не истекла
+	permissions, err = cache.GetUserPermissions(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "reports.write", permissions[0].Code)
+
+	// Проверяем, что моки были вызваны правильно
+	users.AssertExpectations(t)
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+)
+
+const (
+	// DefaultStateCacheTTL время, в течение которого состояние пользователя берется из кеша
+	DefaultStateCacheTTL = 5 * time.Second
+	// maxStateCacheEntries при превышении этого количества записей истекшие записи удаляются
+	maxStateCacheEntries = 10000
+)
+
+type cachedState struct {
+	state     *models.UserAuthState
+	expiresAt time.Time
+}
+
+type cachedSettings struct {
+	settings  *models.OrganizationSettings
+	expiresAt time.Time
+}
+
+type cachedPermissions struct {
+	permissions []*models.Permission
+	// grantVersion версия прав, для которой получены права
+	grantVersion int64
+	expiresAt    time.Time
+}
+
+// stateInvalidator источник состояния пользователей, который хранит его в кеше
+type stateInvalidator interface {
+	InvalidateUser(userID int)
+}
+
+// StateCache кеширует состояние и права пользователей и настройки организаций,
+// чтобы middleware не обращался к БД на каждый запрос.
+// Изменения статуса, прав и настроек становятся видны не позже чем через ttl
+type StateCache struct {
+	users UserStateProvider
+	orgs  OrganizationSettingsProvider
+	ttl   time.Duration
+
+	mu          sync.Mutex
+	states      map[int]cachedState
+	permissions map[int]cachedPermissions
+	settings    map[int]cachedSettings
+}
+
+// NewStateCache создает кеш поверх источников состояния пользователей и настроек организаций
+func NewStateCache(users UserStateProvider, orgs OrganizationSettingsProvider, ttl time.Duration) *StateCache {
+	return &StateCache{
+		users:       users,
+		orgs:        orgs,
+		ttl:         ttl,
+		states:      make(map[int]cachedState),
+		permissions: make(map[int]cachedPermissions),
+		settings:    make(map[int]cachedSettings),
+	}
+}
+
+// GetUserAuthState возвращает состояние пользователя из кеша или из источника
+func (c *StateCache) GetUserAuthState(ctx context.Context, userID int) (*models.UserAuthState, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.states[userID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.state, nil
+	}
+
+	state, err := c.users.GetUserAuthState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.states) >= maxStateCacheEntries {
+		c.evictExpired(now)
+	}
+	c.states[userID] = cachedState{state: state, expiresAt: now.Add(c.ttl)}
+
+	return state, nil
+}
+
+// InvalidateUser удаляет из кеша состояние и права пользователя
+func (c *StateCache) InvalidateUser(userID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.states, userID)
+	delete(c.permissions, userID)
+}
+
+// GetUserPermissions возвращает права пользователя из кеша или из источника.
+// Права из кеша используются, только если они получены для текущей версии прав пользователя
+// и ни одно из них еще не истекло
+func (c *StateCache) GetUserPermissions(ctx context.Context, userID int) ([]*models.Permission, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	var grantVersion int64
+	if state, ok := c.states[userID]; ok {
+		grantVersion = state.state.GrantVersion
+	}
+	entry, ok := c.permissions[userID]
+	c.mu.Unlock()
+	if ok && entry.grantVersion == grantVersion && now.Before(entry.expiresAt) {
+		return entry.permissions, nil
+	}
+
+	permissions, err := c.users.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.permissions) >= maxStateCacheEntries {
+		c.evictExpired(now)
+	}
+	// Истечение срока выдачи не меняет версию прав, поэтому запись живет не дольше самого раннего срока
+	expiresAt := now.Add(c.ttl)
+	for _, permission := range permissions {
+		if permission.ValidUntil != nil && permission.ValidUntil.Before(expiresAt) {
+			expiresAt = *permission.ValidUntil
+		}
+	}
+	c.permissions[userID] = cachedPermissions{
+		permissions:  permissions,
+		grantVersion: grantVersion,
+		expiresAt:    expiresAt,
+	}
+
+	return permissions, nil
+}
+
+// GetOrganizationSettings возвращает настройки организации из кеша или из источника
+func (c *StateCache) GetOrganizationSettings(ctx context.Context, orgID int) (*models.OrganizationSettings, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.settings[orgID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.settings, nil
+	}
+
+	settings, err := c.orgs.GetOrganizationSettings(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.settings) >= maxStateCacheEntries {
+		c.evictExpired(now)
+	}
+	c.settings[orgID] = cachedSettings{settings: settings, expiresAt: now.Add(c.ttl)}
+
+	return settings, nil
+}
+
+// evictExpired удаляет истекшие записи, вызывается под блокировкой
+func (c *StateCache) evictExpired(now time.Time) {
+	for userID, entry := range c.states {
+		if !now.Before(entry.expiresAt) {
+			delete(c.states, userID)
+		}
+	}
+	for userID, entry := range c.permissions {
+		if !now.Before(entry.expiresAt) {
+			delete(c.permissions, userID)
+		}
+	}
+	for orgID, entry := range c.settings {
+		if !now.Before(entry.expiresAt) {
+			delete(c.settings, orgID)
+		}
+	}
+}
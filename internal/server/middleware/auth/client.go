@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// withClientInfo сохраняет в контекст IP адрес и User-Agent клиента
+func withClientInfo(r *http.Request) context.Context {
+	ctx := context.WithValue(r.Context(), ClientIPKey, clientIP(r))
+	return context.WithValue(ctx, UserAgentKey, r.UserAgent())
+}
+
+// clientIP возвращает IP адрес, с которого пришел запрос
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// ClientIP возвращает IP адрес клиента из контекста
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(ClientIPKey).(string)
+	return ip
+}
+
+// UserAgent возвращает User-Agent клиента из контекста
+func UserAgent(ctx context.Context) string {
+	userAgent, _ := ctx.Value(UserAgentKey).(string)
+	return userAgent
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+)
+
+const (
+	// SessionCookie cookie с токеном доступа браузерной сессии, недоступен скриптам страницы
+	SessionCookie = "session"
+	// CSRFCookie cookie с CSRF токеном, который страница читает и передает в CSRFHeader
+	CSRFCookie = "csrf_token"
+	// CSRFHeader заголовок с CSRF токеном для изменяющих запросов с токеном из cookie
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// extractCookieToken извлекает токен доступа из cookie браузерной сессии
+func extractCookieToken(r *http.Request) string {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// checkCSRF проверяет CSRF токен изменяющего запроса, авторизованного cookie.
+// Токен в заголовке должен совпадать с токеном, выданным для этой сессии,
+// поэтому сторонний сайт не может выполнить запрос от имени пользователя
+func (v *Verifier) checkCSRF(r *http.Request, token string) error {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return nil
+	}
+
+	expected := utils.CSRFToken(v.appSecret, token)
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(CSRFHeader)), []byte(expected)) != 1 {
+		return ErrCSRFInvalid
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/dpop"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+)
+
+// DPoPHeader заголовок с доказательством владения ключом клиента (RFC 9449)
+const DPoPHeader = "DPoP"
+
+// DPoPChecker проверяет DPoP доказательства запросов с токенами, привязанными к ключу клиента
+type DPoPChecker struct {
+	proofs  *dpop.Verifier
+	baseURL string
+}
+
+// NewDPoPChecker создает проверку доказательств, выпущенных не раньше чем maxAge назад.
+// baseURL публичный адрес сервиса, с которым сравнивается htu доказательства.
+// Если он не задан, адрес определяется по заголовку Host запроса.
+// Использованные доказательства хранятся в replay, без него - в памяти процесса
+func NewDPoPChecker(maxAge time.Duration, baseURL string, replay dpop.ReplayCache) *DPoPChecker {
+	return &DPoPChecker{
+		proofs:  dpop.NewVerifier(maxAge, replay),
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// extractDPoPToken извлекает токен из заголовка Authorization: DPoP ...
+func extractDPoPToken(r *http.Request) string {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), DPoPHeader+" ")
+	return strings.TrimSpace(token)
+}
+
+// check проверяет, что способ передачи токена соответствует привязке к ключу.
+// Привязанный токен принимается только в схеме DPoP с доказательством для этого запроса, подписанным тем же ключом.
+// Без проверки доказательств привязанные токены не принимаются
+func (c *DPoPChecker) check(r *http.Request, token string, claims *utils.Claims, dpopScheme bool) error {
+	if claims.Cnf == nil {
+		if dpopScheme {
+			return fmt.Errorf("%w: token is not bound to a key", ErrDPoPInvalid)
+		}
+		return nil
+	}
+
+	if !dpopScheme || c == nil {
+		return ErrDPoPRequired
+	}
+
+	proofs := r.Header.Values(DPoPHeader)
+	if len(proofs) != 1 {
+		return fmt.Errorf("%w: exactly one proof is required", ErrDPoPInvalid)
+	}
+
+	jkt, err := c.proofs.Verify(r.Context(), proofs[0], r.Method, c.requestURL(r), token)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDPoPInvalid, err)
+	}
+
+	if jkt != claims.Cnf.JKT {
+		return fmt.Errorf("%w: proof signed with another key", ErrDPoPInvalid)
+	}
+
+	return nil
+}
+
+// requestURL возвращает адрес запроса без query для сравнения с htu доказательства
+func (c *DPoPChecker) requestURL(r *http.Request) string {
+	if c.baseURL != "" {
+		return c.baseURL + r.URL.Path
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return scheme + "://" + r.Host + r.URL.Path
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/dpop"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDPoPKey генерирует ключ клиента и его открытую часть в формате JWK
+func newDPoPKey(t *testing.T) (*ecdsa.PrivateKey, *dpop.JWK) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	return key, &dpop.JWK{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+// newDPoPProof подписывает доказательство для запроса method к адресу htu с токеном accessToken
+func newDPoPProof(t *testing.T, key *ecdsa.PrivateKey, jwk *dpop.JWK, method, htu, accessToken string) string {
+	t.Helper()
+
+	jti, err := utils.GenerateToken()
+	assert.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"jti": jti,
+		"htm": method,
+		"htu": htu,
+		"iat": time.Now().Unix(),
+		"ath": dpop.AccessTokenHash(accessToken),
+	})
+	token.Header["typ"] = "dpop+jwt"
+	token.Header["jwk"] = jwk
+
+	proof, err := token.SignedString(key)
+	assert.NoError(t, err)
+
+	return proof
+}
+
+// TestDPoPChecker_Check тестирует привязку токенов к ключу клиента (claim cnf.jkt)
+func TestDPoPChecker_Check(t *testing.T) {
+	const (
+		accessToken = "access-token"
+		target      = "https://api.example.com/api/user"
+	)
+
+	key, jwk := newDPoPKey(t)
+	otherKey, otherJWK := newDPoPKey(t)
+	bound := &utils.Claims{Cnf: &utils.Confirmation{JKT: jwk.Thumbprint()}}
+
+	tests := []struct {
+		name string
+		// proof доказательство запроса, пустое - без заголовка DPoP
+		proof      string
+		claims     *utils.Claims
+		dpopScheme bool
+		wantErr    error
+	}{
+		{
+			name:       "BoundTokenWithProof",
+			proof:      newDPoPProof(t, key, jwk, http.MethodGet, target, accessToken),
+			claims:     bound,
+			dpopScheme: true,
+		},
+		{
+			name:       "ProofSignedWithAnotherKey",
+			proof:      newDPoPProof(t, otherKey, otherJWK, http.MethodGet, target, accessToken),
+			claims:     bound,
+			dpopScheme: true,
+			wantErr:    ErrDPoPInvalid,
+		},
+		{
+			name:       "BoundTokenAsBearer",
+			claims:     bound,
+			dpopScheme: false,
+			wantErr:    ErrDPoPRequired,
+		},
+		{
+			name:       "BoundTokenWithoutProof",
+			claims:     bound,
+			dpopScheme: true,
+			wantErr:    ErrDPoPInvalid,
+		},
+		{
+			name:       "UnboundTokenInDPoPScheme",
+			proof:      newDPoPProof(t, key, jwk, http.MethodGet, target, accessToken),
+			claims:     &utils.Claims{},
+			dpopScheme: true,
+			wantErr:    ErrDPoPInvalid,
+		},
+		{
+			name:   "UnboundTokenAsBearer",
+			claims: &utils.Claims{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Создаем проверку с публичным адресом сервиса
+			checker := NewDPoPChecker(time.Minute, "https://api.example.com", nil)
+
+			r := httptest.NewRequest(http.MethodGet, "/api/user", nil)
+			if tt.proof != "" {
+				r.Header.Set(DPoPHeader, tt.proof)
+			}
+
+			// Вызываем проверку
+			err := checker.check(r, accessToken, tt.claims, tt.dpopScheme)
+
+			// Проверяем результат
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+)
+
+// OrganizationSettingsProvider источник настроек безопасности организаций
+type OrganizationSettingsProvider interface {
+	GetOrganizationSettings(ctx context.Context, orgID int) (*models.OrganizationSettings, error)
+}
+
+// organizationSettings возвращает настройки организации пользователя.
+// Для пользователей без организации и без источника настроек возвращается nil
+func (v *Verifier) organizationSettings(ctx context.Context, state *models.UserAuthState) (*models.OrganizationSettings, error) {
+	if v.orgs == nil || state == nil || state.OrganizationID == nil {
+		return nil, nil
+	}
+
+	return v.orgs.GetOrganizationSettings(ctx, *state.OrganizationID)
+}
+
+// checkSessionLifetime проверяет, что токен или API ключ выпущен не раньше максимального времени жизни сессии
+// организации. Ограничение действует и на токены и ключи, выпущенные до его изменения
+func (v *Verifier) checkSessionLifetime(ctx context.Context, state *models.UserAuthState, issuedAt *time.Time) error {
+	settings, err := v.organizationSettings(ctx, state)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if settings == nil || settings.MaxSessionTTL <= 0 || issuedAt == nil {
+		return nil
+	}
+
+	if time.Since(*issuedAt) > settings.MaxSessionTTL {
+		return ErrSessionExpired
+	}
+
+	return nil
+}
+
+// checkClientIP проверяет, что запрос участника организации выполнен с разрешенного адреса
+func (v *Verifier) checkClientIP(ctx context.Context, identity *Identity) error {
+	settings, err := v.organizationSettings(ctx, identity.State)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if settings != nil && !settings.IPAllowed(ClientIP(ctx)) {
+		return ErrIPNotAllowed
+	}
+
+	return nil
+}
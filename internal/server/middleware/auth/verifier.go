@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LiFeAiR/crud-ai/internal/models"
+	"github.com/LiFeAiR/crud-ai/internal/utils"
+)
+
+// Identity результат проверки токена доступа
+type Identity struct {
+	UserID int
+	// Permissions действующие коды прав с учетом ограничений токена
+	Permissions []string
+	// Claims claims JWT токена, для API ключей nil
+	Claims *utils.Claims
+	// APIKeyID идентификатор API ключа, если запрос выполнен по API ключу
+	APIKeyID int
+	// State состояние пользователя, nil без источника состояния
+	State *models.UserAuthState
+	// ExpiresAt срок действия токена, nil для бессрочных ключей
+	ExpiresAt *time.Time
+}
+
+// IsAdmin проверяет, есть ли среди прав право администратора
+func (i *Identity) IsAdmin() bool {
+	return isAdmin(i.Permissions)
+}
+
+// Verifier проверяет токены доступа: подпись и срок JWT, статус пользователя, отзыв сессии и актуальность прав.
+// Используется middleware и endpoint'ами, которым нужен авторитетный ответ о токене
+type Verifier struct {
+	appSecret string
+	audience  string
+	users     UserStateProvider
+	sessions  SessionProvider
+	apiKeys   APIKeyProvider
+	orgs      OrganizationSettingsProvider
+}
+
+// NewVerifier создает проверку токенов. Токены с claim aud принимаются, только если в нем указан audience.
+// Без источника сессий, API ключей или настроек организаций соответствующие проверки не выполняются
+func NewVerifier(
+	appSecret, audience string,
+	users UserStateProvider,
+	sessions SessionProvider,
+	apiKeys APIKeyProvider,
+	orgs OrganizationSettingsProvider,
+) *Verifier {
+	return &Verifier{
+		appSecret: appSecret,
+		audience:  audience,
+		users:     users,
+		sessions:  sessions,
+		apiKeys:   apiKeys,
+		orgs:      orgs,
+	}
+}
+
+// VerifyToken проверяет JWT токен, API ключ или персональный токен доступа
+func (v *Verifier) VerifyToken(ctx context.Context, token string) (*Identity, error) {
+	if strings.HasPrefix(token, APIKeyPrefix) || strings.HasPrefix(token, PersonalTokenPrefix) {
+		return v.verifyAPIKey(ctx, token)
+	}
+
+	return v.verifyJWT(ctx, token)
+}
+
+// verifyJWT проверяет подпись и срок JWT токена, состояние пользователя и сессию токена
+func (v *Verifier) verifyJWT(ctx context.Context, token string) (*Identity, error) {
+	claims, err := utils.ValidateJWT(token, v.appSecret)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Токен, выпущенный для другого сервиса, здесь недействителен
+	if len(claims.Audience) > 0 && !containsString(claims.Audience, v.audience) {
+		return nil, ErrInvalidAudience
+	}
+
+	identity := &Identity{UserID: claims.UserID, Claims: claims}
+	if claims.ExpiresAt != nil {
+		identity.ExpiresAt = &claims.ExpiresAt.Time
+	}
+
+	// Проверяем, что пользователь активен и токен не был отозван
+	identity.State, identity.Permissions, err = v.checkUserState(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// Организация может ограничивать время жизни сессий своих участников
+	var issuedAt *time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = &claims.IssuedAt.Time
+	}
+	if err := v.checkSessionLifetime(ctx, identity.State, issuedAt); err != nil {
+		return nil, err
+	}
+
+	// Токен без прав остается без прав, даже если права пользователя изменились после его выпуска
+	if claims.Limited {
+		identity.Permissions = []string{}
+	}
+
+	// Токен, полученный обменом или выданный OAuth клиенту, ограничен своим scope и после изменения прав пользователя
+	if claims.Scope != "" {
+		scope := strings.Fields(claims.Scope)
+		permissions := make([]string, 0, len(scope))
+		for _, code := range identity.Permissions {
+			if containsString(scope, code) {
+				permissions = append(permissions, code)
+			}
+		}
+		identity.Permissions = permissions
+	}
+
+	// Проверяем, что сессия токена не была отозвана
+	if err := v.checkSession(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	// Токен имперсонации действует, пока администратор активен и сохраняет право admin
+	if claims.Act != nil {
+		if err := v.checkActor(ctx, claims.Act); err != nil {
+			return nil, err
+		}
+	}
+
+	return identity, nil
+}
+
+// checkUserState проверяет статус пользователя и момент, после которого выпущенные токены действительны,
+// и возвращает действующие права. Права из токена не используются: выдачи в нем могли истечь после выпуска
+// без изменения версии прав, поэтому права определяются заново на текущий момент. Ограничения токена применяет verifyJWT
+func (v *Verifier) checkUserState(ctx context.Context, claims *utils.Claims) (*models.UserAuthState, []string, error) {
+	if v.users == nil {
+		return nil, claims.Permissions, nil
+	}
+
+	state, err := v.users.GetUserAuthState(ctx, claims.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	// Токен выпущен с более новой версией прав, чем в кеше: права изменились после кеширования,
+	// поэтому состояние и права перечитываются из источника, не дожидаясь истечения кеша
+	if claims.GrantVersion > state.GrantVersion {
+		if cache, ok := v.users.(stateInvalidator); ok {
+			cache.InvalidateUser(claims.UserID)
+			if state, err = v.users.GetUserAuthState(ctx, claims.UserID); err != nil {
+				return nil, nil, ErrInvalidToken
+			}
+		}
+	}
+
+	if state.Status != models.UserStatusActive {
+		return nil, nil, ErrUserInactive
+	}
+
+	// iat хранится с точностью до секунды
+	if state.TokensValidAfter != nil && claims.IssuedAt != nil &&
+		claims.IssuedAt.Time.Before(state.TokensValidAfter.Truncate(time.Second)) {
+		return nil, nil, ErrTokenRevoked
+	}
+
+	permissions, err := v.permissionCodes(ctx, claims.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+
+	return state, permissions, nil
+}
+
+// checkSession проверяет, что сессия токена существует, принадлежит пользователю и не отозвана.
+// Состояние сессий хранится в БД, поэтому отзыв действует на всех репликах сервера
+func (v *Verifier) checkSession(ctx context.Context, claims *utils.Claims) error {
+	if v.sessions == nil {
+		return nil
+	}
+
+	// Токены без jti не привязаны к сессии
+	if claims.ID == "" {
+		return ErrTokenRevoked
+	}
+
+	session, err := v.sessions.GetSession(ctx, claims.ID)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	if session == nil || session.UserID != claims.UserID || session.RevokedAt != nil {
+		return ErrTokenRevoked
+	}
+
+	if err := v.sessions.TouchSession(ctx, session.ID); err != nil {
+		log.Printf("middleware.auth, failed to touch session: %v", err)
+	}
+
+	return nil
+}
+
+// checkActor проверяет, что администратор, действующий от имени пользователя, активен и сохраняет право admin
+func (v *Verifier) checkActor(ctx context.Context, actor *utils.Actor) error {
+	if v.users == nil {
+		return nil
+	}
+
+	state, err := v.users.GetUserAuthState(ctx, actor.UserID)
+	if err != nil || state.Status != models.UserStatusActive {
+		return ErrActorInvalid
+	}
+
+	permissions, err := v.permissionCodes(ctx, actor.UserID)
+	if err != nil || !isAdmin(permissions) {
+		return ErrActorInvalid
+	}
+
+	return nil
+}
+
+// permissionCodes получает текущие коды прав пользователя
+func (v *Verifier) permissionCodes(ctx context.Context, userID int) ([]string, error) {
+	permissions, err := v.users.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		codes = append(codes, permission.Code)
+	}
+
+	return codes, nil
+}
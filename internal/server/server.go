@@ -2,10 +2,12 @@ package server
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/LiFeAiR/crud-ai/internal/handlers"
+	"github.com/LiFeAiR/crud-ai/internal/notifier"
 	"github.com/LiFeAiR/crud-ai/internal/repository"
 	"github.com/LiFeAiR/crud-ai/internal/server/middleware/auth"
 	gw "github.com/LiFeAiR/crud-ai/pkg/server/grpc"
@@ -22,17 +24,21 @@ type Server struct {
 	portProm    string
 	connStr     string
 	secretKey   string
+	cfg         handlers.Config
+	notifier    notifier.Notifier
 	db          *repository.DB
 	baseHandler *handlers.BaseHandler
 }
 
 // NewServer создает новый экземпляр сервера
-func NewServer(portHttp, portProm string, connStr, secretKey string) *Server {
+func NewServer(portHttp, portProm string, connStr, secretKey string, cfg handlers.Config, n notifier.Notifier) *Server {
 	return &Server{
 		portHTTP:  portHttp,
 		portProm:  portProm,
 		connStr:   connStr,
 		secretKey: secretKey,
+		cfg:       cfg,
+		notifier:  n,
 	}
 }
 
@@ -49,7 +55,8 @@ func (s *Server) Close() error {
 	return s.db.Close()
 }
 
-// Start запускает HTTP сервер
+// Start запускает HTTP сервер. При отмене ctx сервер перестает принимать запросы,
+// дожидается завершения фоновых задач обработчиков и закрывает подключение к базе данных
 func (s *Server) Start(ctx context.Context) error {
 	// Подключаемся к базе данных
 	db, err := repository.NewDB(s.connStr)
@@ -64,10 +71,29 @@ func (s *Server) Start(ctx context.Context) error {
 	permRepo := repository.NewPermissionRepository(db)
 	roleRepo := repository.NewRoleRepository(db)
 	tarifRepo := repository.NewTariffRepository(db)
-	baseHandler := handlers.NewBaseHandler(userRepo, orgRepo, permRepo, roleRepo, tarifRepo, s.secretKey)
+	auditRepo := repository.NewAuditRepository(db)
+	attemptRepo := repository.NewLoginAttemptRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	twoFARepo := repository.NewTwoFactorRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	oidcRepo := repository.NewOIDCRepository(db)
+	oauthRepo := repository.NewOAuthRepository(db)
+	accessRequestRepo := repository.NewAccessRequestRepository(db)
+	accessReviewRepo := repository.NewAccessReviewRepository(db)
+	sodRepo := repository.NewSoDRepository(db)
+	dpopRepo := repository.NewDPoPRepository(db)
+	baseHandler := handlers.NewBaseHandler(
+		userRepo, orgRepo, permRepo, roleRepo, tarifRepo, auditRepo, attemptRepo, tokenRepo, twoFARepo, sessionRepo,
+		apiKeyRepo, oidcRepo, oauthRepo, accessRequestRepo, accessReviewRepo, sodRepo, db, s.notifier,
+		s.secretKey, s.cfg,
+	)
 	s.baseHandler = baseHandler
 	defer s.Close()
 
+	// Удаляем выдачи ролей и прав с истекшим сроком
+	go baseHandler.RunGrantExpiry(ctx, s.cfg.Grants.ExpiryInterval)
+
 	grpcServer := grpc.NewServer(
 		grpc.StreamInterceptor(grpc_prometheus.StreamServerInterceptor),
 		grpc.UnaryInterceptor(grpc_prometheus.UnaryServerInterceptor),
@@ -75,7 +101,11 @@ func (s *Server) Start(ctx context.Context) error {
 
 	gw.RegisterCrudServiceServer(grpcServer, s.BaseHandler())
 
-	var group errgroup.Group
+	apiServer := &http.Server{Addr: ":" + s.portHTTP}
+	promServer := &http.Server{Addr: ":" + s.portProm, Handler: promhttp.Handler()}
+
+	// Ошибка одного из серверов останавливает остальные
+	group, groupCtx := errgroup.WithContext(ctx)
 
 	//lis, err := net.Listen("tcp", "localhost:8081")
 	//if err != nil {
@@ -86,7 +116,10 @@ func (s *Server) Start(ctx context.Context) error {
 	//	return grpcServer.Serve(lis)
 	//})
 
-	mux := runtime.NewServeMux(runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{OrigName: true, EmitDefaults: true}))
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{OrigName: true, EmitDefaults: true}),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+	)
 
 	group.Go(func() error {
 		err := gw.RegisterCrudServiceHandlerServer(ctx, mux, s.BaseHandler())
@@ -96,15 +129,63 @@ func (s *Server) Start(ctx context.Context) error {
 
 		log.Printf("CrudService Listening on :%s...", s.portHTTP)
 
+		// OAuth endpoint'ы принимают form-encoded запросы, а вход через OpenID Connect отвечает
+		// перенаправлениями, поэтому они обслуживаются вне gateway
+		root := http.NewServeMux()
+		root.HandleFunc("/oauth/introspect", s.baseHandler.Introspect)
+		root.HandleFunc("/oauth/token", s.baseHandler.Token)
+		root.HandleFunc("/oauth/authorize", s.baseHandler.Authorize)
+		root.HandleFunc("GET /oidc/{organization_id}/login", s.baseHandler.OIDCLogin)
+		root.HandleFunc("GET /oidc/{organization_id}/callback", s.baseHandler.OIDCCallback)
+		root.Handle("/", mux)
+
 		// apply middlewares
-		mw := auth.New(s.secretKey)(mux)
-		return http.ListenAndServe(":"+s.portHTTP, mw)
+		cache := auth.NewStateCache(userRepo, orgRepo, auth.DefaultStateCacheTTL)
+		verifier := auth.NewVerifier(s.secretKey, s.cfg.OAuth.Audience, cache, sessionRepo, apiKeyRepo, cache)
+		dpopChecker := auth.NewDPoPChecker(s.cfg.DPoP.ProofMaxAge, s.cfg.DPoP.BaseURL, dpopRepo)
+		apiServer.Handler = auth.New(verifier, dpopChecker, auditRepo)(root)
+		return listenAndServe(apiServer)
 	})
 
 	group.Go(func() error {
 		log.Printf("Promhttp Handler Listening on :%s...", s.portProm)
-		return http.ListenAndServe(":"+s.portProm, promhttp.Handler())
+		return listenAndServe(promServer)
+	})
+
+	group.Go(func() error {
+		<-groupCtx.Done()
+		log.Println("Shutting down...")
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.cfg.Background.ShutdownTimeout)
+		defer cancel()
+
+		// Сначала перестаем принимать запросы, чтобы не появлялись новые фоновые задачи
+		err := errors.Join(apiServer.Shutdown(shutdownCtx), promServer.Shutdown(shutdownCtx))
+		if bgErr := baseHandler.Shutdown(shutdownCtx); bgErr != nil {
+			log.Printf("Background tasks were not finished before shutdown, err:%v\n", bgErr)
+		}
+
+		return err
 	})
 
 	return group.Wait()
 }
+
+// listenAndServe запускает HTTP сервер. Остановка через Shutdown не считается ошибкой
+func listenAndServe(srv *http.Server) error {
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// outgoingHeaderMatcher передает cookie, установленные обработчиками, в заголовке Set-Cookie.
+// Остальные метаданные ответа передаются с префиксом Grpc-Metadata-, как в gateway по умолчанию
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if key == handlers.SetCookieMetadata {
+		return "Set-Cookie", true
+	}
+
+	return runtime.MetadataHeaderPrefix + key, true
+}
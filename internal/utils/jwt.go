@@ -16,27 +16,49 @@ type Claims struct {
 	Email       string   `json:"email"`
 	Name        string   `json:"name"`
 	Permissions []string `json:"permissions"`
+	// GrantVersion версия прав пользователя на момент выпуска токена. Если она новее версии
+	// в кеше состояния, проверка токена перечитывает состояние и права пользователя
+	GrantVersion int64 `json:"grant_version"`
+	// Act администратор, действующий от имени пользователя
+	Act *Actor `json:"act,omitempty"`
+	// Scope коды прав через пробел, которыми ограничен токен, полученный обменом.
+	// Пустой scope не ограничивает права пользователя
+	Scope string `json:"scope,omitempty"`
+	// Limited токен выпущен без прав, например, до подтверждения email или подключения 2FA.
+	// Права такого токена не определяются заново после изменения прав пользователя
+	Limited bool `json:"limited,omitempty"`
+	// Cnf ключ клиента, к которому привязан токен (RFC 9449). Такой токен принимается только с DPoP доказательством
+	Cnf *Confirmation `json:"cnf,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT генерирует JWT токен для пользователя
-func GenerateJWT(secretKey string, userID int, email, name string, permissions []string) (string, error) {
-	// Устанавливаем срок действия токена (например, 24 часа)
-	expirationTime := time.Now().Add(24 * time.Hour)
+// Confirmation подтверждение владения ключом (RFC 7800)
+type Confirmation struct {
+	// JKT отпечаток открытого ключа клиента (RFC 7638)
+	JKT string `json:"jkt"`
+}
+
+// Actor пользователь, который фактически выполняет запросы с токеном другого пользователя
+type Actor struct {
+	UserID int `json:"user_id"`
+}
+
+// JWTTTL срок действия JWT токена
+const JWTTTL = 24 * time.Hour
+
+// GenerateJWT генерирует JWT токен для пользователя.
+// Время выпуска заполняется автоматически, срок действия - если он не задан в claims
+func GenerateJWT(secretKey string, claims *Claims) (string, error) {
 	pkey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(secretKey))
 	if err != nil {
 		return "", fmt.Errorf("Error marshaling private key: %w", err)
 	}
 
-	claims := &Claims{
-		UserID:      userID,
-		Email:       email,
-		Name:        name,
-		Permissions: permissions,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+	// Устанавливаем срок действия токена
+	now := time.Now()
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(JWTTTL))
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
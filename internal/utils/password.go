@@ -1,17 +1,208 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword хеширует пароль с использованием bcrypt
+// PasswordAlgorithm алгоритм хеширования паролей
+type PasswordAlgorithm string
+
+const (
+	// PasswordAlgorithmArgon2id argon2id, хеш в формате PHC: $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+	PasswordAlgorithmArgon2id PasswordAlgorithm = "argon2id"
+	// PasswordAlgorithmBcrypt bcrypt, хеш в формате $2a$<cost>$...
+	PasswordAlgorithmBcrypt PasswordAlgorithm = "bcrypt"
+)
+
+// IsValid проверяет, что алгоритм известен
+func (a PasswordAlgorithm) IsValid() bool {
+	switch a {
+	case PasswordAlgorithmArgon2id, PasswordAlgorithmBcrypt:
+		return true
+	}
+	return false
+}
+
+// Argon2Params параметры argon2id
+type Argon2Params struct {
+	// Memory объем памяти в KiB
+	Memory uint32
+	// Time количество проходов
+	Time uint32
+	// Threads степень параллелизма
+	Threads uint8
+	// SaltLength длина соли в байтах
+	SaltLength uint32
+	// KeyLength длина хеша в байтах
+	KeyLength uint32
+}
+
+// PasswordHasher хеширует пароли выбранным алгоритмом и проверяет хеши любого поддерживаемого алгоритма.
+// Алгоритм и параметры сохраняются в самом хеше, поэтому их можно менять без сброса паролей
+type PasswordHasher struct {
+	Algorithm  PasswordAlgorithm
+	Argon2     Argon2Params
+	BcryptCost int
+}
+
+// DefaultPasswordHasher возвращает настройки хеширования паролей по умолчанию
+func DefaultPasswordHasher() PasswordHasher {
+	return PasswordHasher{
+		Algorithm: PasswordAlgorithmArgon2id,
+		Argon2: Argon2Params{
+			Memory:     64 * 1024,
+			Time:       3,
+			Threads:    4,
+			SaltLength: 16,
+			KeyLength:  32,
+		},
+		BcryptCost: bcrypt.DefaultCost,
+	}
+}
+
+var (
+	passwordHasherMu sync.RWMutex
+	passwordHasher   = DefaultPasswordHasher()
+	// dummyPasswordHash хеш постороннего пароля с текущими настройками, вычисляется при первой проверке
+	dummyPasswordHash string
+)
+
+// SetPasswordHasher задает настройки, которые используют HashPassword, CheckPassword и PasswordNeedsRehash
+func SetPasswordHasher(h PasswordHasher) {
+	passwordHasherMu.Lock()
+	defer passwordHasherMu.Unlock()
+	passwordHasher = h
+	dummyPasswordHash = ""
+}
+
+func currentPasswordHasher() PasswordHasher {
+	passwordHasherMu.RLock()
+	defer passwordHasherMu.RUnlock()
+	return passwordHasher
+}
+
+// HashPassword хеширует пароль текущим алгоритмом
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+	return currentPasswordHasher().Hash(password)
 }
 
 // CheckPassword проверяет пароль с хэшем
 func CheckPassword(password, hash string) bool {
+	return currentPasswordHasher().Verify(password, hash)
+}
+
+// CheckDummyPassword проверяет пароль с фиксированным хешем текущего алгоритма, результат не важен.
+// Вызывается, когда пользователь не найден, чтобы время ответа не выдавало существование учетной записи
+func CheckDummyPassword(password string) {
+	currentPasswordHasher().Verify(password, currentDummyHash())
+}
+
+func currentDummyHash() string {
+	passwordHasherMu.Lock()
+	defer passwordHasherMu.Unlock()
+	if dummyPasswordHash == "" {
+		// При ошибке хеш вычисляется заново при следующей проверке
+		dummyPasswordHash, _ = passwordHasher.Hash("dummy password")
+	}
+	return dummyPasswordHash
+}
+
+// PasswordNeedsRehash проверяет, создан ли хеш другим алгоритмом или с другими параметрами
+func PasswordNeedsRehash(hash string) bool {
+	return currentPasswordHasher().NeedsRehash(hash)
+}
+
+// Hash хеширует пароль
+func (h PasswordHasher) Hash(password string) (string, error) {
+	switch h.Algorithm {
+	case PasswordAlgorithmBcrypt:
+		bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		return string(bytes), err
+	case PasswordAlgorithmArgon2id:
+		salt := make([]byte, h.Argon2.SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+
+		p := h.Argon2
+		key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLength)
+		return fmt.Sprintf(
+			"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, p.Memory, p.Time, p.Threads,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	}
+
+	return "", fmt.Errorf("unknown password algorithm: %s", h.Algorithm)
+}
+
+// Verify проверяет пароль с хешем независимо от текущего алгоритма
+func (h PasswordHasher) Verify(password, hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		p, salt, key, err := parseArgon2Hash(hash)
+		if err != nil {
+			return false
+		}
+
+		other := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLength)
+		return subtle.ConstantTimeCompare(key, other) == 1
+	}
+
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
-}
\ No newline at end of file
+}
+
+// NeedsRehash проверяет, отличаются ли алгоритм или параметры хеша от текущих
+func (h PasswordHasher) NeedsRehash(hash string) bool {
+	switch h.Algorithm {
+	case PasswordAlgorithmBcrypt:
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.BcryptCost
+	case PasswordAlgorithmArgon2id:
+		p, salt, _, err := parseArgon2Hash(hash)
+		if err != nil {
+			return true
+		}
+		return p.Memory != h.Argon2.Memory || p.Time != h.Argon2.Time || p.Threads != h.Argon2.Threads ||
+			p.KeyLength != h.Argon2.KeyLength || uint32(len(salt)) != h.Argon2.SaltLength
+	}
+
+	return false
+}
+
+// parseArgon2Hash разбирает хеш argon2id в формате PHC
+func parseArgon2Hash(hash string) (p Argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errors.New("unsupported argon2id version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestPasswordHasher тестирует хеширование и проверку паролей
+func TestPasswordHasher(t *testing.T) {
+	// Test 1: Хеш argon2id проверяется и хранит алгоритм и параметры
+	t.Run("Argon2idRoundTrip", func(t *testing.T) {
+		hasher := DefaultPasswordHasher()
+
+		// Вызываем метод Hash
+		hash, err := hasher.Hash("password123")
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Regexp(t, `^\$argon2id\$v=19\$m=65536,t=3,p=4\$`, hash)
+		assert.True(t, hasher.Verify("password123", hash))
+		assert.False(t, hasher.Verify("password124", hash))
+		assert.False(t, hasher.NeedsRehash(hash))
+
+		// Соль случайная, повторный хеш того же пароля отличается
+		other, err := hasher.Hash("password123")
+		assert.NoError(t, err)
+		assert.NotEqual(t, hash, other)
+	})
+
+	// Test 2: Хеш bcrypt проверяется и хранит стоимость
+	t.Run("BcryptRoundTrip", func(t *testing.T) {
+		hasher := DefaultPasswordHasher()
+		hasher.Algorithm = PasswordAlgorithmBcrypt
+
+		// Вызываем метод Hash
+		hash, err := hasher.Hash("password123")
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Regexp(t, `^\$2a\$10\$`, hash)
+		assert.True(t, hasher.Verify("password123", hash))
+		assert.False(t, hasher.Verify("password124", hash))
+		assert.False(t, hasher.NeedsRehash(hash))
+
+		// Изменение стоимости требует обновления хеша
+		hasher.BcryptCost++
+		assert.True(t, hasher.NeedsRehash(hash))
+	})
+
+	// Test 3: Хеш argon2id в формате PHC разбирается на параметры, соль и ключ
+	t.Run("ParseArgon2Hash", func(t *testing.T) {
+		hash := "$argon2id$v=19$m=65536,t=3,p=4$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+
+		// Вызываем функцию parseArgon2Hash
+		p, salt, key, err := parseArgon2Hash(hash)
+
+		// Проверяем результат
+		assert.NoError(t, err)
+		assert.Equal(t, Argon2Params{Memory: 65536, Time: 3, Threads: 4, SaltLength: 16, KeyLength: 32}, p)
+		assert.Equal(t, []byte("saltsaltsaltsalt"), salt)
+		assert.Len(t, key, 32)
+
+		// Некорректные хеши отклоняются
+		for _, invalid := range []string{
+			"",
+			"$argon2i$v=19$m=65536,t=3,p=4$c2FsdA$a2V5",
+			"$argon2id$v=16$m=65536,t=3,p=4$c2FsdA$a2V5",
+			"$argon2id$v=19$m=x,t=3,p=4$c2FsdA$a2V5",
+			"$argon2id$v=19$m=65536,t=3,p=4$!!!$a2V5",
+			"$argon2id$v=19$m=65536,t=3,p=4$c2FsdA",
+		} {
+			_, _, _, err := parseArgon2Hash(invalid)
+			assert.Error(t, err, invalid)
+		}
+	})
+
+	// Test 4: Хеши bcrypt, созданные до перехода на argon2id, проверяются и подлежат замене
+	t.Run("LegacyBcrypt", func(t *testing.T) {
+		legacy, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+		assert.NoError(t, err)
+
+		// Проверяем результат
+		assert.True(t, CheckPassword("password123", string(legacy)))
+		assert.False(t, CheckPassword("password124", string(legacy)))
+		assert.True(t, PasswordNeedsRehash(string(legacy)))
+	})
+
+	// Test 5: Повышение параметров argon2id требует обновления хеша, старый хеш проверяется
+	t.Run("Argon2idStrongerParams", func(t *testing.T) {
+		hash, err := DefaultPasswordHasher().Hash("password123")
+		assert.NoError(t, err)
+
+		stronger := DefaultPasswordHasher()
+		stronger.Argon2.Time++
+
+		// Проверяем результат
+		assert.True(t, stronger.NeedsRehash(hash))
+		assert.True(t, stronger.Verify("password123", hash))
+	})
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// GenerateToken генерирует случайный токен, пригодный для передачи в URL
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken возвращает хеш токена для хранения в БД
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CSRFToken возвращает CSRF токен для токена доступа, переданного в cookie.
+// Токен вычисляется из секрета приложения, поэтому его не нужно хранить
+func CSRFToken(secret, accessToken string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("csrf:" + accessToken))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}